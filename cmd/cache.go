@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+)
+
+// NewCacheCommand creates the cache command
+func NewCacheCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the on-disk cache of EC2 ENI and security group lookups",
+		Long: `Manage the on-disk cache of EC2 ENI and security group lookups.
+
+Commands looking up pods cache the ENIs found by pod IP and the security groups found by ID,
+per AWS account, region and VPC, for --eni-cache-ttl and --sg-cache-ttl. Use --no-cache to bypass
+the cache for a single run.`,
+	}
+
+	cmd.AddCommand(newCacheClearCommand(streams))
+	return cmd
+}
+
+// newCacheClearCommand creates the cache clear command
+func newCacheClearCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	o := usecase.NewCacheClearOptions(streams)
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Remove every cached ENI and security group",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Dir, _ = cmd.Flags().GetString("ec2-cache-dir")
+
+			return o.Run()
+		},
+	}
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestNewCacheCommand(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+	cmd := NewCacheCommand(streams)
+
+	assert.NotNil(t, cmd)
+	assert.Equal(t, "cache", cmd.Name())
+
+	clear, _, err := cmd.Find([]string{"clear"})
+	assert.NoError(t, err)
+	assert.Equal(t, "clear", clear.Name())
+	assert.Error(t, clear.Args(clear, []string{"extra"}))
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+)
+
+// NewCanReachCommand creates the can-reach command
+func NewCanReachCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	o := usecase.NewCanReachOptions(streams)
+	var port int
+	cmd := &cobra.Command{
+		Use:   "can-reach SRC DST --port N",
+		Short: "Check whether security groups allow traffic from one pod to another",
+		Long: `Check whether security groups allow traffic from one pod to another.
+
+The source pod's egress rules and the destination pod's ingress rules are evaluated for the given
+protocol and port, including CIDR matches against the pod IPs and security group references.
+Pods may be given as NAME or NAMESPACE/NAME.
+
+Exit codes: 0 when the traffic is allowed, 2 when it is denied, 1 on errors.`,
+		Args: cobra.ExactArgs(2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			switch rules.NormalizeProtocol(o.Protocol) {
+			case "tcp", "udp":
+				if !cmd.Flags().Changed("port") {
+					return fmt.Errorf("--port is required for protocol %s", o.Protocol)
+				}
+				if port < 0 || port > 65535 {
+					return fmt.Errorf("invalid port: %d", port)
+				}
+			case "all", "icmp", "icmpv6":
+			default:
+				return fmt.Errorf("invalid protocol: %s, valid protocols are: tcp, udp, icmp, icmpv6, all", o.Protocol)
+			}
+			o.Port = int32(port)
+
+			return validateOutputFormat(o.OutputFormat, validOutputFormats)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Source = args[0]
+			o.Destination = args[1]
+			o.AWSOptions = awsClientOptions(cmd)
+
+			return silenceExitError(cmd, o.Run(cmd.Context()))
+		},
+	}
+
+	cmd.Flags().IntVar(&port, "port", 0, "destination port to check")
+	cmd.Flags().StringVar(&o.Protocol, "protocol", o.Protocol, "protocol to check (tcp|udp|icmp|icmpv6|all)")
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "", "output format (json|json-minimal|yaml|table)")
+	cmd.Flags().StringVar(&o.SnapshotDir, "from-snapshot", "", fromSnapshotUsage)
+	o.ConfigFlags.AddFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestNewCanReachCommand(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	t.Run("flags and args", func(t *testing.T) {
+		cmd := NewCanReachCommand(streams)
+		assert.Equal(t, "can-reach", cmd.Name())
+		assert.NotNil(t, cmd.Flag("port"))
+		assert.NotNil(t, cmd.Flag("protocol"))
+		assert.NotNil(t, cmd.Flag("namespace"))
+		assert.Error(t, cmd.Args(cmd, []string{"a"}))
+		assert.NoError(t, cmd.Args(cmd, []string{"a", "b"}))
+	})
+
+	t.Run("port is required for tcp", func(t *testing.T) {
+		cmd := NewCanReachCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{}))
+		err := cmd.PreRunE(cmd, []string{"a", "b"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "--port is required")
+	})
+
+	t.Run("port is not required for icmp", func(t *testing.T) {
+		cmd := NewCanReachCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--protocol", "icmp"}))
+		assert.NoError(t, cmd.PreRunE(cmd, []string{"a", "b"}))
+	})
+
+	t.Run("invalid protocol", func(t *testing.T) {
+		cmd := NewCanReachCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--protocol", "sctp", "--port", "1"}))
+		assert.Error(t, cmd.PreRunE(cmd, []string{"a", "b"}))
+	})
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+)
+
+// NewDiffCommand creates the diff command
+func NewDiffCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	o := usecase.NewDiffOptions(streams)
+	cmd := &cobra.Command{
+		Use:   "diff OLD NEW",
+		Short: "Show how pod security group mappings changed between two points in time",
+		Long: `Show how pod security group mappings changed between two points in time.
+
+OLD and NEW are either files written by "kubectl sgmap pod -o json" or directories written by
+"kubectl sgmap snapshot". Pods are matched by namespace and name, and added and removed pods,
+ENI and security group membership changes, and rule changes of each security group are reported.
+
+Exit codes: 0 when there are no differences, 2 when differences exist, 1 on errors.`,
+		Args: cobra.ExactArgs(2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(o.OutputFormat, validOutputFormats)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Old = args[0]
+			o.New = args[1]
+
+			return silenceExitError(cmd, o.Run(cmd.Context()))
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "", "output format (json|json-minimal|yaml|table)")
+
+	return cmd
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestNewDiffCommand(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old.json")
+	newFile := filepath.Join(dir, "new.json")
+	assert.NoError(t, os.WriteFile(oldFile, []byte(`[{"podName":"web","namespace":"default","eni":"eni-1"}]`), 0o644))
+	assert.NoError(t, os.WriteFile(newFile, []byte(`[]`), 0o644))
+
+	out := &bytes.Buffer{}
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    out,
+		ErrOut: &bytes.Buffer{},
+	}
+	cmd := NewDiffCommand(streams)
+	assert.Equal(t, "diff", cmd.Name())
+	assert.Error(t, cmd.Args(cmd, []string{oldFile}))
+
+	cmd.SetArgs([]string{oldFile, newFile})
+	cmd.SetOut(out)
+	err := cmd.Execute()
+
+	code, ok := ExitCode(err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, code)
+	assert.True(t, cmd.SilenceErrors)
+	assert.Contains(t, out.String(), "removed")
+}
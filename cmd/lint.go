@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+)
+
+var validLintOutputFormats = map[string]struct{}{
+	"json":         {},
+	"yaml":         {},
+	"table":        {},
+	"json-minimal": {},
+	"sarif":        {},
+	"junit":        {},
+}
+
+// NewLintCommand creates the lint command
+func NewLintCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	o := usecase.NewLintOptions(streams)
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check pod security groups for risky exposure",
+		Long: `Check pod security groups for risky exposure.
+
+Built-in rules:
+  SG001  open-ingress                inbound 0.0.0.0/0 or ::/0 on ports other than 80 and 443 (high)
+  SG002  all-protocols               inbound rules allowing all protocols and ports (medium)
+  SG003  node-level-security-groups  pods relying on the node's security groups (medium)
+  SG004  empty-security-group        security groups without any rules (low)
+  SG005  too-many-security-groups    pods with more than 5 security groups (low)
+
+Rules can be disabled and tuned with a YAML file passed to --config.
+
+-o sarif writes a SARIF 2.1.0 log for code scanning tools and -o junit writes a JUnit XML
+report with one test case per pod and rule for CI test reporters.
+
+Exit codes: 0 when there are no findings, 2 when findings are reported, 1 on errors.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(o.OutputFormat, validLintOutputFormats)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.AWSOptions = awsClientOptions(cmd)
+
+			return silenceExitError(cmd, o.Run(cmd.Context()))
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ConfigFile, "config", "", "path to a YAML file enabling, disabling and tuning lint rules")
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "", "output format (json|json-minimal|yaml|table|sarif|junit)")
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+	cmd.Flags().StringVar(&o.SnapshotDir, "from-snapshot", "", fromSnapshotUsage)
+	o.ConfigFlags.AddFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestNewLintCommand(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+	cmd := NewLintCommand(streams)
+
+	assert.Equal(t, "lint", cmd.Name())
+	assert.NotNil(t, cmd.Flag("config"))
+	assert.NotNil(t, cmd.Flag("from-snapshot"))
+	assert.NotNil(t, cmd.Flag("namespace"))
+	assert.Error(t, cmd.Args(cmd, []string{"extra"}))
+
+	assert.NoError(t, cmd.ParseFlags([]string{"-o", "yaml"}))
+	assert.NoError(t, cmd.PreRunE(cmd, nil))
+	assert.NoError(t, cmd.ParseFlags([]string{"-o", "sarif"}))
+	assert.NoError(t, cmd.PreRunE(cmd, nil))
+	assert.NoError(t, cmd.ParseFlags([]string{"-o", "junit"}))
+	assert.NoError(t, cmd.PreRunE(cmd, nil))
+	assert.NoError(t, cmd.ParseFlags([]string{"-o", "xml"}))
+	assert.Error(t, cmd.PreRunE(cmd, nil))
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+)
+
+// NewLoadBalancerCommand creates the lb command
+func NewLoadBalancerCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	o := usecase.NewLoadBalancerOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "lb",
+		Aliases: []string{"loadbalancer", "loadbalancers"},
+		Short:   "Check that load balancers can reach the pods behind services and ingresses",
+		Long: `Check that load balancers can reach the pods behind services and ingresses.
+
+Services of type LoadBalancer and Ingresses handled by the AWS Load Balancer Controller are matched
+to their ELBv2 load balancers by the hostname in their status. The traffic from each load balancer,
+coming from its security groups and the private IPs of its ENIs, is checked against the ingress rules
+of every target: the ready endpoints of the backend services for IP targets, or the nodes running
+them on the NodePort for instance targets. Each path is reported as OK with the permitting rules, or
+as BLOCKED with the sources no rule permits.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(o.OutputFormat, validOutputFormats)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.AWSOptions = awsClientOptions(cmd)
+
+			return o.Run(cmd.Context())
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "", "output format (json|json-minimal|yaml|table)")
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+	o.ConfigFlags.AddFlags(cmd.Flags())
+
+	return cmd
+}
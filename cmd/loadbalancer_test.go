@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestNewLoadBalancerCommand(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+	cmd := NewLoadBalancerCommand(streams)
+
+	assert.NotNil(t, cmd)
+	assert.Equal(t, "lb", cmd.Name())
+	assert.NotNil(t, cmd.Flag("output"))
+	assert.NotNil(t, cmd.Flag("all-namespaces"))
+	assert.NotNil(t, cmd.Flag("context"))
+	assert.Nil(t, cmd.Flag("from-snapshot"))
+
+	t.Run("rejects arguments", func(t *testing.T) {
+		err := cmd.Args(cmd, []string{"web"})
+		assert.Error(t, err)
+	})
+}
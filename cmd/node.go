@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+)
+
+// NewNodeCommand creates the node command
+func NewNodeCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	o := usecase.NewNodeOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "node [NAME]",
+		Aliases: []string{"nodes", "no"},
+		Short:   "Display the ENIs of nodes, their security groups and the pods using them",
+		Long: `Display the ENIs of nodes, their security groups and the pods using them.
+
+The EC2 instance of each node is resolved from its spec.providerID, and every ENI attached to it
+is listed with its role (primary, secondary or trunk), its security groups, the number of pod IPs
+out of the IPs assigned to it, and the pods whose IP it carries. Branch ENIs of pods with security
+groups of their own are listed under the node with the trunk ENI they are associated with.`,
+		Args: cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(o.OutputFormat, validOutputFormats)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				o.NodeName = args[0]
+			}
+			o.AWSOptions = awsClientOptions(cmd)
+
+			return o.Run(cmd.Context())
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "", "output format (json|json-minimal|yaml|table)")
+	cmd.Flags().StringVar(&o.SnapshotDir, "from-snapshot", "", fromSnapshotUsage)
+	o.ConfigFlags.AddFlags(cmd.Flags())
+
+	return cmd
+}
@@ -2,6 +2,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 
 	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
 )
 
 var (
@@ -20,10 +22,22 @@ var (
 		"table":        {},
 		"json-minimal": {},
 	}
+	validPodOutputFormats = map[string]struct{}{
+		"json":         {},
+		"yaml":         {},
+		"table":        {},
+		"json-minimal": {},
+		"wide":         {},
+		"dot":          {},
+		"mermaid":      {},
+		"graph-json":   {},
+	}
 )
 
 func NewPodCommand(streams *genericclioptions.IOStreams) *cobra.Command {
 	o := usecase.NewPodOptions(streams)
+	var showRules bool
+	var tags []string
 	cmd := &cobra.Command{
 		Use:     "pod [NAME]",
 		Aliases: []string{"pods", "po"},
@@ -43,32 +57,205 @@ func NewPodCommand(streams *genericclioptions.IOStreams) *cobra.Command {
 				}
 			}
 
-			if o.OutputFormat != "" {
-				if _, ok := validOutputFormats[o.OutputFormat]; !ok {
-					formats := make([]string, 0, len(validOutputFormats))
-					for format := range validOutputFormats {
-						formats = append(formats, format)
+			if len(args) > 0 && (o.LabelSelector != "" || o.FieldSelector != "") {
+				return fmt.Errorf("a pod name cannot be combined with --selector or --field-selector")
+			}
+
+			if o.Attachment != "" && o.Attachment != "pod" && o.Attachment != "node" {
+				return fmt.Errorf("invalid attachment: %s, valid attachments are: pod, node", o.Attachment)
+			}
+
+			if len(tags) > 0 {
+				o.Tags = make(map[string]string, len(tags))
+				for _, tag := range tags {
+					key, value, ok := strings.Cut(tag, "=")
+					if !ok || key == "" {
+						return fmt.Errorf("invalid tag: %s, expected key=value", tag)
 					}
-					sort.Strings(formats)
-					return fmt.Errorf("invalid output format: %s, valid formats are: %s", o.OutputFormat, strings.Join(formats, ", "))
+					o.Tags[key] = value
+				}
+			}
+
+			if showRules {
+				switch o.OutputFormat {
+				case "", "table", "wide":
+					o.OutputFormat = "wide"
+				default:
+					return fmt.Errorf("--rules cannot be combined with output format %s", o.OutputFormat)
 				}
 			}
 
-			return nil
+			if err := validatePodFlags(podFlags{PodOptions: o, showRules: showRules, context: cmd.Flags().Changed("context")}); err != nil {
+				return err
+			}
+
+			if o.ShowNetworkPolicies || o.ShowPolicies {
+				return validateOutputFormat(o.OutputFormat, validOutputFormats)
+			}
+
+			return validateOutputFormat(o.OutputFormat, validPodOutputFormats)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
 				o.PodName = args[0]
 			}
+			o.AWSOptions = awsClientOptions(cmd)
 
-			return o.Run(cmd.Context())
+			return silenceExitError(cmd, o.Run(cmd.Context()))
 		},
 	}
 
-	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "", "output format (json|json-minimal|yaml|table)")
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "", "output format (json|json-minimal|yaml|table|wide|dot|mermaid|graph-json)")
+	cmd.Flags().BoolVar(&showRules, "rules", false, "If present, list every inbound and outbound rule of each security group (same as -o wide)")
+	cmd.Flags().BoolVar(&o.ShowPolicies, "policies", false, "If present, show the security groups expected by matching SecurityGroupPolicies next to the ones attached to each pod's ENI")
+	cmd.Flags().BoolVar(&o.ShowNetworkPolicies, "network-policies", false, "If present, show the rules of the NetworkPolicies selecting each pod next to its security group rules, and mark traffic allowed by one layer but blocked by the other")
+	cmd.Flags().BoolVar(&o.ResolvePeers, "resolve-peers", false, "If present, resolve security group peers of rules into their names and the pods and nodes using them, and prefix lists into their names and CIDRs")
+	cmd.Flags().BoolVar(&o.ShowTags, "show-tags", false, "If present, add the tags of the security groups to table and wide output")
+	cmd.Flags().BoolVar(&o.CollapseOwners, "collapse-owners", false, "If present, graph output formats show one node per controller (Deployment, StatefulSet, ...) instead of one per pod")
+	cmd.Flags().BoolVar(&o.CIDRPeers, "cidr-peers", false, "If present, graph output formats include the CIDR peers of security group rules as nodes")
+	cmd.Flags().BoolVarP(&o.Watch, "watch", "w", false, "If present, watch pods and print a row whenever the security group mapping of a pod is added, modified or deleted. Existing pods are printed as ADDED first")
+	cmd.Flags().BoolVar(&o.Strict, "strict", false, fmt.Sprintf("If present, exit with code %d when the EC2 lookup of any pod failed. Pods that could be looked up are still shown", usecase.ExitCodeIncomplete))
+	cmd.Flags().StringSliceVar(&o.Contexts, "contexts", nil, "Comma separated kubeconfig contexts to query concurrently. Results gain a CONTEXT column")
+	cmd.Flags().BoolVar(&o.AllContexts, "all-contexts", false, "If present, query every context of the kubeconfig concurrently. Results gain a CONTEXT column")
+	cmd.Flags().StringVarP(&o.LabelSelector, "selector", "l", "", "Selector (label query) to filter pods on, supports '=', '==', '!=', 'in', 'notin'. (e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringVar(&o.FieldSelector, "field-selector", "", "Selector (field query) to filter pods on, supports '=', '==', and '!='. (e.g. --field-selector spec.nodeName=node-1)")
+	cmd.Flags().StringSliceVar(&o.SecurityGroups, "sg", nil, "Comma separated security group IDs or names. Only pods using at least one of them are shown")
+	cmd.Flags().StringVar(&o.Attachment, "attachment", "", "Only show pods whose security groups are attached at this level (pod|node)")
+	cmd.Flags().StringSliceVar(&o.ENIs, "eni", nil, "Comma separated ENI IDs. Only pods on one of them are shown")
+	cmd.Flags().StringArrayVar(&tags, "tag", nil, "Security group tag as key=value, can be repeated. Only pods using a security group carrying all the tags are shown")
 	cmd.Flags().StringVar(&o.SortField, "sort", "pod", fmt.Sprintf("Specify the field to sort by (%s)", strings.Join(validSortFields, "|")))
 	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+	cmd.Flags().StringVar(&o.SnapshotDir, "from-snapshot", "", fromSnapshotUsage)
 	o.ConfigFlags.AddFlags(cmd.Flags())
 
 	return cmd
 }
+
+// podFlags holds the flags of the pod command that are checked against each other
+type podFlags struct {
+	*usecase.PodOptions
+	// showRules is set by --rules
+	showRules bool
+	// context is set when --context is given
+	context bool
+}
+
+// podFlagConstraint is a combination of flags of the pod command that is rejected with message
+type podFlagConstraint struct {
+	invalid func(f podFlags) bool
+	message string
+}
+
+// podFlagConstraints lists the flag combinations the pod command rejects, in the order they are checked
+var podFlagConstraints = []podFlagConstraint{
+	{
+		invalid: func(f podFlags) bool {
+			return f.ResolvePeers && (f.Watch || f.ShowPolicies || f.ShowNetworkPolicies || len(f.Contexts) > 0 || f.AllContexts)
+		},
+		message: "--resolve-peers cannot be combined with --watch, --policies, --network-policies, --contexts or --all-contexts",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return f.ResolvePeers && f.OutputFormat != "wide" && !output.IsStructuredFormat(f.OutputFormat)
+		},
+		message: "--resolve-peers requires --rules or output format wide, json, json-minimal or yaml",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return f.ShowTags && (f.Watch || f.ShowPolicies || f.ShowNetworkPolicies)
+		},
+		message: "--show-tags cannot be combined with --watch, --policies or --network-policies",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return f.ShowTags && f.OutputFormat != "" && f.OutputFormat != "table" && f.OutputFormat != "wide"
+		},
+		message: "--show-tags requires table or wide output, json, json-minimal and yaml output always include tags",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return (f.CollapseOwners || f.CIDRPeers) && !output.IsGraphFormat(f.OutputFormat)
+		},
+		message: "--collapse-owners and --cidr-peers require output format dot, mermaid or graph-json",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return f.Watch && f.OutputFormat != "" && f.OutputFormat != "table"
+		},
+		message: "--watch only supports table output",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return f.Watch && (f.showRules || f.ShowPolicies || f.ShowNetworkPolicies)
+		},
+		message: "--watch cannot be combined with --rules, --policies or --network-policies",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return f.Watch && (f.SnapshotDir != "" || len(f.Contexts) > 0 || f.AllContexts)
+		},
+		message: "--watch cannot be combined with --from-snapshot, --contexts or --all-contexts",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return f.Watch && (len(f.SecurityGroups) > 0 || f.Attachment != "" || len(f.ENIs) > 0 || len(f.Tags) > 0)
+		},
+		message: "--watch cannot be combined with --sg, --attachment, --eni or --tag",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return len(f.Contexts) > 0 && f.AllContexts
+		},
+		message: "--contexts and --all-contexts cannot be combined",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return (len(f.Contexts) > 0 || f.AllContexts) && (f.context || f.SnapshotDir != "")
+		},
+		message: "--contexts and --all-contexts cannot be combined with --context or --from-snapshot",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return (len(f.Contexts) > 0 || f.AllContexts) && (f.ShowPolicies || f.ShowNetworkPolicies || output.IsGraphFormat(f.OutputFormat))
+		},
+		message: "--contexts and --all-contexts cannot be combined with --policies, --network-policies or graph output formats",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return f.ShowNetworkPolicies && (f.ShowPolicies || f.showRules || f.OutputFormat == "wide")
+		},
+		message: "--network-policies cannot be combined with --policies, --rules or -o wide",
+	},
+	{
+		invalid: func(f podFlags) bool {
+			return f.ShowPolicies && (f.showRules || f.OutputFormat == "wide")
+		},
+		message: "--policies cannot be combined with --rules or -o wide",
+	},
+}
+
+// validatePodFlags returns an error for the first flag combination of podFlagConstraints in the flags
+func validatePodFlags(f podFlags) error {
+	for _, c := range podFlagConstraints {
+		if c.invalid(f) {
+			return errors.New(c.message)
+		}
+	}
+	return nil
+}
+
+// validateOutputFormat returns an error if the format is set and is not one of the valid formats
+func validateOutputFormat(format string, valid map[string]struct{}) error {
+	if format == "" {
+		return nil
+	}
+	if _, ok := valid[format]; !ok {
+		formats := make([]string, 0, len(valid))
+		for f := range valid {
+			formats = append(formats, f)
+		}
+		sort.Strings(formats)
+		return fmt.Errorf("invalid output format: %s, valid formats are: %s", format, strings.Join(formats, ", "))
+	}
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
 )
 
 func TestNewPodCommand(t *testing.T) {
@@ -72,3 +74,345 @@ func TestPodCommand_Help(t *testing.T) {
 	assert.Contains(t, output, "Usage:")
 	assert.Contains(t, output, "pod [NAME] [flags]")
 }
+
+func TestPodCommand_Rules(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	t.Run("rules switches table output to wide", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--rules"}))
+		assert.NoError(t, cmd.PreRunE(cmd, nil))
+		assert.Equal(t, "wide", cmd.Flag("output").Value.String())
+	})
+
+	t.Run("rules cannot be combined with json", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--rules", "-o", "json"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+}
+
+func TestPodCommand_Policies(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	t.Run("policies accepts json", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--policies", "-o", "json"}))
+		assert.NoError(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("policies cannot be combined with rules", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--policies", "--rules"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("network policies accept yaml", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--network-policies", "-o", "yaml"}))
+		assert.NoError(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("network policies cannot be combined with policies or graph formats", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--network-policies", "--policies"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+
+		cmd = NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--network-policies", "-o", "dot"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+}
+
+func TestPodCommand_ResolvePeers(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	t.Run("resolve peers accepts rules and json", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--resolve-peers", "--rules"}))
+		assert.NoError(t, cmd.PreRunE(cmd, nil))
+
+		cmd = NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--resolve-peers", "-o", "json"}))
+		assert.NoError(t, cmd.PreRunE(cmd, nil))
+
+		cmd = NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--resolve-peers", "-o", "yaml"}))
+		assert.NoError(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("resolve peers requires an output showing rules", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--resolve-peers"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+
+		cmd = NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--resolve-peers", "-o", "json", "--policies"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+}
+
+func TestPodCommand_Tags(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	t.Run("tags are parsed into the filter", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--tag", "team=payments", "--tag", "module=web-sg", "--tag", "empty=", "--show-tags", "--rules"}))
+		assert.NoError(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("invalid tags are rejected", func(t *testing.T) {
+		for _, tag := range []string{"team", "=payments"} {
+			cmd := NewPodCommand(streams)
+			assert.NoError(t, cmd.ParseFlags([]string{"--tag", tag}))
+			assert.Error(t, cmd.PreRunE(cmd, nil), tag)
+		}
+	})
+
+	t.Run("show tags requires a table", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--show-tags", "-o", "json"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+
+		cmd = NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--show-tags", "--watch"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("tags cannot be combined with watch", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--tag", "team=payments", "--watch"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+}
+
+func TestPodCommand_Graph(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	t.Run("graph formats accept graph options", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"-o", "mermaid", "--collapse-owners", "--cidr-peers"}))
+		assert.NoError(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("graph options require a graph format", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--collapse-owners"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+}
+
+func TestPodCommand_Contexts(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	t.Run("contexts accept table output", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--contexts", "prod,staging", "-A"}))
+		assert.NoError(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("contexts cannot be combined with all-contexts", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--contexts", "prod", "--all-contexts"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("all-contexts cannot be combined with context", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--all-contexts", "--context", "prod"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("contexts cannot be combined with policies", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--contexts", "prod", "--policies"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+}
+
+func TestPodCommand_Watch(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	t.Run("watch accepts table output", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"-w", "-A"}))
+		assert.NoError(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("watch cannot be combined with json", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"-w", "-o", "json"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("watch cannot be combined with snapshots", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"-w", "--from-snapshot", "dir"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+}
+
+func TestPodCommand_Filters(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	t.Run("selectors and filters are accepted", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"-l", "app=web", "--field-selector", "spec.nodeName=node-1", "--sg", "sg-1,web", "--attachment", "pod", "--eni", "eni-1"}))
+		assert.NoError(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("selector cannot be combined with a pod name", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"-l", "app=web"}))
+		assert.Error(t, cmd.PreRunE(cmd, []string{"web"}))
+	})
+
+	t.Run("attachment must be pod or node", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"--attachment", "branch"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+
+	t.Run("filters cannot be combined with watch", func(t *testing.T) {
+		cmd := NewPodCommand(streams)
+		assert.NoError(t, cmd.ParseFlags([]string{"-w", "--sg", "sg-1"}))
+		assert.Error(t, cmd.PreRunE(cmd, nil))
+	})
+}
+
+func TestValidatePodFlags(t *testing.T) {
+	testCases := []struct {
+		name    string
+		set     func(f *podFlags)
+		wantErr string
+	}{
+		{
+			name: "no flags",
+			set:  func(f *podFlags) {},
+		},
+		{
+			name: "resolve peers with yaml",
+			set:  func(f *podFlags) { f.ResolvePeers, f.OutputFormat = true, "yaml" },
+		},
+		{
+			name:    "resolve peers with contexts",
+			set:     func(f *podFlags) { f.ResolvePeers, f.OutputFormat, f.AllContexts = true, "json", true },
+			wantErr: "--resolve-peers cannot be combined with --watch, --policies, --network-policies, --contexts or --all-contexts",
+		},
+		{
+			name:    "resolve peers with table",
+			set:     func(f *podFlags) { f.ResolvePeers = true },
+			wantErr: "--resolve-peers requires --rules or output format wide, json, json-minimal or yaml",
+		},
+		{
+			name:    "show tags with policies",
+			set:     func(f *podFlags) { f.ShowTags, f.ShowPolicies = true, true },
+			wantErr: "--show-tags cannot be combined with --watch, --policies or --network-policies",
+		},
+		{
+			name:    "show tags with yaml",
+			set:     func(f *podFlags) { f.ShowTags, f.OutputFormat = true, "yaml" },
+			wantErr: "--show-tags requires table or wide output, json, json-minimal and yaml output always include tags",
+		},
+		{
+			name:    "collapse owners with table",
+			set:     func(f *podFlags) { f.CollapseOwners = true },
+			wantErr: "--collapse-owners and --cidr-peers require output format dot, mermaid or graph-json",
+		},
+		{
+			name: "cidr peers with mermaid",
+			set:  func(f *podFlags) { f.CIDRPeers, f.OutputFormat = true, "mermaid" },
+		},
+		{
+			name:    "watch with json",
+			set:     func(f *podFlags) { f.Watch, f.OutputFormat = true, "json" },
+			wantErr: "--watch only supports table output",
+		},
+		{
+			name:    "watch with rules",
+			set:     func(f *podFlags) { f.Watch, f.showRules = true, true },
+			wantErr: "--watch cannot be combined with --rules, --policies or --network-policies",
+		},
+		{
+			name:    "watch with snapshot",
+			set:     func(f *podFlags) { f.Watch, f.SnapshotDir = true, "snapshot" },
+			wantErr: "--watch cannot be combined with --from-snapshot, --contexts or --all-contexts",
+		},
+		{
+			name:    "watch with eni",
+			set:     func(f *podFlags) { f.Watch, f.ENIs = true, []string{"eni-1"} },
+			wantErr: "--watch cannot be combined with --sg, --attachment, --eni or --tag",
+		},
+		{
+			name:    "contexts with all contexts",
+			set:     func(f *podFlags) { f.Contexts, f.AllContexts = []string{"prod"}, true },
+			wantErr: "--contexts and --all-contexts cannot be combined",
+		},
+		{
+			name:    "all contexts with context",
+			set:     func(f *podFlags) { f.AllContexts, f.context = true, true },
+			wantErr: "--contexts and --all-contexts cannot be combined with --context or --from-snapshot",
+		},
+		{
+			name:    "contexts with dot",
+			set:     func(f *podFlags) { f.Contexts, f.OutputFormat = []string{"prod"}, "dot" },
+			wantErr: "--contexts and --all-contexts cannot be combined with --policies, --network-policies or graph output formats",
+		},
+		{
+			name:    "network policies with policies",
+			set:     func(f *podFlags) { f.ShowNetworkPolicies, f.ShowPolicies = true, true },
+			wantErr: "--network-policies cannot be combined with --policies, --rules or -o wide",
+		},
+		{
+			name:    "policies with wide",
+			set:     func(f *podFlags) { f.ShowPolicies, f.OutputFormat = true, "wide" },
+			wantErr: "--policies cannot be combined with --rules or -o wide",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := podFlags{PodOptions: usecase.NewPodOptions(&genericclioptions.IOStreams{})}
+			tc.set(&f)
+
+			err := validatePodFlags(f)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
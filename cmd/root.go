@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
 
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/utils"
 )
 
 var (
@@ -18,12 +25,80 @@ var streams = genericclioptions.IOStreams{
 	ErrOut: os.Stderr,
 }
 
+// requestStats counts the EC2 lookups of the command for --verbose
+var requestStats utils.BatchStats
+
 // Execute executes the root command
 func Execute() error {
 	rootCmd := NewSgmapCommand(&streams)
 	rootCmd.AddCommand(newVersionCommand())
 
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	// Statistics are also printed when the command fails, to tell throttling apart from other errors
+	if verbose, _ := rootCmd.PersistentFlags().GetBool("verbose"); verbose {
+		printRequestStats(streams.ErrOut, &requestStats)
+	}
+	return err
+}
+
+// printRequestStats prints the number of EC2 lookup batches, retries and failures
+func printRequestStats(w io.Writer, stats *utils.BatchStats) {
+	fmt.Fprintf(w, "EC2 lookups: %d batches, %d retries, %d failed\n", stats.Batches.Load(), stats.Retries.Load(), stats.Failures.Load())
+}
+
+// ExitCode returns the exit code requested by a command that completed with a result
+// to be reported through the exit code, and false for any other error
+func ExitCode(err error) (int, bool) {
+	var exitErr *usecase.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code, true
+	}
+	return 0, false
+}
+
+// silenceExitError stops cobra from printing the error and usage when the command reports its
+// result through the exit code, and returns the error unchanged
+func silenceExitError(cmd *cobra.Command, err error) error {
+	var exitErr *usecase.ExitError
+	if errors.As(err, &exitErr) {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+	}
+	return err
+}
+
+// awsClientOptions returns the AWS account, region, cache and request flags of the root command.
+// Flags that are not defined, such as when a subcommand runs on its own, are left empty, which also
+// disables the cache and retries.
+func awsClientOptions(cmd *cobra.Command) aws.ClientOptions {
+	get := func(name string) string {
+		value, _ := cmd.Flags().GetString(name)
+		return value
+	}
+	opts := aws.ClientOptions{
+		Region:          get("region"),
+		Profile:         get("profile"),
+		RoleARN:         get("role-arn"),
+		ExternalID:      get("external-id"),
+		RoleSessionName: get("role-session-name"),
+		EndpointURL:     get("aws-endpoint-url"),
+	}
+	if noCache, _ := cmd.Flags().GetBool("no-cache"); !noCache {
+		opts.Cache.Dir = get("ec2-cache-dir")
+		opts.Cache.ENITTL, _ = cmd.Flags().GetDuration("eni-cache-ttl")
+		opts.Cache.SecurityGroupTTL, _ = cmd.Flags().GetDuration("sg-cache-ttl")
+		if opts.Cache.Enabled() {
+			opts.Cache.Warnings = cmd.ErrOrStderr()
+		}
+	}
+	opts.Requests.Concurrency, _ = cmd.Flags().GetInt("concurrency")
+	opts.Requests.RateLimit, _ = cmd.Flags().GetFloat64("rate-limit")
+	opts.Requests.MaxRetries, _ = cmd.Flags().GetInt("max-retries")
+	opts.Requests.Timeout, _ = cmd.Flags().GetDuration("batch-timeout")
+	if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+		opts.Requests.Stats = &requestStats
+	}
+	return opts
 }
 
 // SetVersionInfo sets the version and revision information
@@ -2,11 +2,14 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
 )
 
 func Test_newVersionCommand(t *testing.T) {
@@ -105,3 +108,12 @@ func TestExecute(t *testing.T) {
 		})
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	code, ok := ExitCode(&usecase.ExitError{Code: 2, Reason: "denied"})
+	assert.True(t, ok)
+	assert.Equal(t, 2, code)
+
+	_, ok = ExitCode(errors.New("boom"))
+	assert.False(t, ok)
+}
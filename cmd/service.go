@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+)
+
+// NewServiceCommand creates the service command
+func NewServiceCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	o := usecase.NewServiceOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "service [NAME]",
+		Aliases: []string{"services", "svc"},
+		Short:   "Display the security groups behind the endpoints of services",
+		Long: `Display the security groups behind the endpoints of services.
+
+The ready endpoints in the EndpointSlices of each service are resolved to their ENI and security
+groups. Each distinct set of security groups is shown with the number of endpoints carrying it, and
+services whose endpoints disagree are flagged as inconsistent. The ingress rules of each set are
+checked against the target ports of the service, and ports no rule permits are reported as DENIED.`,
+		Args: cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(o.OutputFormat, validOutputFormats)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				o.Name = args[0]
+			}
+			o.AWSOptions = awsClientOptions(cmd)
+
+			return o.Run(cmd.Context())
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "", "output format (json|json-minimal|yaml|table)")
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+	cmd.Flags().StringVar(&o.SnapshotDir, "from-snapshot", "", fromSnapshotUsage)
+	o.ConfigFlags.AddFlags(cmd.Flags())
+
+	return cmd
+}
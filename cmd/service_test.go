@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestNewServiceCommand(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+	cmd := NewServiceCommand(streams)
+
+	assert.NotNil(t, cmd)
+	assert.Equal(t, "service", cmd.Name())
+	assert.NotNil(t, cmd.Flag("output"))
+	assert.NotNil(t, cmd.Flag("from-snapshot"))
+	assert.NotNil(t, cmd.Flag("all-namespaces"))
+	assert.NotNil(t, cmd.Flag("context"))
+
+	t.Run("accepts no argument", func(t *testing.T) {
+		err := cmd.Args(cmd, []string{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects more than one argument", func(t *testing.T) {
+		err := cmd.Args(cmd, []string{"web", "api"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+)
+
+// NewSecurityGroupCommand creates the sg command
+func NewSecurityGroupCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	o := usecase.NewSecurityGroupOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "sg SG_ID_OR_NAME [SG_ID_OR_NAME...]",
+		Aliases: []string{"securitygroup", "securitygroups"},
+		Short:   "Display the pods using the given security groups",
+		Long: `Display the pods using the given security groups.
+
+Every ENI attached to the security groups is resolved and its private IPs are mapped back to pods
+across all namespaces. ENIs in the security groups that match no pod are reported as well.
+
+Security group names are only unique within a VPC. A name used in several VPCs is rejected with the
+matching IDs; pass the ID or the name prefixed with its VPC, such as vpc-0123456789abcdef0/default.`,
+		Args: cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(o.OutputFormat, validOutputFormats)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.SecurityGroups = args
+			o.AWSOptions = awsClientOptions(cmd)
+
+			return o.Run(cmd.Context())
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "", "output format (json|json-minimal|yaml|table)")
+	cmd.Flags().StringVar(&o.SnapshotDir, "from-snapshot", "", fromSnapshotUsage)
+	o.ConfigFlags.AddFlags(cmd.Flags())
+
+	return cmd
+}
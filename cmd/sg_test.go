@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestNewSecurityGroupCommand(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+	cmd := NewSecurityGroupCommand(streams)
+
+	assert.NotNil(t, cmd)
+	assert.Equal(t, "sg", cmd.Name())
+	assert.NotNil(t, cmd.Flag("output"))
+	assert.NotNil(t, cmd.Flag("context"))
+
+	t.Run("requires at least one argument", func(t *testing.T) {
+		err := cmd.Args(cmd, []string{})
+		assert.Error(t, err)
+	})
+
+	t.Run("accepts multiple arguments", func(t *testing.T) {
+		err := cmd.Args(cmd, []string{"sg-1", "web"})
+		assert.NoError(t, err)
+	})
+}
@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
 )
 
 // NewSgmapCommand creates the sgmap command
@@ -13,6 +17,36 @@ func NewSgmapCommand(streams *genericclioptions.IOStreams) *cobra.Command {
 		Long:  `A kubectl plugin to display security group information for Kubernetes workloads running on AWS`,
 	}
 
+	cmd.PersistentFlags().String("region", "", "AWS region of the cluster's VPC. Defaults to the region of the EKS cluster endpoint in the kubeconfig, then to the AWS configuration")
+	cmd.PersistentFlags().String("profile", "", "AWS shared configuration profile to use")
+	cmd.PersistentFlags().String("role-arn", "", "ARN of an IAM role to assume, for VPCs in another account")
+	cmd.PersistentFlags().String("external-id", "", "external ID to pass when assuming --role-arn")
+	cmd.PersistentFlags().String("role-session-name", "", fmt.Sprintf("session name to use when assuming --role-arn (default %q)", aws.DefaultRoleSessionName))
+	cmd.PersistentFlags().String("aws-endpoint-url", "", "URL to send EC2 and ELBv2 requests to instead of the AWS endpoints, such as a LocalStack endpoint")
+	cmd.PersistentFlags().Bool("no-cache", false, "If present, always look up ENIs and security groups in EC2 instead of using the on-disk cache")
+	cmd.PersistentFlags().String("ec2-cache-dir", "", "directory of the on-disk cache of EC2 lookups (default is kubectl-sgmap in the user cache directory)")
+	cmd.PersistentFlags().Duration("eni-cache-ttl", aws.DefaultENICacheTTL, "how long ENIs looked up by pod IP are cached, 0 to disable")
+	cmd.PersistentFlags().Duration("sg-cache-ttl", aws.DefaultSecurityGroupCacheTTL, "how long security groups are cached, 0 to disable")
+	cmd.PersistentFlags().Int("concurrency", aws.DefaultConcurrency, "maximum number of EC2 lookups running at the same time")
+	cmd.PersistentFlags().Float64("rate-limit", 0, "maximum number of EC2 lookups started per second, 0 for no limit")
+	cmd.PersistentFlags().Int("max-retries", aws.DefaultMaxRetries, "how many times an EC2 lookup is retried, with exponential backoff, when throttled or timed out")
+	cmd.PersistentFlags().Duration("batch-timeout", aws.DefaultBatchTimeout, "timeout of each attempt of an EC2 lookup, 0 for no timeout")
+	cmd.PersistentFlags().Bool("verbose", false, "If present, print statistics of the EC2 lookups, such as retries, to stderr")
+
 	cmd.AddCommand(NewPodCommand(streams))
+	cmd.AddCommand(NewDeploymentCommand(streams))
+	cmd.AddCommand(NewStatefulSetCommand(streams))
+	cmd.AddCommand(NewDaemonSetCommand(streams))
+	cmd.AddCommand(NewJobCommand(streams))
+	cmd.AddCommand(NewCronJobCommand(streams))
+	cmd.AddCommand(NewSecurityGroupCommand(streams))
+	cmd.AddCommand(NewNodeCommand(streams))
+	cmd.AddCommand(NewServiceCommand(streams))
+	cmd.AddCommand(NewLoadBalancerCommand(streams))
+	cmd.AddCommand(NewCanReachCommand(streams))
+	cmd.AddCommand(NewSnapshotCommand(streams))
+	cmd.AddCommand(NewDiffCommand(streams))
+	cmd.AddCommand(NewLintCommand(streams))
+	cmd.AddCommand(NewCacheCommand(streams))
 	return cmd
 }
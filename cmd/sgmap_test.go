@@ -3,10 +3,15 @@ package cmd
 import (
 	"bytes"
 	"io"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/utils"
 )
 
 func TestNewSgmapCommand(t *testing.T) {
@@ -51,3 +56,85 @@ func TestSgmapCommand_Help(t *testing.T) {
 	assert.Contains(t, output, "Usage:")
 	assert.Contains(t, output, "sgmap [command]")
 }
+
+func TestSgmapCommand_AWSFlags(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+	cmd := NewSgmapCommand(streams)
+	sub, _, err := cmd.Find([]string{"pod"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, sub.ParseFlags([]string{"--region", "eu-west-1", "--role-arn", "arn:aws:iam::123456789012:role/sgmap", "--external-id", "ext", "--aws-endpoint-url", "http://localhost:4566"}))
+	assert.Equal(t, aws.ClientOptions{
+		Region:      "eu-west-1",
+		RoleARN:     "arn:aws:iam::123456789012:role/sgmap",
+		ExternalID:  "ext",
+		EndpointURL: "http://localhost:4566",
+		Cache: aws.CacheOptions{
+			ENITTL:           aws.DefaultENICacheTTL,
+			SecurityGroupTTL: aws.DefaultSecurityGroupCacheTTL,
+			Warnings:         os.Stderr,
+		},
+		Requests: aws.RequestOptions{
+			Concurrency: aws.DefaultConcurrency,
+			MaxRetries:  aws.DefaultMaxRetries,
+			Timeout:     aws.DefaultBatchTimeout,
+		},
+	}, awsClientOptions(sub))
+
+	// Subcommands created on their own have no AWS flags and use the default configuration
+	assert.Equal(t, aws.ClientOptions{}, awsClientOptions(NewPodCommand(streams)))
+}
+
+func TestSgmapCommand_CacheFlags(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	t.Run("cache flags set the cache options", func(t *testing.T) {
+		sub, _, err := NewSgmapCommand(streams).Find([]string{"pod"})
+		assert.NoError(t, err)
+		assert.NoError(t, sub.ParseFlags([]string{"--ec2-cache-dir", "/tmp/sgmap", "--eni-cache-ttl", "30s", "--sg-cache-ttl", "0"}))
+		assert.Equal(t, aws.CacheOptions{Dir: "/tmp/sgmap", ENITTL: 30 * time.Second, Warnings: os.Stderr}, awsClientOptions(sub).Cache)
+	})
+
+	t.Run("no-cache disables the cache", func(t *testing.T) {
+		sub, _, err := NewSgmapCommand(streams).Find([]string{"pod"})
+		assert.NoError(t, err)
+		assert.NoError(t, sub.ParseFlags([]string{"--no-cache"}))
+		assert.False(t, awsClientOptions(sub).Cache.Enabled())
+	})
+}
+
+func TestSgmapCommand_RequestFlags(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	sub, _, err := NewSgmapCommand(streams).Find([]string{"pod"})
+	assert.NoError(t, err)
+	assert.NoError(t, sub.ParseFlags([]string{"--concurrency", "2", "--rate-limit", "10", "--max-retries", "0", "--batch-timeout", "5s", "--verbose"}))
+	assert.Equal(t, aws.RequestOptions{
+		Concurrency: 2,
+		RateLimit:   10,
+		Timeout:     5 * time.Second,
+		Stats:       &requestStats,
+	}, awsClientOptions(sub).Requests)
+}
+
+func TestPrintRequestStats(t *testing.T) {
+	stats := &utils.BatchStats{}
+	stats.Batches.Add(3)
+	stats.Retries.Add(2)
+
+	buf := new(bytes.Buffer)
+	printRequestStats(buf, stats)
+	assert.Equal(t, "EC2 lookups: 3 batches, 2 retries, 0 failed\n", buf.String())
+}
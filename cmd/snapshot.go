@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+)
+
+// fromSnapshotUsage is the help text of the --from-snapshot flag shared by the commands reading pods and security groups
+const fromSnapshotUsage = "Read pods and security groups from a directory saved by the snapshot command instead of the cluster and AWS"
+
+// NewSnapshotCommand creates the snapshot command
+func NewSnapshotCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	o := usecase.NewSnapshotOptions(streams)
+	cmd := &cobra.Command{
+		Use:   "snapshot DIR",
+		Short: "Save the cluster and AWS state needed by sgmap into a directory",
+		Long: `Save the cluster and AWS state needed by sgmap into a directory.
+
+Pods, workload controllers, service accounts and SecurityGroupPolicies are saved from all namespaces,
+together with every network interface and security group visible to the AWS credentials. The
+directory can then be passed to --from-snapshot to reproduce results without cluster or AWS access.
+
+The files use the same format as "kubectl get <resource> -A -o json",
+"aws ec2 describe-network-interfaces" and "aws ec2 describe-security-groups", so a snapshot can
+also be assembled by hand.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Dir = args[0]
+			o.AWSOptions = awsClientOptions(cmd)
+
+			return o.Run(cmd.Context())
+		},
+	}
+
+	o.ConfigFlags.AddFlags(cmd.Flags())
+
+	return cmd
+}
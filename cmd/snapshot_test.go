@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestNewSnapshotCommand(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+	cmd := NewSnapshotCommand(streams)
+
+	assert.NotNil(t, cmd)
+	assert.Equal(t, "snapshot", cmd.Name())
+	assert.NotNil(t, cmd.Flag("context"))
+	assert.Error(t, cmd.Args(cmd, []string{}))
+	assert.NoError(t, cmd.Args(cmd, []string{"./snapshot"}))
+
+	t.Run("commands reading pods accept --from-snapshot", func(t *testing.T) {
+		assert.NotNil(t, NewPodCommand(streams).Flag("from-snapshot"))
+		assert.NotNil(t, NewDeploymentCommand(streams).Flag("from-snapshot"))
+		assert.NotNil(t, NewSecurityGroupCommand(streams).Flag("from-snapshot"))
+		assert.NotNil(t, NewCanReachCommand(streams).Flag("from-snapshot"))
+	})
+}
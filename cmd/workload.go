@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/internal/usecase"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/workload"
+)
+
+// NewDeploymentCommand creates the deployment command
+func NewDeploymentCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	return newWorkloadCommand(streams, workload.KindDeployment, "deployment", []string{"deployments", "deploy"})
+}
+
+// NewStatefulSetCommand creates the statefulset command
+func NewStatefulSetCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	return newWorkloadCommand(streams, workload.KindStatefulSet, "statefulset", []string{"statefulsets", "sts"})
+}
+
+// NewDaemonSetCommand creates the daemonset command
+func NewDaemonSetCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	return newWorkloadCommand(streams, workload.KindDaemonSet, "daemonset", []string{"daemonsets", "ds"})
+}
+
+// NewJobCommand creates the job command
+func NewJobCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	return newWorkloadCommand(streams, workload.KindJob, "job", []string{"jobs"})
+}
+
+// NewCronJobCommand creates the cronjob command
+func NewCronJobCommand(streams *genericclioptions.IOStreams) *cobra.Command {
+	return newWorkloadCommand(streams, workload.KindCronJob, "cronjob", []string{"cronjobs", "cj"})
+}
+
+// newWorkloadCommand creates a command that groups pod security groups by the given controller kind
+func newWorkloadCommand(streams *genericclioptions.IOStreams, kind, resource string, aliases []string) *cobra.Command {
+	o := usecase.NewWorkloadOptions(streams, kind)
+	cmd := &cobra.Command{
+		Use:     resource + " [NAME]",
+		Aliases: aliases,
+		Short:   fmt.Sprintf("Display security group information grouped by %s", resource),
+		Long: fmt.Sprintf(`Display security group information grouped by %s.
+
+Pods are resolved to their top-level controller through owner references. Each distinct set of
+security groups is shown with the number of replicas carrying it, and controllers whose replicas
+disagree are flagged as inconsistent.`, resource),
+		Args: cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(o.OutputFormat, validOutputFormats)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				o.Name = args[0]
+			}
+			o.AWSOptions = awsClientOptions(cmd)
+
+			return o.Run(cmd.Context())
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "", "output format (json|json-minimal|yaml|table)")
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+	cmd.Flags().StringVar(&o.SnapshotDir, "from-snapshot", "", fromSnapshotUsage)
+	o.ConfigFlags.AddFlags(cmd.Flags())
+
+	return cmd
+}
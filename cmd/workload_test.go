@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestWorkloadCommands(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+
+	testCases := []struct {
+		name    string
+		cmd     *cobra.Command
+		use     string
+		aliases []string
+	}{
+		{"deployment", NewDeploymentCommand(streams), "deployment [NAME]", []string{"deployments", "deploy"}},
+		{"statefulset", NewStatefulSetCommand(streams), "statefulset [NAME]", []string{"statefulsets", "sts"}},
+		{"daemonset", NewDaemonSetCommand(streams), "daemonset [NAME]", []string{"daemonsets", "ds"}},
+		{"job", NewJobCommand(streams), "job [NAME]", []string{"jobs"}},
+		{"cronjob", NewCronJobCommand(streams), "cronjob [NAME]", []string{"cronjobs", "cj"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.use, tc.cmd.Use)
+			assert.Equal(t, tc.aliases, tc.cmd.Aliases)
+			assert.NotNil(t, tc.cmd.Flag("output"))
+			assert.NotNil(t, tc.cmd.Flag("all-namespaces"))
+			assert.NotNil(t, tc.cmd.Flag("namespace"))
+
+			err := tc.cmd.Args(tc.cmd, []string{"a", "b"})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestWorkloadCommand_InvalidOutput(t *testing.T) {
+	streams := &genericclioptions.IOStreams{
+		In:     bytes.NewBufferString(""),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	}
+	cmd := NewDeploymentCommand(streams)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"-o", "xml"})
+
+	err := cmd.Execute()
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid output format: xml")
+}
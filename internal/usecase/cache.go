@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"fmt"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+// CacheClearOptions contains options for the cache clear command
+type CacheClearOptions struct {
+	// Dir is the cache root, the default cache directory when empty
+	Dir       string
+	IOStreams *genericclioptions.IOStreams
+}
+
+// NewCacheClearOptions creates new CacheClearOptions with default values
+func NewCacheClearOptions(streams *genericclioptions.IOStreams) *CacheClearOptions {
+	return &CacheClearOptions{
+		IOStreams: streams,
+	}
+}
+
+// Run removes the cached lookups of every account and region
+func (o *CacheClearOptions) Run() error {
+	dir := o.Dir
+	if dir == "" {
+		var err error
+		dir, err = aws.DefaultCacheDir()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := aws.ClearCache(dir); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.IOStreams.Out, "Cache cleared: %s\n", dir)
+	return nil
+}
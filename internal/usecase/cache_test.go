@@ -0,0 +1,31 @@
+package usecase
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestCacheClearOptions_Run(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	if err := os.MkdirAll(filepath.Join(dir, "123456789012", "us-east-1"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	o := NewCacheClearOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.Dir = dir
+
+	if err := o.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("cache directory still exists: %v", err)
+	}
+	if got, want := out.String(), "Cache cleared: "+dir+"\n"; got != want {
+		t.Errorf("Run() output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+)
+
+// CanReachOptions contains options for the can-reach command
+type CanReachOptions struct {
+	Source       string
+	Destination  string
+	Protocol     string
+	Port         int32
+	OutputFormat string
+	SnapshotDir  string
+	AWSOptions   aws.ClientOptions
+	ConfigFlags  *genericclioptions.ConfigFlags
+	IOStreams    *genericclioptions.IOStreams
+	K8sClient    kubernetes.Interface
+	AWSClient    aws.Interface
+}
+
+// NewCanReachOptions creates new CanReachOptions with default values
+func NewCanReachOptions(streams *genericclioptions.IOStreams) *CanReachOptions {
+	return &CanReachOptions{
+		Protocol:    "tcp",
+		ConfigFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// Run executes the can-reach command business logic.
+// It returns an *ExitError when the traffic is denied in either direction.
+func (o *CanReachOptions) Run(ctx context.Context) error {
+	k8sClient, awsClient, err := newClients(ctx, o.SnapshotDir, o.ConfigFlags, o.AWSOptions, o.K8sClient, o.AWSClient)
+	if err != nil {
+		return err
+	}
+	o.AWSClient = awsClient
+
+	namespace, err := resolveNamespace(o.ConfigFlags, false)
+	if err != nil {
+		return fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	var pods []corev1.Pod
+	for _, ref := range []string{o.Source, o.Destination} {
+		ns, name := splitPodRef(ref, namespace)
+		pod, podErr := k8sClient.GetPod(ctx, name, ns)
+		if podErr != nil {
+			return podErr
+		}
+		pods = append(pods, *pod)
+	}
+
+	result, err := o.AWSClient.FetchSecurityGroupsByPods(ctx, pods)
+	if err != nil {
+		return fmt.Errorf("failed to get security groups: %w", err)
+	}
+
+	infos := make(map[string]aws.PodSecurityGroupInfo, len(result))
+	for _, info := range result {
+		infos[info.Pod.Namespace+"/"+info.Pod.Name] = info
+	}
+	var endpoints []aws.PodSecurityGroupInfo
+	for _, pod := range pods {
+		info, ok := infos[pod.Namespace+"/"+pod.Name]
+		if !ok {
+			return fmt.Errorf("no security group information found for pod %s/%s", pod.Namespace, pod.Name)
+		}
+		if !info.Mapped() {
+			return fmt.Errorf("no security group information found for pod %s/%s: %s", pod.Namespace, pod.Name, info.Reason)
+		}
+		endpoints = append(endpoints, info)
+	}
+
+	prefixLists, err := o.AWSClient.FetchPrefixLists(ctx, slices.Concat(endpoints[0].SecurityGroups, endpoints[1].SecurityGroups))
+	if err != nil {
+		return fmt.Errorf("failed to get prefix lists: %w", err)
+	}
+
+	reachability := rules.CheckReachability(endpoints[0], endpoints[1], o.Protocol, o.Port, prefixLists)
+	if err := output.OutputReachability(o.IOStreams.Out, reachability, o.OutputFormat); err != nil {
+		return err
+	}
+
+	if !reachability.Allowed() {
+		return &ExitError{Code: ExitCodeFindings, Reason: "traffic denied by security group rules"}
+	}
+	return nil
+}
+
+// splitPodRef splits a NAMESPACE/NAME reference, falling back to the default namespace for a bare NAME
+func splitPodRef(ref, defaultNamespace string) (string, string) {
+	if ns, name, ok := strings.Cut(ref, "/"); ok {
+		return ns, name
+	}
+	return defaultNamespace, ref
+}
@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+func TestCanReachOptions_Run(t *testing.T) {
+	k8sClient := &fakeK8sClient{
+		GetPodFunc: func(ctx context.Context, name, namespace string) (*corev1.Pod, error) {
+			ips := map[string]string{"frontend": "10.0.1.5", "backend": "10.0.2.7"}
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Status:     corev1.PodStatus{PodIP: ips[name]},
+			}, nil
+		},
+	}
+	awsClient := &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			return []aws.PodSecurityGroupInfo{
+				{
+					Pod:             pods[0],
+					ENI:             "eni-frontend",
+					AttachmentLevel: "pod",
+					Status:          aws.StatusMapped,
+					SecurityGroups: []types.SecurityGroup{{
+						GroupId:             awsSDK.String("sg-frontend"),
+						IpPermissionsEgress: []types.IpPermission{{IpProtocol: awsSDK.String("-1"), IpRanges: []types.IpRange{{CidrIp: awsSDK.String("0.0.0.0/0")}}}},
+					}},
+				},
+				{
+					Pod:             pods[1],
+					ENI:             "eni-backend",
+					AttachmentLevel: "pod",
+					Status:          aws.StatusMapped,
+					SecurityGroups: []types.SecurityGroup{{
+						GroupId: awsSDK.String("sg-backend"),
+						IpPermissions: []types.IpPermission{
+							{
+								IpProtocol:       awsSDK.String("tcp"),
+								FromPort:         awsSDK.Int32(8080),
+								ToPort:           awsSDK.Int32(8080),
+								UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: awsSDK.String("sg-frontend")}},
+							},
+							{
+								IpProtocol:    awsSDK.String("tcp"),
+								FromPort:      awsSDK.Int32(8443),
+								ToPort:        awsSDK.Int32(8443),
+								PrefixListIds: []types.PrefixListId{{PrefixListId: awsSDK.String("pl-apps")}},
+							},
+						},
+					}},
+				},
+			}, nil
+		},
+		FetchPrefixListsFunc: func(ctx context.Context, securityGroups []types.SecurityGroup) (map[string]aws.PrefixList, error) {
+			return map[string]aws.PrefixList{"pl-apps": {ID: "pl-apps", CIDRs: []string{"10.0.1.0/24"}}}, nil
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		port        int32
+		wantExit    bool
+		expectedOut string
+	}{
+		{name: "allowed", port: 8080, expectedOut: "RESULT: ALLOWED"},
+		{name: "allowed by prefix list", port: 8443, expectedOut: "RESULT: ALLOWED"},
+		{name: "denied", port: 9090, wantExit: true, expectedOut: "RESULT: DENIED"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			streams := &genericclioptions.IOStreams{
+				Out:    &bytes.Buffer{},
+				ErrOut: &bytes.Buffer{},
+			}
+			o := NewCanReachOptions(streams)
+			o.K8sClient = k8sClient
+			o.AWSClient = awsClient
+			o.Source = "frontend"
+			o.Destination = "apps/backend"
+			o.Port = tc.port
+			o.ConfigFlags.Namespace = stringPointer("default")
+
+			err := o.Run(context.Background())
+
+			var exitErr *ExitError
+			if tc.wantExit != errors.As(err, &exitErr) {
+				t.Fatalf("Run() error = %v, wantExit %v", err, tc.wantExit)
+			}
+			if tc.wantExit && exitErr.Code != ExitCodeFindings {
+				t.Errorf("exit code = %d, want %d", exitErr.Code, ExitCodeFindings)
+			}
+
+			if got := streams.Out.(*bytes.Buffer).String(); !strings.Contains(got, tc.expectedOut) {
+				t.Errorf("Run() output = %q, want it to contain %q", got, tc.expectedOut)
+			}
+		})
+	}
+}
+
+func TestSplitPodRef(t *testing.T) {
+	ns, name := splitPodRef("apps/backend", "default")
+	if ns != "apps" || name != "backend" {
+		t.Errorf("splitPodRef() = %q, %q", ns, name)
+	}
+
+	ns, name = splitPodRef("backend", "default")
+	if ns != "default" || name != "backend" {
+		t.Errorf("splitPodRef() = %q, %q", ns, name)
+	}
+}
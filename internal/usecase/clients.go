@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+)
+
+// newClients returns the given clients, creating the missing ones from the snapshot directory
+// when it is set, or from the kubeconfig and the AWS configuration selected by awsOptions otherwise
+func newClients(ctx context.Context, snapshotDir string, configFlags *genericclioptions.ConfigFlags, awsOptions aws.ClientOptions, k8sClient kubernetes.Interface, awsClient aws.Interface) (kubernetes.Interface, aws.Interface, error) {
+	if k8sClient == nil {
+		var err error
+		if snapshotDir != "" {
+			k8sClient, err = kubernetes.NewSnapshotClient(snapshotDir)
+		} else {
+			k8sClient, err = kubernetes.NewClient(configFlags)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if awsClient == nil {
+		var api aws.EC2API
+		var elbv2API aws.ELBv2API
+		if snapshotDir != "" {
+			snapshot, err := aws.NewSnapshotEC2API(snapshotDir)
+			if err != nil {
+				return nil, nil, err
+			}
+			api = snapshot
+		} else {
+			if awsOptions.Region == "" {
+				awsOptions.Region = detectRegion(configFlags)
+			}
+			ec2API, err := aws.NewEC2API(ctx, awsOptions)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create aws client: %w", err)
+			}
+			api = ec2API
+			elbv2API, err = aws.NewELBv2API(ctx, awsOptions)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create aws client: %w", err)
+			}
+		}
+		client, err := aws.NewClient(api, awsOptions.Requests)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create aws client: %w", err)
+		}
+		awsClient = client.WithELBv2API(elbv2API)
+	}
+
+	return k8sClient, awsClient, nil
+}
+
+// detectRegion returns the region of the EKS cluster the kubeconfig points at, or an empty string
+// to fall back to the region of the AWS configuration
+func detectRegion(configFlags *genericclioptions.ConfigFlags) string {
+	if configFlags == nil {
+		return ""
+	}
+	restConfig, err := configFlags.ToRESTConfig()
+	if err != nil {
+		return ""
+	}
+	return aws.RegionFromEKSEndpoint(restConfig.Host)
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+)
+
+// contextClientsFunc creates the clients for a kubeconfig context
+type contextClientsFunc func(ctx context.Context, name string) (kubernetes.Interface, aws.Interface, error)
+
+// resolveContexts returns the requested contexts without duplicates, or every context of the
+// kubeconfig sorted by name when all is set
+func resolveContexts(configFlags *genericclioptions.ConfigFlags, names []string, all bool) ([]string, error) {
+	if !all {
+		var contexts []string
+		for _, name := range names {
+			if !slices.Contains(contexts, name) {
+				contexts = append(contexts, name)
+			}
+		}
+		return contexts, nil
+	}
+
+	rawConfig, err := configFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	if len(rawConfig.Contexts) == 0 {
+		return nil, fmt.Errorf("no contexts found in kubeconfig")
+	}
+	contexts := make([]string, 0, len(rawConfig.Contexts))
+	for name := range rawConfig.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
+
+// contextConfigFlags returns config flags with every flag of configFlags, such as the kubeconfig,
+// impersonation, credentials and server overrides, and the context replaced. The namespace flag is
+// kept, so without it each context uses its own default namespace.
+//
+// The flags are copied field by field rather than copying the struct, which also holds the client
+// config cached for the current context and its locks.
+func contextConfigFlags(configFlags *genericclioptions.ConfigFlags, name string) *genericclioptions.ConfigFlags {
+	flags := genericclioptions.NewConfigFlags(true)
+	flags.CacheDir = configFlags.CacheDir
+	flags.KubeConfig = configFlags.KubeConfig
+	flags.ClusterName = configFlags.ClusterName
+	flags.AuthInfoName = configFlags.AuthInfoName
+	flags.Namespace = configFlags.Namespace
+	flags.APIServer = configFlags.APIServer
+	flags.TLSServerName = configFlags.TLSServerName
+	flags.Insecure = configFlags.Insecure
+	flags.CertFile = configFlags.CertFile
+	flags.KeyFile = configFlags.KeyFile
+	flags.CAFile = configFlags.CAFile
+	flags.BearerToken = configFlags.BearerToken
+	flags.Impersonate = configFlags.Impersonate
+	flags.ImpersonateUID = configFlags.ImpersonateUID
+	flags.ImpersonateGroup = configFlags.ImpersonateGroup
+	flags.ImpersonateUserExtra = configFlags.ImpersonateUserExtra
+	flags.Username = configFlags.Username
+	flags.Password = configFlags.Password
+	flags.Timeout = configFlags.Timeout
+	flags.DisableCompression = configFlags.DisableCompression
+	flags.WrapConfigFn = configFlags.WrapConfigFn
+	flags.Context = &name
+	return flags
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+)
+
+// DiffOptions contains options for the diff command
+type DiffOptions struct {
+	Old          string
+	New          string
+	OutputFormat string
+	IOStreams    *genericclioptions.IOStreams
+}
+
+// NewDiffOptions creates new DiffOptions with default values
+func NewDiffOptions(streams *genericclioptions.IOStreams) *DiffOptions {
+	return &DiffOptions{
+		IOStreams: streams,
+	}
+}
+
+// Run executes the diff command business logic.
+// It returns an *ExitError when the two mappings differ.
+func (o *DiffOptions) Run(ctx context.Context) error {
+	oldPods, oldInfos, err := loadPodOutputs(ctx, o.Old)
+	if err != nil {
+		return err
+	}
+	newPods, newInfos, err := loadPodOutputs(ctx, o.New)
+	if err != nil {
+		return err
+	}
+
+	d := output.DiffPodOutputs(oldPods, newPods)
+	if err := output.OutputDiff(o.IOStreams.Out, d, o.OutputFormat); err != nil {
+		return err
+	}
+
+	if err := incompleteError(o.IOStreams.ErrOut, append(oldInfos, newInfos...)); err != nil {
+		return err
+	}
+
+	if !d.Empty() {
+		return &ExitError{Code: ExitCodeFindings, Reason: "differences found"}
+	}
+	return nil
+}
+
+// loadPodOutputs reads a pod mapping from a file written by `pod -o json`, or computes it for all
+// namespaces from a snapshot directory. The looked up pods are returned as well for a snapshot
+// directory.
+func loadPodOutputs(ctx context.Context, path string) ([]output.PodOutput, []aws.PodSecurityGroupInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if !info.IsDir() {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var pods []output.PodOutput
+		if err := json.Unmarshal(b, &pods); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		return pods, nil, nil
+	}
+
+	k8sClient, awsClient, err := newClients(ctx, path, nil, aws.ClientOptions{}, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	pods, err := k8sClient.ListPods(ctx, "", kubernetes.PodSelector{})
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := awsClient.FetchSecurityGroupsByPods(ctx, pods)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get security groups from %s: %w", path, err)
+	}
+	return output.ToPodOutputs(result), result, nil
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestDiffOptions_Run(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old.json")
+	newFile := filepath.Join(dir, "new.json")
+	if err := os.WriteFile(oldFile, []byte(`[{"podName":"web","namespace":"default","eni":"eni-1","securityGroups":[{"id":"sg-1"}]}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newFile, []byte(`[{"podName":"web","namespace":"default","eni":"eni-1","securityGroups":[{"id":"sg-2"}]}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name         string
+		old, new     string
+		wantExitCode bool
+		wantErr      bool
+		expectedOut  string
+	}{
+		{name: "differences", old: oldFile, new: newFile, wantExitCode: true, expectedOut: "+sg-2 -sg-1"},
+		{name: "no differences", old: oldFile, new: oldFile, expectedOut: "No differences found"},
+		{name: "missing file", old: oldFile, new: filepath.Join(dir, "missing.json"), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			o := NewDiffOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+			o.Old = tc.old
+			o.New = tc.new
+
+			err := o.Run(context.Background())
+
+			var exitErr *ExitError
+			if got := errors.As(err, &exitErr); got != tc.wantExitCode {
+				t.Fatalf("Run() error = %v, want exit error %v", err, tc.wantExitCode)
+			}
+			if (err != nil && !tc.wantExitCode) != tc.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !strings.Contains(out.String(), tc.expectedOut) {
+				t.Errorf("Run() output = %q, want it to contain %q", out.String(), tc.expectedOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,473 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws/ec2test"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/loadbalancer"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+)
+
+// The end-to-end tests run the commands with the client-go fake clientset and the real AWS SDK
+// client pointed at an ec2test.Server, so requests go through HTTP, pagination and batching.
+
+// e2eAWSOptions returns AWS options sending the requests to the server, isolated from the AWS
+// configuration of the machine running the tests
+func e2eAWSOptions(t *testing.T, server *ec2test.Server) aws.ClientOptions {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	return aws.ClientOptions{Region: "us-east-1", EndpointURL: server.URL}
+}
+
+func e2eK8sClient(objects ...runtime.Object) kubernetes.Interface {
+	return kubernetes.NewClientFromClientsets(fake.NewClientset(objects...), nil)
+}
+
+func TestE2E_Pod(t *testing.T) {
+	fixture, err := ec2test.LoadFixture("testdata/ec2.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := ec2test.NewServer(fixture)
+	defer server.Close()
+
+	pending := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	agent := runningPod("agent", "10.0.2.11")
+	agent.Namespace = "kube-system"
+	out := &bytes.Buffer{}
+	o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = e2eK8sClient(runningPod("web", "10.0.1.10"), agent, runningPod("gone", "10.0.9.9"), pending)
+	o.AWSOptions = e2eAWSOptions(t, server)
+	o.AllNamespaces = true
+	o.OutputFormat = "json"
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result []output.PodOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out.String())
+	}
+	got := make(map[string]string)
+	for _, p := range result {
+		var sgs []string
+		for _, sg := range p.SecurityGroups {
+			sgs = append(sgs, awsSDK.ToString(sg.Name))
+		}
+		got[p.PodName] = fmt.Sprintf("%s %s %s %s", p.Status, p.ENI, p.AttachmentLevel, strings.Join(sgs, ","))
+	}
+	want := map[string]string{
+		"web":     "Mapped eni-0000000000000web1 pod web",
+		"agent":   "Mapped eni-000000000000node1 node node",
+		"gone":    "ENINotFound   ",
+		"pending": "NotRunning   ",
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("pod %s = %q, want %q", name, got[name], w)
+		}
+	}
+	if n := server.RequestCount("DescribeNetworkInterfaces"); n != 1 {
+		t.Errorf("DescribeNetworkInterfaces requests = %d, want 1", n)
+	}
+}
+
+func TestE2E_PodRuleIDs(t *testing.T) {
+	fixture, err := ec2test.LoadFixture("testdata/ec2.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := ec2test.NewServer(fixture)
+	defer server.Close()
+
+	out := &bytes.Buffer{}
+	o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = e2eK8sClient(runningPod("web", "10.0.1.10"), runningPod("agent", "10.0.2.11"))
+	o.AWSOptions = e2eAWSOptions(t, server)
+	o.ConfigFlags.Namespace = stringPointer("default")
+	o.Tags = map[string]string{"team": "payments"}
+	o.OutputFormat = "json"
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result []output.PodOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(result) != 1 || result[0].PodName != "web" {
+		t.Fatalf("Run() output = %+v, want only the pod using the tagged security group", result)
+	}
+	sg := result[0].SecurityGroups[0]
+	if sg.Tags["team"] != "payments" {
+		t.Errorf("security group tags = %v, want team=payments", sg.Tags)
+	}
+	want := output.RuleEntryOutput{ID: "sgr-00000000000web443", Peer: "10.0.0.0/8", Description: "allow from payments API"}
+	if got := sg.InboundRules[0].Entries; len(got) != 1 || got[0] != want {
+		t.Errorf("inbound rule entries = %+v, want [%+v]", got, want)
+	}
+	if n := server.RequestCount("DescribeSecurityGroupRules"); n != 1 {
+		t.Errorf("DescribeSecurityGroupRules requests = %d, want 1", n)
+	}
+}
+
+func TestE2E_PodResolvePeers(t *testing.T) {
+	fixture, err := ec2test.LoadFixture("testdata/ec2.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := ec2test.NewServer(fixture)
+	defer server.Close()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0123456789abcdef0"},
+	}
+	coredns := runningPod("coredns", "10.0.2.11")
+	coredns.Namespace = "kube-system"
+
+	out := &bytes.Buffer{}
+	o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = e2eK8sClient(node, runningPod("web", "10.0.1.10"), coredns)
+	o.AWSOptions = e2eAWSOptions(t, server)
+	o.PodName = "web"
+	o.OutputFormat = "json"
+	o.ResolvePeers = true
+	o.ConfigFlags.Namespace = stringPointer("default")
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result []output.PodOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(result) != 1 || len(result[0].SecurityGroups) != 1 || len(result[0].SecurityGroups[0].InboundRules) != 2 {
+		t.Fatalf("Run() output = %+v, want the two inbound rules of web", result)
+	}
+
+	var got []string
+	for _, peer := range result[0].SecurityGroups[0].InboundRules[1].Peers {
+		got = append(got, fmt.Sprintf("%s %s %v %v %v", peer.ID, peer.Name, peer.Pods, peer.Nodes, peer.CIDRs))
+	}
+	want := []string{
+		"sg-0000000000000node node [kube-system/coredns] [node-1] []",
+		"pl-0000000000office office [] [] [192.0.2.0/24]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("peers = %q, want %q", got, want)
+	}
+	if n := server.RequestCount("GetManagedPrefixListEntries"); n != 1 {
+		t.Errorf("GetManagedPrefixListEntries requests = %d, want 1", n)
+	}
+}
+
+func TestE2E_PodBatchingAndPagination(t *testing.T) {
+	// 450 pods on 9 ENIs are looked up in 3 batches of up to 200 IPs, each returning its ENIs over
+	// several pages of one ENI
+	enis := make([]types.NetworkInterface, 9)
+	var objects []runtime.Object
+	for i := range 450 {
+		ip := fmt.Sprintf("10.1.%d.%d", i/256, i%256)
+		eni := &enis[i/50]
+		eni.NetworkInterfaceId = awsSDK.String(fmt.Sprintf("eni-bulk%d", i/50))
+		eni.InterfaceType = types.NetworkInterfaceTypeBranch
+		eni.PrivateIpAddresses = append(eni.PrivateIpAddresses, types.NetworkInterfacePrivateIpAddress{PrivateIpAddress: awsSDK.String(ip)})
+		objects = append(objects, runningPod(fmt.Sprintf("pod-%03d", i), ip))
+	}
+	fixture, err := ec2test.LoadFixture("testdata/ec2.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture.NetworkInterfaces = append(fixture.NetworkInterfaces, enis...)
+	server := ec2test.NewServer(fixture, ec2test.WithPageSize(1))
+	defer server.Close()
+
+	out := &bytes.Buffer{}
+	o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = e2eK8sClient(objects...)
+	o.AWSOptions = e2eAWSOptions(t, server)
+	o.AWSOptions.Requests.Concurrency = 2
+	o.ConfigFlags.Namespace = stringPointer("default")
+	o.OutputFormat = "json"
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result []output.PodOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(result) != 450 {
+		t.Fatalf("got %d pods, want 450", len(result))
+	}
+	for _, p := range result {
+		var i int
+		fmt.Sscanf(p.PodName, "pod-%d", &i)
+		if want := fmt.Sprintf("eni-bulk%d", i/50); p.Status != aws.StatusMapped || p.ENI != want {
+			t.Fatalf("pod %s = %s on %q, want Mapped on %s", p.PodName, p.Status, p.ENI, want)
+		}
+	}
+
+	batches, pages := 0, 0
+	for _, r := range server.Requests() {
+		if r.Action != "DescribeNetworkInterfaces" {
+			continue
+		}
+		pages++
+		if r.Params.Get("NextToken") == "" {
+			batches++
+		}
+	}
+	// Batches of IPs 0-199, 200-399 and 400-449 cover 4, 4 and 1 ENIs
+	if batches != 3 || pages != 9 {
+		t.Errorf("DescribeNetworkInterfaces batches = %d and pages = %d, want 3 and 9", batches, pages)
+	}
+}
+
+func TestE2E_SecurityGroup(t *testing.T) {
+	fixture, err := ec2test.LoadFixture("testdata/ec2.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := ec2test.NewServer(fixture)
+	defer server.Close()
+
+	out := &bytes.Buffer{}
+	o := NewSecurityGroupOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = e2eK8sClient(runningPod("web", "10.0.1.10"))
+	o.AWSOptions = e2eAWSOptions(t, server)
+	o.SecurityGroups = []string{"web"}
+	o.OutputFormat = "json"
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result []output.SecurityGroupUsageOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(result) != 1 || len(result[0].Pods) != 1 || len(result[0].UnmatchedENIs) != 1 {
+		t.Fatalf("Run() output = %+v, want the web pod and the load balancer ENI", result)
+	}
+	if result[0].UnmatchedENIs[0].ID != "eni-0000000000000lb01" {
+		t.Errorf("unmatched ENI = %s, want eni-0000000000000lb01", result[0].UnmatchedENIs[0].ID)
+	}
+}
+
+func TestE2E_Node(t *testing.T) {
+	fixture, err := ec2test.LoadFixture("testdata/ec2.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := ec2test.NewServer(fixture)
+	defer server.Close()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0123456789abcdef0"},
+	}
+	web := runningPod("web", "10.0.1.10")
+	web.Spec.NodeName = "node-1"
+	coredns := runningPod("coredns", "10.0.2.11")
+	coredns.Spec.NodeName = "node-1"
+
+	out := &bytes.Buffer{}
+	o := NewNodeOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = e2eK8sClient(node, web, coredns)
+	o.AWSOptions = e2eAWSOptions(t, server)
+	o.NodeName = "node-1"
+	o.OutputFormat = "json"
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result []output.NodeOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(result) != 1 || result[0].Status != aws.StatusMapped {
+		t.Fatalf("Run() output = %+v, want node-1 mapped", result)
+	}
+
+	var got []string
+	for _, eni := range result[0].ENIs {
+		got = append(got, fmt.Sprintf("%s %s %v", eni.ID, eni.Role, eni.Pods))
+	}
+	want := []string{
+		"eni-000000000000node1 primary [default/coredns]",
+		"eni-000000000000trunk trunk []",
+		"eni-0000000000000web1 branch [default/web]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ENIs = %q, want %q", got, want)
+	}
+	if result[0].ENIs[1].BranchENIs != 1 {
+		t.Errorf("branch ENIs of the trunk = %d, want 1", result[0].ENIs[1].BranchENIs)
+	}
+}
+
+func TestE2E_Service(t *testing.T) {
+	fixture, err := ec2test.LoadFixture("testdata/ec2.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := ec2test.NewServer(fixture)
+	defer server.Close()
+
+	ready := true
+	https, metrics := int32(443), int32(9090)
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web-abcde",
+			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "web"},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       []discoveryv1.EndpointPort{{Port: &https}, {Port: &metrics}},
+		Endpoints: []discoveryv1.Endpoint{{
+			Addresses:  []string{"10.0.1.10"},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+			TargetRef:  &corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web"},
+		}},
+	}
+
+	out := &bytes.Buffer{}
+	o := NewServiceOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = e2eK8sClient(svc, slice, runningPod("web", "10.0.1.10"))
+	o.AWSOptions = e2eAWSOptions(t, server)
+	o.ConfigFlags.Namespace = stringPointer("default")
+	o.OutputFormat = "json"
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result []output.ServiceOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(result) != 1 || len(result[0].SecurityGroupSets) != 1 {
+		t.Fatalf("Run() output = %+v, want one security group set", result)
+	}
+
+	var got []string
+	for _, port := range result[0].SecurityGroupSets[0].TargetPorts {
+		got = append(got, fmt.Sprintf("%s/%d %t", port.Protocol, port.Port, port.Allowed))
+	}
+	want := []string{"tcp/443 true", "tcp/9090 false"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("target ports = %q, want %q", got, want)
+	}
+	if !result[0].Blocked {
+		t.Error("Blocked = false, want true")
+	}
+}
+
+func TestE2E_LoadBalancer(t *testing.T) {
+	fixture, err := ec2test.LoadFixture("testdata/ec2.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := ec2test.NewServer(fixture)
+	defer server.Close()
+
+	ready := true
+	https := int32(443)
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 443}}},
+	}
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web-abcde",
+			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "web"},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       []discoveryv1.EndpointPort{{Port: &https}},
+		Endpoints: []discoveryv1.Endpoint{{
+			Addresses:  []string{"10.0.1.10"},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+			TargetRef:  &corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web"},
+		}},
+	}
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+			Annotations: map[string]string{
+				loadbalancer.IngressClassAnnotation:  "alb",
+				loadbalancer.ALBTargetTypeAnnotation: loadbalancer.TargetTypeIP,
+			},
+		},
+		Spec: networkingv1.IngressSpec{DefaultBackend: &networkingv1.IngressBackend{
+			Service: &networkingv1.IngressServiceBackend{Name: "web", Port: networkingv1.ServiceBackendPort{Number: 443}},
+		}},
+		Status: networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+			Ingress: []networkingv1.IngressLoadBalancerIngress{{Hostname: "web-0123456789abcdef.us-east-1.elb.amazonaws.com"}},
+		}},
+	}
+
+	out := &bytes.Buffer{}
+	o := NewLoadBalancerOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = e2eK8sClient(svc, slice, ingress, runningPod("web", "10.0.1.10"))
+	o.AWSOptions = e2eAWSOptions(t, server)
+	o.ConfigFlags.Namespace = stringPointer("default")
+	o.OutputFormat = "json"
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result []output.LoadBalancerPathOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(result) != 1 {
+		t.Fatalf("Run() output = %+v, want one path", result)
+	}
+
+	got := result[0]
+	if got.LoadBalancer != "web" || strings.Join(got.LoadBalancerIPs, ",") != "10.0.3.10" {
+		t.Errorf("load balancer = %s %v, want web [10.0.3.10]", got.LoadBalancer, got.LoadBalancerIPs)
+	}
+	if got.Target != "web" || got.Port != 443 || got.Status != loadbalancer.StatusOK {
+		t.Errorf("path = %s %d %s, want web 443 %s", got.Target, got.Port, got.Status, loadbalancer.StatusOK)
+	}
+}
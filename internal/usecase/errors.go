@@ -0,0 +1,22 @@
+package usecase
+
+import "fmt"
+
+// ExitCodeFindings is the exit code used when a command completes but reports a negative result,
+// such as denied traffic, so that scripts can tell it apart from a failure (exit code 1).
+const ExitCodeFindings = 2
+
+// ExitCodeIncomplete is the exit code used when a command outputs partial results because some EC2
+// lookups failed. The pod command only uses it in strict mode.
+const ExitCodeIncomplete = 3
+
+// ExitError signals that the command completed and its result should be reported through the exit code.
+// The result itself has already been written to the output, so the error message is not printed again.
+type ExitError struct {
+	Code   int
+	Reason string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s (exit code %d)", e.Reason, e.Code)
+}
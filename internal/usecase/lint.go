@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/lint"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+)
+
+// LintOptions contains options for the lint command
+type LintOptions struct {
+	ConfigFile    string
+	OutputFormat  string
+	AllNamespaces bool
+	SnapshotDir   string
+	AWSOptions    aws.ClientOptions
+	ConfigFlags   *genericclioptions.ConfigFlags
+	IOStreams     *genericclioptions.IOStreams
+	K8sClient     kubernetes.Interface
+	AWSClient     aws.Interface
+}
+
+// NewLintOptions creates new LintOptions with default values
+func NewLintOptions(streams *genericclioptions.IOStreams) *LintOptions {
+	return &LintOptions{
+		ConfigFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// Run executes the lint command business logic.
+// It returns an *ExitError when any finding is reported.
+func (o *LintOptions) Run(ctx context.Context) error {
+	var cfg lint.Config
+	if o.ConfigFile != "" {
+		var err error
+		cfg, err = lint.LoadConfig(o.ConfigFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	k8sClient, awsClient, err := newClients(ctx, o.SnapshotDir, o.ConfigFlags, o.AWSOptions, o.K8sClient, o.AWSClient)
+	if err != nil {
+		return err
+	}
+	o.AWSClient = awsClient
+
+	namespace, err := resolveNamespace(o.ConfigFlags, o.AllNamespaces)
+	if err != nil {
+		return fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	pods, err := k8sClient.ListPods(ctx, namespace, kubernetes.PodSelector{})
+	if err != nil {
+		return err
+	}
+
+	var result []aws.PodSecurityGroupInfo
+	if len(pods) > 0 {
+		result, err = o.AWSClient.FetchSecurityGroupsByPods(ctx, pods)
+		if err != nil {
+			return fmt.Errorf("failed to get security groups: %w", err)
+		}
+	}
+
+	report := lint.Run(result, cfg)
+	if err := output.OutputLintReport(o.IOStreams.Out, report, o.OutputFormat); err != nil {
+		return err
+	}
+
+	if err := incompleteError(o.IOStreams.ErrOut, result); err != nil {
+		return err
+	}
+
+	if len(report.Findings) > 0 {
+		return &ExitError{Code: ExitCodeFindings, Reason: fmt.Sprintf("%d lint findings", len(report.Findings))}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+)
+
+func TestLintOptions_Run(t *testing.T) {
+	k8sClient := &fakeK8sClient{
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			return []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}}, nil
+		},
+	}
+	nodeLevel := &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			return []aws.PodSecurityGroupInfo{{
+				Pod:             pods[0],
+				AttachmentLevel: "node",
+				SecurityGroups:  []types.SecurityGroup{{GroupId: awsSDK.String("sg-1"), IpPermissionsEgress: []types.IpPermission{{IpProtocol: awsSDK.String("-1")}}}},
+			}}, nil
+		},
+	}
+
+	configDir := t.TempDir()
+	disableNodeLevel := filepath.Join(configDir, "lint.yaml")
+	if err := os.WriteFile(disableNodeLevel, []byte("rules:\n  SG003:\n    enabled: false\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name         string
+		awsClient    *fakeAWSClient
+		configFile   string
+		wantExitCode bool
+		wantErr      bool
+		expectedOut  string
+	}{
+		{name: "findings", awsClient: nodeLevel, wantExitCode: true, expectedOut: "SG003"},
+		{name: "rule disabled by config", awsClient: nodeLevel, configFile: disableNodeLevel, expectedOut: "No findings"},
+		{name: "missing config", awsClient: nodeLevel, configFile: filepath.Join(configDir, "missing.yaml"), wantErr: true},
+		{
+			name: "aws client error",
+			awsClient: &fakeAWSClient{
+				FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+					return nil, fmt.Errorf("aws error")
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			o := NewLintOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+			o.K8sClient = k8sClient
+			o.AWSClient = tc.awsClient
+			o.ConfigFile = tc.configFile
+			o.ConfigFlags.Namespace = stringPointer("default")
+
+			err := o.Run(context.Background())
+
+			var exitErr *ExitError
+			if got := errors.As(err, &exitErr); got != tc.wantExitCode {
+				t.Fatalf("Run() error = %v, want exit error %v", err, tc.wantExitCode)
+			}
+			if (err != nil && !tc.wantExitCode) != tc.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !strings.Contains(out.String(), tc.expectedOut) {
+				t.Errorf("Run() output = %q, want it to contain %q", out.String(), tc.expectedOut)
+			}
+		})
+	}
+}
+
+func TestLintOptions_Run_LookupFailed(t *testing.T) {
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	o := NewLintOptions(&genericclioptions.IOStreams{Out: out, ErrOut: errOut})
+	o.K8sClient = &fakeK8sClient{
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			return []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}}, nil
+		},
+	}
+	o.AWSClient = &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			return []aws.PodSecurityGroupInfo{{
+				Pod:    pods[0],
+				Status: aws.StatusLookupFailed,
+				Reason: "failed to look up ENI of IP 10.0.1.10: UnauthorizedOperation",
+			}}, nil
+		},
+	}
+	o.ConfigFlags.Namespace = stringPointer("default")
+
+	err := o.Run(context.Background())
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitCodeIncomplete {
+		t.Fatalf("Run() error = %v, want exit code %d", err, ExitCodeIncomplete)
+	}
+	if got := errOut.String(); got != "Warning: pod default/web: failed to look up ENI of IP 10.0.1.10: UnauthorizedOperation\n" {
+		t.Errorf("Run() error output = %q", got)
+	}
+}
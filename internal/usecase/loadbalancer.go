@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/loadbalancer"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+)
+
+// LoadBalancerOptions contains options for the lb command
+type LoadBalancerOptions struct {
+	OutputFormat  string
+	AllNamespaces bool
+	AWSOptions    aws.ClientOptions
+	ConfigFlags   *genericclioptions.ConfigFlags
+	IOStreams     *genericclioptions.IOStreams
+	K8sClient     kubernetes.Interface
+	AWSClient     aws.Interface
+}
+
+// NewLoadBalancerOptions creates new LoadBalancerOptions with default values
+func NewLoadBalancerOptions(streams *genericclioptions.IOStreams) *LoadBalancerOptions {
+	return &LoadBalancerOptions{
+		ConfigFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// Run executes the lb command business logic. The load balancers of the Services of type LoadBalancer
+// and the ALB Ingresses are found by the hostnames in their status, and the targets are looked up by
+// IP like the pod command does.
+func (o *LoadBalancerOptions) Run(ctx context.Context) error {
+	k8sClient, awsClient, err := newClients(ctx, "", o.ConfigFlags, o.AWSOptions, o.K8sClient, o.AWSClient)
+	if err != nil {
+		return err
+	}
+	o.AWSClient = awsClient
+
+	namespace, err := resolveNamespace(o.ConfigFlags, o.AllNamespaces)
+	if err != nil {
+		return fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	services, err := k8sClient.ListServices(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	ingresses, err := k8sClient.ListIngresses(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	classes, err := k8sClient.ListIngressClasses(ctx)
+	if err != nil {
+		return err
+	}
+
+	frontends := loadbalancer.Frontends(services, ingresses, classes)
+	if len(frontends) == 0 {
+		fmt.Fprintf(o.IOStreams.Out, "No resources found in namespace.\n")
+		return nil
+	}
+
+	endpointSlices, err := k8sClient.ListEndpointSlices(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	pods, err := k8sClient.ListPods(ctx, namespace, kubernetes.PodSelector{})
+	if err != nil {
+		return err
+	}
+	nodes, err := k8sClient.ListNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	targets := make([][]loadbalancer.Target, len(frontends))
+	var hostnames []string
+	var targetPods []corev1.Pod
+	seen := make(map[string]struct{})
+	for i, f := range frontends {
+		hostnames = append(hostnames, f.Hostnames...)
+		targets[i] = loadbalancer.Targets(f, services, endpointSlices, pods, nodes)
+		for _, t := range targets[i] {
+			if _, ok := seen[t.Key()]; !ok {
+				seen[t.Key()] = struct{}{}
+				targetPods = append(targetPods, t.Pod)
+			}
+		}
+	}
+
+	lbs := make(map[string]*aws.LoadBalancer)
+	if len(hostnames) > 0 {
+		result, err := o.AWSClient.FetchLoadBalancers(ctx, hostnames)
+		if err != nil {
+			return fmt.Errorf("failed to get load balancers: %w", err)
+		}
+		for i := range result {
+			lbs[strings.ToLower(result[i].DNSName())] = &result[i]
+		}
+	}
+
+	var looked []aws.PodSecurityGroupInfo
+	infos := make(map[string]aws.PodSecurityGroupInfo, len(targetPods))
+	if len(targetPods) > 0 {
+		var err error
+		looked, err = o.AWSClient.FetchSecurityGroupsByPods(ctx, targetPods)
+		if err != nil {
+			return fmt.Errorf("failed to get security groups: %w", err)
+		}
+		for _, info := range looked {
+			infos[info.Pod.Namespace+"/"+info.Pod.Name] = info
+		}
+	}
+
+	var targetSGs []types.SecurityGroup
+	for _, info := range looked {
+		targetSGs = append(targetSGs, info.SecurityGroups...)
+	}
+	prefixLists, err := o.AWSClient.FetchPrefixLists(ctx, targetSGs)
+	if err != nil {
+		return fmt.Errorf("failed to get prefix lists: %w", err)
+	}
+
+	var paths []loadbalancer.Path
+	for i, f := range frontends {
+		var lb *aws.LoadBalancer
+		for _, hostname := range f.Hostnames {
+			if found, ok := lbs[strings.ToLower(hostname)]; ok {
+				lb = found
+				break
+			}
+		}
+		paths = append(paths, loadbalancer.Paths(f, lb, targets[i], infos, prefixLists)...)
+	}
+	if err := output.OutputLoadBalancerPaths(o.IOStreams.Out, paths, o.OutputFormat); err != nil {
+		return err
+	}
+	return incompleteError(o.IOStreams.ErrOut, looked)
+}
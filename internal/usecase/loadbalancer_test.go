@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/loadbalancer"
+)
+
+func TestLoadBalancerOptions_Run(t *testing.T) {
+	ready := true
+	port, protocol := int32(8080), corev1.ProtocolTCP
+	services := []corev1.Service{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{loadbalancer.NLBTargetTypeAnnotation: "ip"}},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{{Port: 80}}},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+			Ingress: []corev1.LoadBalancerIngress{{Hostname: "web.elb.amazonaws.com"}},
+		}},
+	}}
+	endpointSlices := []discoveryv1.EndpointSlice{{
+		ObjectMeta:  metav1.ObjectMeta{Name: "web-abcde", Namespace: "default", Labels: map[string]string{discoveryv1.LabelServiceName: "web"}},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       []discoveryv1.EndpointPort{{Port: &port, Protocol: &protocol}},
+		Endpoints: []discoveryv1.Endpoint{{
+			Addresses:  []string{"10.0.1.10"},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+			TargetRef:  &corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-1"},
+		}},
+	}}
+	k8sClient := func(services []corev1.Service) *fakeK8sClient {
+		return &fakeK8sClient{
+			ListServicesFunc: func(ctx context.Context, namespace string) ([]corev1.Service, error) {
+				return services, nil
+			},
+			ListIngressesFunc: emptyList[networkingv1.Ingress],
+			ListIngressClassesFunc: func(ctx context.Context) ([]networkingv1.IngressClass, error) {
+				return nil, nil
+			},
+			ListEndpointSlicesFunc: func(ctx context.Context, namespace string) ([]discoveryv1.EndpointSlice, error) {
+				return endpointSlices, nil
+			},
+			ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+				return []corev1.Pod{*runningPod("web-1", "10.0.1.10")}, nil
+			},
+			ListNodesFunc: func(ctx context.Context) ([]corev1.Node, error) {
+				return nil, nil
+			},
+		}
+	}
+	fromLB := types.IpPermission{
+		IpProtocol: awsSDK.String("tcp"),
+		FromPort:   awsSDK.Int32(8080),
+		ToPort:     awsSDK.Int32(8080),
+		IpRanges:   []types.IpRange{{CidrIp: awsSDK.String("10.0.0.0/24")}},
+	}
+	fetchPods := func(permissions ...types.IpPermission) func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+		return func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			if len(pods) != 1 {
+				return nil, fmt.Errorf("expected 1 pod, got %d", len(pods))
+			}
+			return []aws.PodSecurityGroupInfo{{
+				Pod:             pods[0],
+				ENI:             "eni-web",
+				AttachmentLevel: "pod",
+				SecurityGroups:  []types.SecurityGroup{{GroupId: awsSDK.String("sg-web"), IpPermissions: permissions}},
+			}}, nil
+		}
+	}
+	fetchLoadBalancers := func(ctx context.Context, dnsNames []string) ([]aws.LoadBalancer, error) {
+		return []aws.LoadBalancer{{
+			LoadBalancer: elbv2types.LoadBalancer{LoadBalancerName: awsSDK.String("web"), DNSName: awsSDK.String("WEB.elb.amazonaws.com")},
+			PrivateIPs:   []string{"10.0.0.10"},
+		}}, nil
+	}
+
+	noPrefixLists := func(ctx context.Context, securityGroups []types.SecurityGroup) (map[string]aws.PrefixList, error) {
+		return map[string]aws.PrefixList{}, nil
+	}
+
+	testCases := []struct {
+		name        string
+		k8sClient   *fakeK8sClient
+		awsClient   *fakeAWSClient
+		wantErr     bool
+		expectedOut string
+	}{
+		{
+			name:      "path permitted by the target security group",
+			k8sClient: k8sClient(services),
+			awsClient: &fakeAWSClient{
+				FetchLoadBalancersFunc:        fetchLoadBalancers,
+				FetchSecurityGroupsByPodsFunc: fetchPods(fromLB),
+				FetchPrefixListsFunc:          noPrefixLists,
+			},
+			expectedOut: "OK      sg-web tcp 8080 from 10.0.0.0/24",
+		},
+		{
+			name:      "path blocked by the target security group",
+			k8sClient: k8sClient(services),
+			awsClient: &fakeAWSClient{
+				FetchLoadBalancersFunc:        fetchLoadBalancers,
+				FetchSecurityGroupsByPodsFunc: fetchPods(),
+				FetchPrefixListsFunc:          noPrefixLists,
+			},
+			expectedOut: "BLOCKED  no ingress rule permits tcp/8080 from 10.0.0.10",
+		},
+		{
+			name:      "load balancer lookup fails",
+			k8sClient: k8sClient(services),
+			awsClient: &fakeAWSClient{
+				FetchLoadBalancersFunc: func(ctx context.Context, dnsNames []string) ([]aws.LoadBalancer, error) {
+					return nil, fmt.Errorf("access denied")
+				},
+				FetchSecurityGroupsByPodsFunc: fetchPods(),
+			},
+			wantErr: true,
+		},
+		{
+			name:        "no load balancers",
+			k8sClient:   k8sClient(nil),
+			awsClient:   &fakeAWSClient{},
+			expectedOut: "No resources found in namespace.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			streams := &genericclioptions.IOStreams{
+				Out:    &bytes.Buffer{},
+				ErrOut: &bytes.Buffer{},
+			}
+			o := NewLoadBalancerOptions(streams)
+			o.K8sClient = tc.k8sClient
+			o.AWSClient = tc.awsClient
+			o.ConfigFlags.Namespace = stringPointer("default")
+
+			err := o.Run(context.Background())
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if got := streams.Out.(*bytes.Buffer).String(); !strings.Contains(got, tc.expectedOut) {
+				t.Errorf("Run() output = %q, want it to contain %q", got, tc.expectedOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+)
+
+// NodeOptions contains options for the node command
+type NodeOptions struct {
+	NodeName     string
+	OutputFormat string
+	SnapshotDir  string
+	AWSOptions   aws.ClientOptions
+	ConfigFlags  *genericclioptions.ConfigFlags
+	IOStreams    *genericclioptions.IOStreams
+	K8sClient    kubernetes.Interface
+	AWSClient    aws.Interface
+}
+
+// NewNodeOptions creates new NodeOptions with default values
+func NewNodeOptions(streams *genericclioptions.IOStreams) *NodeOptions {
+	return &NodeOptions{
+		ConfigFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// Run executes the node command business logic. The pods of the nodes are listed across all
+// namespaces because a node is not scoped to a namespace.
+func (o *NodeOptions) Run(ctx context.Context) error {
+	k8sClient, awsClient, err := newClients(ctx, o.SnapshotDir, o.ConfigFlags, o.AWSOptions, o.K8sClient, o.AWSClient)
+	if err != nil {
+		return err
+	}
+	o.AWSClient = awsClient
+
+	var nodes []corev1.Node
+	var selector kubernetes.PodSelector
+	if o.NodeName != "" {
+		node, err := k8sClient.GetNode(ctx, o.NodeName)
+		if err != nil {
+			return err
+		}
+		nodes = []corev1.Node{*node}
+		selector.Field = "spec.nodeName=" + o.NodeName
+	} else {
+		nodes, err = k8sClient.ListNodes(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	pods, err := k8sClient.ListPods(ctx, "", selector)
+	if err != nil {
+		return err
+	}
+
+	result, err := o.AWSClient.FetchNodeNetworkInterfaces(ctx, nodes, pods)
+	if err != nil {
+		return fmt.Errorf("failed to get node network interfaces: %w", err)
+	}
+
+	return output.OutputNodeNetworkInterfaces(o.IOStreams.Out, result, o.OutputFormat)
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+)
+
+func TestNodeOptions_Run(t *testing.T) {
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+	}
+	fetchNodes := &fakeAWSClient{
+		FetchNodeNetworkInterfacesFunc: func(ctx context.Context, nodes []corev1.Node, pods []corev1.Pod) ([]aws.NodeNetworkInterfaces, error) {
+			var result []aws.NodeNetworkInterfaces
+			for _, node := range nodes {
+				result = append(result, aws.NodeNetworkInterfaces{Node: node, Status: aws.StatusNoInstance, Reason: fmt.Sprintf("%d pods", len(pods))})
+			}
+			return result, nil
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		nodeName    string
+		k8sClient   *fakeK8sClient
+		awsClient   *fakeAWSClient
+		wantErr     bool
+		expectedOut []string
+	}{
+		{
+			name: "lists every node with the pods of all namespaces",
+			k8sClient: &fakeK8sClient{
+				ListNodesFunc: func(ctx context.Context) ([]corev1.Node, error) {
+					return nodes, nil
+				},
+				ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+					if namespace != "" || selector.Field != "" {
+						return nil, fmt.Errorf("expected all pods, got namespace %q and selector %q", namespace, selector.Field)
+					}
+					return []corev1.Pod{{}, {}}, nil
+				},
+			},
+			awsClient:   fetchNodes,
+			expectedOut: []string{"node-1", "node-2", "2 pods"},
+		},
+		{
+			name:     "selects the pods of the named node",
+			nodeName: "node-2",
+			k8sClient: &fakeK8sClient{
+				GetNodeFunc: func(ctx context.Context, name string) (*corev1.Node, error) {
+					return &nodes[1], nil
+				},
+				ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+					if selector.Field != "spec.nodeName=node-2" {
+						return nil, fmt.Errorf("unexpected field selector %q", selector.Field)
+					}
+					return []corev1.Pod{{}}, nil
+				},
+			},
+			awsClient:   fetchNodes,
+			expectedOut: []string{"node-2", "1 pods"},
+		},
+		{
+			name:     "node not found",
+			nodeName: "node-3",
+			k8sClient: &fakeK8sClient{
+				GetNodeFunc: func(ctx context.Context, name string) (*corev1.Node, error) {
+					return nil, fmt.Errorf("node %s not found", name)
+				},
+			},
+			awsClient: fetchNodes,
+			wantErr:   true,
+		},
+		{
+			name: "aws client error",
+			k8sClient: &fakeK8sClient{
+				ListNodesFunc: func(ctx context.Context) ([]corev1.Node, error) {
+					return nodes, nil
+				},
+				ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+					return nil, nil
+				},
+			},
+			awsClient: &fakeAWSClient{
+				FetchNodeNetworkInterfacesFunc: func(ctx context.Context, nodes []corev1.Node, pods []corev1.Pod) ([]aws.NodeNetworkInterfaces, error) {
+					return nil, fmt.Errorf("aws error")
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			streams := &genericclioptions.IOStreams{
+				Out:    &bytes.Buffer{},
+				ErrOut: &bytes.Buffer{},
+			}
+			o := NewNodeOptions(streams)
+			o.K8sClient = tc.k8sClient
+			o.AWSClient = tc.awsClient
+			o.NodeName = tc.nodeName
+
+			err := o.Run(context.Background())
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			got := streams.Out.(*bytes.Buffer).String()
+			for _, want := range tc.expectedOut {
+				if !strings.Contains(got, want) {
+					t.Errorf("Run() output = %q, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
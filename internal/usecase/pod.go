@@ -4,25 +4,61 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
 
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 
 	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
 	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/netpol"
 	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/policy"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/workload"
 )
 
 // PodOptions contains options for the pod command
 type PodOptions struct {
 	PodName       string
+	LabelSelector string
+	FieldSelector string
 	OutputFormat  string
 	SortField     string
 	AllNamespaces bool
-	ConfigFlags   *genericclioptions.ConfigFlags
-	IOStreams     *genericclioptions.IOStreams
-	K8sClient     kubernetes.Interface
-	AWSClient     aws.Interface
+	ShowPolicies  bool
+	// ShowNetworkPolicies outputs the NetworkPolicies selecting each pod next to its security group
+	// rules, each rule checked against the other layer
+	ShowNetworkPolicies bool
+	ResolvePeers        bool
+	ShowTags            bool
+	CollapseOwners      bool
+	CIDRPeers           bool
+	Watch               bool
+	Strict              bool
+	Contexts            []string
+	AllContexts         bool
+	SnapshotDir         string
+	// SecurityGroups, Attachment, ENIs and Tags keep only the results using one of the security
+	// groups (by ID or name), attached at the level, on one of the ENIs, or using a security group
+	// carrying all the tags
+	SecurityGroups []string
+	Attachment     string
+	ENIs           []string
+	Tags           map[string]string
+	AWSOptions     aws.ClientOptions
+	ConfigFlags    *genericclioptions.ConfigFlags
+	IOStreams      *genericclioptions.IOStreams
+	K8sClient      kubernetes.Interface
+	AWSClient      aws.Interface
+
+	// newContextClients overrides the client construction of each context in tests
+	newContextClients contextClientsFunc
 }
 
 // NewPodOptions creates new PodOptions with default values
@@ -35,28 +71,30 @@ func NewPodOptions(streams *genericclioptions.IOStreams) *PodOptions {
 
 // Run executes the pod command business logic
 func (o *PodOptions) Run(ctx context.Context) error {
-	k8sClient := o.K8sClient
-	if k8sClient == nil {
-		var err error
-		k8sClient, err = kubernetes.NewClient(o.ConfigFlags)
-		if err != nil {
-			return err
-		}
+	if len(o.Contexts) > 0 || o.AllContexts {
+		return o.runContexts(ctx)
 	}
 
-	if o.AWSClient == nil {
-		var err error
-		o.AWSClient, err = aws.NewClient(nil)
-		if err != nil {
-			return fmt.Errorf("failed to create aws client: %w", err)
-		}
+	if o.Watch {
+		// Watching follows ENIs as they are attached and detached, which cached lookups would hide
+		o.AWSOptions.Cache = aws.CacheOptions{}
 	}
 
+	k8sClient, awsClient, err := newClients(ctx, o.SnapshotDir, o.ConfigFlags, o.AWSOptions, o.K8sClient, o.AWSClient)
+	if err != nil {
+		return err
+	}
+	o.AWSClient = awsClient
+
 	namespace, err := o.getNamespace()
 	if err != nil {
 		return fmt.Errorf("failed to get namespace: %w", err)
 	}
 
+	if o.Watch {
+		return o.runWatch(ctx, k8sClient, namespace)
+	}
+
 	var pods []corev1.Pod
 	if o.PodName != "" {
 		pod, podErr := k8sClient.GetPod(ctx, o.PodName, namespace)
@@ -66,7 +104,7 @@ func (o *PodOptions) Run(ctx context.Context) error {
 		pods = []corev1.Pod{*pod}
 	} else {
 		var listErr error
-		pods, listErr = k8sClient.ListPods(ctx, namespace)
+		pods, listErr = k8sClient.ListPods(ctx, namespace, o.selector())
 		if listErr != nil {
 			return listErr
 		}
@@ -81,22 +119,409 @@ func (o *PodOptions) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get security groups: %w", err)
 	}
+	result = o.filterResults(result)
 
 	if len(result) == 0 {
 		fmt.Fprintln(o.IOStreams.Out, "No security group information found for the specified pods")
 		return nil
 	}
+	warnLookupFailures(o.IOStreams.ErrOut, result)
+
+	switch {
+	case o.ShowPolicies:
+		err = o.outputPolicyDrift(ctx, k8sClient, namespace, result)
+	case o.ShowNetworkPolicies:
+		err = o.outputNetworkPolicies(ctx, k8sClient, namespace, result)
+	case output.IsGraphFormat(o.OutputFormat):
+		err = o.outputGraph(ctx, k8sClient, namespace, result)
+	default:
+		err = o.outputSecurityGroups(ctx, k8sClient, result)
+	}
+	if err != nil {
+		return err
+	}
+	return o.strictError(result)
+}
+
+// contextResult holds the pods looked up in a kubeconfig context, with the rules of their security
+// groups for structured output
+type contextResult struct {
+	infos []aws.PodSecurityGroupInfo
+	rules []types.SecurityGroupRule
+	// rulesErr is the failed lookup of the rules, which only omits the rule IDs
+	rulesErr error
+}
+
+// runContexts looks up the pods of every requested kubeconfig context concurrently, with one
+// Kubernetes and one AWS client per context, and outputs the merged results with the context of
+// each pod. A failing context is reported on stderr without aborting the others.
+func (o *PodOptions) runContexts(ctx context.Context) error {
+	contexts, err := resolveContexts(o.ConfigFlags, o.Contexts, o.AllContexts)
+	if err != nil {
+		return err
+	}
+
+	newContextClients := o.newContextClients
+	if newContextClients == nil {
+		newContextClients = func(ctx context.Context, name string) (kubernetes.Interface, aws.Interface, error) {
+			return newClients(ctx, "", contextConfigFlags(o.ConfigFlags, name), o.AWSOptions, nil, nil)
+		}
+	}
+
+	results := make([]contextResult, len(contexts))
+	errs := make([]error, len(contexts))
+	var wg sync.WaitGroup
+	for i, name := range contexts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = o.fetchContext(ctx, name, newContextClients)
+		}()
+	}
+	wg.Wait()
+
+	var merged []aws.PodSecurityGroupInfo
+	opts := output.SecurityGroupOptions{ShowTags: o.ShowTags}
+	failed := 0
+	for i, name := range contexts {
+		if errs[i] != nil {
+			failed++
+			fmt.Fprintf(o.IOStreams.ErrOut, "error: context %s: %v\n", name, errs[i])
+			continue
+		}
+		if results[i].rulesErr != nil {
+			fmt.Fprintf(o.IOStreams.ErrOut, "Warning: context %s: security group rule IDs are omitted: %v\n", name, results[i].rulesErr)
+		}
+		merged = append(merged, results[i].infos...)
+		opts.Rules = append(opts.Rules, results[i].rules...)
+	}
+	if failed == len(contexts) {
+		return fmt.Errorf("failed to query all %d contexts", len(contexts))
+	}
+
+	if len(merged) == 0 {
+		fmt.Fprintln(o.IOStreams.Out, "No security group information found for the specified pods")
+		return nil
+	}
+	warnLookupFailures(o.IOStreams.ErrOut, merged)
+
+	if err := output.OutputPodSecurityGroups(o.IOStreams.Out, merged, o.OutputFormat, o.SortField, opts); err != nil {
+		return err
+	}
+	return o.strictError(merged)
+}
+
+// fetchContext returns the security groups of the pods in a kubeconfig context that match the
+// filters, with the rules of the security groups looked up with the AWS client of the context for
+// structured output. A pod requested by name that does not exist in the context yields no result
+// rather than an error.
+func (o *PodOptions) fetchContext(ctx context.Context, name string, newContextClients contextClientsFunc) (contextResult, error) {
+	k8sClient, awsClient, err := newContextClients(ctx, name)
+	if err != nil {
+		return contextResult{}, err
+	}
 
-	return output.OutputPodSecurityGroups(o.IOStreams.Out, result, o.OutputFormat, o.SortField)
+	namespace, err := resolveNamespace(contextConfigFlags(o.ConfigFlags, name), o.AllNamespaces)
+	if err != nil {
+		return contextResult{}, fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	var pods []corev1.Pod
+	if o.PodName != "" {
+		pod, err := k8sClient.GetPod(ctx, o.PodName, namespace)
+		if apierrors.IsNotFound(err) {
+			return contextResult{}, nil
+		}
+		if err != nil {
+			return contextResult{}, err
+		}
+		pods = []corev1.Pod{*pod}
+	} else {
+		pods, err = k8sClient.ListPods(ctx, namespace, o.selector())
+		if err != nil {
+			return contextResult{}, err
+		}
+	}
+	if len(pods) == 0 {
+		return contextResult{}, nil
+	}
+
+	result, err := awsClient.FetchSecurityGroupsByPods(ctx, pods)
+	if err != nil {
+		return contextResult{}, fmt.Errorf("failed to get security groups: %w", err)
+	}
+	for i := range result {
+		result[i].Context = name
+	}
+
+	res := contextResult{infos: o.filterResults(result)}
+	if output.IsStructuredFormat(o.OutputFormat) {
+		var sgs []types.SecurityGroup
+		for _, info := range res.infos {
+			sgs = append(sgs, info.SecurityGroups...)
+		}
+		res.rules, res.rulesErr = fetchSecurityGroupRules(ctx, awsClient, sgs)
+	}
+	return res, nil
+}
+
+// warnLookupFailures reports on stderr the pods whose ENI or security groups could not be found in
+// EC2 or whose lookup failed, keeping stdout parseable for structured output formats
+func warnLookupFailures(w io.Writer, result []aws.PodSecurityGroupInfo) {
+	for _, info := range result {
+		switch info.Status {
+		case aws.StatusENINotFound, aws.StatusSGNotFound, aws.StatusLookupFailed:
+			fmt.Fprintf(w, "Warning: pod %s/%s: %s\n", info.Pod.Namespace, info.Pod.Name, info.Reason)
+		}
+	}
+}
+
+// strictError returns an *ExitError in strict mode when the EC2 lookup of some pods failed. The
+// other pods have already been output.
+func (o *PodOptions) strictError(result []aws.PodSecurityGroupInfo) error {
+	if !o.Strict {
+		return nil
+	}
+	return lookupFailedError(result)
+}
+
+// lookupFailedError returns an *ExitError when the EC2 lookup of some pods failed
+func lookupFailedError(result []aws.PodSecurityGroupInfo) error {
+	failed := 0
+	for _, info := range result {
+		if info.Status == aws.StatusLookupFailed {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return &ExitError{Code: ExitCodeIncomplete, Reason: fmt.Sprintf("failed to look up %d pods", failed)}
+}
+
+// incompleteError reports on stderr the pods whose EC2 lookup failed and returns an *ExitError for
+// them. Commands other than pod always fail this way, so that a failed lookup does not pass for a
+// complete result.
+func incompleteError(w io.Writer, result []aws.PodSecurityGroupInfo) error {
+	for _, info := range result {
+		if info.Status == aws.StatusLookupFailed {
+			fmt.Fprintf(w, "Warning: pod %s/%s: %s\n", info.Pod.Namespace, info.Pod.Name, info.Reason)
+		}
+	}
+	return lookupFailedError(result)
+}
+
+// outputPolicyDrift evaluates the SecurityGroupPolicies in the namespace against the pods and
+// outputs the security groups each pod should have next to the ones attached to its ENI
+func (o *PodOptions) outputPolicyDrift(ctx context.Context, k8sClient kubernetes.Interface, namespace string, result []aws.PodSecurityGroupInfo) error {
+	policies, err := k8sClient.ListSecurityGroupPolicies(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	serviceAccounts, err := k8sClient.ListServiceAccounts(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Pod.Namespace != result[j].Pod.Namespace {
+			return result[i].Pod.Namespace < result[j].Pod.Namespace
+		}
+		return result[i].Pod.Name < result[j].Pod.Name
+	})
+
+	return output.OutputPolicyDrift(o.IOStreams.Out, policy.Evaluate(result, policies, serviceAccounts), o.OutputFormat)
+}
+
+// outputSecurityGroups outputs the security groups of the pods, with the peers of their rules
+// resolved when requested. Peers are matched against the pods and nodes of the whole cluster.
+// Structured output carries the ID of each rule as well.
+func (o *PodOptions) outputSecurityGroups(ctx context.Context, k8sClient kubernetes.Interface, result []aws.PodSecurityGroupInfo) error {
+	opts := output.SecurityGroupOptions{ShowTags: o.ShowTags}
+	var sgs []types.SecurityGroup
+	for _, info := range result {
+		sgs = append(sgs, info.SecurityGroups...)
+	}
+	if o.ResolvePeers {
+		pods, err := k8sClient.ListPods(ctx, "", kubernetes.PodSelector{})
+		if err != nil {
+			return err
+		}
+		nodes, err := k8sClient.ListNodes(ctx)
+		if err != nil {
+			return err
+		}
+		if opts.Peers, err = o.AWSClient.ResolvePeers(ctx, sgs, pods, nodes); err != nil {
+			return fmt.Errorf("failed to resolve rule peers: %w", err)
+		}
+	}
+	if output.IsStructuredFormat(o.OutputFormat) {
+		rules, err := fetchSecurityGroupRules(ctx, o.AWSClient, sgs)
+		if err != nil {
+			fmt.Fprintf(o.IOStreams.ErrOut, "Warning: security group rule IDs are omitted: %v\n", err)
+		}
+		opts.Rules = rules
+	}
+	return output.OutputPodSecurityGroups(o.IOStreams.Out, result, o.OutputFormat, o.SortField, opts)
+}
+
+// fetchSecurityGroupRules looks up the rules of the security groups for their IDs. The IDs are only
+// informational, so callers report a failed lookup, for example without the
+// ec2:DescribeSecurityGroupRules permission, as a warning and output the rules without them.
+func fetchSecurityGroupRules(ctx context.Context, awsClient aws.Interface, sgs []types.SecurityGroup) ([]types.SecurityGroupRule, error) {
+	var ids []string
+	for _, sg := range sgs {
+		if id := awsSDK.ToString(sg.GroupId); !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return awsClient.FetchSecurityGroupRules(ctx, ids)
+}
+
+// outputNetworkPolicies evaluates the NetworkPolicies in the namespace against the pods and outputs the
+// traffic each NetworkPolicy and security group rule allows, checked against the other layer. The pods
+// selected by NetworkPolicy rules are looked up in EC2 too, so that rules referencing their security
+// groups can be checked.
+func (o *PodOptions) outputNetworkPolicies(ctx context.Context, k8sClient kubernetes.Interface, namespace string, result []aws.PodSecurityGroupInfo) error {
+	policies, err := k8sClient.ListNetworkPolicies(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	cluster := netpol.Cluster{SecurityGroups: make(map[string]aws.PodSecurityGroupInfo, len(result))}
+	podsNamespace := namespace
+	if netpol.SelectsOtherNamespaces(policies) {
+		podsNamespace = ""
+		if cluster.Namespaces, err = k8sClient.ListNamespaces(ctx); err != nil {
+			return err
+		}
+	}
+	if cluster.Pods, err = k8sClient.ListPods(ctx, podsNamespace, kubernetes.PodSelector{}); err != nil {
+		return err
+	}
+
+	pods := make([]corev1.Pod, 0, len(result))
+	for _, info := range result {
+		pods = append(pods, info.Pod)
+		cluster.SecurityGroups[info.Pod.Namespace+"/"+info.Pod.Name] = info
+	}
+	var peers []corev1.Pod
+	for _, pod := range netpol.PeerPods(pods, policies, cluster) {
+		if _, ok := cluster.SecurityGroups[pod.Namespace+"/"+pod.Name]; !ok {
+			peers = append(peers, pod)
+		}
+	}
+	if len(peers) > 0 {
+		peerInfos, err := o.AWSClient.FetchSecurityGroupsByPods(ctx, peers)
+		if err != nil {
+			return fmt.Errorf("failed to get security groups: %w", err)
+		}
+		for _, info := range peerInfos {
+			cluster.SecurityGroups[info.Pod.Namespace+"/"+info.Pod.Name] = info
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Pod.Namespace != result[j].Pod.Namespace {
+			return result[i].Pod.Namespace < result[j].Pod.Namespace
+		}
+		return result[i].Pod.Name < result[j].Pod.Name
+	})
+
+	return output.OutputNetworkPolicies(o.IOStreams.Out, netpol.Evaluate(result, policies, cluster), o.OutputFormat)
+}
+
+// outputGraph outputs the pods and their security groups as a graph, collapsing pods into their
+// controllers when requested
+func (o *PodOptions) outputGraph(ctx context.Context, k8sClient kubernetes.Interface, namespace string, result []aws.PodSecurityGroupInfo) error {
+	opts := output.GraphOptions{CIDRPeers: o.CIDRPeers}
+	if o.CollapseOwners {
+		replicaSets, err := k8sClient.ListReplicaSets(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		jobs, err := k8sClient.ListJobs(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		opts.Owners = workload.NewResolver(replicaSets, jobs)
+	}
+	return output.OutputGraph(o.IOStreams.Out, result, o.OutputFormat, opts)
+}
+
+// selector returns the label and field selectors to list pods with
+func (o *PodOptions) selector() kubernetes.PodSelector {
+	return kubernetes.PodSelector{Label: o.LabelSelector, Field: o.FieldSelector}
+}
+
+// filterResults keeps the results matching the security group, attachment and ENI filters. Each
+// filter that is set must match.
+func (o *PodOptions) filterResults(result []aws.PodSecurityGroupInfo) []aws.PodSecurityGroupInfo {
+	if len(o.SecurityGroups) == 0 && o.Attachment == "" && len(o.ENIs) == 0 && len(o.Tags) == 0 {
+		return result
+	}
+
+	var filtered []aws.PodSecurityGroupInfo
+	for _, info := range result {
+		if len(o.SecurityGroups) > 0 && !usesAnySecurityGroup(info, o.SecurityGroups) {
+			continue
+		}
+		if o.Attachment != "" && info.AttachmentLevel != o.Attachment {
+			continue
+		}
+		if len(o.ENIs) > 0 && !slices.Contains(o.ENIs, info.ENI) {
+			continue
+		}
+		if len(o.Tags) > 0 && !usesTaggedSecurityGroup(info, o.Tags) {
+			continue
+		}
+		filtered = append(filtered, info)
+	}
+	return filtered
+}
+
+// usesAnySecurityGroup reports whether one of the security groups of the pod has one of the IDs or names
+func usesAnySecurityGroup(info aws.PodSecurityGroupInfo, refs []string) bool {
+	for _, sg := range info.SecurityGroups {
+		if slices.Contains(refs, awsSDK.ToString(sg.GroupId)) || slices.Contains(refs, awsSDK.ToString(sg.GroupName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesTaggedSecurityGroup reports whether one of the security groups of the pod carries all the tags
+func usesTaggedSecurityGroup(info aws.PodSecurityGroupInfo, tags map[string]string) bool {
+	for _, sg := range info.SecurityGroups {
+		matched := 0
+		for _, t := range sg.Tags {
+			if value, ok := tags[awsSDK.ToString(t.Key)]; ok && value == awsSDK.ToString(t.Value) {
+				matched++
+			}
+		}
+		if matched == len(tags) {
+			return true
+		}
+	}
+	return false
 }
 
 func (o *PodOptions) getNamespace() (string, error) {
-	namespace, _, err := o.ConfigFlags.ToRawKubeConfigLoader().Namespace()
+	return resolveNamespace(o.ConfigFlags, o.AllNamespaces)
+}
+
+// resolveNamespace returns the namespace from the kubeconfig or flags, or an empty string for all namespaces
+func resolveNamespace(configFlags *genericclioptions.ConfigFlags, allNamespaces bool) (string, error) {
+	namespace, _, err := configFlags.ToRawKubeConfigLoader().Namespace()
 	if err != nil {
 		return "", err
 	}
 
-	if o.AllNamespaces {
+	if allNamespaces {
 		return "", nil
 	}
 
@@ -3,38 +3,198 @@ package usecase
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 
 	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
 	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
 )
 
 type fakeK8sClient struct {
-	GetPodFunc   func(ctx context.Context, name, namespace string) (*corev1.Pod, error)
-	ListPodsFunc func(ctx context.Context, namespace string) ([]corev1.Pod, error)
+	GetPodFunc           func(ctx context.Context, name, namespace string) (*corev1.Pod, error)
+	ListPodsFunc         func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error)
+	WatchPodsFunc        func(ctx context.Context, namespace, resourceVersion string, selector kubernetes.PodSelector) (watch.Interface, error)
+	ListReplicaSetsFunc  func(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error)
+	GetDeploymentFunc    func(ctx context.Context, name, namespace string) (*appsv1.Deployment, error)
+	ListDeploymentsFunc  func(ctx context.Context, namespace string) ([]appsv1.Deployment, error)
+	GetStatefulSetFunc   func(ctx context.Context, name, namespace string) (*appsv1.StatefulSet, error)
+	ListStatefulSetsFunc func(ctx context.Context, namespace string) ([]appsv1.StatefulSet, error)
+	GetDaemonSetFunc     func(ctx context.Context, name, namespace string) (*appsv1.DaemonSet, error)
+	ListDaemonSetsFunc   func(ctx context.Context, namespace string) ([]appsv1.DaemonSet, error)
+	GetJobFunc           func(ctx context.Context, name, namespace string) (*batchv1.Job, error)
+	ListJobsFunc         func(ctx context.Context, namespace string) ([]batchv1.Job, error)
+	GetCronJobFunc       func(ctx context.Context, name, namespace string) (*batchv1.CronJob, error)
+	ListCronJobsFunc     func(ctx context.Context, namespace string) ([]batchv1.CronJob, error)
+
+	ListServiceAccountsFunc       func(ctx context.Context, namespace string) ([]corev1.ServiceAccount, error)
+	ListSecurityGroupPoliciesFunc func(ctx context.Context, namespace string) ([]kubernetes.SecurityGroupPolicy, error)
+	GetNodeFunc                   func(ctx context.Context, name string) (*corev1.Node, error)
+	ListNodesFunc                 func(ctx context.Context) ([]corev1.Node, error)
+	GetServiceFunc                func(ctx context.Context, name, namespace string) (*corev1.Service, error)
+	ListServicesFunc              func(ctx context.Context, namespace string) ([]corev1.Service, error)
+	ListEndpointSlicesFunc        func(ctx context.Context, namespace string) ([]discoveryv1.EndpointSlice, error)
+	ListIngressesFunc             func(ctx context.Context, namespace string) ([]networkingv1.Ingress, error)
+	ListIngressClassesFunc        func(ctx context.Context) ([]networkingv1.IngressClass, error)
+	ListNetworkPoliciesFunc       func(ctx context.Context, namespace string) ([]networkingv1.NetworkPolicy, error)
+	ListNamespacesFunc            func(ctx context.Context) ([]corev1.Namespace, error)
 }
 
 func (f *fakeK8sClient) GetPod(ctx context.Context, name, namespace string) (*corev1.Pod, error) {
 	return f.GetPodFunc(ctx, name, namespace)
 }
 
-func (f *fakeK8sClient) ListPods(ctx context.Context, namespace string) ([]corev1.Pod, error) {
-	return f.ListPodsFunc(ctx, namespace)
+func (f *fakeK8sClient) ListPods(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+	return f.ListPodsFunc(ctx, namespace, selector)
+}
+
+func (f *fakeK8sClient) WatchPods(ctx context.Context, namespace, resourceVersion string, selector kubernetes.PodSelector) (watch.Interface, error) {
+	return f.WatchPodsFunc(ctx, namespace, resourceVersion, selector)
+}
+
+func (f *fakeK8sClient) ListReplicaSets(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error) {
+	return f.ListReplicaSetsFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) GetDeployment(ctx context.Context, name, namespace string) (*appsv1.Deployment, error) {
+	return f.GetDeploymentFunc(ctx, name, namespace)
+}
+
+func (f *fakeK8sClient) ListDeployments(ctx context.Context, namespace string) ([]appsv1.Deployment, error) {
+	return f.ListDeploymentsFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) GetStatefulSet(ctx context.Context, name, namespace string) (*appsv1.StatefulSet, error) {
+	return f.GetStatefulSetFunc(ctx, name, namespace)
+}
+
+func (f *fakeK8sClient) ListStatefulSets(ctx context.Context, namespace string) ([]appsv1.StatefulSet, error) {
+	return f.ListStatefulSetsFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) GetDaemonSet(ctx context.Context, name, namespace string) (*appsv1.DaemonSet, error) {
+	return f.GetDaemonSetFunc(ctx, name, namespace)
+}
+
+func (f *fakeK8sClient) ListDaemonSets(ctx context.Context, namespace string) ([]appsv1.DaemonSet, error) {
+	return f.ListDaemonSetsFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) GetJob(ctx context.Context, name, namespace string) (*batchv1.Job, error) {
+	return f.GetJobFunc(ctx, name, namespace)
+}
+
+func (f *fakeK8sClient) ListJobs(ctx context.Context, namespace string) ([]batchv1.Job, error) {
+	return f.ListJobsFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) GetCronJob(ctx context.Context, name, namespace string) (*batchv1.CronJob, error) {
+	return f.GetCronJobFunc(ctx, name, namespace)
+}
+
+func (f *fakeK8sClient) ListCronJobs(ctx context.Context, namespace string) ([]batchv1.CronJob, error) {
+	return f.ListCronJobsFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) ListServiceAccounts(ctx context.Context, namespace string) ([]corev1.ServiceAccount, error) {
+	return f.ListServiceAccountsFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) ListSecurityGroupPolicies(ctx context.Context, namespace string) ([]kubernetes.SecurityGroupPolicy, error) {
+	return f.ListSecurityGroupPoliciesFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) GetNode(ctx context.Context, name string) (*corev1.Node, error) {
+	return f.GetNodeFunc(ctx, name)
+}
+
+func (f *fakeK8sClient) ListNodes(ctx context.Context) ([]corev1.Node, error) {
+	return f.ListNodesFunc(ctx)
+}
+
+func (f *fakeK8sClient) GetService(ctx context.Context, name, namespace string) (*corev1.Service, error) {
+	return f.GetServiceFunc(ctx, name, namespace)
+}
+
+func (f *fakeK8sClient) ListServices(ctx context.Context, namespace string) ([]corev1.Service, error) {
+	return f.ListServicesFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) ListEndpointSlices(ctx context.Context, namespace string) ([]discoveryv1.EndpointSlice, error) {
+	return f.ListEndpointSlicesFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) ListIngresses(ctx context.Context, namespace string) ([]networkingv1.Ingress, error) {
+	return f.ListIngressesFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) ListIngressClasses(ctx context.Context) ([]networkingv1.IngressClass, error) {
+	return f.ListIngressClassesFunc(ctx)
+}
+
+func (f *fakeK8sClient) ListNetworkPolicies(ctx context.Context, namespace string) ([]networkingv1.NetworkPolicy, error) {
+	return f.ListNetworkPoliciesFunc(ctx, namespace)
+}
+
+func (f *fakeK8sClient) ListNamespaces(ctx context.Context) ([]corev1.Namespace, error) {
+	return f.ListNamespacesFunc(ctx)
 }
 
 type fakeAWSClient struct {
-	FetchSecurityGroupsByPodsFunc func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error)
+	FetchSecurityGroupsByPodsFunc  func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error)
+	FetchPodsBySecurityGroupsFunc  func(ctx context.Context, sgRefs []string, pods []corev1.Pod) ([]aws.SecurityGroupUsage, error)
+	FetchNodeNetworkInterfacesFunc func(ctx context.Context, nodes []corev1.Node, pods []corev1.Pod) ([]aws.NodeNetworkInterfaces, error)
+	FetchLoadBalancersFunc         func(ctx context.Context, dnsNames []string) ([]aws.LoadBalancer, error)
+	ResolvePeersFunc               func(ctx context.Context, securityGroups []types.SecurityGroup, pods []corev1.Pod, nodes []corev1.Node) (aws.Peers, error)
+	FetchSecurityGroupRulesFunc    func(ctx context.Context, sgIDs []string) ([]types.SecurityGroupRule, error)
+	FetchPrefixListsFunc           func(ctx context.Context, securityGroups []types.SecurityGroup) (map[string]aws.PrefixList, error)
 }
 
 func (f *fakeAWSClient) FetchSecurityGroupsByPods(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
 	return f.FetchSecurityGroupsByPodsFunc(ctx, pods)
 }
 
+func (f *fakeAWSClient) FetchPodsBySecurityGroups(ctx context.Context, sgRefs []string, pods []corev1.Pod) ([]aws.SecurityGroupUsage, error) {
+	return f.FetchPodsBySecurityGroupsFunc(ctx, sgRefs, pods)
+}
+
+func (f *fakeAWSClient) FetchNodeNetworkInterfaces(ctx context.Context, nodes []corev1.Node, pods []corev1.Pod) ([]aws.NodeNetworkInterfaces, error) {
+	return f.FetchNodeNetworkInterfacesFunc(ctx, nodes, pods)
+}
+
+func (f *fakeAWSClient) FetchLoadBalancers(ctx context.Context, dnsNames []string) ([]aws.LoadBalancer, error) {
+	return f.FetchLoadBalancersFunc(ctx, dnsNames)
+}
+
+func (f *fakeAWSClient) ResolvePeers(ctx context.Context, securityGroups []types.SecurityGroup, pods []corev1.Pod, nodes []corev1.Node) (aws.Peers, error) {
+	return f.ResolvePeersFunc(ctx, securityGroups, pods, nodes)
+}
+
+func (f *fakeAWSClient) FetchSecurityGroupRules(ctx context.Context, sgIDs []string) ([]types.SecurityGroupRule, error) {
+	return f.FetchSecurityGroupRulesFunc(ctx, sgIDs)
+}
+
+func (f *fakeAWSClient) FetchPrefixLists(ctx context.Context, securityGroups []types.SecurityGroup) (map[string]aws.PrefixList, error) {
+	return f.FetchPrefixListsFunc(ctx, securityGroups)
+}
+
 func TestPodOptions_Run(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -48,7 +208,7 @@ func TestPodOptions_Run(t *testing.T) {
 		{
 			name: "no pods found",
 			k8sClient: &fakeK8sClient{
-				ListPodsFunc: func(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+				ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
 					return []corev1.Pod{}, nil
 				},
 			},
@@ -74,7 +234,7 @@ func TestPodOptions_Run(t *testing.T) {
 		{
 			name: "k8s client error",
 			k8sClient: &fakeK8sClient{
-				ListPodsFunc: func(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+				ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
 					return nil, fmt.Errorf("k8s error")
 				},
 			},
@@ -84,7 +244,7 @@ func TestPodOptions_Run(t *testing.T) {
 		{
 			name: "aws client error",
 			k8sClient: &fakeK8sClient{
-				ListPodsFunc: func(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+				ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
 					return []corev1.Pod{{}}, nil
 				},
 			},
@@ -124,6 +284,198 @@ func TestPodOptions_Run(t *testing.T) {
 	}
 }
 
+func TestPodOptions_Run_LookupFailures(t *testing.T) {
+	k8sClient := &fakeK8sClient{
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			return []corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "default"}},
+			}, nil
+		},
+	}
+	awsClient := &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			return []aws.PodSecurityGroupInfo{
+				{Pod: pods[0], Status: aws.StatusENINotFound, Reason: "no ENI has IP 10.0.0.1"},
+				{Pod: pods[1], Status: aws.StatusNotRunning, Reason: "pod phase is Pending"},
+			}, nil
+		},
+	}
+
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: errOut})
+	o.K8sClient = k8sClient
+	o.AWSClient = awsClient
+	o.OutputFormat = "json"
+	o.ConfigFlags.Namespace = stringPointer("default")
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result []output.PodOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(result) != 2 || result[0].Status != aws.StatusNotRunning || result[1].Status != aws.StatusENINotFound {
+		t.Errorf("Run() output = %+v, want both pods with their status", result)
+	}
+	if got, want := errOut.String(), "Warning: pod default/web: no ENI has IP 10.0.0.1\n"; got != want {
+		t.Errorf("Run() error output = %q, want %q", got, want)
+	}
+}
+
+func TestPodOptions_Run_Strict(t *testing.T) {
+	k8sClient := &fakeK8sClient{
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			return []corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"}},
+			}, nil
+		},
+	}
+	awsClient := &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			return []aws.PodSecurityGroupInfo{
+				{Pod: pods[0], ENI: "eni-1", AttachmentLevel: "pod", Status: aws.StatusMapped},
+				{Pod: pods[1], Status: aws.StatusLookupFailed, Reason: "failed to look up ENI of IP 10.0.0.2: RequestLimitExceeded"},
+			}, nil
+		},
+	}
+
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict=%v", strict), func(t *testing.T) {
+			out := &bytes.Buffer{}
+			errOut := &bytes.Buffer{}
+			o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: errOut})
+			o.K8sClient = k8sClient
+			o.AWSClient = awsClient
+			o.OutputFormat = "json"
+			o.Strict = strict
+			o.ConfigFlags.Namespace = stringPointer("default")
+
+			err := o.Run(context.Background())
+
+			var exitErr *ExitError
+			if strict {
+				if !errors.As(err, &exitErr) || exitErr.Code != ExitCodeIncomplete {
+					t.Fatalf("Run() error = %v, want exit code %d", err, ExitCodeIncomplete)
+				}
+			} else if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			var result []output.PodOutput
+			if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+				t.Fatalf("output is not valid JSON: %v\n%s", err, out.String())
+			}
+			if len(result) != 2 {
+				t.Errorf("Run() output = %+v, want the pods that could be looked up as well", result)
+			}
+			if got, want := errOut.String(), "Warning: pod default/api: failed to look up ENI of IP 10.0.0.2: RequestLimitExceeded\n"; got != want {
+				t.Errorf("Run() error output = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPodOptions_Run_Filters(t *testing.T) {
+	web := types.SecurityGroup{
+		GroupId:   awsSDK.String("sg-1"),
+		GroupName: awsSDK.String("web"),
+		Tags:      []types.Tag{{Key: awsSDK.String("team"), Value: awsSDK.String("payments")}, {Key: awsSDK.String("env"), Value: awsSDK.String("prod")}},
+	}
+	api := types.SecurityGroup{
+		GroupId:   awsSDK.String("sg-2"),
+		GroupName: awsSDK.String("api"),
+		Tags:      []types.Tag{{Key: awsSDK.String("team"), Value: awsSDK.String("platform")}},
+	}
+	var gotSelector kubernetes.PodSelector
+	k8sClient := &fakeK8sClient{
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			gotSelector = selector
+			return []corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "node-agent", Namespace: "default"}},
+			}, nil
+		},
+	}
+	awsClient := &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			return []aws.PodSecurityGroupInfo{
+				{Pod: pods[0], ENI: "eni-1", AttachmentLevel: "pod", SecurityGroups: []types.SecurityGroup{web}},
+				{Pod: pods[1], ENI: "eni-2", AttachmentLevel: "pod", SecurityGroups: []types.SecurityGroup{api}},
+				{Pod: pods[2], ENI: "eni-3", AttachmentLevel: "node", SecurityGroups: []types.SecurityGroup{web}},
+			}, nil
+		},
+		FetchSecurityGroupRulesFunc: func(ctx context.Context, sgIDs []string) ([]types.SecurityGroupRule, error) {
+			return nil, nil
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		configure     func(o *PodOptions)
+		expectedNames []string
+	}{
+		{name: "no filters", configure: func(o *PodOptions) {}, expectedNames: []string{"api", "node-agent", "web"}},
+		{name: "security group by ID", configure: func(o *PodOptions) { o.SecurityGroups = []string{"sg-1"} }, expectedNames: []string{"node-agent", "web"}},
+		{name: "security group by name", configure: func(o *PodOptions) { o.SecurityGroups = []string{"api"} }, expectedNames: []string{"api"}},
+		{name: "attachment", configure: func(o *PodOptions) { o.Attachment = "node" }, expectedNames: []string{"node-agent"}},
+		{name: "eni", configure: func(o *PodOptions) { o.ENIs = []string{"eni-1", "eni-2"} }, expectedNames: []string{"api", "web"}},
+		{name: "tag", configure: func(o *PodOptions) { o.Tags = map[string]string{"team": "platform"} }, expectedNames: []string{"api"}},
+		{
+			name:          "all tags on one security group",
+			configure:     func(o *PodOptions) { o.Tags = map[string]string{"team": "payments", "env": "prod"} },
+			expectedNames: []string{"node-agent", "web"},
+		},
+		{
+			name: "combined filters",
+			configure: func(o *PodOptions) {
+				o.SecurityGroups = []string{"sg-1"}
+				o.Attachment = "pod"
+			},
+			expectedNames: []string{"web"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+			o.K8sClient = k8sClient
+			o.AWSClient = awsClient
+			o.OutputFormat = "json"
+			o.SortField = "pod"
+			o.LabelSelector = "app=web"
+			o.FieldSelector = "spec.nodeName=node-1"
+			o.ConfigFlags.Namespace = stringPointer("default")
+			tc.configure(o)
+
+			if err := o.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if want := (kubernetes.PodSelector{Label: "app=web", Field: "spec.nodeName=node-1"}); gotSelector != want {
+				t.Errorf("ListPods() selector = %+v, want %+v", gotSelector, want)
+			}
+			var result []output.PodOutput
+			if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+				t.Fatalf("output is not valid JSON: %v", err)
+			}
+			var names []string
+			for _, p := range result {
+				names = append(names, p.PodName)
+			}
+			if !reflect.DeepEqual(names, tc.expectedNames) {
+				t.Errorf("Run() pods = %v, want %v", names, tc.expectedNames)
+			}
+		})
+	}
+}
+
 func TestPodOptions_getNamespace(t *testing.T) {
 	testCases := []struct {
 		name              string
@@ -186,3 +538,497 @@ func TestPodOptions_getNamespace(t *testing.T) {
 func stringPointer(s string) *string {
 	return &s
 }
+
+func TestPodOptions_Run_Policies(t *testing.T) {
+	k8sClient := &fakeK8sClient{
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			return []corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"}}},
+			}, nil
+		},
+		ListSecurityGroupPoliciesFunc: func(ctx context.Context, namespace string) ([]kubernetes.SecurityGroupPolicy, error) {
+			p := kubernetes.SecurityGroupPolicy{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+			p.Spec.PodSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+			p.Spec.SecurityGroups.Groups = []string{"sg-web"}
+			return []kubernetes.SecurityGroupPolicy{p}, nil
+		},
+		ListServiceAccountsFunc: func(ctx context.Context, namespace string) ([]corev1.ServiceAccount, error) {
+			return nil, nil
+		},
+	}
+	awsClient := &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			return []aws.PodSecurityGroupInfo{{Pod: pods[0], ENI: "eni-node", AttachmentLevel: "node", Status: aws.StatusMapped}}, nil
+		},
+	}
+
+	out := &bytes.Buffer{}
+	o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = k8sClient
+	o.AWSClient = awsClient
+	o.ShowPolicies = true
+	o.OutputFormat = "json-minimal"
+	o.ConfigFlags.Namespace = stringPointer("default")
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	expected := `[{"podName":"web","namespace":"default","attachmentLevel":"node","policies":["web"],"multiplePolicies":false,"expectedSecurityGroups":["sg-web"],"actualSecurityGroups":[],"status":"drift"}]`
+	if got := out.String(); got != expected {
+		t.Errorf("Run() output = %q, want %q", got, expected)
+	}
+}
+
+func TestPodOptions_Run_NetworkPolicies(t *testing.T) {
+	web := *runningPod("web", "10.0.1.10")
+	web.Labels = map[string]string{"app": "web"}
+	front := *runningPod("front", "10.0.1.20")
+	front.Labels = map[string]string{"app": "front"}
+
+	k8sClient := &fakeK8sClient{
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			if selector.Label == "app=web" {
+				return []corev1.Pod{web}, nil
+			}
+			return []corev1.Pod{web, front}, nil
+		},
+		ListNetworkPoliciesFunc: func(ctx context.Context, namespace string) ([]networkingv1.NetworkPolicy, error) {
+			return []networkingv1.NetworkPolicy{{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					Ingress: []networkingv1.NetworkPolicyIngressRule{{
+						From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "front"}}}},
+					}},
+				},
+			}}, nil
+		},
+	}
+	fromVPC := types.IpPermission{
+		IpProtocol: awsSDK.String("tcp"),
+		FromPort:   awsSDK.Int32(8080),
+		ToPort:     awsSDK.Int32(8080),
+		IpRanges:   []types.IpRange{{CidrIp: awsSDK.String("10.0.0.0/16")}},
+	}
+	var lookedUp []string
+	awsClient := &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			var infos []aws.PodSecurityGroupInfo
+			for _, pod := range pods {
+				lookedUp = append(lookedUp, pod.Name)
+				sg := types.SecurityGroup{GroupId: awsSDK.String("sg-" + pod.Name)}
+				if pod.Name == "web" {
+					sg.IpPermissions = []types.IpPermission{fromVPC}
+				}
+				infos = append(infos, aws.PodSecurityGroupInfo{Pod: pod, ENI: "eni-" + pod.Name, AttachmentLevel: "pod", SecurityGroups: []types.SecurityGroup{sg}})
+			}
+			return infos, nil
+		},
+	}
+
+	out := &bytes.Buffer{}
+	o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = k8sClient
+	o.AWSClient = awsClient
+	o.ShowNetworkPolicies = true
+	o.LabelSelector = "app=web"
+	o.ConfigFlags.Namespace = stringPointer("default")
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if strings.Join(lookedUp, ",") != "web,front" {
+		t.Errorf("looked up pods = %v, want the pod and then its NetworkPolicy peer", lookedUp)
+	}
+	for _, want := range []string{
+		"ingress    SecurityGroup  sg-web  tcp       8080        10.0.0.0/16      BLOCKED-BY-NETPOL",
+		"ingress    NetworkPolicy  web     all       all         pods(app=front)  BLOCKED-BY-SG",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Run() output = %q, want it to contain %q", out.String(), want)
+		}
+	}
+}
+
+func TestPodOptions_Run_ResolvePeers(t *testing.T) {
+	web := *runningPod("web", "10.0.1.10")
+	db := *runningPod("db-0", "10.0.2.10")
+	db.Namespace = "data"
+
+	var listedNamespaces []string
+	k8sClient := &fakeK8sClient{
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			listedNamespaces = append(listedNamespaces, namespace)
+			if namespace == "" {
+				return []corev1.Pod{web, db}, nil
+			}
+			return []corev1.Pod{web}, nil
+		},
+		ListNodesFunc: func(ctx context.Context) ([]corev1.Node, error) {
+			return []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}, nil
+		},
+	}
+	toDB := types.IpPermission{
+		IpProtocol:       awsSDK.String("tcp"),
+		FromPort:         awsSDK.Int32(5432),
+		ToPort:           awsSDK.Int32(5432),
+		UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: awsSDK.String("sg-db")}},
+	}
+	awsClient := &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			return []aws.PodSecurityGroupInfo{{
+				Pod:            pods[0],
+				ENI:            "eni-web",
+				SecurityGroups: []types.SecurityGroup{{GroupId: awsSDK.String("sg-web"), IpPermissionsEgress: []types.IpPermission{toDB}}},
+			}}, nil
+		},
+		ResolvePeersFunc: func(ctx context.Context, securityGroups []types.SecurityGroup, pods []corev1.Pod, nodes []corev1.Node) (aws.Peers, error) {
+			if len(securityGroups) != 1 || len(pods) != 2 || len(nodes) != 1 {
+				return aws.Peers{}, fmt.Errorf("unexpected input: %d security groups, %d pods, %d nodes", len(securityGroups), len(pods), len(nodes))
+			}
+			return aws.Peers{SecurityGroups: map[string]aws.PeerSecurityGroup{
+				"sg-db": {ID: "sg-db", Name: "db", Pods: []string{"data/db-0"}},
+			}}, nil
+		},
+	}
+
+	out := &bytes.Buffer{}
+	o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = k8sClient
+	o.AWSClient = awsClient
+	o.ResolvePeers = true
+	o.OutputFormat = "wide"
+	o.ConfigFlags.Namespace = stringPointer("default")
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if strings.Join(listedNamespaces, ",") != "default," {
+		t.Errorf("listed pods in namespaces %q, want the namespace and then the whole cluster", listedNamespaces)
+	}
+	if want := "sg-db (db; pods: data/db-0)"; !strings.Contains(out.String(), want) {
+		t.Errorf("Run() output = %q, want it to contain %q", out.String(), want)
+	}
+}
+
+func TestPodOptions_Run_RuleIDs(t *testing.T) {
+	k8sClient := &fakeK8sClient{
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			return []corev1.Pod{*runningPod("web", "10.0.1.10")}, nil
+		},
+	}
+	sg := types.SecurityGroup{
+		GroupId: awsSDK.String("sg-web"),
+		IpPermissions: []types.IpPermission{{
+			IpProtocol: awsSDK.String("tcp"),
+			FromPort:   awsSDK.Int32(443),
+			ToPort:     awsSDK.Int32(443),
+			IpRanges:   []types.IpRange{{CidrIp: awsSDK.String("10.0.0.0/8"), Description: awsSDK.String("allow from payments API")}},
+		}},
+	}
+
+	testCases := []struct {
+		name      string
+		rules     []types.SecurityGroupRule
+		rulesErr  error
+		wantEntry string
+		wantWarn  string
+	}{
+		{
+			name: "rules found",
+			rules: []types.SecurityGroupRule{{
+				SecurityGroupRuleId: awsSDK.String("sgr-1"),
+				GroupId:             awsSDK.String("sg-web"),
+				IsEgress:            awsSDK.Bool(false),
+				IpProtocol:          awsSDK.String("tcp"),
+				FromPort:            awsSDK.Int32(443),
+				ToPort:              awsSDK.Int32(443),
+				CidrIpv4:            awsSDK.String("10.0.0.0/8"),
+			}},
+			wantEntry: `"entries":[{"id":"sgr-1","peer":"10.0.0.0/8","description":"allow from payments API"}]`,
+		},
+		{
+			name:      "lookup failed",
+			rulesErr:  errors.New("UnauthorizedOperation"),
+			wantEntry: `"entries":[{"peer":"10.0.0.0/8","description":"allow from payments API"}]`,
+			wantWarn:  "Warning: security group rule IDs are omitted: UnauthorizedOperation\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotIDs []string
+			awsClient := &fakeAWSClient{
+				FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+					return []aws.PodSecurityGroupInfo{{Pod: pods[0], ENI: "eni-1", AttachmentLevel: "pod", SecurityGroups: []types.SecurityGroup{sg, sg}}}, nil
+				},
+				FetchSecurityGroupRulesFunc: func(ctx context.Context, sgIDs []string) ([]types.SecurityGroupRule, error) {
+					gotIDs = sgIDs
+					return tc.rules, tc.rulesErr
+				},
+			}
+
+			out := &bytes.Buffer{}
+			errOut := &bytes.Buffer{}
+			o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: errOut})
+			o.K8sClient = k8sClient
+			o.AWSClient = awsClient
+			o.OutputFormat = "json-minimal"
+			o.ConfigFlags.Namespace = stringPointer("default")
+
+			if err := o.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(gotIDs, []string{"sg-web"}) {
+				t.Errorf("FetchSecurityGroupRules() security groups = %v, want [sg-web]", gotIDs)
+			}
+			if !strings.Contains(out.String(), tc.wantEntry) {
+				t.Errorf("Run() output = %s, want it to contain %s", out.String(), tc.wantEntry)
+			}
+			if errOut.String() != tc.wantWarn {
+				t.Errorf("Run() error output = %q, want %q", errOut.String(), tc.wantWarn)
+			}
+		})
+	}
+}
+
+func TestPodOptions_Run_GraphCollapseOwners(t *testing.T) {
+	controller := true
+	ownedBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+	}
+	k8sClient := &fakeK8sClient{
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			return []corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", OwnerReferences: ownedBy("ReplicaSet", "web-abc")}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "default", OwnerReferences: ownedBy("ReplicaSet", "web-abc")}},
+			}, nil
+		},
+		ListReplicaSetsFunc: func(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error) {
+			return []appsv1.ReplicaSet{
+				{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default", OwnerReferences: ownedBy("Deployment", "web")}},
+			}, nil
+		},
+		ListJobsFunc: func(ctx context.Context, namespace string) ([]batchv1.Job, error) {
+			return nil, nil
+		},
+	}
+	awsClient := &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			var infos []aws.PodSecurityGroupInfo
+			for i, pod := range pods {
+				infos = append(infos, aws.PodSecurityGroupInfo{
+					Pod:             pod,
+					ENI:             fmt.Sprintf("eni-%d", i),
+					AttachmentLevel: "pod",
+					SecurityGroups:  []types.SecurityGroup{{GroupId: awsSDK.String("sg-web")}},
+				})
+			}
+			return infos, nil
+		},
+	}
+
+	out := &bytes.Buffer{}
+	o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.K8sClient = k8sClient
+	o.AWSClient = awsClient
+	o.OutputFormat = "graph-json"
+	o.CollapseOwners = true
+	o.ConfigFlags.Namespace = stringPointer("default")
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var graph output.GraphOutput
+	if err := json.Unmarshal(out.Bytes(), &graph); err != nil {
+		t.Fatalf("invalid graph JSON: %v", err)
+	}
+	expectedNodes := []output.GraphNodeOutput{
+		{ID: "sg:sg-web", Kind: output.NodeSecurityGroup, Label: "sg-web"},
+		{ID: "workload:Deployment/default/web", Kind: output.NodeWorkload, Label: "Deployment default/web (2 pods)"},
+	}
+	if !reflect.DeepEqual(graph.Nodes, expectedNodes) {
+		t.Errorf("nodes = %+v, want %+v", graph.Nodes, expectedNodes)
+	}
+	if len(graph.Edges) != 1 {
+		t.Errorf("edges = %+v, want a single attachment edge", graph.Edges)
+	}
+}
+
+func TestPodOptions_Run_Contexts(t *testing.T) {
+	clientsFor := func(ctx context.Context, name string) (kubernetes.Interface, aws.Interface, error) {
+		if name == "broken" {
+			return nil, nil, fmt.Errorf("connection refused")
+		}
+		k8sClient := &fakeK8sClient{
+			ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+				return []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}}, nil
+			},
+		}
+		awsClient := &fakeAWSClient{
+			FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+				return []aws.PodSecurityGroupInfo{{
+					Pod:             pods[0],
+					ENI:             "eni-" + name,
+					AttachmentLevel: "pod",
+					Status:          aws.StatusMapped,
+					SecurityGroups: []types.SecurityGroup{{
+						GroupId: awsSDK.String("sg-" + name),
+						IpPermissions: []types.IpPermission{{
+							IpProtocol: awsSDK.String("tcp"),
+							FromPort:   awsSDK.Int32(443),
+							ToPort:     awsSDK.Int32(443),
+							IpRanges:   []types.IpRange{{CidrIp: awsSDK.String("10.0.0.0/8")}},
+						}},
+					}},
+				}}, nil
+			},
+			FetchSecurityGroupRulesFunc: func(ctx context.Context, sgIDs []string) ([]types.SecurityGroupRule, error) {
+				if name == "prod" {
+					return nil, errors.New("UnauthorizedOperation")
+				}
+				return []types.SecurityGroupRule{{
+					SecurityGroupRuleId: awsSDK.String("sgr-" + name),
+					GroupId:             awsSDK.String(sgIDs[0]),
+					IsEgress:            awsSDK.Bool(false),
+					IpProtocol:          awsSDK.String("tcp"),
+					FromPort:            awsSDK.Int32(443),
+					ToPort:              awsSDK.Int32(443),
+					CidrIpv4:            awsSDK.String("10.0.0.0/8"),
+				}}, nil
+			},
+		}
+		return k8sClient, awsClient, nil
+	}
+
+	t.Run("merges results and reports failing contexts", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		errOut := &bytes.Buffer{}
+		o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: errOut})
+		o.Contexts = []string{"staging", "broken", "prod", "prod"}
+		o.ConfigFlags.Namespace = stringPointer("default")
+		o.newContextClients = clientsFor
+
+		if err := o.Run(context.Background()); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		expected := "CONTEXT  POD NAME  STATUS  IP ADDRESS  ENI ID       ATTACHMENT  SECURITY GROUPS\n" +
+			"prod     web       Mapped              eni-prod     pod         sg-prod\n" +
+			"staging  web       Mapped              eni-staging  pod         sg-staging\n"
+		if got := out.String(); got != expected {
+			t.Errorf("Run() output = %q, want %q", got, expected)
+		}
+		if got := errOut.String(); got != "error: context broken: connection refused\n" {
+			t.Errorf("Run() error output = %q", got)
+		}
+	})
+
+	t.Run("looks up rule IDs in each context for yaml output", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		errOut := &bytes.Buffer{}
+		o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: errOut})
+		o.Contexts = []string{"staging", "prod"}
+		o.ConfigFlags.Namespace = stringPointer("default")
+		o.OutputFormat = "yaml"
+		o.newContextClients = clientsFor
+
+		if err := o.Run(context.Background()); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if got := strings.Count(out.String(), "id: sgr-"); got != 1 || !strings.Contains(out.String(), "id: sgr-staging") {
+			t.Errorf("Run() output = %q, want only the rule ID of staging", out.String())
+		}
+		if got := errOut.String(); got != "Warning: context prod: security group rule IDs are omitted: UnauthorizedOperation\n" {
+			t.Errorf("Run() error output = %q", got)
+		}
+	})
+
+	t.Run("fails when every context fails", func(t *testing.T) {
+		o := NewPodOptions(&genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}})
+		o.Contexts = []string{"broken"}
+		o.ConfigFlags.Namespace = stringPointer("default")
+		o.newContextClients = clientsFor
+
+		if err := o.Run(context.Background()); err == nil {
+			t.Error("Run() expected an error")
+		}
+	})
+}
+
+func TestResolveContexts(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	content := `apiVersion: v1
+kind: Config
+clusters:
+- name: c
+  cluster:
+    server: https://example.com
+users:
+- name: u
+  user: {}
+contexts:
+- name: staging
+  context: {cluster: c, user: u}
+- name: prod
+  context: {cluster: c, user: u}
+`
+	if err := os.WriteFile(kubeconfig, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	flags := genericclioptions.NewConfigFlags(true)
+	flags.KubeConfig = &kubeconfig
+
+	contexts, err := resolveContexts(flags, nil, true)
+	if err != nil {
+		t.Fatalf("resolveContexts() error = %v", err)
+	}
+	if !reflect.DeepEqual(contexts, []string{"prod", "staging"}) {
+		t.Errorf("resolveContexts() = %v", contexts)
+	}
+
+	if got := *contextConfigFlags(flags, "prod").Context; got != "prod" {
+		t.Errorf("contextConfigFlags() context = %q", got)
+	}
+}
+
+func TestContextConfigFlags_KeepsFlags(t *testing.T) {
+	flags := genericclioptions.NewConfigFlags(true)
+	current := "dev"
+	flags.Context = &current
+	// Give every exported pointer field a value, so that flags added to ConfigFlags later and not
+	// copied by contextConfigFlags fail the test
+	v := reflect.ValueOf(flags).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !v.Type().Field(i).IsExported() || field.Kind() != reflect.Pointer || v.Type().Field(i).Name == "Context" {
+			continue
+		}
+		field.Set(reflect.New(field.Type().Elem()))
+	}
+	*flags.Impersonate = "alice"
+	*flags.ImpersonateGroup = []string{"admins"}
+	*flags.BearerToken = "token"
+	*flags.APIServer = "https://example.com"
+
+	got := contextConfigFlags(flags, "prod")
+	if *got.Context != "prod" || *flags.Context != "dev" {
+		t.Errorf("contextConfigFlags() context = %q, original context = %q", *got.Context, *flags.Context)
+	}
+	gv := reflect.ValueOf(got).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if !v.Type().Field(i).IsExported() || name == "Context" || v.Field(i).Kind() != reflect.Pointer {
+			continue
+		}
+		if gv.Field(i).Pointer() != v.Field(i).Pointer() {
+			t.Errorf("contextConfigFlags() did not keep flag %s", name)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/service"
+)
+
+// ServiceOptions contains options for the service command
+type ServiceOptions struct {
+	Name          string
+	OutputFormat  string
+	AllNamespaces bool
+	SnapshotDir   string
+	AWSOptions    aws.ClientOptions
+	ConfigFlags   *genericclioptions.ConfigFlags
+	IOStreams     *genericclioptions.IOStreams
+	K8sClient     kubernetes.Interface
+	AWSClient     aws.Interface
+}
+
+// NewServiceOptions creates new ServiceOptions with default values
+func NewServiceOptions(streams *genericclioptions.IOStreams) *ServiceOptions {
+	return &ServiceOptions{
+		ConfigFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// Run executes the service command business logic. The ready endpoints of each Service are resolved
+// to pods through its EndpointSlices, and the pods are looked up by IP like the pod command does.
+func (o *ServiceOptions) Run(ctx context.Context) error {
+	k8sClient, awsClient, err := newClients(ctx, o.SnapshotDir, o.ConfigFlags, o.AWSOptions, o.K8sClient, o.AWSClient)
+	if err != nil {
+		return err
+	}
+	o.AWSClient = awsClient
+
+	namespace, err := resolveNamespace(o.ConfigFlags, o.AllNamespaces)
+	if err != nil {
+		return fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	var services []corev1.Service
+	if o.Name != "" {
+		svc, err := k8sClient.GetService(ctx, o.Name, namespace)
+		if err != nil {
+			return err
+		}
+		services = []corev1.Service{*svc}
+	} else {
+		services, err = k8sClient.ListServices(ctx, namespace)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(services) == 0 {
+		fmt.Fprintf(o.IOStreams.Out, "No resources found in namespace.\n")
+		return nil
+	}
+
+	endpointSlices, err := k8sClient.ListEndpointSlices(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	pods, err := k8sClient.ListPods(ctx, namespace, kubernetes.PodSelector{})
+	if err != nil {
+		return err
+	}
+
+	endpoints := make([][]service.Endpoint, len(services))
+	var endpointPods []corev1.Pod
+	seen := make(map[string]struct{})
+	for i, svc := range services {
+		endpoints[i] = service.ReadyEndpoints(svc, endpointSlices, pods)
+		for _, ep := range endpoints[i] {
+			key := ep.Pod.Namespace + "/" + ep.Pod.Name
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				endpointPods = append(endpointPods, ep.Pod)
+			}
+		}
+	}
+
+	var looked []aws.PodSecurityGroupInfo
+	infos := make(map[string]aws.PodSecurityGroupInfo, len(endpointPods))
+	if len(endpointPods) > 0 {
+		looked, err = o.AWSClient.FetchSecurityGroupsByPods(ctx, endpointPods)
+		if err != nil {
+			return fmt.Errorf("failed to get security groups: %w", err)
+		}
+		for _, info := range looked {
+			infos[info.Pod.Namespace+"/"+info.Pod.Name] = info
+		}
+	}
+
+	result := make([]service.SecurityGroupInfo, 0, len(services))
+	for i, svc := range services {
+		result = append(result, service.Group(svc, endpoints[i], infos))
+	}
+	if err := output.OutputServiceSecurityGroups(o.IOStreams.Out, result, o.OutputFormat); err != nil {
+		return err
+	}
+	return incompleteError(o.IOStreams.ErrOut, looked)
+}
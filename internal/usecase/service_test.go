@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+)
+
+func TestServiceOptions_Run(t *testing.T) {
+	ready := true
+	port, protocol := int32(8080), corev1.ProtocolTCP
+	endpointSlices := []discoveryv1.EndpointSlice{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "web-abcde",
+				Namespace: "default",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: "web"},
+			},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Ports:       []discoveryv1.EndpointPort{{Port: &port, Protocol: &protocol}},
+			Endpoints: []discoveryv1.Endpoint{
+				{
+					Addresses:  []string{"10.0.1.10"},
+					Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+					TargetRef:  &corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-1"},
+				},
+			},
+		},
+	}
+	services := []corev1.Service{{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}}
+
+	testCases := []struct {
+		name        string
+		k8sClient   *fakeK8sClient
+		awsClient   *fakeAWSClient
+		service     string
+		wantErr     bool
+		expectedOut string
+	}{
+		{
+			name: "checks target ports of a service",
+			k8sClient: &fakeK8sClient{
+				ListServicesFunc: func(ctx context.Context, namespace string) ([]corev1.Service, error) {
+					return services, nil
+				},
+				ListEndpointSlicesFunc: func(ctx context.Context, namespace string) ([]discoveryv1.EndpointSlice, error) {
+					return endpointSlices, nil
+				},
+				ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+					return []corev1.Pod{*runningPod("web-1", "10.0.1.10")}, nil
+				},
+			},
+			awsClient: &fakeAWSClient{
+				FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+					if len(pods) != 1 {
+						return nil, fmt.Errorf("expected 1 pod, got %d", len(pods))
+					}
+					return []aws.PodSecurityGroupInfo{{
+						Pod:             pods[0],
+						ENI:             "eni-web",
+						AttachmentLevel: "pod",
+						SecurityGroups:  []types.SecurityGroup{{GroupId: awsSDK.String("sg-web")}},
+					}}, nil
+				},
+			},
+			expectedOut: "tcp/8080 DENIED",
+		},
+		{
+			name: "named service not found",
+			k8sClient: &fakeK8sClient{
+				GetServiceFunc: func(ctx context.Context, name, namespace string) (*corev1.Service, error) {
+					return nil, fmt.Errorf("not found")
+				},
+			},
+			awsClient: &fakeAWSClient{},
+			service:   "web",
+			wantErr:   true,
+		},
+		{
+			name: "no services",
+			k8sClient: &fakeK8sClient{
+				ListServicesFunc: func(ctx context.Context, namespace string) ([]corev1.Service, error) {
+					return nil, nil
+				},
+			},
+			awsClient:   &fakeAWSClient{},
+			expectedOut: "No resources found in namespace.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			streams := &genericclioptions.IOStreams{
+				Out:    &bytes.Buffer{},
+				ErrOut: &bytes.Buffer{},
+			}
+			o := NewServiceOptions(streams)
+			o.K8sClient = tc.k8sClient
+			o.AWSClient = tc.awsClient
+			o.Name = tc.service
+			o.ConfigFlags.Namespace = stringPointer("default")
+
+			err := o.Run(context.Background())
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if got := streams.Out.(*bytes.Buffer).String(); !strings.Contains(got, tc.expectedOut) {
+				t.Errorf("Run() output = %q, want it to contain %q", got, tc.expectedOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+)
+
+// SecurityGroupOptions contains options for the sg command
+type SecurityGroupOptions struct {
+	SecurityGroups []string
+	OutputFormat   string
+	SnapshotDir    string
+	AWSOptions     aws.ClientOptions
+	ConfigFlags    *genericclioptions.ConfigFlags
+	IOStreams      *genericclioptions.IOStreams
+	K8sClient      kubernetes.Interface
+	AWSClient      aws.Interface
+}
+
+// NewSecurityGroupOptions creates new SecurityGroupOptions with default values
+func NewSecurityGroupOptions(streams *genericclioptions.IOStreams) *SecurityGroupOptions {
+	return &SecurityGroupOptions{
+		ConfigFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// Run executes the sg command business logic. Pods are always listed across all namespaces
+// because a security group is not scoped to a namespace.
+func (o *SecurityGroupOptions) Run(ctx context.Context) error {
+	k8sClient, awsClient, err := newClients(ctx, o.SnapshotDir, o.ConfigFlags, o.AWSOptions, o.K8sClient, o.AWSClient)
+	if err != nil {
+		return err
+	}
+	o.AWSClient = awsClient
+
+	pods, err := k8sClient.ListPods(ctx, "", kubernetes.PodSelector{})
+	if err != nil {
+		return err
+	}
+
+	result, err := o.AWSClient.FetchPodsBySecurityGroups(ctx, o.SecurityGroups, pods)
+	if err != nil {
+		return fmt.Errorf("failed to get security group usage: %w", err)
+	}
+
+	return output.OutputSecurityGroupUsage(o.IOStreams.Out, result, o.OutputFormat)
+}
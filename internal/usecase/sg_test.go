@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+)
+
+func TestSecurityGroupOptions_Run(t *testing.T) {
+	testCases := []struct {
+		name        string
+		k8sClient   *fakeK8sClient
+		awsClient   *fakeAWSClient
+		wantErr     bool
+		expectedOut string
+	}{
+		{
+			name: "lists pods across all namespaces",
+			k8sClient: &fakeK8sClient{
+				ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+					if namespace != "" {
+						return nil, fmt.Errorf("expected all namespaces, got %q", namespace)
+					}
+					return []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1"}}}, nil
+				},
+			},
+			awsClient: &fakeAWSClient{
+				FetchPodsBySecurityGroupsFunc: func(ctx context.Context, sgRefs []string, pods []corev1.Pod) ([]aws.SecurityGroupUsage, error) {
+					return []aws.SecurityGroupUsage{
+						{
+							SecurityGroup: types.SecurityGroup{GroupId: awsSDK.String(sgRefs[0])},
+							Pods:          []aws.PodSecurityGroupInfo{{Pod: pods[0], ENI: "eni-1", AttachmentLevel: "pod"}},
+						},
+					}, nil
+				},
+			},
+			expectedOut: "sg-1            ns1        pod1",
+		},
+		{
+			name: "aws client error",
+			k8sClient: &fakeK8sClient{
+				ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+					return nil, nil
+				},
+			},
+			awsClient: &fakeAWSClient{
+				FetchPodsBySecurityGroupsFunc: func(ctx context.Context, sgRefs []string, pods []corev1.Pod) ([]aws.SecurityGroupUsage, error) {
+					return nil, fmt.Errorf("aws error")
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			streams := &genericclioptions.IOStreams{
+				Out:    &bytes.Buffer{},
+				ErrOut: &bytes.Buffer{},
+			}
+			o := NewSecurityGroupOptions(streams)
+			o.K8sClient = tc.k8sClient
+			o.AWSClient = tc.awsClient
+			o.SecurityGroups = []string{"sg-1"}
+
+			err := o.Run(context.Background())
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if got := streams.Out.(*bytes.Buffer).String(); !strings.Contains(got, tc.expectedOut) {
+				t.Errorf("Run() output = %q, want it to contain %q", got, tc.expectedOut)
+			}
+		})
+	}
+}
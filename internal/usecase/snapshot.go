@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+)
+
+// SnapshotOptions contains options for the snapshot command
+type SnapshotOptions struct {
+	Dir         string
+	AWSOptions  aws.ClientOptions
+	ConfigFlags *genericclioptions.ConfigFlags
+	IOStreams   *genericclioptions.IOStreams
+	K8sClient   kubernetes.Interface
+	EC2Client   aws.EC2API
+}
+
+// NewSnapshotOptions creates new SnapshotOptions with default values
+func NewSnapshotOptions(streams *genericclioptions.IOStreams) *SnapshotOptions {
+	return &SnapshotOptions{
+		ConfigFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// Run saves the Kubernetes objects of all namespaces and the network interfaces and security groups
+// of the AWS account into the snapshot directory, for later use with --from-snapshot
+func (o *SnapshotOptions) Run(ctx context.Context) error {
+	k8sClient := o.K8sClient
+	if k8sClient == nil {
+		var err error
+		k8sClient, err = kubernetes.NewClient(o.ConfigFlags)
+		if err != nil {
+			return err
+		}
+	}
+
+	ec2Client := o.EC2Client
+	if ec2Client == nil {
+		var err error
+		awsOptions := o.AWSOptions
+		// A snapshot records the current state, so it never reads cached lookups
+		awsOptions.Cache = aws.CacheOptions{}
+		if awsOptions.Region == "" {
+			awsOptions.Region = detectRegion(o.ConfigFlags)
+		}
+		ec2Client, err = aws.NewEC2API(ctx, awsOptions)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := kubernetes.WriteSnapshot(ctx, k8sClient, o.Dir); err != nil {
+		return fmt.Errorf("failed to save kubernetes snapshot: %w", err)
+	}
+	if err := aws.WriteSnapshot(ctx, ec2Client, o.Dir); err != nil {
+		return fmt.Errorf("failed to save aws snapshot: %w", err)
+	}
+
+	fmt.Fprintf(o.IOStreams.Out, "Snapshot saved to %s\n", o.Dir)
+	return nil
+}
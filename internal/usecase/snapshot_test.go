@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+)
+
+type fakeEC2API struct {
+	networkInterfaces []types.NetworkInterface
+	securityGroups    []types.SecurityGroup
+}
+
+func (f *fakeEC2API) DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: f.networkInterfaces}, nil
+}
+
+func (f *fakeEC2API) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: f.securityGroups}, nil
+}
+
+func (f *fakeEC2API) DescribeManagedPrefixLists(ctx context.Context, params *ec2.DescribeManagedPrefixListsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeManagedPrefixListsOutput, error) {
+	return &ec2.DescribeManagedPrefixListsOutput{}, nil
+}
+
+func (f *fakeEC2API) GetManagedPrefixListEntries(ctx context.Context, params *ec2.GetManagedPrefixListEntriesInput, optFns ...func(*ec2.Options)) (*ec2.GetManagedPrefixListEntriesOutput, error) {
+	return &ec2.GetManagedPrefixListEntriesOutput{}, nil
+}
+
+func (f *fakeEC2API) DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	return &ec2.DescribeSecurityGroupRulesOutput{}, nil
+}
+
+func emptyList[T any](ctx context.Context, namespace string) ([]T, error) {
+	return nil, nil
+}
+
+func TestSnapshotOptions_Run(t *testing.T) {
+	k8sClient := &fakeK8sClient{
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			return []corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+					Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"},
+				},
+			}, nil
+		},
+		ListReplicaSetsFunc:           emptyList,
+		ListDeploymentsFunc:           emptyList,
+		ListStatefulSetsFunc:          emptyList,
+		ListDaemonSetsFunc:            emptyList,
+		ListJobsFunc:                  emptyList,
+		ListCronJobsFunc:              emptyList,
+		ListServiceAccountsFunc:       emptyList,
+		ListSecurityGroupPoliciesFunc: emptyList[kubernetes.SecurityGroupPolicy],
+		ListNodesFunc: func(ctx context.Context) ([]corev1.Node, error) {
+			return nil, nil
+		},
+		ListServicesFunc:       emptyList,
+		ListEndpointSlicesFunc: emptyList,
+		ListIngressesFunc:      emptyList,
+		ListIngressClassesFunc: func(ctx context.Context) ([]networkingv1.IngressClass, error) {
+			return nil, nil
+		},
+		ListNetworkPoliciesFunc: emptyList,
+		ListNamespacesFunc: func(ctx context.Context) ([]corev1.Namespace, error) {
+			return nil, nil
+		},
+	}
+	ec2Client := &fakeEC2API{
+		networkInterfaces: []types.NetworkInterface{
+			{
+				NetworkInterfaceId: awsSDK.String("eni-1"),
+				InterfaceType:      types.NetworkInterfaceTypeBranch,
+				PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: awsSDK.String("10.0.0.1")}},
+				Groups:             []types.GroupIdentifier{{GroupId: awsSDK.String("sg-1")}},
+			},
+		},
+		securityGroups: []types.SecurityGroup{{GroupId: awsSDK.String("sg-1"), GroupName: awsSDK.String("web")}},
+	}
+
+	dir := t.TempDir()
+	streams := &genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
+	s := NewSnapshotOptions(streams)
+	s.Dir = dir
+	s.K8sClient = k8sClient
+	s.EC2Client = ec2Client
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.SnapshotDir = dir
+	o.ConfigFlags.KubeConfig = stringPointer("/dev/null")
+	o.ConfigFlags.Namespace = stringPointer("default")
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("Run() from snapshot error = %v", err)
+	}
+
+	if got := out.String(); !strings.Contains(got, "eni-1") || !strings.Contains(got, "sg-1 (web)") {
+		t.Errorf("Run() from snapshot output = %q", got)
+	}
+}
@@ -0,0 +1,314 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+)
+
+const (
+	// watchDebounce is how long pod events are collected before their ENIs are looked up in one batch
+	watchDebounce = time.Second
+	// watchRetryDelay is the delay before looking up again pods whose ENI is not visible yet,
+	// such as a branch ENI that the VPC resource controller is still attaching
+	watchRetryDelay = 3 * time.Second
+	// watchMaxAttempts is the number of lookups after which a pod without a visible ENI is given up
+	watchMaxAttempts = 5
+)
+
+// podWatcher tracks the security group mapping of watched pods and turns pod events into mapping changes
+type podWatcher struct {
+	awsClient aws.Interface
+	podName   string
+	errOut    io.Writer
+
+	// mapped holds the last reported mapping of each pod by namespace/name
+	mapped map[string]aws.PodSecurityGroupInfo
+	// pending holds the pods waiting for an ENI lookup and attempts the lookups already made for them
+	pending  map[string]corev1.Pod
+	attempts map[string]int
+}
+
+func newPodWatcher(awsClient aws.Interface, podName string, errOut io.Writer) *podWatcher {
+	return &podWatcher{
+		awsClient: awsClient,
+		podName:   podName,
+		errOut:    errOut,
+		mapped:    make(map[string]aws.PodSecurityGroupInfo),
+		pending:   make(map[string]corev1.Pod),
+		attempts:  make(map[string]int),
+	}
+}
+
+// handle records a pod event. Running pods that are new or got a new IP are queued for an ENI
+// lookup; pods that are deleted or stop running are returned as DELETED events right away.
+func (pw *podWatcher) handle(eventType watch.EventType, pod *corev1.Pod) []output.PodWatchEvent {
+	if pw.podName != "" && pod.Name != pw.podName {
+		return nil
+	}
+	key := pod.Namespace + "/" + pod.Name
+
+	running := pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != ""
+	if eventType == watch.Deleted || !running {
+		delete(pw.pending, key)
+		delete(pw.attempts, key)
+		info, ok := pw.mapped[key]
+		if !ok {
+			return nil
+		}
+		delete(pw.mapped, key)
+		return []output.PodWatchEvent{{Type: output.EventDeleted, Info: info}}
+	}
+
+	// The ENI of a pod does not change while it keeps its IP, so there is nothing to look up
+	if info, ok := pw.mapped[key]; ok && info.Pod.Status.PodIP == pod.Status.PodIP {
+		info.Pod = *pod
+		pw.mapped[key] = info
+		return nil
+	}
+
+	if _, ok := pw.pending[key]; !ok {
+		delete(pw.attempts, key)
+	}
+	pw.pending[key] = *pod
+	return nil
+}
+
+// resync drops the pods missing from a fresh list of the watched pods and returns DELETED events
+// for the ones that were reported, so that pods deleted while the watch was down do not linger
+func (pw *podWatcher) resync(pods []corev1.Pod) []output.PodWatchEvent {
+	present := make(map[string]struct{}, len(pods))
+	for _, pod := range pods {
+		present[pod.Namespace+"/"+pod.Name] = struct{}{}
+	}
+
+	for key := range pw.pending {
+		if _, ok := present[key]; !ok {
+			delete(pw.pending, key)
+			delete(pw.attempts, key)
+		}
+	}
+
+	var keys []string
+	for key := range pw.mapped {
+		if _, ok := present[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	events := make([]output.PodWatchEvent, 0, len(keys))
+	for _, key := range keys {
+		events = append(events, output.PodWatchEvent{Type: output.EventDeleted, Info: pw.mapped[key]})
+		delete(pw.mapped, key)
+	}
+	return events
+}
+
+// hasPending reports whether pods are waiting for an ENI lookup
+func (pw *podWatcher) hasPending() bool {
+	return len(pw.pending) > 0
+}
+
+// flush looks up the ENIs of the pending pods in one batch and returns ADDED and MODIFIED events
+// for the mappings that changed. Pods whose ENI is not visible yet or whose EC2 lookup failed stay
+// pending for a retry.
+func (pw *podWatcher) flush(ctx context.Context) []output.PodWatchEvent {
+	if len(pw.pending) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(pw.pending))
+	pods := make([]corev1.Pod, 0, len(pw.pending))
+	for key := range pw.pending {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		pods = append(pods, pw.pending[key])
+	}
+
+	infos, err := pw.awsClient.FetchSecurityGroupsByPods(ctx, pods)
+	if err != nil {
+		fmt.Fprintf(pw.errOut, "Warning: failed to get security groups: %v\n", err)
+		for _, key := range keys {
+			pw.retry(key, err.Error())
+		}
+		return nil
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Pod.Namespace+"/"+infos[i].Pod.Name < infos[j].Pod.Namespace+"/"+infos[j].Pod.Name
+	})
+
+	var events []output.PodWatchEvent
+	failures := make(map[string]string)
+	for _, info := range infos {
+		key := info.Pod.Namespace + "/" + info.Pod.Name
+		if _, ok := pw.pending[key]; !ok {
+			continue
+		}
+		if info.Status == aws.StatusLookupFailed {
+			failures[key] = info.Reason
+			continue
+		}
+		if !info.Mapped() {
+			continue
+		}
+		delete(pw.pending, key)
+		delete(pw.attempts, key)
+
+		prev, ok := pw.mapped[key]
+		pw.mapped[key] = info
+		switch {
+		case !ok:
+			events = append(events, output.PodWatchEvent{Type: output.EventAdded, Info: info})
+		case mappingKey(prev) != mappingKey(info):
+			events = append(events, output.PodWatchEvent{Type: output.EventModified, Info: info})
+		}
+	}
+
+	for _, key := range keys {
+		if _, ok := pw.pending[key]; ok {
+			pw.retry(key, failures[key])
+		}
+	}
+	return events
+}
+
+// retry counts a failed lookup of a pending pod and gives the pod up after watchMaxAttempts lookups.
+// reason is the error of the last EC2 lookup, or empty when the lookup found no ENI for the pod.
+func (pw *podWatcher) retry(key, reason string) {
+	pw.attempts[key]++
+	if pw.attempts[key] < watchMaxAttempts {
+		return
+	}
+	if reason != "" {
+		fmt.Fprintf(pw.errOut, "Warning: failed to look up pod %s after %d attempts: %s\n", key, pw.attempts[key], reason)
+	} else {
+		fmt.Fprintf(pw.errOut, "Warning: no ENI found for pod %s (IP %s) after %d attempts\n", key, pw.pending[key].Status.PodIP, pw.attempts[key])
+	}
+	delete(pw.pending, key)
+	delete(pw.attempts, key)
+}
+
+// mappingKey identifies the IP, ENI and security groups of a mapping
+func mappingKey(info aws.PodSecurityGroupInfo) string {
+	ids := make([]string, 0, len(info.SecurityGroups))
+	for _, sg := range info.SecurityGroups {
+		ids = append(ids, awsSDK.ToString(sg.GroupId))
+	}
+	sort.Strings(ids)
+	return strings.Join([]string{info.Pod.Status.PodIP, info.ENI, info.AttachmentLevel, strings.Join(ids, ",")}, "|")
+}
+
+// runWatch watches the pods in the namespace and outputs a row whenever the security group mapping
+// of a pod is added, modified or deleted, until the context is cancelled. ENI lookups are debounced
+// so that bursts of pod events result in a single EC2 call. A closed watch is resumed from the last
+// resource version, or started over when that version has expired. Starting over replays the
+// existing pods as ADDED events but not the deletions missed meanwhile, so the pods are listed
+// again and the ones gone are reported as DELETED.
+func (o *PodOptions) runWatch(ctx context.Context, k8sClient kubernetes.Interface, namespace string) error {
+	pw := newPodWatcher(o.AWSClient, o.PodName, o.IOStreams.ErrOut)
+	header := true
+	write := func(events []output.PodWatchEvent) error {
+		if len(events) == 0 {
+			return nil
+		}
+		err := output.OutputPodWatchEvents(o.IOStreams.Out, events, header)
+		header = false
+		return err
+	}
+
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	defer timer.Stop()
+	var deadline time.Time
+	schedule := func(d time.Duration) {
+		if at := time.Now().Add(d); deadline.IsZero() || at.Before(deadline) {
+			deadline = at
+			timer.Reset(d)
+		}
+	}
+
+	resourceVersion := ""
+	resync := false
+	for {
+		w, err := k8sClient.WatchPods(ctx, namespace, resourceVersion, o.selector())
+		if err != nil {
+			return err
+		}
+		if resync {
+			// Listing after the watch started, deletions after the list are seen by the watch
+			pods, err := k8sClient.ListPods(ctx, namespace, o.selector())
+			if err != nil {
+				w.Stop()
+				return err
+			}
+			if err := write(pw.resync(pods)); err != nil {
+				w.Stop()
+				return err
+			}
+			resync = false
+		}
+
+	events:
+		for {
+			select {
+			case <-ctx.Done():
+				w.Stop()
+				return nil
+			case <-timer.C:
+				deadline = time.Time{}
+				if err := write(pw.flush(ctx)); err != nil {
+					w.Stop()
+					return err
+				}
+				if pw.hasPending() {
+					schedule(watchRetryDelay)
+				}
+			case event, ok := <-w.ResultChan():
+				if !ok {
+					break events
+				}
+				if event.Type == watch.Error {
+					err := apierrors.FromObject(event.Object)
+					if !apierrors.IsResourceExpired(err) && !apierrors.IsGone(err) {
+						w.Stop()
+						return fmt.Errorf("failed to watch pods: %w", err)
+					}
+					resourceVersion = ""
+					resync = true
+					break events
+				}
+				pod, ok := event.Object.(*corev1.Pod)
+				if !ok {
+					continue
+				}
+				resourceVersion = pod.ResourceVersion
+				if event.Type == watch.Bookmark {
+					continue
+				}
+				if err := write(pw.handle(event.Type, pod)); err != nil {
+					w.Stop()
+					return err
+				}
+				if pw.hasPending() {
+					schedule(watchDebounce)
+				}
+			}
+		}
+		w.Stop()
+	}
+}
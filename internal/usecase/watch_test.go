@@ -0,0 +1,242 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+)
+
+func runningPod(name, ip string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ip},
+	}
+}
+
+// eniByIP returns a fake AWS client resolving pods through the ENIs and security groups known for their IPs
+func eniByIP(enis map[string]string, calls *int) *fakeAWSClient {
+	return &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			*calls++
+			var infos []aws.PodSecurityGroupInfo
+			for _, pod := range pods {
+				eni, ok := enis[pod.Status.PodIP]
+				if !ok {
+					continue
+				}
+				infos = append(infos, aws.PodSecurityGroupInfo{
+					Pod:             pod,
+					ENI:             eni,
+					AttachmentLevel: "pod",
+					SecurityGroups:  []types.SecurityGroup{{GroupId: awsSDK.String("sg-" + eni)}},
+				})
+			}
+			return infos, nil
+		},
+	}
+}
+
+func eventTypes(events []output.PodWatchEvent) []string {
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type+" "+e.Info.Pod.Name)
+	}
+	return types
+}
+
+func TestPodWatcher(t *testing.T) {
+	enis := map[string]string{"10.0.0.1": "eni-1", "10.0.0.2": "eni-2"}
+	calls := 0
+	errOut := &bytes.Buffer{}
+	pw := newPodWatcher(eniByIP(enis, &calls), "", errOut)
+	ctx := context.Background()
+
+	// Events are batched into a single lookup
+	pw.handle(watch.Added, runningPod("a", "10.0.0.1"))
+	pw.handle(watch.Added, runningPod("b", "10.0.0.2"))
+	pw.handle(watch.Added, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"}})
+	if got := eventTypes(pw.flush(ctx)); strings.Join(got, ",") != "ADDED a,ADDED b" {
+		t.Errorf("flush() = %v", got)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 lookup, got %d", calls)
+	}
+
+	// Updates that keep the pod IP need no lookup
+	pw.handle(watch.Modified, runningPod("a", "10.0.0.1"))
+	if pw.hasPending() {
+		t.Error("expected no pending pods after an update keeping the IP")
+	}
+
+	// A pod whose ENI is not visible yet is retried until it appears
+	pw.handle(watch.Added, runningPod("c", "10.0.0.3"))
+	if got := pw.flush(ctx); len(got) != 0 {
+		t.Errorf("flush() = %v, want no events", eventTypes(got))
+	}
+	if !pw.hasPending() {
+		t.Fatal("expected the pod without an ENI to stay pending")
+	}
+	enis["10.0.0.3"] = "eni-3"
+	if got := eventTypes(pw.flush(ctx)); strings.Join(got, ",") != "ADDED c" {
+		t.Errorf("flush() = %v", got)
+	}
+
+	// A new IP on another ENI is reported as a modification
+	pw.handle(watch.Modified, runningPod("b", "10.0.0.1"))
+	if got := eventTypes(pw.flush(ctx)); strings.Join(got, ",") != "MODIFIED b" {
+		t.Errorf("flush() = %v", got)
+	}
+
+	if got := eventTypes(pw.handle(watch.Deleted, runningPod("a", "10.0.0.1"))); strings.Join(got, ",") != "DELETED a" {
+		t.Errorf("handle() = %v", got)
+	}
+	if got := pw.handle(watch.Deleted, runningPod("unknown", "10.0.0.9")); len(got) != 0 {
+		t.Errorf("handle() = %v, want no events for an unmapped pod", eventTypes(got))
+	}
+}
+
+func TestPodWatcher_GivesUp(t *testing.T) {
+	calls := 0
+	errOut := &bytes.Buffer{}
+	pw := newPodWatcher(eniByIP(map[string]string{}, &calls), "", errOut)
+
+	pw.handle(watch.Added, runningPod("a", "10.0.0.1"))
+	for i := 0; i < watchMaxAttempts; i++ {
+		pw.flush(context.Background())
+	}
+	if pw.hasPending() {
+		t.Error("expected the pod to be given up")
+	}
+	if !strings.Contains(errOut.String(), "no ENI found for pod default/a") {
+		t.Errorf("unexpected warnings: %q", errOut.String())
+	}
+}
+
+func TestPodWatcher_GivesUpLookupFailed(t *testing.T) {
+	errOut := &bytes.Buffer{}
+	awsClient := &fakeAWSClient{
+		FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+			return []aws.PodSecurityGroupInfo{{
+				Pod:    pods[0],
+				Status: aws.StatusLookupFailed,
+				Reason: "failed to look up ENI of IP 10.0.0.1: RequestLimitExceeded",
+			}}, nil
+		},
+	}
+	pw := newPodWatcher(awsClient, "", errOut)
+
+	pw.handle(watch.Added, runningPod("a", "10.0.0.1"))
+	for i := 0; i < watchMaxAttempts; i++ {
+		if got := pw.flush(context.Background()); len(got) != 0 {
+			t.Errorf("flush() = %v, want no events", eventTypes(got))
+		}
+	}
+	if pw.hasPending() {
+		t.Error("expected the pod to be given up")
+	}
+	want := "Warning: failed to look up pod default/a after 5 attempts: failed to look up ENI of IP 10.0.0.1: RequestLimitExceeded\n"
+	if got := errOut.String(); got != want {
+		t.Errorf("unexpected warnings: %q, want %q", got, want)
+	}
+}
+
+func TestPodWatcher_PodName(t *testing.T) {
+	calls := 0
+	pw := newPodWatcher(eniByIP(map[string]string{}, &calls), "a", &bytes.Buffer{})
+
+	pw.handle(watch.Added, runningPod("b", "10.0.0.2"))
+	if pw.hasPending() {
+		t.Error("expected pods other than the named pod to be ignored")
+	}
+}
+
+func TestPodWatcher_Resync(t *testing.T) {
+	calls := 0
+	pw := newPodWatcher(eniByIP(map[string]string{"10.0.0.1": "eni-1", "10.0.0.2": "eni-2"}, &calls), "", &bytes.Buffer{})
+	pw.handle(watch.Added, runningPod("a", "10.0.0.1"))
+	pw.handle(watch.Added, runningPod("b", "10.0.0.2"))
+	pw.flush(context.Background())
+	pw.handle(watch.Added, runningPod("c", "10.0.0.3"))
+
+	if got := eventTypes(pw.resync([]corev1.Pod{*runningPod("a", "10.0.0.1")})); strings.Join(got, ",") != "DELETED b" {
+		t.Errorf("resync() = %v", got)
+	}
+	if pw.hasPending() {
+		t.Error("expected the pending pod missing from the list to be dropped")
+	}
+	if got := pw.resync([]corev1.Pod{*runningPod("a", "10.0.0.1")}); len(got) != 0 {
+		t.Errorf("resync() = %v, want no events", eventTypes(got))
+	}
+}
+
+func TestPodOptions_RunWatch_Expired(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	lookedUp := make(chan struct{}, 1)
+	awsClient := eniByIP(map[string]string{"10.0.0.1": "eni-1", "10.0.0.2": "eni-2"}, &calls)
+	fetch := awsClient.FetchSecurityGroupsByPodsFunc
+	awsClient.FetchSecurityGroupsByPodsFunc = func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+		defer func() { lookedUp <- struct{}{} }()
+		return fetch(ctx, pods)
+	}
+
+	watchers := []*watch.FakeWatcher{watch.NewFake(), watch.NewFake()}
+	var resourceVersions []string
+	k8sClient := &fakeK8sClient{
+		WatchPodsFunc: func(ctx context.Context, namespace, resourceVersion string, selector kubernetes.PodSelector) (watch.Interface, error) {
+			resourceVersions = append(resourceVersions, resourceVersion)
+			return watchers[len(resourceVersions)-1], nil
+		},
+		// Pod b was deleted while the watch was down
+		ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+			return []corev1.Pod{*runningPod("a", "10.0.0.1")}, nil
+		},
+	}
+
+	go func() {
+		a, b := runningPod("a", "10.0.0.1"), runningPod("b", "10.0.0.2")
+		a.ResourceVersion, b.ResourceVersion = "1", "2"
+		watchers[0].Add(a)
+		watchers[0].Add(b)
+		<-lookedUp
+		// Sends block until runWatch receives them, after the lookup results are written
+		watchers[0].Error(&apierrors.NewResourceExpired("too old resource version").ErrStatus)
+		watchers[1].Add(a)
+		cancel()
+	}()
+
+	out := &bytes.Buffer{}
+	o := NewPodOptions(&genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	o.AWSClient = awsClient
+	if err := o.runWatch(ctx, k8sClient, "default"); err != nil {
+		t.Fatalf("runWatch() error = %v", err)
+	}
+
+	// The expired watch is started over
+	if strings.Join(resourceVersions, ",") != "," {
+		t.Errorf("WatchPods() resource versions = %q", resourceVersions)
+	}
+	var events []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n")[1:] {
+		fields := strings.Fields(line)
+		events = append(events, fields[0]+" "+fields[2])
+	}
+	if got := strings.Join(events, ","); got != "ADDED a,ADDED b,DELETED b" {
+		t.Errorf("runWatch() events = %v\n%s", events, out.String())
+	}
+}
@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/output"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/workload"
+)
+
+// WorkloadOptions contains options for the workload controller commands
+type WorkloadOptions struct {
+	Kind          string
+	Name          string
+	OutputFormat  string
+	AllNamespaces bool
+	SnapshotDir   string
+	AWSOptions    aws.ClientOptions
+	ConfigFlags   *genericclioptions.ConfigFlags
+	IOStreams     *genericclioptions.IOStreams
+	K8sClient     kubernetes.Interface
+	AWSClient     aws.Interface
+}
+
+// NewWorkloadOptions creates new WorkloadOptions for the given controller kind with default values
+func NewWorkloadOptions(streams *genericclioptions.IOStreams, kind string) *WorkloadOptions {
+	return &WorkloadOptions{
+		Kind:        kind,
+		ConfigFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// Run executes the workload command business logic
+func (o *WorkloadOptions) Run(ctx context.Context) error {
+	k8sClient, awsClient, err := newClients(ctx, o.SnapshotDir, o.ConfigFlags, o.AWSOptions, o.K8sClient, o.AWSClient)
+	if err != nil {
+		return err
+	}
+	o.AWSClient = awsClient
+
+	namespace, err := resolveNamespace(o.ConfigFlags, o.AllNamespaces)
+	if err != nil {
+		return fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	refs, err := o.listWorkloads(ctx, k8sClient, namespace)
+	if err != nil {
+		return err
+	}
+
+	if len(refs) == 0 {
+		fmt.Fprintf(o.IOStreams.Out, "No resources found in namespace.\n")
+		return nil
+	}
+
+	resolver, err := o.newResolver(ctx, k8sClient, namespace)
+	if err != nil {
+		return err
+	}
+
+	pods, err := k8sClient.ListPods(ctx, namespace, kubernetes.PodSelector{})
+	if err != nil {
+		return err
+	}
+
+	owned := filterPodsOwnedBy(pods, refs, resolver)
+
+	var infos []aws.PodSecurityGroupInfo
+	if len(owned) > 0 {
+		infos, err = o.AWSClient.FetchSecurityGroupsByPods(ctx, owned)
+		if err != nil {
+			return fmt.Errorf("failed to get security groups: %w", err)
+		}
+	}
+
+	if err := output.OutputWorkloadSecurityGroups(o.IOStreams.Out, workload.Group(refs, infos, resolver), o.OutputFormat); err != nil {
+		return err
+	}
+	return incompleteError(o.IOStreams.ErrOut, infos)
+}
+
+// listWorkloads returns the controllers of the configured kind, or only the named one when Name is set
+func (o *WorkloadOptions) listWorkloads(ctx context.Context, client kubernetes.Interface, namespace string) ([]workload.Ref, error) {
+	var refs []workload.Ref
+	add := func(namespace, name string) {
+		refs = append(refs, workload.Ref{Kind: o.Kind, Namespace: namespace, Name: name})
+	}
+
+	switch o.Kind {
+	case workload.KindDeployment:
+		if o.Name != "" {
+			d, err := client.GetDeployment(ctx, o.Name, namespace)
+			if err != nil {
+				return nil, err
+			}
+			add(d.Namespace, d.Name)
+			break
+		}
+		items, err := client.ListDeployments(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range items {
+			add(d.Namespace, d.Name)
+		}
+	case workload.KindStatefulSet:
+		if o.Name != "" {
+			s, err := client.GetStatefulSet(ctx, o.Name, namespace)
+			if err != nil {
+				return nil, err
+			}
+			add(s.Namespace, s.Name)
+			break
+		}
+		items, err := client.ListStatefulSets(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range items {
+			add(s.Namespace, s.Name)
+		}
+	case workload.KindDaemonSet:
+		if o.Name != "" {
+			d, err := client.GetDaemonSet(ctx, o.Name, namespace)
+			if err != nil {
+				return nil, err
+			}
+			add(d.Namespace, d.Name)
+			break
+		}
+		items, err := client.ListDaemonSets(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range items {
+			add(d.Namespace, d.Name)
+		}
+	case workload.KindJob:
+		if o.Name != "" {
+			j, err := client.GetJob(ctx, o.Name, namespace)
+			if err != nil {
+				return nil, err
+			}
+			add(j.Namespace, j.Name)
+			break
+		}
+		items, err := client.ListJobs(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, j := range items {
+			add(j.Namespace, j.Name)
+		}
+	case workload.KindCronJob:
+		if o.Name != "" {
+			c, err := client.GetCronJob(ctx, o.Name, namespace)
+			if err != nil {
+				return nil, err
+			}
+			add(c.Namespace, c.Name)
+			break
+		}
+		items, err := client.ListCronJobs(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range items {
+			add(c.Namespace, c.Name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported workload kind: %s", o.Kind)
+	}
+
+	return refs, nil
+}
+
+// newResolver lists the intermediate controllers needed to walk pods up to the configured kind
+func (o *WorkloadOptions) newResolver(ctx context.Context, client kubernetes.Interface, namespace string) (*workload.Resolver, error) {
+	var replicaSets []appsv1.ReplicaSet
+	var jobs []batchv1.Job
+	var err error
+
+	switch o.Kind {
+	case workload.KindDeployment:
+		replicaSets, err = client.ListReplicaSets(ctx, namespace)
+	case workload.KindCronJob:
+		jobs, err = client.ListJobs(ctx, namespace)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return workload.NewResolver(replicaSets, jobs), nil
+}
+
+// filterPodsOwnedBy returns the pods whose top-level controller is one of refs
+func filterPodsOwnedBy(pods []corev1.Pod, refs []workload.Ref, resolver *workload.Resolver) []corev1.Pod {
+	wanted := make(map[workload.Ref]struct{}, len(refs))
+	for _, ref := range refs {
+		wanted[ref] = struct{}{}
+	}
+
+	var owned []corev1.Pod
+	for i := range pods {
+		ref, ok := resolver.OwnerOf(&pods[i])
+		if !ok {
+			continue
+		}
+		if _, ok := wanted[ref]; ok {
+			owned = append(owned, pods[i])
+		}
+	}
+	return owned
+}
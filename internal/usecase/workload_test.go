@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/workload"
+)
+
+func ownedBy(kind, name string) []metav1.OwnerReference {
+	isController := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &isController}}
+}
+
+func TestWorkloadOptions_Run(t *testing.T) {
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-abc-1", Namespace: "default", OwnerReferences: ownedBy(workload.KindReplicaSet, "web-abc")}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-abc-2", Namespace: "default", OwnerReferences: ownedBy(workload.KindReplicaSet, "web-abc")}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "default", OwnerReferences: ownedBy(workload.KindStatefulSet, "db")}},
+	}
+	replicaSets := []appsv1.ReplicaSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default", OwnerReferences: ownedBy(workload.KindDeployment, "web")}},
+	}
+
+	testCases := []struct {
+		name        string
+		k8sClient   *fakeK8sClient
+		awsClient   *fakeAWSClient
+		workload    string
+		wantErr     bool
+		expectedOut string
+	}{
+		{
+			name: "groups replicas of a deployment",
+			k8sClient: &fakeK8sClient{
+				ListDeploymentsFunc: func(ctx context.Context, namespace string) ([]appsv1.Deployment, error) {
+					return []appsv1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}}, nil
+				},
+				ListReplicaSetsFunc: func(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error) {
+					return replicaSets, nil
+				},
+				ListPodsFunc: func(ctx context.Context, namespace string, selector kubernetes.PodSelector) ([]corev1.Pod, error) {
+					return pods, nil
+				},
+			},
+			awsClient: &fakeAWSClient{
+				FetchSecurityGroupsByPodsFunc: func(ctx context.Context, pods []corev1.Pod) ([]aws.PodSecurityGroupInfo, error) {
+					if len(pods) != 2 {
+						return nil, fmt.Errorf("expected 2 pods, got %d", len(pods))
+					}
+					var infos []aws.PodSecurityGroupInfo
+					for _, pod := range pods {
+						infos = append(infos, aws.PodSecurityGroupInfo{
+							Pod:             pod,
+							ENI:             "eni-" + pod.Name,
+							AttachmentLevel: "pod",
+							SecurityGroups:  []types.SecurityGroup{{GroupId: awsSDK.String("sg-web")}},
+							Status:          aws.StatusMapped,
+						})
+					}
+					return infos, nil
+				},
+			},
+			expectedOut: "default    web   2         pod         sg-web           yes",
+		},
+		{
+			name: "named deployment not found",
+			k8sClient: &fakeK8sClient{
+				GetDeploymentFunc: func(ctx context.Context, name, namespace string) (*appsv1.Deployment, error) {
+					return nil, fmt.Errorf("not found")
+				},
+			},
+			awsClient: &fakeAWSClient{},
+			workload:  "web",
+			wantErr:   true,
+		},
+		{
+			name: "no deployments",
+			k8sClient: &fakeK8sClient{
+				ListDeploymentsFunc: func(ctx context.Context, namespace string) ([]appsv1.Deployment, error) {
+					return nil, nil
+				},
+			},
+			awsClient:   &fakeAWSClient{},
+			expectedOut: "No resources found in namespace.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			streams := &genericclioptions.IOStreams{
+				Out:    &bytes.Buffer{},
+				ErrOut: &bytes.Buffer{},
+			}
+			o := NewWorkloadOptions(streams, workload.KindDeployment)
+			o.K8sClient = tc.k8sClient
+			o.AWSClient = tc.awsClient
+			o.Name = tc.workload
+			o.ConfigFlags.Namespace = stringPointer("default")
+
+			err := o.Run(context.Background())
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if got := streams.Out.(*bytes.Buffer).String(); !strings.Contains(got, tc.expectedOut) {
+				t.Errorf("Run() output = %q, want it to contain %q", got, tc.expectedOut)
+			}
+		})
+	}
+}
@@ -17,6 +17,9 @@ func main() {
 	cmd.SetVersionInfo(Version, Revision)
 
 	if err := cmd.Execute(); err != nil {
+		if code, ok := cmd.ExitCode(err); ok {
+			os.Exit(code)
+		}
 		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
 		os.Exit(1)
 	}
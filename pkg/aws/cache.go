@@ -0,0 +1,559 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Default time to live of cached lookups. Security groups change less often than the ENIs of pods,
+// whose private IPs are reused as pods come and go.
+const (
+	DefaultENICacheTTL           = 2 * time.Minute
+	DefaultSecurityGroupCacheTTL = 10 * time.Minute
+)
+
+// Cache file names, inside the directory of a VPC
+const (
+	networkInterfacesCacheFile = "network-interfaces.json"
+	securityGroupsCacheFile    = "security-groups.json"
+)
+
+// CacheOptions configures the on-disk cache of EC2 lookups. A resource type is cached only when
+// its TTL is positive, so the zero value disables the cache.
+type CacheOptions struct {
+	// Dir is the cache root, DefaultCacheDir when empty
+	Dir              string
+	ENITTL           time.Duration
+	SecurityGroupTTL time.Duration
+	// Warnings receives a warning when the cache cannot be used, such as when the account of the
+	// credentials cannot be determined
+	Warnings io.Writer
+}
+
+// Enabled reports whether any resource type is cached
+func (o CacheOptions) Enabled() bool {
+	return o.ENITTL > 0 || o.SecurityGroupTTL > 0
+}
+
+// DefaultCacheDir returns the kubectl-sgmap directory under the user cache directory
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "kubectl-sgmap"), nil
+}
+
+// cacheFiles are the files written in the directory of a VPC
+var cacheFiles = []string{networkInterfacesCacheFile, securityGroupsCacheFile}
+
+// ClearCache removes the cache files of every account, region and VPC under the cache root. Only
+// the files kubectl-sgmap writes are removed, and directories are removed only once they are empty,
+// so a root shared with other files, such as a home directory, keeps everything else.
+func ClearCache(dir string) error {
+	accounts, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clear cache %s: %w", dir, err)
+	}
+
+	for _, account := range accounts {
+		if !account.IsDir() || !isAccountID(account.Name()) {
+			continue
+		}
+		accountDir := filepath.Join(dir, account.Name())
+		regions, err := os.ReadDir(accountDir)
+		if err != nil {
+			return fmt.Errorf("failed to clear cache %s: %w", accountDir, err)
+		}
+		for _, region := range regions {
+			if !region.IsDir() {
+				continue
+			}
+			regionDir := filepath.Join(accountDir, region.Name())
+			vpcs, err := vpcCacheDirs(regionDir)
+			if err != nil {
+				return fmt.Errorf("failed to clear cache %s: %w", regionDir, err)
+			}
+			for _, vpc := range vpcs {
+				vpcDir := filepath.Join(regionDir, vpc)
+				if err := clearCacheFiles(vpcDir); err != nil {
+					return err
+				}
+				_ = os.Remove(vpcDir)
+			}
+			// Files of versions caching a whole region in one directory are removed too
+			if err := clearCacheFiles(regionDir); err != nil {
+				return err
+			}
+			// Directories still holding other files are kept
+			_ = os.Remove(regionDir)
+		}
+		_ = os.Remove(accountDir)
+	}
+	_ = os.Remove(dir)
+	return nil
+}
+
+// vpcCacheDirs returns the names of the VPC directories in the directory of a region
+func vpcCacheDirs(regionDir string) ([]string, error) {
+	entries, err := os.ReadDir(regionDir)
+	if err != nil {
+		return nil, err
+	}
+	var vpcs []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), "vpc-") {
+			vpcs = append(vpcs, entry.Name())
+		}
+	}
+	return vpcs, nil
+}
+
+// clearCacheFiles removes the cache files of a directory, with temporary files left by
+// interrupted writes
+func clearCacheFiles(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to clear cache %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !isCacheFile(entry.Name()) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to clear cache %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// isCacheFile reports whether the name is a cache file or a temporary file of one
+func isCacheFile(name string) bool {
+	for _, f := range cacheFiles {
+		if name == f || strings.HasPrefix(name, f+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// isAccountID reports whether the name is a 12 digit AWS account ID
+func isAccountID(name string) bool {
+	if len(name) != 12 {
+		return false
+	}
+	for _, r := range name {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheEntry is a cached lookup result with the time it was fetched
+type cacheEntry[T any] struct {
+	FetchedAt time.Time `json:"fetchedAt"`
+	Value     T         `json:"value"`
+}
+
+// cacheFile holds the entries of one resource type
+type cacheFile[T any] struct {
+	Entries map[string]cacheEntry[T] `json:"entries"`
+}
+
+// get returns the value of the key if it was fetched less than ttl ago
+func (f *cacheFile[T]) get(key string, ttl time.Duration, now time.Time) (T, bool) {
+	entry, ok := f.Entries[key]
+	if !ok || now.Sub(entry.FetchedAt) >= ttl {
+		var zero T
+		return zero, false
+	}
+	return entry.Value, true
+}
+
+// cachedENIs are the ENIs of one VPC found for a private IP, with every VPC the lookup found ENIs
+// in, so that an IP is served from the cache only while the entries of all those VPCs are present
+type cachedENIs struct {
+	VPCs []string                 `json:"vpcs"`
+	ENIs []types.NetworkInterface `json:"enis"`
+}
+
+// vpcCache holds the cache files of one VPC
+type vpcCache struct {
+	enis cacheFile[cachedENIs]
+	sgs  cacheFile[types.SecurityGroup]
+}
+
+// CachedEC2API is an EC2API keeping the ENIs found by private IP and the security groups found by
+// ID on disk, so that repeated runs skip the EC2 calls for lookups that are still fresh. Other
+// requests, such as filters by security group or VPC, are passed through.
+//
+// Entries are stored per account, region and VPC. EC2 looks up ENIs by private IP across the VPCs
+// of the region, so the ENIs found for an IP are stored in the directory of their VPC, each entry
+// listing the VPCs of the lookup. An IP is served from the cache only when the entries of the same
+// lookup are still present in all those VPCs, and pods of different VPCs sharing an IP get the
+// same answer as without the cache. Security groups are stored in the directory of their VPC.
+// IPs without an ENI are not cached so that ENIs still being attached are found on the next run.
+type CachedEC2API struct {
+	api  EC2API
+	dir  string
+	opts CacheOptions
+	now  func() time.Time
+
+	mu   sync.Mutex
+	vpcs map[string]*vpcCache
+}
+
+var _ EC2API = (*CachedEC2API)(nil)
+
+// NewCachedEC2API wraps the API with a cache in the directory of the account and region under the
+// cache root, with a directory per VPC. Unreadable cache files are ignored and rewritten.
+func NewCachedEC2API(api EC2API, account, region string, opts CacheOptions) (*CachedEC2API, error) {
+	root := opts.Dir
+	if root == "" {
+		var err error
+		root, err = DefaultCacheDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c := &CachedEC2API{
+		api:  api,
+		dir:  filepath.Join(root, account, region),
+		opts: opts,
+		now:  time.Now,
+		vpcs: make(map[string]*vpcCache),
+	}
+	// A region without a cache yet has no directory
+	vpcs, _ := vpcCacheDirs(c.dir)
+	for _, vpc := range vpcs {
+		dir := filepath.Join(c.dir, vpc)
+		c.vpcs[vpc] = &vpcCache{
+			enis: readCacheFile[cachedENIs](dir, networkInterfacesCacheFile),
+			sgs:  readCacheFile[types.SecurityGroup](dir, securityGroupsCacheFile),
+		}
+	}
+	return c, nil
+}
+
+// newCachedEC2APIFromConfig wraps the EC2 client with a cache for the account of the credentials.
+// The cache is skipped with a warning, rather than failing the command, when the account cannot
+// be determined.
+func newCachedEC2APIFromConfig(ctx context.Context, cfg aws.Config, api EC2API, opts CacheOptions) EC2API {
+	warn := func(format string, args ...any) {
+		if opts.Warnings != nil {
+			fmt.Fprintf(opts.Warnings, "Warning: EC2 cache disabled: "+format+"\n", args...)
+		}
+	}
+
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		warn("failed to determine the AWS account: %v", err)
+		return api
+	}
+	if aws.ToString(identity.Account) == "" {
+		warn("the AWS account of the credentials is unknown")
+		return api
+	}
+	cached, err := NewCachedEC2API(api, aws.ToString(identity.Account), cfg.Region, opts)
+	if err != nil {
+		warn("%v", err)
+		return api
+	}
+	return cached
+}
+
+// vpc returns the cache of the VPC, creating an empty one when the VPC has no entries yet.
+// c.mu must be held.
+func (c *CachedEC2API) vpc(id string) *vpcCache {
+	v, ok := c.vpcs[id]
+	if !ok {
+		v = &vpcCache{
+			enis: cacheFile[cachedENIs]{Entries: map[string]cacheEntry[cachedENIs]{}},
+			sgs:  cacheFile[types.SecurityGroup]{Entries: map[string]cacheEntry[types.SecurityGroup]{}},
+		}
+		c.vpcs[id] = v
+	}
+	return v
+}
+
+// cachedENIsByIP returns the ENIs of every VPC of the latest fresh lookup of the IP, or false when
+// the IP is not cached, expired, or the entry of one of the VPCs is missing or from another lookup.
+// c.mu must be held.
+func (c *CachedEC2API) cachedENIsByIP(ip string, now time.Time) ([]types.NetworkInterface, bool) {
+	var latest cacheEntry[cachedENIs]
+	found := false
+	for _, v := range c.vpcs {
+		entry, ok := v.enis.Entries[ip]
+		if !ok || now.Sub(entry.FetchedAt) >= c.opts.ENITTL {
+			continue
+		}
+		if !found || entry.FetchedAt.After(latest.FetchedAt) {
+			latest, found = entry, true
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	var enis []types.NetworkInterface
+	for _, id := range latest.Value.VPCs {
+		v, ok := c.vpcs[id]
+		if !ok {
+			return nil, false
+		}
+		entry, ok := v.enis.Entries[ip]
+		if !ok || !entry.FetchedAt.Equal(latest.FetchedAt) {
+			return nil, false
+		}
+		enis = append(enis, entry.Value.ENIs...)
+	}
+	return enis, true
+}
+
+// writeVPCs writes the cache file of each VPC. The cache only saves EC2 calls, so a cache directory
+// that cannot be written is not an error. c.mu must be held.
+func (c *CachedEC2API) writeVPCs(vpcs map[string]struct{}, name string) {
+	for id := range vpcs {
+		v := c.vpcs[id]
+		dir := filepath.Join(c.dir, id)
+		switch name {
+		case networkInterfacesCacheFile:
+			_ = writeCacheFile(dir, name, v.enis)
+		case securityGroupsCacheFile:
+			_ = writeCacheFile(dir, name, v.sgs)
+		}
+	}
+}
+
+func readCacheFile[T any](dir, name string) cacheFile[T] {
+	f := cacheFile[T]{Entries: map[string]cacheEntry[T]{}}
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return f
+	}
+	if err := json.Unmarshal(b, &f); err != nil || f.Entries == nil {
+		return cacheFile[T]{Entries: map[string]cacheEntry[T]{}}
+	}
+	return f
+}
+
+// writeCacheFile replaces the cache file atomically so that concurrent runs never read a partial file
+func writeCacheFile(dir, name string, v any) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache file %s: %w", name, err)
+	}
+	tmp, err := os.CreateTemp(dir, name+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache file %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache file %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("failed to write cache file %s: %w", name, err)
+	}
+	return nil
+}
+
+// privateIPLookup returns the IPs of a request looking up ENIs by private IP only, the lookup sgmap
+// does for pods
+func privateIPLookup(params *ec2.DescribeNetworkInterfacesInput) ([]string, bool) {
+	if params == nil || params.NextToken != nil || len(params.NetworkInterfaceIds) > 0 || len(params.Filters) != 1 {
+		return nil, false
+	}
+	f := params.Filters[0]
+	if aws.ToString(f.Name) != "addresses.private-ip-address" {
+		return nil, false
+	}
+	return f.Values, true
+}
+
+// DescribeNetworkInterfaces serves lookups by private IP from the cache and fetches only the IPs
+// that are missing or expired, returning all matching ENIs in a single page
+func (c *CachedEC2API) DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	ips, ok := privateIPLookup(params)
+	if !ok || c.opts.ENITTL <= 0 {
+		return c.api.DescribeNetworkInterfaces(ctx, params, optFns...)
+	}
+
+	var result []types.NetworkInterface
+	seen := make(map[string]struct{})
+	add := func(enis []types.NetworkInterface) {
+		for _, eni := range enis {
+			id := aws.ToString(eni.NetworkInterfaceId)
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				result = append(result, eni)
+			}
+		}
+	}
+
+	now := c.now()
+	var misses []string
+	c.mu.Lock()
+	for _, ip := range ips {
+		if enis, ok := c.cachedENIsByIP(ip, now); ok {
+			add(enis)
+		} else {
+			misses = append(misses, ip)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(misses) == 0 {
+		return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: result}, nil
+	}
+
+	input := &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{{Name: aws.String("addresses.private-ip-address"), Values: misses}},
+	}
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(c.api, input)
+	var fetched []types.NetworkInterface
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx, optFns...)
+		if err != nil {
+			return nil, err
+		}
+		fetched = append(fetched, page.NetworkInterfaces...)
+	}
+	add(fetched)
+
+	missing := make(map[string]struct{}, len(misses))
+	for _, ip := range misses {
+		missing[ip] = struct{}{}
+	}
+	// ENIs by IP and VPC
+	byIP := make(map[string]map[string][]types.NetworkInterface)
+	for _, eni := range fetched {
+		vpc := aws.ToString(eni.VpcId)
+		for _, addr := range eni.PrivateIpAddresses {
+			ip := aws.ToString(addr.PrivateIpAddress)
+			if _, ok := missing[ip]; !ok {
+				continue
+			}
+			if byIP[ip] == nil {
+				byIP[ip] = make(map[string][]types.NetworkInterface)
+			}
+			byIP[ip][vpc] = append(byIP[ip][vpc], eni)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	touched := make(map[string]struct{})
+	for ip, byVPC := range byIP {
+		// ENIs without a VPC cannot be stored, so the IP is looked up again next time
+		if _, ok := byVPC[""]; ok {
+			continue
+		}
+		vpcs := make([]string, 0, len(byVPC))
+		for vpc := range byVPC {
+			vpcs = append(vpcs, vpc)
+		}
+		slices.Sort(vpcs)
+		for vpc, enis := range byVPC {
+			c.vpc(vpc).enis.Entries[ip] = cacheEntry[cachedENIs]{FetchedAt: now, Value: cachedENIs{VPCs: vpcs, ENIs: enis}}
+			touched[vpc] = struct{}{}
+		}
+	}
+	c.writeVPCs(touched, networkInterfacesCacheFile)
+	return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: result}, nil
+}
+
+// DescribeSecurityGroups serves lookups by group ID from the cache and fetches only the groups
+// that are missing or expired
+func (c *CachedEC2API) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	if params == nil || len(params.GroupIds) == 0 || len(params.GroupNames) > 0 || len(params.Filters) > 0 || params.NextToken != nil || c.opts.SecurityGroupTTL <= 0 {
+		return c.api.DescribeSecurityGroups(ctx, params, optFns...)
+	}
+
+	now := c.now()
+	var result []types.SecurityGroup
+	var misses []string
+	c.mu.Lock()
+	for _, id := range params.GroupIds {
+		if sg, ok := c.cachedSecurityGroup(id, now); ok {
+			result = append(result, sg)
+		} else {
+			misses = append(misses, id)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(misses) == 0 {
+		return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: result}, nil
+	}
+
+	resp, err := c.api.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{GroupIds: misses}, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, resp.SecurityGroups...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	touched := make(map[string]struct{})
+	for _, sg := range resp.SecurityGroups {
+		vpc := aws.ToString(sg.VpcId)
+		if vpc == "" {
+			continue
+		}
+		c.vpc(vpc).sgs.Entries[aws.ToString(sg.GroupId)] = cacheEntry[types.SecurityGroup]{FetchedAt: now, Value: sg}
+		touched[vpc] = struct{}{}
+	}
+	c.writeVPCs(touched, securityGroupsCacheFile)
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: result}, nil
+}
+
+// cachedSecurityGroup returns the security group of the ID if a VPC has a fresh entry for it.
+// c.mu must be held.
+func (c *CachedEC2API) cachedSecurityGroup(id string, now time.Time) (types.SecurityGroup, bool) {
+	for _, v := range c.vpcs {
+		if sg, ok := v.sgs.get(id, c.opts.SecurityGroupTTL, now); ok {
+			return sg, true
+		}
+	}
+	return types.SecurityGroup{}, false
+}
+
+// DescribeManagedPrefixLists is not cached
+func (c *CachedEC2API) DescribeManagedPrefixLists(ctx context.Context, params *ec2.DescribeManagedPrefixListsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeManagedPrefixListsOutput, error) {
+	return c.api.DescribeManagedPrefixLists(ctx, params, optFns...)
+}
+
+// GetManagedPrefixListEntries is not cached
+func (c *CachedEC2API) GetManagedPrefixListEntries(ctx context.Context, params *ec2.GetManagedPrefixListEntriesInput, optFns ...func(*ec2.Options)) (*ec2.GetManagedPrefixListEntriesOutput, error) {
+	return c.api.GetManagedPrefixListEntries(ctx, params, optFns...)
+}
+
+// DescribeSecurityGroupRules is not cached
+func (c *CachedEC2API) DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	return c.api.DescribeSecurityGroupRules(ctx, params, optFns...)
+}
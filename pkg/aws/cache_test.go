@@ -0,0 +1,246 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func privateIPInput(ips ...string) *ec2.DescribeNetworkInterfacesInput {
+	return &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{{Name: aws.String("addresses.private-ip-address"), Values: ips}},
+	}
+}
+
+func eniWithIP(id, ip string) types.NetworkInterface {
+	return eniInVPC(id, ip, "vpc-1")
+}
+
+func eniInVPC(id, ip, vpc string) types.NetworkInterface {
+	return types.NetworkInterface{
+		NetworkInterfaceId: aws.String(id),
+		VpcId:              aws.String(vpc),
+		PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String(ip)}},
+	}
+}
+
+func TestCachedEC2API_DescribeNetworkInterfaces(t *testing.T) {
+	dir := t.TempDir()
+	opts := CacheOptions{Dir: dir, ENITTL: time.Minute}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mockClient := new(MockEC2Client)
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, privateIPInput("10.0.0.1", "10.0.0.2", "10.0.0.3")).Return(
+		&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: []types.NetworkInterface{eniWithIP("eni-1", "10.0.0.1"), eniWithIP("eni-2", "10.0.0.2")}}, nil).Once()
+
+	cached, err := NewCachedEC2API(mockClient, "123456789012", "us-east-1", opts)
+	require.NoError(t, err)
+	cached.now = func() time.Time { return now }
+
+	out, err := cached.DescribeNetworkInterfaces(context.Background(), privateIPInput("10.0.0.1", "10.0.0.2", "10.0.0.3"))
+	require.NoError(t, err)
+	assert.Len(t, out.NetworkInterfaces, 2)
+	assert.FileExists(t, filepath.Join(dir, "123456789012", "us-east-1", "vpc-1", networkInterfacesCacheFile))
+
+	// A new run reads the cache from disk and only looks up the IP without an ENI
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, privateIPInput("10.0.0.3")).Return(
+		&ec2.DescribeNetworkInterfacesOutput{}, nil).Once()
+	reloaded, err := NewCachedEC2API(mockClient, "123456789012", "us-east-1", opts)
+	require.NoError(t, err)
+	reloaded.now = func() time.Time { return now.Add(30 * time.Second) }
+
+	out, err = reloaded.DescribeNetworkInterfaces(context.Background(), privateIPInput("10.0.0.1", "10.0.0.2", "10.0.0.3"))
+	require.NoError(t, err)
+	assert.Equal(t, "eni-1", aws.ToString(out.NetworkInterfaces[0].NetworkInterfaceId))
+	assert.Len(t, out.NetworkInterfaces, 2)
+
+	// Expired entries are looked up again
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, privateIPInput("10.0.0.1")).Return(
+		&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: []types.NetworkInterface{eniWithIP("eni-9", "10.0.0.1")}}, nil).Once()
+	reloaded.now = func() time.Time { return now.Add(2 * time.Minute) }
+
+	out, err = reloaded.DescribeNetworkInterfaces(context.Background(), privateIPInput("10.0.0.1"))
+	require.NoError(t, err)
+	assert.Equal(t, "eni-9", aws.ToString(out.NetworkInterfaces[0].NetworkInterfaceId))
+	mockClient.AssertExpectations(t)
+}
+
+func TestCachedEC2API_DescribeSecurityGroups(t *testing.T) {
+	opts := CacheOptions{Dir: t.TempDir(), SecurityGroupTTL: time.Hour}
+
+	mockClient := new(MockEC2Client)
+	mockClient.On("DescribeSecurityGroups", mock.Anything, &ec2.DescribeSecurityGroupsInput{GroupIds: []string{"sg-1", "sg-2"}}).Return(
+		&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []types.SecurityGroup{
+			{GroupId: aws.String("sg-1"), GroupName: aws.String("web"), VpcId: aws.String("vpc-1")},
+			{GroupId: aws.String("sg-2"), GroupName: aws.String("db"), VpcId: aws.String("vpc-2")},
+		}}, nil).Once()
+	mockClient.On("DescribeSecurityGroups", mock.Anything, &ec2.DescribeSecurityGroupsInput{GroupIds: []string{"sg-3"}}).Return(
+		&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []types.SecurityGroup{{GroupId: aws.String("sg-3"), VpcId: aws.String("vpc-1")}}}, nil).Once()
+
+	cached, err := NewCachedEC2API(mockClient, "123456789012", "us-east-1", opts)
+	require.NoError(t, err)
+
+	_, err = cached.DescribeSecurityGroups(context.Background(), &ec2.DescribeSecurityGroupsInput{GroupIds: []string{"sg-1", "sg-2"}})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(opts.Dir, "123456789012", "us-east-1", "vpc-1", securityGroupsCacheFile))
+	assert.FileExists(t, filepath.Join(opts.Dir, "123456789012", "us-east-1", "vpc-2", securityGroupsCacheFile))
+
+	out, err := cached.DescribeSecurityGroups(context.Background(), &ec2.DescribeSecurityGroupsInput{GroupIds: []string{"sg-1", "sg-3"}})
+	require.NoError(t, err)
+	require.Len(t, out.SecurityGroups, 2)
+	assert.Equal(t, "web", aws.ToString(out.SecurityGroups[0].GroupName))
+	assert.Equal(t, "sg-3", aws.ToString(out.SecurityGroups[1].GroupId))
+	mockClient.AssertExpectations(t)
+}
+
+func TestCachedEC2API_DescribeNetworkInterfaces_VPCs(t *testing.T) {
+	dir := t.TempDir()
+	opts := CacheOptions{Dir: dir, ENITTL: time.Minute}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	regionDir := filepath.Join(dir, "123456789012", "us-east-1")
+
+	// The same IP is used in two VPCs, and each VPC keeps its own ENI
+	mockClient := new(MockEC2Client)
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, privateIPInput("10.0.0.1")).Return(
+		&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: []types.NetworkInterface{
+			eniInVPC("eni-a", "10.0.0.1", "vpc-a"),
+			eniInVPC("eni-b", "10.0.0.1", "vpc-b"),
+		}}, nil).Once()
+
+	cached, err := NewCachedEC2API(mockClient, "123456789012", "us-east-1", opts)
+	require.NoError(t, err)
+	cached.now = func() time.Time { return now }
+	_, err = cached.DescribeNetworkInterfaces(context.Background(), privateIPInput("10.0.0.1"))
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(regionDir, "vpc-a", networkInterfacesCacheFile))
+	assert.FileExists(t, filepath.Join(regionDir, "vpc-b", networkInterfacesCacheFile))
+
+	// A new run serves both ENIs of the IP from the cache
+	reloaded, err := NewCachedEC2API(mockClient, "123456789012", "us-east-1", opts)
+	require.NoError(t, err)
+	reloaded.now = func() time.Time { return now.Add(30 * time.Second) }
+	out, err := reloaded.DescribeNetworkInterfaces(context.Background(), privateIPInput("10.0.0.1"))
+	require.NoError(t, err)
+	assert.Len(t, out.NetworkInterfaces, 2)
+
+	// Without the entry of one of the VPCs, the IP is looked up again rather than answered in part
+	require.NoError(t, os.Remove(filepath.Join(regionDir, "vpc-b", networkInterfacesCacheFile)))
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, privateIPInput("10.0.0.1")).Return(
+		&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: []types.NetworkInterface{
+			eniInVPC("eni-a", "10.0.0.1", "vpc-a"),
+			eniInVPC("eni-b", "10.0.0.1", "vpc-b"),
+		}}, nil).Once()
+	partial, err := NewCachedEC2API(mockClient, "123456789012", "us-east-1", opts)
+	require.NoError(t, err)
+	partial.now = func() time.Time { return now.Add(30 * time.Second) }
+	out, err = partial.DescribeNetworkInterfaces(context.Background(), privateIPInput("10.0.0.1"))
+	require.NoError(t, err)
+	assert.Len(t, out.NetworkInterfaces, 2)
+	mockClient.AssertExpectations(t)
+}
+
+func TestNewCachedEC2APIFromConfig_Warning(t *testing.T) {
+	var warnings bytes.Buffer
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient:  failingHTTPClient{},
+		Retryer:     func() aws.Retryer { return aws.NopRetryer{} },
+	}
+	mockClient := new(MockEC2Client)
+
+	api := newCachedEC2APIFromConfig(context.Background(), cfg, mockClient, CacheOptions{Dir: t.TempDir(), ENITTL: time.Minute, Warnings: &warnings})
+	assert.Same(t, mockClient, api)
+	assert.Contains(t, warnings.String(), "Warning: EC2 cache disabled: failed to determine the AWS account")
+}
+
+// failingHTTPClient fails every request without reaching the network
+type failingHTTPClient struct{}
+
+func (failingHTTPClient) Do(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestCachedEC2API_PassThrough(t *testing.T) {
+	// ENIs are not cached with a zero TTL, and other lookups are never cached
+	opts := CacheOptions{Dir: t.TempDir(), SecurityGroupTTL: time.Hour}
+	byGroup := &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{{Name: aws.String("group-id"), Values: []string{"sg-1"}}},
+	}
+	byName := &ec2.DescribeSecurityGroupsInput{GroupNames: []string{"web"}}
+
+	mockClient := new(MockEC2Client)
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, privateIPInput("10.0.0.1")).Return(&ec2.DescribeNetworkInterfacesOutput{}, nil).Twice()
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, byGroup).Return(&ec2.DescribeNetworkInterfacesOutput{}, nil).Twice()
+	mockClient.On("DescribeSecurityGroups", mock.Anything, byName).Return(&ec2.DescribeSecurityGroupsOutput{}, nil).Twice()
+
+	cached, err := NewCachedEC2API(mockClient, "123456789012", "us-east-1", opts)
+	require.NoError(t, err)
+	for range 2 {
+		_, err = cached.DescribeNetworkInterfaces(context.Background(), privateIPInput("10.0.0.1"))
+		require.NoError(t, err)
+		_, err = cached.DescribeNetworkInterfaces(context.Background(), byGroup)
+		require.NoError(t, err)
+		_, err = cached.DescribeSecurityGroups(context.Background(), byName)
+		require.NoError(t, err)
+	}
+	mockClient.AssertExpectations(t)
+}
+
+func TestCachedEC2API_CorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	accountDir := filepath.Join(dir, "123456789012", "us-east-1", "vpc-1")
+	require.NoError(t, os.MkdirAll(accountDir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(accountDir, securityGroupsCacheFile), []byte("{not json"), 0o600))
+
+	mockClient := new(MockEC2Client)
+	mockClient.On("DescribeSecurityGroups", mock.Anything, mock.Anything).Return(
+		&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []types.SecurityGroup{{GroupId: aws.String("sg-1"), VpcId: aws.String("vpc-1")}}}, nil).Once()
+
+	cached, err := NewCachedEC2API(mockClient, "123456789012", "us-east-1", CacheOptions{Dir: dir, SecurityGroupTTL: time.Hour})
+	require.NoError(t, err)
+	out, err := cached.DescribeSecurityGroups(context.Background(), &ec2.DescribeSecurityGroupsInput{GroupIds: []string{"sg-1"}})
+	require.NoError(t, err)
+	assert.Len(t, out.SecurityGroups, 1)
+	mockClient.AssertExpectations(t)
+}
+
+func TestClearCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "kubectl-sgmap")
+	require.NoError(t, writeCacheFile(filepath.Join(dir, "123456789012", "us-east-1", "vpc-1"), securityGroupsCacheFile, cacheFile[types.SecurityGroup]{}))
+	require.NoError(t, writeCacheFile(filepath.Join(dir, "123456789012", "us-east-1"), securityGroupsCacheFile, cacheFile[types.SecurityGroup]{}))
+
+	require.NoError(t, ClearCache(dir))
+	assert.NoDirExists(t, dir)
+	assert.NoError(t, ClearCache(dir), "clearing a missing cache is not an error")
+}
+
+func TestClearCache_KeepsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	regionDir := filepath.Join(dir, "123456789012", "us-east-1", "vpc-1")
+	require.NoError(t, writeCacheFile(regionDir, networkInterfacesCacheFile, cacheFile[cachedENIs]{}))
+	require.NoError(t, writeCacheFile(regionDir, securityGroupsCacheFile, cacheFile[types.SecurityGroup]{}))
+	require.NoError(t, os.WriteFile(filepath.Join(regionDir, "notes.txt"), []byte("keep"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "Documents"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Documents", securityGroupsCacheFile), []byte("keep"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".bashrc"), []byte("keep"), 0o600))
+
+	require.NoError(t, ClearCache(dir))
+	assert.NoFileExists(t, filepath.Join(regionDir, networkInterfacesCacheFile))
+	assert.NoFileExists(t, filepath.Join(regionDir, securityGroupsCacheFile))
+	assert.FileExists(t, filepath.Join(regionDir, "notes.txt"))
+	assert.FileExists(t, filepath.Join(dir, "Documents", securityGroupsCacheFile))
+	assert.FileExists(t, filepath.Join(dir, ".bashrc"))
+}
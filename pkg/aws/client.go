@@ -3,10 +3,11 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/naka-gawa/kubectl-sgmap/pkg/utils"
@@ -18,17 +19,28 @@ import (
 type EC2API interface {
 	DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error)
 	DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+	DescribeManagedPrefixLists(ctx context.Context, params *ec2.DescribeManagedPrefixListsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeManagedPrefixListsOutput, error)
+	GetManagedPrefixListEntries(ctx context.Context, params *ec2.GetManagedPrefixListEntriesInput, optFns ...func(*ec2.Options)) (*ec2.GetManagedPrefixListEntriesOutput, error)
+	DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error)
 }
 
 // Client provides access to AWS EC2 APIs
 type Client struct {
-	ec2Client EC2API
+	ec2Client   EC2API
+	elbv2Client ELBv2API
+	requests    RequestOptions
 }
 
 // Interface defines the methods provided by the AWS EC2 client.
 // Used for dependency injection and testing.
 type Interface interface {
 	FetchSecurityGroupsByPods(ctx context.Context, pods []corev1.Pod) ([]PodSecurityGroupInfo, error)
+	FetchPodsBySecurityGroups(ctx context.Context, sgRefs []string, pods []corev1.Pod) ([]SecurityGroupUsage, error)
+	FetchNodeNetworkInterfaces(ctx context.Context, nodes []corev1.Node, pods []corev1.Pod) ([]NodeNetworkInterfaces, error)
+	FetchLoadBalancers(ctx context.Context, dnsNames []string) ([]LoadBalancer, error)
+	ResolvePeers(ctx context.Context, securityGroups []types.SecurityGroup, pods []corev1.Pod, nodes []corev1.Node) (Peers, error)
+	FetchSecurityGroupRules(ctx context.Context, sgIDs []string) ([]types.SecurityGroupRule, error)
+	FetchPrefixLists(ctx context.Context, securityGroups []types.SecurityGroup) (map[string]PrefixList, error)
 }
 
 // PodSecurityGroupInfo represents the security group information associated with a Pod
@@ -37,70 +49,171 @@ type PodSecurityGroupInfo struct {
 	SecurityGroups  []types.SecurityGroup `json:"securityGroups" yaml:"securityGroups"`
 	ENI             string                `json:"eni" yaml:"eni"`
 	AttachmentLevel string                `json:"attachmentLevel" yaml:"attachmentLevel"`
+	// Context is the kubeconfig context the pod was read from when querying several clusters
+	Context string `json:"context,omitempty" yaml:"context,omitempty"`
+	// Status tells whether the pod could be mapped to an ENI, and Reason explains any other status
+	Status string `json:"status" yaml:"status"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
 }
 
-// NewClient creates a new AWS EC2 client
-func NewClient(api EC2API) (*Client, error) {
+// Mapping statuses of a pod
+const (
+	// StatusMapped means the ENI and all security groups of the pod were found
+	StatusMapped = "Mapped"
+	// StatusHostNetwork means the pod shares the network namespace, and so the ENI, of its node
+	StatusHostNetwork = "HostNetwork"
+	// StatusFargate means the pod runs on Fargate with an ENI of its own
+	StatusFargate = "Fargate"
+	// StatusNotRunning means the pod is not in the Running phase
+	StatusNotRunning = "NotRunning"
+	// StatusNoIP means the pod is running but has no IP address yet
+	StatusNoIP = "NoIP"
+	// StatusENINotFound means no ENI has the IP address of the pod
+	StatusENINotFound = "ENINotFound"
+	// StatusSGNotFound means some security groups attached to the ENI of the pod could not be described
+	StatusSGNotFound = "SGNotFound"
+	// StatusLookupFailed means the EC2 lookup of the ENI or security groups of the pod failed
+	StatusLookupFailed = "LookupFailed"
+)
+
+// FargateProfileLabel is the label EKS sets on pods scheduled by a Fargate profile
+const FargateProfileLabel = "eks.amazonaws.com/fargate-profile"
+
+// Mapped reports whether the ENI of the pod was found and its security groups were looked up.
+// A pod whose security group lookup failed has an ENI but only some of its security groups.
+func (i PodSecurityGroupInfo) Mapped() bool {
+	return i.ENI != "" && i.Status != StatusLookupFailed
+}
+
+// NewClient creates a new AWS EC2 client looking up resources with the request options
+func NewClient(api EC2API, requests RequestOptions) (*Client, error) {
 	if api == nil {
-		cfg, err := config.LoadDefaultConfig(context.Background())
+		var err error
+		api, err = NewEC2API(context.Background(), ClientOptions{})
 		if err != nil {
-			return nil, fmt.Errorf("failed to load aws config: %w", err)
+			return nil, err
 		}
-		api = ec2.NewFromConfig(cfg)
 	}
 
 	return &Client{
 		ec2Client: api,
+		requests:  requests,
 	}, nil
 }
 
-// FetchSecurityGroupsByPods fetches security groups associated with pods by resolving their ENIs
+// NewEC2API creates an EC2 API client from the AWS configuration selected by the options, caching
+// lookups on disk when the cache is enabled
+func NewEC2API(ctx context.Context, opts ClientOptions) (EC2API, error) {
+	cfg, err := LoadConfig(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	api := ec2.NewFromConfig(cfg, func(o *ec2.Options) {
+		withEndpoint(opts, &o.BaseEndpoint)
+	})
+	if opts.Cache.Enabled() {
+		return newCachedEC2APIFromConfig(ctx, cfg, api, opts.Cache), nil
+	}
+	return api, nil
+}
+
+// FetchSecurityGroupsByPods fetches security groups associated with pods by resolving their ENIs.
+// Every pod is returned in input order; pods that could not be mapped carry a status and reason.
+// Failed EC2 lookups do not fail the whole call: the affected pods get StatusLookupFailed instead.
 func (c *Client) FetchSecurityGroupsByPods(ctx context.Context, pods []corev1.Pod) ([]PodSecurityGroupInfo, error) {
-	podIPs, ipToPod := filterRunningPodsWithIPs(pods)
-	if len(podIPs) == 0 {
-		return nil, nil
+	var (
+		eniMap     map[string]types.NetworkInterface
+		ipToENI    map[string]string
+		eniToSGIDs map[string][]string
+		sgMap      map[string]types.SecurityGroup
+		failures   lookupFailures
+	)
+	if podIPs := runningPodIPs(pods); len(podIPs) > 0 {
+		opts := c.requests.batchOptions()
+		opts.Partial = true
+
+		var err error
+		eniMap, ipToENI, eniToSGIDs, err = c.fetchENIAndSGIDs(ctx, podIPs, opts)
+		if failures.ips, err = partialFailures(ctx, err); err != nil {
+			return nil, fmt.Errorf("failed to describe ENIs: %w", err)
+		}
+
+		sgMap, err = c.getSecurityGroups(ctx, collectUniqueSGIDs(eniToSGIDs), opts)
+		if failures.sgs, err = partialFailures(ctx, err); err != nil {
+			return nil, err
+		}
 	}
 
-	eniMap, ipToENI, eniToSGIDs, err := c.fetchENIAndSGIDs(ctx, podIPs)
-	if err != nil {
-		return nil, err
+	result := make([]PodSecurityGroupInfo, 0, len(pods))
+	for _, pod := range pods {
+		result = append(result, buildPodSecurityGroupInfo(pod, ipToENI, eniToSGIDs, sgMap, eniMap, failures))
 	}
+	return result, nil
+}
 
-	sgMap, err := c.GetSecurityGroupsParallel(ctx, collectUniqueSGIDs(eniToSGIDs))
-	if err != nil {
+// lookupFailures holds the error of each pod IP and security group ID whose lookup failed
+type lookupFailures struct {
+	ips map[string]error
+	sgs map[string]error
+}
+
+// partialFailures returns the error of each item of the failed batches of a partial lookup. Other
+// errors, and any error once the context is done, are returned as is.
+func partialFailures(ctx context.Context, err error) (map[string]error, error) {
+	var batchErr *utils.BatchError[string]
+	if err == nil || ctx.Err() != nil || !errors.As(err, &batchErr) {
 		return nil, err
 	}
-
-	return buildPodSecurityGroupInfo(ipToPod, ipToENI, eniToSGIDs, sgMap, eniMap), nil
+	failed := make(map[string]error)
+	for _, f := range batchErr.Failures {
+		for _, item := range f.Items {
+			failed[item] = f.Err
+		}
+	}
+	return failed, nil
 }
 
-// filterRunningPodsWithIPs filters pods in the Running phase and extracts their IPs
-func filterRunningPodsWithIPs(pods []corev1.Pod) ([]string, map[string]corev1.Pod) {
+// runningPodIPs extracts the unique IPs of pods in the Running phase.
+// Pods using the host network share the IP of their node, so an IP may belong to several pods.
+func runningPodIPs(pods []corev1.Pod) []string {
 	var ips []string
-	ipToPod := make(map[string]corev1.Pod)
+	seen := make(map[string]struct{})
 
 	for _, pod := range pods {
-		if pod.Status.Phase != corev1.PodRunning {
+		if unresolvedStatus(pod) != "" {
 			continue
 		}
-		if ip := pod.Status.PodIP; ip != "" {
+		ip := pod.Status.PodIP
+		if _, ok := seen[ip]; !ok {
+			seen[ip] = struct{}{}
 			ips = append(ips, ip)
-			ipToPod[ip] = pod
 		}
 	}
-	return ips, ipToPod
+	return ips
+}
+
+// unresolvedStatus returns the status of a pod whose ENI cannot be looked up, or "" if it can
+func unresolvedStatus(pod corev1.Pod) string {
+	switch {
+	case pod.Status.Phase != corev1.PodRunning:
+		return StatusNotRunning
+	case pod.Status.PodIP == "":
+		return StatusNoIP
+	}
+	return ""
 }
 
-// fetchENIAndSGIDs retrieves ENIs and extracts corresponding SG IDs from private IPs
-func (c *Client) fetchENIAndSGIDs(ctx context.Context, podIPs []string) (
+// fetchENIAndSGIDs retrieves ENIs and extracts corresponding SG IDs from private IPs.
+// In partial mode the ENIs of the successful batches are returned along with the batch error.
+func (c *Client) fetchENIAndSGIDs(ctx context.Context, podIPs []string, opts utils.BatchOptions) (
 	map[string]types.NetworkInterface,
 	map[string]string,
 	map[string][]string,
 	error,
 ) {
-	eniMap, err := c.GetENIsByPrivateIPs(ctx, podIPs)
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to describe ENIs: %w", err)
+	eniMap, err := c.getENIsByPrivateIPs(ctx, podIPs, opts)
+	if eniMap == nil {
+		return nil, nil, nil, err
 	}
 
 	ipToENI := make(map[string]string)
@@ -114,7 +227,7 @@ func (c *Client) fetchENIAndSGIDs(ctx context.Context, podIPs []string) (
 			eniToSGIDs[eniID] = append(eniToSGIDs[eniID], aws.ToString(group.GroupId))
 		}
 	}
-	return eniMap, ipToENI, eniToSGIDs, nil
+	return eniMap, ipToENI, eniToSGIDs, err
 }
 
 // collectUniqueSGIDs deduplicates SG IDs from ENI to SG ID map
@@ -132,40 +245,83 @@ func collectUniqueSGIDs(eniToSGIDs map[string][]string) []string {
 	return result
 }
 
-// buildPodSecurityGroupInfo builds the final mapping between Pod and its associated SGs and ENI
+// buildPodSecurityGroupInfo builds the mapping between a Pod and its associated SGs and ENI
 func buildPodSecurityGroupInfo(
-	ipToPod map[string]corev1.Pod,
+	pod corev1.Pod,
 	ipToENI map[string]string,
 	eniToSGIDs map[string][]string,
 	sgMap map[string]types.SecurityGroup,
 	eniMap map[string]types.NetworkInterface,
-) []PodSecurityGroupInfo {
-	var result []PodSecurityGroupInfo
+	failures lookupFailures,
+) PodSecurityGroupInfo {
+	info := PodSecurityGroupInfo{Pod: pod}
+
+	switch unresolvedStatus(pod) {
+	case StatusNotRunning:
+		phase := string(pod.Status.Phase)
+		if phase == "" {
+			phase = "Unknown"
+		}
+		info.Status, info.Reason = StatusNotRunning, fmt.Sprintf("pod phase is %s", phase)
+		return info
+	case StatusNoIP:
+		info.Status, info.Reason = StatusNoIP, "pod has no IP address"
+		return info
+	}
 
-	for ip, pod := range ipToPod {
-		eniID, ok := ipToENI[ip]
-		if !ok {
-			fmt.Printf("Warning: ENI not found for pod %s/%s (IP %s)\n", pod.Namespace, pod.Name, ip)
-			continue
+	eniID, ok := ipToENI[pod.Status.PodIP]
+	if !ok {
+		if err, failed := failures.ips[pod.Status.PodIP]; failed {
+			info.Status, info.Reason = StatusLookupFailed, fmt.Sprintf("failed to look up ENI of IP %s: %v", pod.Status.PodIP, err)
+		} else {
+			info.Status, info.Reason = StatusENINotFound, fmt.Sprintf("no ENI has IP %s", pod.Status.PodIP)
 		}
-		var sgs []types.SecurityGroup
-		for _, sgID := range eniToSGIDs[eniID] {
-			if sg, ok := sgMap[sgID]; ok {
-				sgs = append(sgs, sg)
+		return info
+	}
+
+	var missing []string
+	var lookupErr error
+	for _, sgID := range eniToSGIDs[eniID] {
+		if sg, ok := sgMap[sgID]; ok {
+			info.SecurityGroups = append(info.SecurityGroups, sg)
+		} else {
+			missing = append(missing, sgID)
+			if err, failed := failures.sgs[sgID]; failed && lookupErr == nil {
+				lookupErr = err
 			}
 		}
+	}
 
-		// Determine attachment level based on ENI characteristics
-		attachmentLevel := determineAttachmentLevel(eniMap[eniID])
+	info.ENI = eniID
+	// Determine attachment level based on ENI characteristics
+	info.AttachmentLevel = determineAttachmentLevel(eniMap[eniID])
+	if isFargatePod(pod) {
+		info.AttachmentLevel = "pod"
+	}
+	info.Status, info.Reason = mappedStatus(pod, missing)
+	if lookupErr != nil {
+		info.Status, info.Reason = StatusLookupFailed, fmt.Sprintf("failed to look up security groups %s: %v", strings.Join(missing, ", "), lookupErr)
+	}
+	return info
+}
 
-		result = append(result, PodSecurityGroupInfo{
-			Pod:             pod,
-			ENI:             eniID,
-			SecurityGroups:  sgs,
-			AttachmentLevel: attachmentLevel,
-		})
+// mappedStatus returns the status and reason of a pod whose ENI was found
+func mappedStatus(pod corev1.Pod, missingSGIDs []string) (string, string) {
+	switch {
+	case len(missingSGIDs) > 0:
+		return StatusSGNotFound, fmt.Sprintf("security groups not found: %s", strings.Join(missingSGIDs, ", "))
+	case pod.Spec.HostNetwork:
+		return StatusHostNetwork, "pod uses the network of its node"
+	case isFargatePod(pod):
+		return StatusFargate, fmt.Sprintf("pod runs on Fargate profile %s", pod.Labels[FargateProfileLabel])
 	}
-	return result
+	return StatusMapped, ""
+}
+
+// isFargatePod reports whether the pod was scheduled by a Fargate profile
+func isFargatePod(pod corev1.Pod) bool {
+	_, ok := pod.Labels[FargateProfileLabel]
+	return ok
 }
 
 // determineAttachmentLevel determines where the security group is attached based on ENI characteristics
@@ -221,11 +377,15 @@ func containsAny(s string, substrings []string) bool {
 
 // GetENIsByPrivateIPs retrieves network interfaces from EC2 based on private IP addresses using batch processing
 func (c *Client) GetENIsByPrivateIPs(ctx context.Context, ips []string) (map[string]types.NetworkInterface, error) {
+	return c.getENIsByPrivateIPs(ctx, ips, c.requests.batchOptions())
+}
+
+func (c *Client) getENIsByPrivateIPs(ctx context.Context, ips []string, opts utils.BatchOptions) (map[string]types.NetworkInterface, error) {
 	if len(ips) == 0 {
 		return nil, fmt.Errorf("input list of private IPs is empty")
 	}
 
-	return utils.RunBatchParallel(ctx, ips, 200, 5, func(ctx context.Context, batch []string) (map[string]types.NetworkInterface, error) {
+	return utils.RunBatchParallel(ctx, ips, opts, func(ctx context.Context, batch []string) (map[string]types.NetworkInterface, error) {
 		input := &ec2.DescribeNetworkInterfacesInput{
 			Filters: []types.Filter{
 				{
@@ -238,7 +398,7 @@ func (c *Client) GetENIsByPrivateIPs(ctx context.Context, ips []string) (map[str
 		paginator := ec2.NewDescribeNetworkInterfacesPaginator(c.ec2Client, input)
 		result := make(map[string]types.NetworkInterface)
 		for paginator.HasMorePages() {
-			page, err := paginator.NextPage(ctx)
+			page, err := paginator.NextPage(ctx, withoutSDKRetries)
 			if err != nil {
 				return nil, fmt.Errorf("failed to paginate DescribeNetworkInterfaces: %w", err)
 			}
@@ -252,8 +412,12 @@ func (c *Client) GetENIsByPrivateIPs(ctx context.Context, ips []string) (map[str
 
 // GetSecurityGroupsParallel retrieves security groups by their IDs using parallel processing.
 // It deduplicates input IDs, splits them into batches of up to 200 IDs (AWS API limit),
-// and processes each batch concurrently using a worker pool.
+// and processes each batch concurrently using a worker pool, retrying throttled batches.
 func (c *Client) GetSecurityGroupsParallel(ctx context.Context, sgIDs []string) (map[string]types.SecurityGroup, error) {
+	return c.getSecurityGroups(ctx, sgIDs, c.requests.batchOptions())
+}
+
+func (c *Client) getSecurityGroups(ctx context.Context, sgIDs []string, opts utils.BatchOptions) (map[string]types.SecurityGroup, error) {
 	seen := make(map[string]struct{})
 	var deduped []string
 	for _, id := range sgIDs {
@@ -267,12 +431,12 @@ func (c *Client) GetSecurityGroupsParallel(ctx context.Context, sgIDs []string)
 		return map[string]types.SecurityGroup{}, nil
 	}
 
-	return utils.RunBatchParallel(ctx, deduped, 200, 5, func(ctx context.Context, ids []string) (map[string]types.SecurityGroup, error) {
+	return utils.RunBatchParallel(ctx, deduped, opts, func(ctx context.Context, ids []string) (map[string]types.SecurityGroup, error) {
 		input := &ec2.DescribeSecurityGroupsInput{
 			GroupIds: ids,
 		}
 
-		resp, err := c.ec2Client.DescribeSecurityGroups(ctx, input)
+		resp, err := c.ec2Client.DescribeSecurityGroups(ctx, input, withoutSDKRetries)
 		if err != nil {
 			return nil, fmt.Errorf("DescribeSecurityGroups failed: %w", err)
 		}
@@ -36,6 +36,30 @@ func (m *MockEC2Client) DescribeSecurityGroups(ctx context.Context, params *ec2.
 	return args.Get(0).(*ec2.DescribeSecurityGroupsOutput), args.Error(1)
 }
 
+func (m *MockEC2Client) DescribeManagedPrefixLists(ctx context.Context, params *ec2.DescribeManagedPrefixListsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeManagedPrefixListsOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ec2.DescribeManagedPrefixListsOutput), args.Error(1)
+}
+
+func (m *MockEC2Client) GetManagedPrefixListEntries(ctx context.Context, params *ec2.GetManagedPrefixListEntriesInput, optFns ...func(*ec2.Options)) (*ec2.GetManagedPrefixListEntriesOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ec2.GetManagedPrefixListEntriesOutput), args.Error(1)
+}
+
+func (m *MockEC2Client) DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ec2.DescribeSecurityGroupRulesOutput), args.Error(1)
+}
+
 func TestGetENIsByPrivateIPs_Success(t *testing.T) {
 	mockClient := new(MockEC2Client)
 	client := &Client{ec2Client: mockClient}
@@ -110,9 +134,14 @@ func TestFetchSecurityGroupsByPods_SGError(t *testing.T) {
 		nil, fmt.Errorf("aws error"),
 	)
 
-	_, err := client.FetchSecurityGroupsByPods(context.Background(), pods)
+	result, err := client.FetchSecurityGroupsByPods(context.Background(), pods)
 
-	assert.Error(t, err)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, StatusLookupFailed, result[0].Status)
+	assert.Equal(t, "eni-1", result[0].ENI)
+	assert.Contains(t, result[0].Reason, "failed to look up security groups sg-1")
+	assert.Contains(t, result[0].Reason, "aws error")
 	mockClient.AssertExpectations(t)
 }
 
@@ -127,17 +156,17 @@ func TestGetSecurityGroupsParallel_Empty(t *testing.T) {
 	mockClient.AssertNotCalled(t, "DescribeSecurityGroups")
 }
 
-func TestFilterRunningPodsWithIPs(t *testing.T) {
+func TestRunningPodIPs(t *testing.T) {
 	pods := []corev1.Pod{
 		{Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"}},
 		{Status: corev1.PodStatus{Phase: corev1.PodPending, PodIP: "10.0.0.2"}},
 		{Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ""}},
+		{Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"}},
 	}
 
-	ips, ipToPod := filterRunningPodsWithIPs(pods)
+	ips := runningPodIPs(pods)
 
 	assert.Equal(t, []string{"10.0.0.1"}, ips)
-	assert.Len(t, ipToPod, 1)
 }
 
 func TestCollectUniqueSGIDs(t *testing.T) {
@@ -173,20 +202,85 @@ func TestDetermineAttachmentLevel(t *testing.T) {
 }
 
 func TestBuildPodSecurityGroupInfo(t *testing.T) {
-	ipToPod := map[string]corev1.Pod{
-		"10.0.0.1": {ObjectMeta: metav1.ObjectMeta{Name: "pod1"}},
-	}
-	ipToENI := map[string]string{"10.0.0.1": "eni-1"}
-	eniToSGIDs := map[string][]string{"eni-1": {"sg-1"}}
+	ipToENI := map[string]string{"10.0.0.1": "eni-1", "10.0.0.2": "eni-2"}
+	eniToSGIDs := map[string][]string{"eni-1": {"sg-1"}, "eni-2": {"sg-1", "sg-2"}}
 	sgMap := map[string]types.SecurityGroup{"sg-1": {GroupId: aws.String("sg-1")}}
-	eniMap := map[string]types.NetworkInterface{"eni-1": {NetworkInterfaceId: aws.String("eni-1")}}
+	eniMap := map[string]types.NetworkInterface{
+		"eni-1": {NetworkInterfaceId: aws.String("eni-1")},
+		"eni-2": {NetworkInterfaceId: aws.String("eni-2")},
+	}
+	running := corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"}
 
-	result := buildPodSecurityGroupInfo(ipToPod, ipToENI, eniToSGIDs, sgMap, eniMap)
+	testCases := []struct {
+		name           string
+		pod            corev1.Pod
+		expectedStatus string
+		expectedReason string
+		expectedENI    string
+		expectedSGs    int
+	}{
+		{
+			name:           "Mapped",
+			pod:            corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}, Status: running},
+			expectedStatus: StatusMapped,
+			expectedENI:    "eni-1",
+			expectedSGs:    1,
+		},
+		{
+			name:           "Pending",
+			pod:            corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}, Status: corev1.PodStatus{Phase: corev1.PodPending}},
+			expectedStatus: StatusNotRunning,
+			expectedReason: "pod phase is Pending",
+		},
+		{
+			name:           "NoIP",
+			pod:            corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+			expectedStatus: StatusNoIP,
+			expectedReason: "pod has no IP address",
+		},
+		{
+			name:           "ENINotFound",
+			pod:            corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}, Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.9"}},
+			expectedStatus: StatusENINotFound,
+			expectedReason: "no ENI has IP 10.0.0.9",
+		},
+		{
+			name:           "SGNotFound",
+			pod:            corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}, Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.2"}},
+			expectedStatus: StatusSGNotFound,
+			expectedReason: "security groups not found: sg-2",
+			expectedENI:    "eni-2",
+			expectedSGs:    1,
+		},
+		{
+			name:           "HostNetwork",
+			pod:            corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}, Spec: corev1.PodSpec{HostNetwork: true}, Status: running},
+			expectedStatus: StatusHostNetwork,
+			expectedReason: "pod uses the network of its node",
+			expectedENI:    "eni-1",
+			expectedSGs:    1,
+		},
+		{
+			name:           "Fargate",
+			pod:            corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{FargateProfileLabel: "fp-default"}}, Status: running},
+			expectedStatus: StatusFargate,
+			expectedReason: "pod runs on Fargate profile fp-default",
+			expectedENI:    "eni-1",
+			expectedSGs:    1,
+		},
+	}
 
-	assert.Len(t, result, 1)
-	assert.Equal(t, "pod1", result[0].Pod.Name)
-	assert.Equal(t, "eni-1", result[0].ENI)
-	assert.Len(t, result[0].SecurityGroups, 1)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := buildPodSecurityGroupInfo(tc.pod, ipToENI, eniToSGIDs, sgMap, eniMap, lookupFailures{})
+
+			assert.Equal(t, "pod1", result.Pod.Name)
+			assert.Equal(t, tc.expectedStatus, result.Status)
+			assert.Equal(t, tc.expectedReason, result.Reason)
+			assert.Equal(t, tc.expectedENI, result.ENI)
+			assert.Len(t, result.SecurityGroups, tc.expectedSGs)
+		})
+	}
 }
 
 func TestFetchSecurityGroupsByPods_Success(t *testing.T) {
@@ -229,6 +323,7 @@ func TestFetchSecurityGroupsByPods_Success(t *testing.T) {
 	assert.Equal(t, "pod1", result[0].Pod.Name)
 	assert.Len(t, result[0].SecurityGroups, 1)
 	assert.Equal(t, "sg-1", *result[0].SecurityGroups[0].GroupId)
+	assert.Equal(t, StatusMapped, result[0].Status)
 	mockClient.AssertExpectations(t)
 }
 
@@ -246,7 +341,8 @@ func TestFetchSecurityGroupsByPods_NoRunningPods(t *testing.T) {
 	result, err := client.FetchSecurityGroupsByPods(context.Background(), pods)
 
 	assert.NoError(t, err)
-	assert.Empty(t, result)
+	assert.Len(t, result, 1)
+	assert.Equal(t, StatusNotRunning, result[0].Status)
 	mockClient.AssertNotCalled(t, "DescribeNetworkInterfaces")
 	mockClient.AssertNotCalled(t, "DescribeSecurityGroups")
 }
@@ -266,12 +362,85 @@ func TestFetchSecurityGroupsByPods_ENIError(t *testing.T) {
 		nil, fmt.Errorf("aws error"),
 	)
 
-	_, err := client.FetchSecurityGroupsByPods(context.Background(), pods)
+	result, err := client.FetchSecurityGroupsByPods(context.Background(), pods)
 
-	assert.Error(t, err)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, StatusLookupFailed, result[0].Status)
+	assert.Contains(t, result[0].Reason, "failed to look up ENI of IP 10.0.0.1")
+	assert.False(t, result[0].Mapped())
 	mockClient.AssertExpectations(t)
 }
 
+func TestFetchSecurityGroupsByPods_PartialENIError(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	// 201 pods are looked up in two batches, and only the second one fails
+	var pods []corev1.Pod
+	for i := range 201 {
+		pods = append(pods, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod%d", i), Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: fmt.Sprintf("10.0.%d.%d", i/256, i%256)},
+		})
+	}
+	isLastBatch := func(input *ec2.DescribeNetworkInterfacesInput) bool {
+		return len(input.Filters[0].Values) == 1
+	}
+
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, mock.MatchedBy(func(input *ec2.DescribeNetworkInterfacesInput) bool {
+		return !isLastBatch(input)
+	})).Return(
+		&ec2.DescribeNetworkInterfacesOutput{
+			NetworkInterfaces: []types.NetworkInterface{
+				{
+					NetworkInterfaceId: aws.String("eni-1"),
+					PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{
+						{PrivateIpAddress: aws.String("10.0.0.0")},
+					},
+				},
+			},
+		}, nil,
+	)
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, mock.MatchedBy(isLastBatch)).Return(
+		nil, fmt.Errorf("aws error"),
+	)
+	mockClient.On("DescribeSecurityGroups", mock.Anything, mock.Anything).Return(
+		&ec2.DescribeSecurityGroupsOutput{}, nil,
+	).Maybe()
+
+	result, err := client.FetchSecurityGroupsByPods(context.Background(), pods)
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 201)
+	assert.Equal(t, StatusMapped, result[0].Status)
+	assert.Equal(t, StatusENINotFound, result[1].Status)
+	assert.Equal(t, StatusLookupFailed, result[200].Status)
+	mockClient.AssertExpectations(t)
+}
+
+func TestFetchSecurityGroupsByPods_Canceled(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, mock.Anything).Return(
+		nil, context.Canceled,
+	).Maybe()
+
+	_, err := client.FetchSecurityGroupsByPods(ctx, pods)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
 func TestGetENIsByPrivateIPs_EmptyIPs(t *testing.T) {
 	mockClient := new(MockEC2Client)
 	client := &Client{ec2Client: mockClient}
@@ -0,0 +1,94 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// DefaultRoleSessionName is the session name used when assuming a role without an explicit session name
+const DefaultRoleSessionName = "kubectl-sgmap"
+
+// ClientOptions selects the account and region the AWS clients talk to.
+// Empty fields fall back to the default AWS configuration (environment variables and shared config files).
+type ClientOptions struct {
+	Region          string
+	Profile         string
+	RoleARN         string
+	ExternalID      string
+	RoleSessionName string
+	// EndpointURL sends the EC2 and ELBv2 requests to another endpoint, such as LocalStack or
+	// ec2test.Server. STS requests, to assume the role or find the account of the cache, are not sent there.
+	EndpointURL string
+	// Cache keeps ENI and security group lookups on disk between runs
+	Cache CacheOptions
+	// Requests controls the concurrency, rate limit and retries of EC2 lookups
+	Requests RequestOptions
+}
+
+// LoadConfig loads the AWS configuration for the region and profile of the options, assuming the
+// role when a role ARN is set
+func LoadConfig(ctx context.Context, opts ClientOptions) (aws.Config, error) {
+	if opts.RoleARN == "" && (opts.ExternalID != "" || opts.RoleSessionName != "") {
+		return aws.Config{}, fmt.Errorf("an external ID or role session name requires a role ARN")
+	}
+
+	var loadOpts []func(*config.LoadOptions) error
+	if opts.Region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(opts.Region))
+	}
+	if opts.Profile != "" {
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(opts.Profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load aws config: %w", err)
+	}
+
+	if opts.RoleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), opts.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = DefaultRoleSessionName
+			if opts.RoleSessionName != "" {
+				o.RoleSessionName = opts.RoleSessionName
+			}
+			if opts.ExternalID != "" {
+				o.ExternalID = aws.String(opts.ExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return cfg, nil
+}
+
+// withEndpoint sets the endpoint URL of the options on the options of an EC2 or ELBv2 client,
+// keeping the endpoint of the AWS configuration when none is set
+func withEndpoint(opts ClientOptions, baseEndpoint **string) {
+	if opts.EndpointURL != "" {
+		*baseEndpoint = aws.String(opts.EndpointURL)
+	}
+}
+
+// RegionFromEKSEndpoint returns the region of an EKS cluster API server endpoint such as
+// https://0123456789ABCDEF.gr7.us-west-2.eks.amazonaws.com, or an empty string for other servers
+func RegionFromEKSEndpoint(server string) string {
+	host := server
+	if u, err := url.Parse(server); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+
+	labels := strings.Split(strings.ToLower(host), ".")
+	for i := 1; i+1 < len(labels); i++ {
+		if labels[i] == "eks" && labels[i+1] == "amazonaws" {
+			return labels[i-1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegionFromEKSEndpoint(t *testing.T) {
+	tests := []struct {
+		server string
+		want   string
+	}{
+		{"https://0123456789ABCDEF0123456789ABCDEF.gr7.us-west-2.eks.amazonaws.com", "us-west-2"},
+		{"https://0123456789ABCDEF.yl4.eu-central-1.eks.amazonaws.com:443", "eu-central-1"},
+		{"https://0123456789ABCDEF.sk1.cn-north-1.eks.amazonaws.com.cn", "cn-north-1"},
+		{"https://127.0.0.1:6443", ""},
+		{"https://kubernetes.example.com", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			assert.Equal(t, tt.want, RegionFromEKSEndpoint(tt.server))
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_PROFILE", "")
+
+	t.Run("region overrides the environment", func(t *testing.T) {
+		cfg, err := LoadConfig(context.Background(), ClientOptions{Region: "ap-northeast-1"})
+		require.NoError(t, err)
+		assert.Equal(t, "ap-northeast-1", cfg.Region)
+	})
+
+	t.Run("role ARN replaces the credentials provider", func(t *testing.T) {
+		cfg, err := LoadConfig(context.Background(), ClientOptions{RoleARN: "arn:aws:iam::123456789012:role/sgmap", ExternalID: "ext"})
+		require.NoError(t, err)
+		assert.Equal(t, "us-east-1", cfg.Region)
+		cache, ok := cfg.Credentials.(*aws.CredentialsCache)
+		require.True(t, ok)
+		assert.True(t, cache.IsCredentialsProvider(&stscreds.AssumeRoleProvider{}))
+	})
+
+	t.Run("endpoint URL is left to the EC2 and ELBv2 clients", func(t *testing.T) {
+		cfg, err := LoadConfig(context.Background(), ClientOptions{EndpointURL: "http://localhost:4566"})
+		require.NoError(t, err)
+		assert.Nil(t, cfg.BaseEndpoint, "STS keeps the AWS endpoint")
+
+		var baseEndpoint *string
+		withEndpoint(ClientOptions{EndpointURL: "http://localhost:4566"}, &baseEndpoint)
+		assert.Equal(t, "http://localhost:4566", aws.ToString(baseEndpoint))
+		withEndpoint(ClientOptions{}, &baseEndpoint)
+		assert.Equal(t, "http://localhost:4566", aws.ToString(baseEndpoint), "an empty endpoint URL keeps the configured endpoint")
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		_, err := LoadConfig(context.Background(), ClientOptions{Profile: "missing"})
+		assert.Error(t, err)
+	})
+
+	t.Run("external ID without role ARN", func(t *testing.T) {
+		_, err := LoadConfig(context.Background(), ClientOptions{ExternalID: "ext"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,359 @@
+// Package ec2test provides an in-process fake of the EC2 and ELBv2 Query APIs for end-to-end tests.
+// It serves the Describe calls used by sgmap over HTTP, so that the real SDK clients can be pointed
+// at it with aws.ClientOptions.EndpointURL.
+package ec2test
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"sigs.k8s.io/yaml"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+// Fixture is the EC2 and ELBv2 state served by a Server. It has the same shape as the output of
+// `aws ec2 describe-network-interfaces`, `aws ec2 describe-security-groups`,
+// `aws ec2 describe-managed-prefix-lists`, `aws ec2 describe-security-group-rules` and
+// `aws elbv2 describe-load-balancers`, in YAML or JSON. Prefix lists carry their Entries as well.
+type Fixture struct {
+	NetworkInterfaces  []types.NetworkInterface
+	SecurityGroups     []types.SecurityGroup
+	PrefixLists        []aws.SnapshotPrefixList
+	SecurityGroupRules []types.SecurityGroupRule
+	LoadBalancers      []elbv2types.LoadBalancer
+}
+
+// LoadFixture reads a YAML or JSON fixture file
+func LoadFixture(path string) (Fixture, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("failed to read fixture %s: %w", path, err)
+	}
+	var f Fixture
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return Fixture{}, fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Request is a request received by a Server
+type Request struct {
+	Action string
+	Params url.Values
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithPageSize splits the results of requests without MaxResults into pages of the given size,
+// to exercise pagination. Results are returned in a single page by default.
+func WithPageSize(size int) Option {
+	return func(s *Server) {
+		s.pageSize = size
+	}
+}
+
+// Server is a running fake EC2 endpoint. Filters, IDs and names are matched the way snapshots
+// match them, and NextToken is the offset of the next page.
+type Server struct {
+	// URL is the endpoint of the server, to use as aws.ClientOptions.EndpointURL
+	URL string
+
+	api           *aws.SnapshotEC2API
+	loadBalancers []elbv2types.LoadBalancer
+	pageSize      int
+	server        *httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// NewServer starts a server serving the fixture. The caller must call Close when done.
+func NewServer(fixture Fixture, opts ...Option) *Server {
+	s := &Server{
+		api: aws.NewStaticEC2API(fixture.NetworkInterfaces, fixture.SecurityGroups).
+			WithPrefixLists(fixture.PrefixLists).
+			WithSecurityGroupRules(fixture.SecurityGroupRules),
+		loadBalancers: fixture.LoadBalancers,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Requests returns the requests received so far, in order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// RequestCount returns the number of requests received for the action
+func (s *Server) RequestCount(action string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, r := range s.requests {
+		if r.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedQueryString", err.Error())
+		return
+	}
+	action := r.Form.Get("Action")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Action: action, Params: r.Form})
+	s.mu.Unlock()
+
+	var resp any
+	var err error
+	switch action {
+	case "DescribeNetworkInterfaces":
+		resp, err = s.describeNetworkInterfaces(r.Context(), r.Form)
+	case "DescribeSecurityGroups":
+		resp, err = s.describeSecurityGroups(r.Context(), r.Form)
+	case "DescribeManagedPrefixLists":
+		resp, err = s.describeManagedPrefixLists(r.Context(), r.Form)
+	case "GetManagedPrefixListEntries":
+		resp, err = s.getManagedPrefixListEntries(r.Context(), r.Form)
+	case "DescribeSecurityGroupRules":
+		resp, err = s.describeSecurityGroupRules(r.Context(), r.Form)
+	case "DescribeLoadBalancers":
+		resp, err = s.describeLoadBalancers(r.Form)
+	default:
+		writeError(w, http.StatusBadRequest, "InvalidAction", fmt.Sprintf("The action %s is not valid for this web service.", action))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidParameterValue", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) describeNetworkInterfaces(ctx context.Context, form url.Values) (any, error) {
+	out, err := s.api.DescribeNetworkInterfaces(ctx, &ec2.DescribeNetworkInterfacesInput{
+		NetworkInterfaceIds: listParam(form, "NetworkInterfaceId"),
+		Filters:             filterParams(form),
+	})
+	if err != nil {
+		return nil, err
+	}
+	page, next, err := s.page(form.Get("NextToken"), form.Get("MaxResults"), len(out.NetworkInterfaces))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &describeNetworkInterfacesResponse{Namespace: xmlNamespace, RequestID: requestID, NextToken: next}
+	for _, eni := range out.NetworkInterfaces[page[0]:page[1]] {
+		resp.NetworkInterfaces = append(resp.NetworkInterfaces, toXMLNetworkInterface(eni))
+	}
+	return resp, nil
+}
+
+func (s *Server) describeSecurityGroups(ctx context.Context, form url.Values) (any, error) {
+	out, err := s.api.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		GroupIds:   listParam(form, "GroupId"),
+		GroupNames: listParam(form, "GroupName"),
+		Filters:    filterParams(form),
+	})
+	if err != nil {
+		return nil, err
+	}
+	page, next, err := s.page(form.Get("NextToken"), form.Get("MaxResults"), len(out.SecurityGroups))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &describeSecurityGroupsResponse{Namespace: xmlNamespace, RequestID: requestID, NextToken: next}
+	for _, sg := range out.SecurityGroups[page[0]:page[1]] {
+		resp.SecurityGroups = append(resp.SecurityGroups, toXMLSecurityGroup(sg))
+	}
+	return resp, nil
+}
+
+func (s *Server) describeManagedPrefixLists(ctx context.Context, form url.Values) (any, error) {
+	out, err := s.api.DescribeManagedPrefixLists(ctx, &ec2.DescribeManagedPrefixListsInput{
+		PrefixListIds: listParam(form, "PrefixListId"),
+		Filters:       filterParams(form),
+	})
+	if err != nil {
+		return nil, err
+	}
+	page, next, err := s.page(form.Get("NextToken"), form.Get("MaxResults"), len(out.PrefixLists))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &describeManagedPrefixListsResponse{Namespace: xmlNamespace, RequestID: requestID, NextToken: next}
+	for _, pl := range out.PrefixLists[page[0]:page[1]] {
+		resp.PrefixLists = append(resp.PrefixLists, toXMLPrefixList(pl))
+	}
+	return resp, nil
+}
+
+func (s *Server) getManagedPrefixListEntries(ctx context.Context, form url.Values) (any, error) {
+	out, err := s.api.GetManagedPrefixListEntries(ctx, &ec2.GetManagedPrefixListEntriesInput{
+		PrefixListId: awsSDK.String(form.Get("PrefixListId")),
+	})
+	if err != nil {
+		return nil, err
+	}
+	page, next, err := s.page(form.Get("NextToken"), form.Get("MaxResults"), len(out.Entries))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &getManagedPrefixListEntriesResponse{Namespace: xmlNamespace, RequestID: requestID, NextToken: next}
+	for _, entry := range out.Entries[page[0]:page[1]] {
+		resp.Entries = append(resp.Entries, xmlPrefixListEntry{Cidr: awsSDK.ToString(entry.Cidr), Description: awsSDK.ToString(entry.Description)})
+	}
+	return resp, nil
+}
+
+func (s *Server) describeSecurityGroupRules(ctx context.Context, form url.Values) (any, error) {
+	out, err := s.api.DescribeSecurityGroupRules(ctx, &ec2.DescribeSecurityGroupRulesInput{
+		SecurityGroupRuleIds: listParam(form, "SecurityGroupRuleId"),
+		Filters:              filterParams(form),
+	})
+	if err != nil {
+		return nil, err
+	}
+	page, next, err := s.page(form.Get("NextToken"), form.Get("MaxResults"), len(out.SecurityGroupRules))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &describeSecurityGroupRulesResponse{Namespace: xmlNamespace, RequestID: requestID, NextToken: next}
+	for _, rule := range out.SecurityGroupRules[page[0]:page[1]] {
+		resp.SecurityGroupRules = append(resp.SecurityGroupRules, toXMLSecurityGroupRule(rule))
+	}
+	return resp, nil
+}
+
+func (s *Server) describeLoadBalancers(form url.Values) (any, error) {
+	arns := listParam(form, "LoadBalancerArns.member")
+	names := listParam(form, "Names.member")
+
+	var lbs []elbv2types.LoadBalancer
+	for _, lb := range s.loadBalancers {
+		if len(arns) > 0 && !slices.Contains(arns, awsSDK.ToString(lb.LoadBalancerArn)) {
+			continue
+		}
+		if len(names) > 0 && !slices.Contains(names, awsSDK.ToString(lb.LoadBalancerName)) {
+			continue
+		}
+		lbs = append(lbs, lb)
+	}
+	page, next, err := s.page(form.Get("Marker"), form.Get("PageSize"), len(lbs))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &describeLoadBalancersResponse{Namespace: elbv2XMLNamespace, NextMarker: next, RequestID: requestID}
+	for _, lb := range lbs[page[0]:page[1]] {
+		resp.LoadBalancers = append(resp.LoadBalancers, toXMLLoadBalancer(lb))
+	}
+	return resp, nil
+}
+
+// page returns the bounds of the requested page among total results, and the token of the next page.
+// token is the offset of the page and maxResults its size.
+func (s *Server) page(token, maxResults string, total int) ([2]int, string, error) {
+	start := 0
+	if token != "" {
+		n, err := strconv.Atoi(token)
+		if err != nil || n < 0 || n > total {
+			return [2]int{}, "", fmt.Errorf("invalid page token %q", token)
+		}
+		start = n
+	}
+
+	size := s.pageSize
+	if maxResults != "" {
+		n, err := strconv.Atoi(maxResults)
+		if err != nil || n <= 0 {
+			return [2]int{}, "", fmt.Errorf("invalid page size %q", maxResults)
+		}
+		size = n
+	}
+
+	end := total
+	if size > 0 && start+size < total {
+		end = start + size
+	}
+	next := ""
+	if end < total {
+		next = strconv.Itoa(end)
+	}
+	return [2]int{start, end}, next, nil
+}
+
+// listParam returns the values of a flattened list parameter such as GroupId.1, GroupId.2, ...
+func listParam(form url.Values, name string) []string {
+	var values []string
+	for i := 1; ; i++ {
+		v, ok := form[fmt.Sprintf("%s.%d", name, i)]
+		if !ok {
+			return values
+		}
+		values = append(values, v[0])
+	}
+}
+
+// filterParams returns the filters of the request, sent as Filter.N.Name and Filter.N.Value.M
+func filterParams(form url.Values) []types.Filter {
+	var filters []types.Filter
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("Filter.%d.", i)
+		name := form.Get(prefix + "Name")
+		if name == "" {
+			return filters
+		}
+		filters = append(filters, types.Filter{
+			Name:   awsSDK.String(name),
+			Values: listParam(form, prefix+"Value"),
+		})
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(&errorResponse{
+		Errors:    []xmlError{{Code: code, Message: message}},
+		RequestID: requestID,
+	})
+}
@@ -0,0 +1,216 @@
+package ec2test
+
+import (
+	"context"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, opts ...Option) (*Server, *ec2.Client) {
+	t.Helper()
+	fixture, err := LoadFixture("testdata/fixture.yaml")
+	require.NoError(t, err)
+
+	server := NewServer(fixture, opts...)
+	t.Cleanup(server.Close)
+
+	client := ec2.New(ec2.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: awsSDK.String(server.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	})
+	return server, client
+}
+
+func TestLoadFixture(t *testing.T) {
+	fixture, err := LoadFixture("testdata/fixture.yaml")
+	require.NoError(t, err)
+	assert.Len(t, fixture.NetworkInterfaces, 3)
+	assert.Len(t, fixture.SecurityGroups, 2)
+	assert.Len(t, fixture.PrefixLists, 1)
+	assert.Len(t, fixture.SecurityGroupRules, 3)
+	assert.Len(t, fixture.LoadBalancers, 2)
+	assert.Equal(t, types.NetworkInterfaceTypeBranch, fixture.NetworkInterfaces[0].InterfaceType)
+
+	_, err = LoadFixture("testdata/missing.yaml")
+	assert.Error(t, err)
+}
+
+func TestServer_DescribeNetworkInterfaces(t *testing.T) {
+	server, client := newTestServer(t)
+
+	out, err := client.DescribeNetworkInterfaces(context.Background(), &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{{Name: awsSDK.String("addresses.private-ip-address"), Values: []string{"10.0.1.10", "10.0.2.11"}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, out.NetworkInterfaces, 2)
+
+	branch := out.NetworkInterfaces[0]
+	assert.Equal(t, "eni-0aaaaaaaaaaaaaaa1", awsSDK.ToString(branch.NetworkInterfaceId))
+	assert.Equal(t, types.NetworkInterfaceTypeBranch, branch.InterfaceType)
+	assert.Equal(t, "web", awsSDK.ToString(branch.Groups[0].GroupName))
+	assert.Equal(t, "eks:eni:owner", awsSDK.ToString(branch.TagSet[0].Key))
+
+	node := out.NetworkInterfaces[1]
+	assert.Len(t, node.PrivateIpAddresses, 2)
+	assert.Equal(t, "i-0123456789abcdef0", awsSDK.ToString(node.Attachment.InstanceId))
+	assert.Equal(t, int32(0), awsSDK.ToInt32(node.Attachment.DeviceIndex))
+
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "DescribeNetworkInterfaces", requests[0].Action)
+}
+
+func TestServer_DescribeSecurityGroups(t *testing.T) {
+	_, client := newTestServer(t)
+
+	out, err := client.DescribeSecurityGroups(context.Background(), &ec2.DescribeSecurityGroupsInput{
+		GroupIds: []string{"sg-0aaaaaaaaaaaaaaa1"},
+	})
+	require.NoError(t, err)
+	require.Len(t, out.SecurityGroups, 1)
+
+	sg := out.SecurityGroups[0]
+	assert.Equal(t, "web", awsSDK.ToString(sg.GroupName))
+	assert.Equal(t, "web pods", awsSDK.ToString(sg.Description))
+	require.Len(t, sg.IpPermissions, 2)
+	assert.Equal(t, int32(443), awsSDK.ToInt32(sg.IpPermissions[0].FromPort))
+	assert.Equal(t, "from vpc", awsSDK.ToString(sg.IpPermissions[0].IpRanges[0].Description))
+	assert.Equal(t, "sg-0aaaaaaaaaaaaaaa2", awsSDK.ToString(sg.IpPermissions[1].UserIdGroupPairs[0].GroupId))
+	require.Len(t, sg.IpPermissionsEgress, 1)
+	assert.Equal(t, "::/0", awsSDK.ToString(sg.IpPermissionsEgress[0].Ipv6Ranges[0].CidrIpv6))
+	assert.Equal(t, "pl-12345678", awsSDK.ToString(sg.IpPermissionsEgress[0].PrefixListIds[0].PrefixListId))
+
+	out, err = client.DescribeSecurityGroups(context.Background(), &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{{Name: awsSDK.String("group-name"), Values: []string{"node"}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, out.SecurityGroups, 1)
+	assert.Equal(t, "sg-0aaaaaaaaaaaaaaa2", awsSDK.ToString(out.SecurityGroups[0].GroupId))
+}
+
+func TestServer_ManagedPrefixLists(t *testing.T) {
+	server, client := newTestServer(t, WithPageSize(1))
+
+	out, err := client.DescribeManagedPrefixLists(context.Background(), &ec2.DescribeManagedPrefixListsInput{
+		Filters: []types.Filter{{Name: awsSDK.String("prefix-list-id"), Values: []string{"pl-12345678", "pl-deleted"}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, out.PrefixLists, 1)
+	assert.Equal(t, "office", awsSDK.ToString(out.PrefixLists[0].PrefixListName))
+	assert.Equal(t, types.PrefixListStateCreateComplete, out.PrefixLists[0].State)
+
+	var cidrs []string
+	paginator := ec2.NewGetManagedPrefixListEntriesPaginator(client, &ec2.GetManagedPrefixListEntriesInput{PrefixListId: awsSDK.String("pl-12345678")})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		require.NoError(t, err)
+		for _, entry := range page.Entries {
+			cidrs = append(cidrs, awsSDK.ToString(entry.Cidr))
+		}
+	}
+	assert.Equal(t, []string{"192.0.2.0/24", "198.51.100.0/24"}, cidrs)
+	assert.Equal(t, 2, server.RequestCount("GetManagedPrefixListEntries"))
+
+	_, err = client.GetManagedPrefixListEntries(context.Background(), &ec2.GetManagedPrefixListEntriesInput{PrefixListId: awsSDK.String("pl-deleted")})
+	assert.Error(t, err)
+}
+
+func TestServer_DescribeSecurityGroupRules(t *testing.T) {
+	server, client := newTestServer(t, WithPageSize(2))
+
+	var rules []types.SecurityGroupRule
+	paginator := ec2.NewDescribeSecurityGroupRulesPaginator(client, &ec2.DescribeSecurityGroupRulesInput{
+		Filters: []types.Filter{{Name: awsSDK.String("group-id"), Values: []string{"sg-0aaaaaaaaaaaaaaa1"}}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		require.NoError(t, err)
+		rules = append(rules, page.SecurityGroupRules...)
+	}
+	require.Len(t, rules, 3)
+	assert.Equal(t, 2, server.RequestCount("DescribeSecurityGroupRules"))
+
+	assert.Equal(t, "sgr-0aaaaaaaaaaaaaaa1", awsSDK.ToString(rules[0].SecurityGroupRuleId))
+	assert.Equal(t, "from vpc", awsSDK.ToString(rules[0].Description))
+	assert.False(t, awsSDK.ToBool(rules[0].IsEgress))
+	assert.Equal(t, "sg-0aaaaaaaaaaaaaaa2", awsSDK.ToString(rules[1].ReferencedGroupInfo.GroupId))
+	assert.True(t, awsSDK.ToBool(rules[2].IsEgress))
+	assert.Equal(t, "pl-12345678", awsSDK.ToString(rules[2].PrefixListId))
+	assert.Equal(t, int32(-1), awsSDK.ToInt32(rules[2].FromPort))
+}
+
+func TestServer_DescribeLoadBalancers(t *testing.T) {
+	server, _ := newTestServer(t, WithPageSize(1))
+	client := elasticloadbalancingv2.New(elasticloadbalancingv2.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: awsSDK.String(server.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	})
+
+	var lbs []elbv2types.LoadBalancer
+	paginator := elasticloadbalancingv2.NewDescribeLoadBalancersPaginator(client, &elasticloadbalancingv2.DescribeLoadBalancersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		require.NoError(t, err)
+		lbs = append(lbs, page.LoadBalancers...)
+	}
+	require.Len(t, lbs, 2)
+	assert.Equal(t, 2, server.RequestCount("DescribeLoadBalancers"))
+
+	web := lbs[0]
+	assert.Equal(t, "web", awsSDK.ToString(web.LoadBalancerName))
+	assert.Equal(t, "web-123456789.us-east-1.elb.amazonaws.com", awsSDK.ToString(web.DNSName))
+	assert.Equal(t, elbv2types.LoadBalancerTypeEnumApplication, web.Type)
+	assert.Equal(t, []string{"sg-0aaaaaaaaaaaaaaa2"}, web.SecurityGroups)
+	assert.Equal(t, "subnet-1", awsSDK.ToString(web.AvailabilityZones[0].SubnetId))
+	assert.Empty(t, lbs[1].SecurityGroups)
+
+	out, err := client.DescribeLoadBalancers(context.Background(), &elasticloadbalancingv2.DescribeLoadBalancersInput{Names: []string{"api"}})
+	require.NoError(t, err)
+	require.Len(t, out.LoadBalancers, 1)
+	assert.Equal(t, elbv2types.LoadBalancerTypeEnumNetwork, out.LoadBalancers[0].Type)
+}
+
+func TestServer_Pagination(t *testing.T) {
+	server, client := newTestServer(t, WithPageSize(1))
+
+	var ids []string
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(client, &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{{Name: awsSDK.String("vpc-id"), Values: []string{"vpc-1", "vpc-2"}}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		require.NoError(t, err)
+		for _, eni := range page.NetworkInterfaces {
+			ids = append(ids, awsSDK.ToString(eni.NetworkInterfaceId))
+		}
+	}
+
+	assert.Equal(t, []string{"eni-0aaaaaaaaaaaaaaa1", "eni-0aaaaaaaaaaaaaaa2", "eni-0aaaaaaaaaaaaaaa3"}, ids)
+	assert.Equal(t, 3, server.RequestCount("DescribeNetworkInterfaces"))
+}
+
+func TestServer_Errors(t *testing.T) {
+	_, client := newTestServer(t)
+
+	_, err := client.DescribeNetworkInterfaces(context.Background(), &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{{Name: awsSDK.String("unsupported"), Values: []string{"x"}}},
+	})
+	var apiErr smithy.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "InvalidParameterValue", apiErr.ErrorCode())
+
+	_, err = client.DescribeVpcs(context.Background(), &ec2.DescribeVpcsInput{})
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "InvalidAction", apiErr.ErrorCode())
+}
@@ -0,0 +1,372 @@
+package ec2test
+
+import (
+	"encoding/xml"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+// The XML documents of the EC2 Query API, limited to the fields sgmap reads
+
+const (
+	xmlNamespace = "http://ec2.amazonaws.com/doc/2016-11-15/"
+	requestID    = "00000000-0000-0000-0000-000000000000"
+)
+
+type describeNetworkInterfacesResponse struct {
+	XMLName           xml.Name              `xml:"DescribeNetworkInterfacesResponse"`
+	Namespace         string                `xml:"xmlns,attr"`
+	RequestID         string                `xml:"requestId"`
+	NetworkInterfaces []xmlNetworkInterface `xml:"networkInterfaceSet>item"`
+	NextToken         string                `xml:"nextToken,omitempty"`
+}
+
+type describeSecurityGroupsResponse struct {
+	XMLName        xml.Name           `xml:"DescribeSecurityGroupsResponse"`
+	Namespace      string             `xml:"xmlns,attr"`
+	RequestID      string             `xml:"requestId"`
+	SecurityGroups []xmlSecurityGroup `xml:"securityGroupInfo>item"`
+	NextToken      string             `xml:"nextToken,omitempty"`
+}
+
+type describeManagedPrefixListsResponse struct {
+	XMLName     xml.Name        `xml:"DescribeManagedPrefixListsResponse"`
+	Namespace   string          `xml:"xmlns,attr"`
+	RequestID   string          `xml:"requestId"`
+	PrefixLists []xmlPrefixList `xml:"prefixListSet>item"`
+	NextToken   string          `xml:"nextToken,omitempty"`
+}
+
+type getManagedPrefixListEntriesResponse struct {
+	XMLName   xml.Name             `xml:"GetManagedPrefixListEntriesResponse"`
+	Namespace string               `xml:"xmlns,attr"`
+	RequestID string               `xml:"requestId"`
+	Entries   []xmlPrefixListEntry `xml:"entrySet>item"`
+	NextToken string               `xml:"nextToken,omitempty"`
+}
+
+type describeSecurityGroupRulesResponse struct {
+	XMLName            xml.Name               `xml:"DescribeSecurityGroupRulesResponse"`
+	Namespace          string                 `xml:"xmlns,attr"`
+	RequestID          string                 `xml:"requestId"`
+	SecurityGroupRules []xmlSecurityGroupRule `xml:"securityGroupRuleSet>item"`
+	NextToken          string                 `xml:"nextToken,omitempty"`
+}
+
+type errorResponse struct {
+	XMLName   xml.Name   `xml:"Response"`
+	Errors    []xmlError `xml:"Errors>Error"`
+	RequestID string     `xml:"RequestID"`
+}
+
+type xmlError struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+type xmlNetworkInterface struct {
+	NetworkInterfaceID string                `xml:"networkInterfaceId,omitempty"`
+	InterfaceType      string                `xml:"interfaceType,omitempty"`
+	Description        string                `xml:"description,omitempty"`
+	Status             string                `xml:"status,omitempty"`
+	VpcID              string                `xml:"vpcId,omitempty"`
+	SubnetID           string                `xml:"subnetId,omitempty"`
+	AvailabilityZone   string                `xml:"availabilityZone,omitempty"`
+	OwnerID            string                `xml:"ownerId,omitempty"`
+	RequesterID        string                `xml:"requesterId,omitempty"`
+	RequesterManaged   *bool                 `xml:"requesterManaged,omitempty"`
+	MacAddress         string                `xml:"macAddress,omitempty"`
+	PrivateIPAddress   string                `xml:"privateIpAddress,omitempty"`
+	PrivateIPAddresses []xmlPrivateIPAddress `xml:"privateIpAddressesSet>item"`
+	Groups             []xmlGroupIdentifier  `xml:"groupSet>item"`
+	Attachment         *xmlAttachment        `xml:"attachment,omitempty"`
+	Tags               []xmlTag              `xml:"tagSet>item"`
+}
+
+type xmlPrivateIPAddress struct {
+	PrivateIPAddress string `xml:"privateIpAddress,omitempty"`
+	Primary          *bool  `xml:"primary,omitempty"`
+}
+
+type xmlGroupIdentifier struct {
+	GroupID   string `xml:"groupId,omitempty"`
+	GroupName string `xml:"groupName,omitempty"`
+}
+
+type xmlAttachment struct {
+	AttachmentID    string `xml:"attachmentId,omitempty"`
+	AttachTime      string `xml:"attachTime,omitempty"`
+	DeviceIndex     *int32 `xml:"deviceIndex,omitempty"`
+	InstanceID      string `xml:"instanceId,omitempty"`
+	InstanceOwnerID string `xml:"instanceOwnerId,omitempty"`
+	Status          string `xml:"status,omitempty"`
+}
+
+type xmlTag struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
+type xmlSecurityGroup struct {
+	GroupID             string            `xml:"groupId,omitempty"`
+	GroupName           string            `xml:"groupName,omitempty"`
+	Description         string            `xml:"groupDescription,omitempty"`
+	OwnerID             string            `xml:"ownerId,omitempty"`
+	VpcID               string            `xml:"vpcId,omitempty"`
+	IPPermissions       []xmlIPPermission `xml:"ipPermissions>item"`
+	IPPermissionsEgress []xmlIPPermission `xml:"ipPermissionsEgress>item"`
+	Tags                []xmlTag          `xml:"tagSet>item"`
+}
+
+type xmlIPPermission struct {
+	IPProtocol    string               `xml:"ipProtocol,omitempty"`
+	FromPort      *int32               `xml:"fromPort,omitempty"`
+	ToPort        *int32               `xml:"toPort,omitempty"`
+	Groups        []xmlUserIDGroupPair `xml:"groups>item"`
+	IPRanges      []xmlIPRange         `xml:"ipRanges>item"`
+	IPv6Ranges    []xmlIPv6Range       `xml:"ipv6Ranges>item"`
+	PrefixListIDs []xmlPrefixListID    `xml:"prefixListIds>item"`
+}
+
+type xmlUserIDGroupPair struct {
+	UserID                 string `xml:"userId,omitempty"`
+	GroupID                string `xml:"groupId,omitempty"`
+	GroupName              string `xml:"groupName,omitempty"`
+	VpcID                  string `xml:"vpcId,omitempty"`
+	VpcPeeringConnectionID string `xml:"vpcPeeringConnectionId,omitempty"`
+	Description            string `xml:"description,omitempty"`
+}
+
+type xmlIPRange struct {
+	CidrIP      string `xml:"cidrIp,omitempty"`
+	Description string `xml:"description,omitempty"`
+}
+
+type xmlIPv6Range struct {
+	CidrIPv6    string `xml:"cidrIpv6,omitempty"`
+	Description string `xml:"description,omitempty"`
+}
+
+type xmlPrefixListID struct {
+	PrefixListID string `xml:"prefixListId,omitempty"`
+	Description  string `xml:"description,omitempty"`
+}
+
+type xmlPrefixList struct {
+	PrefixListID   string `xml:"prefixListId,omitempty"`
+	PrefixListName string `xml:"prefixListName,omitempty"`
+	PrefixListArn  string `xml:"prefixListArn,omitempty"`
+	AddressFamily  string `xml:"addressFamily,omitempty"`
+	State          string `xml:"state,omitempty"`
+	MaxEntries     *int32 `xml:"maxEntries,omitempty"`
+	OwnerID        string `xml:"ownerId,omitempty"`
+}
+
+type xmlPrefixListEntry struct {
+	Cidr        string `xml:"cidr,omitempty"`
+	Description string `xml:"description,omitempty"`
+}
+
+type xmlSecurityGroupRule struct {
+	SecurityGroupRuleID string                  `xml:"securityGroupRuleId,omitempty"`
+	GroupID             string                  `xml:"groupId,omitempty"`
+	GroupOwnerID        string                  `xml:"groupOwnerId,omitempty"`
+	IsEgress            *bool                   `xml:"isEgress,omitempty"`
+	IPProtocol          string                  `xml:"ipProtocol,omitempty"`
+	FromPort            *int32                  `xml:"fromPort,omitempty"`
+	ToPort              *int32                  `xml:"toPort,omitempty"`
+	CidrIPv4            string                  `xml:"cidrIpv4,omitempty"`
+	CidrIPv6            string                  `xml:"cidrIpv6,omitempty"`
+	PrefixListID        string                  `xml:"prefixListId,omitempty"`
+	ReferencedGroupInfo *xmlReferencedGroupInfo `xml:"referencedGroupInfo,omitempty"`
+	Description         string                  `xml:"description,omitempty"`
+	Tags                []xmlTag                `xml:"tagSet>item"`
+}
+
+type xmlReferencedGroupInfo struct {
+	GroupID                string `xml:"groupId,omitempty"`
+	UserID                 string `xml:"userId,omitempty"`
+	VpcID                  string `xml:"vpcId,omitempty"`
+	VpcPeeringConnectionID string `xml:"vpcPeeringConnectionId,omitempty"`
+}
+
+func toXMLNetworkInterface(eni types.NetworkInterface) xmlNetworkInterface {
+	x := xmlNetworkInterface{
+		NetworkInterfaceID: awsSDK.ToString(eni.NetworkInterfaceId),
+		InterfaceType:      string(eni.InterfaceType),
+		Description:        awsSDK.ToString(eni.Description),
+		Status:             string(eni.Status),
+		VpcID:              awsSDK.ToString(eni.VpcId),
+		SubnetID:           awsSDK.ToString(eni.SubnetId),
+		AvailabilityZone:   awsSDK.ToString(eni.AvailabilityZone),
+		OwnerID:            awsSDK.ToString(eni.OwnerId),
+		RequesterID:        awsSDK.ToString(eni.RequesterId),
+		RequesterManaged:   eni.RequesterManaged,
+		MacAddress:         awsSDK.ToString(eni.MacAddress),
+		PrivateIPAddress:   awsSDK.ToString(eni.PrivateIpAddress),
+		Tags:               toXMLTags(eni.TagSet),
+	}
+	for _, ip := range eni.PrivateIpAddresses {
+		x.PrivateIPAddresses = append(x.PrivateIPAddresses, xmlPrivateIPAddress{
+			PrivateIPAddress: awsSDK.ToString(ip.PrivateIpAddress),
+			Primary:          ip.Primary,
+		})
+	}
+	for _, g := range eni.Groups {
+		x.Groups = append(x.Groups, xmlGroupIdentifier{GroupID: awsSDK.ToString(g.GroupId), GroupName: awsSDK.ToString(g.GroupName)})
+	}
+	if a := eni.Attachment; a != nil {
+		x.Attachment = &xmlAttachment{
+			AttachmentID:    awsSDK.ToString(a.AttachmentId),
+			DeviceIndex:     a.DeviceIndex,
+			InstanceID:      awsSDK.ToString(a.InstanceId),
+			InstanceOwnerID: awsSDK.ToString(a.InstanceOwnerId),
+			Status:          string(a.Status),
+		}
+		if a.AttachTime != nil {
+			x.Attachment.AttachTime = a.AttachTime.UTC().Format(time.RFC3339)
+		}
+	}
+	return x
+}
+
+func toXMLSecurityGroup(sg types.SecurityGroup) xmlSecurityGroup {
+	return xmlSecurityGroup{
+		GroupID:             awsSDK.ToString(sg.GroupId),
+		GroupName:           awsSDK.ToString(sg.GroupName),
+		Description:         awsSDK.ToString(sg.Description),
+		OwnerID:             awsSDK.ToString(sg.OwnerId),
+		VpcID:               awsSDK.ToString(sg.VpcId),
+		IPPermissions:       toXMLIPPermissions(sg.IpPermissions),
+		IPPermissionsEgress: toXMLIPPermissions(sg.IpPermissionsEgress),
+		Tags:                toXMLTags(sg.Tags),
+	}
+}
+
+func toXMLIPPermissions(perms []types.IpPermission) []xmlIPPermission {
+	var result []xmlIPPermission
+	for _, p := range perms {
+		x := xmlIPPermission{
+			IPProtocol: awsSDK.ToString(p.IpProtocol),
+			FromPort:   p.FromPort,
+			ToPort:     p.ToPort,
+		}
+		for _, pair := range p.UserIdGroupPairs {
+			x.Groups = append(x.Groups, xmlUserIDGroupPair{
+				UserID:                 awsSDK.ToString(pair.UserId),
+				GroupID:                awsSDK.ToString(pair.GroupId),
+				GroupName:              awsSDK.ToString(pair.GroupName),
+				VpcID:                  awsSDK.ToString(pair.VpcId),
+				VpcPeeringConnectionID: awsSDK.ToString(pair.VpcPeeringConnectionId),
+				Description:            awsSDK.ToString(pair.Description),
+			})
+		}
+		for _, r := range p.IpRanges {
+			x.IPRanges = append(x.IPRanges, xmlIPRange{CidrIP: awsSDK.ToString(r.CidrIp), Description: awsSDK.ToString(r.Description)})
+		}
+		for _, r := range p.Ipv6Ranges {
+			x.IPv6Ranges = append(x.IPv6Ranges, xmlIPv6Range{CidrIPv6: awsSDK.ToString(r.CidrIpv6), Description: awsSDK.ToString(r.Description)})
+		}
+		for _, pl := range p.PrefixListIds {
+			x.PrefixListIDs = append(x.PrefixListIDs, xmlPrefixListID{PrefixListID: awsSDK.ToString(pl.PrefixListId), Description: awsSDK.ToString(pl.Description)})
+		}
+		result = append(result, x)
+	}
+	return result
+}
+
+func toXMLPrefixList(pl types.ManagedPrefixList) xmlPrefixList {
+	return xmlPrefixList{
+		PrefixListID:   awsSDK.ToString(pl.PrefixListId),
+		PrefixListName: awsSDK.ToString(pl.PrefixListName),
+		PrefixListArn:  awsSDK.ToString(pl.PrefixListArn),
+		AddressFamily:  awsSDK.ToString(pl.AddressFamily),
+		State:          string(pl.State),
+		MaxEntries:     pl.MaxEntries,
+		OwnerID:        awsSDK.ToString(pl.OwnerId),
+	}
+}
+
+func toXMLSecurityGroupRule(rule types.SecurityGroupRule) xmlSecurityGroupRule {
+	x := xmlSecurityGroupRule{
+		SecurityGroupRuleID: awsSDK.ToString(rule.SecurityGroupRuleId),
+		GroupID:             awsSDK.ToString(rule.GroupId),
+		GroupOwnerID:        awsSDK.ToString(rule.GroupOwnerId),
+		IsEgress:            rule.IsEgress,
+		IPProtocol:          awsSDK.ToString(rule.IpProtocol),
+		FromPort:            rule.FromPort,
+		ToPort:              rule.ToPort,
+		CidrIPv4:            awsSDK.ToString(rule.CidrIpv4),
+		CidrIPv6:            awsSDK.ToString(rule.CidrIpv6),
+		PrefixListID:        awsSDK.ToString(rule.PrefixListId),
+		Description:         awsSDK.ToString(rule.Description),
+		Tags:                toXMLTags(rule.Tags),
+	}
+	if ref := rule.ReferencedGroupInfo; ref != nil {
+		x.ReferencedGroupInfo = &xmlReferencedGroupInfo{
+			GroupID:                awsSDK.ToString(ref.GroupId),
+			UserID:                 awsSDK.ToString(ref.UserId),
+			VpcID:                  awsSDK.ToString(ref.VpcId),
+			VpcPeeringConnectionID: awsSDK.ToString(ref.VpcPeeringConnectionId),
+		}
+	}
+	return x
+}
+
+func toXMLTags(tags []types.Tag) []xmlTag {
+	var result []xmlTag
+	for _, t := range tags {
+		result = append(result, xmlTag{Key: awsSDK.ToString(t.Key), Value: awsSDK.ToString(t.Value)})
+	}
+	return result
+}
+
+// The XML documents of the ELBv2 Query API, limited to the fields sgmap reads
+
+const elbv2XMLNamespace = "http://elasticloadbalancing.amazonaws.com/doc/2015-12-01/"
+
+type describeLoadBalancersResponse struct {
+	XMLName       xml.Name          `xml:"DescribeLoadBalancersResponse"`
+	Namespace     string            `xml:"xmlns,attr"`
+	LoadBalancers []xmlLoadBalancer `xml:"DescribeLoadBalancersResult>LoadBalancers>member"`
+	NextMarker    string            `xml:"DescribeLoadBalancersResult>NextMarker,omitempty"`
+	RequestID     string            `xml:"ResponseMetadata>RequestId"`
+}
+
+type xmlLoadBalancer struct {
+	LoadBalancerArn   string                `xml:"LoadBalancerArn,omitempty"`
+	LoadBalancerName  string                `xml:"LoadBalancerName,omitempty"`
+	DNSName           string                `xml:"DNSName,omitempty"`
+	Scheme            string                `xml:"Scheme,omitempty"`
+	Type              string                `xml:"Type,omitempty"`
+	VpcID             string                `xml:"VpcId,omitempty"`
+	SecurityGroups    []string              `xml:"SecurityGroups>member"`
+	AvailabilityZones []xmlAvailabilityZone `xml:"AvailabilityZones>member"`
+}
+
+type xmlAvailabilityZone struct {
+	ZoneName string `xml:"ZoneName,omitempty"`
+	SubnetID string `xml:"SubnetId,omitempty"`
+}
+
+func toXMLLoadBalancer(lb elbv2types.LoadBalancer) xmlLoadBalancer {
+	x := xmlLoadBalancer{
+		LoadBalancerArn:  awsSDK.ToString(lb.LoadBalancerArn),
+		LoadBalancerName: awsSDK.ToString(lb.LoadBalancerName),
+		DNSName:          awsSDK.ToString(lb.DNSName),
+		Scheme:           string(lb.Scheme),
+		Type:             string(lb.Type),
+		VpcID:            awsSDK.ToString(lb.VpcId),
+		SecurityGroups:   lb.SecurityGroups,
+	}
+	for _, az := range lb.AvailabilityZones {
+		x.AvailabilityZones = append(x.AvailabilityZones, xmlAvailabilityZone{
+			ZoneName: awsSDK.ToString(az.ZoneName),
+			SubnetID: awsSDK.ToString(az.SubnetId),
+		})
+	}
+	return x
+}
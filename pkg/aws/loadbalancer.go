@@ -0,0 +1,164 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/utils"
+)
+
+// ELBv2API defines the methods we use from the AWS Elastic Load Balancing v2 client.
+// This is used for dependency injection and testing.
+type ELBv2API interface {
+	DescribeLoadBalancers(ctx context.Context, params *elasticloadbalancingv2.DescribeLoadBalancersInput, optFns ...func(*elasticloadbalancingv2.Options)) (*elasticloadbalancingv2.DescribeLoadBalancersOutput, error)
+}
+
+// LoadBalancer is an ELBv2 load balancer with the security groups and private IPs its traffic to
+// the targets comes from
+type LoadBalancer struct {
+	LoadBalancer   elbv2types.LoadBalancer
+	SecurityGroups []types.SecurityGroup
+	// PrivateIPs are the addresses of the ENIs of the load balancer in its subnets
+	PrivateIPs []string
+}
+
+// Name returns the name of the load balancer
+func (lb LoadBalancer) Name() string {
+	return aws.ToString(lb.LoadBalancer.LoadBalancerName)
+}
+
+// DNSName returns the DNS name of the load balancer
+func (lb LoadBalancer) DNSName() string {
+	return aws.ToString(lb.LoadBalancer.DNSName)
+}
+
+// SecurityGroupIDs returns the IDs of the security groups attached to the load balancer, including
+// those that could not be described
+func (lb LoadBalancer) SecurityGroupIDs() []string {
+	return lb.LoadBalancer.SecurityGroups
+}
+
+// NewELBv2API creates an Elastic Load Balancing v2 API client from the AWS configuration selected by the options
+func NewELBv2API(ctx context.Context, opts ClientOptions) (ELBv2API, error) {
+	cfg, err := LoadConfig(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return elasticloadbalancingv2.NewFromConfig(cfg, func(o *elasticloadbalancingv2.Options) {
+		withEndpoint(opts, &o.BaseEndpoint)
+	}), nil
+}
+
+// WithELBv2API sets the API the client looks up load balancers with and returns the client
+func (c *Client) WithELBv2API(api ELBv2API) *Client {
+	c.elbv2Client = api
+	return c
+}
+
+// FetchLoadBalancers looks up the ELBv2 load balancers with the given DNS names, such as the hostnames
+// in the status of LoadBalancer Services and Ingresses, together with their security groups and the
+// private IPs of their ENIs. Names no load balancer has are left out.
+func (c *Client) FetchLoadBalancers(ctx context.Context, dnsNames []string) ([]LoadBalancer, error) {
+	if c.elbv2Client == nil {
+		return nil, fmt.Errorf("no ELBv2 API is configured to look up load balancers")
+	}
+
+	wanted := make(map[string]struct{}, len(dnsNames))
+	for _, name := range dnsNames {
+		wanted[strings.ToLower(name)] = struct{}{}
+	}
+
+	var lbs []elbv2types.LoadBalancer
+	paginator := elasticloadbalancingv2.NewDescribeLoadBalancersPaginator(c.elbv2Client, &elasticloadbalancingv2.DescribeLoadBalancersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to paginate DescribeLoadBalancers: %w", err)
+		}
+		for _, lb := range page.LoadBalancers {
+			if _, ok := wanted[strings.ToLower(aws.ToString(lb.DNSName))]; ok {
+				lbs = append(lbs, lb)
+			}
+		}
+	}
+	if len(lbs) == 0 {
+		return nil, nil
+	}
+
+	var descriptions, sgIDs []string
+	for _, lb := range lbs {
+		descriptions = append(descriptions, LoadBalancerENIDescription(aws.ToString(lb.LoadBalancerArn)))
+		sgIDs = append(sgIDs, lb.SecurityGroups...)
+	}
+	enis, err := c.getENIsByDescriptions(ctx, descriptions, c.requests.batchOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe ENIs: %w", err)
+	}
+	sgMap, err := c.getSecurityGroups(ctx, sgIDs, c.requests.batchOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	ipsByDescription := make(map[string][]string)
+	for _, eni := range enis {
+		description := aws.ToString(eni.Description)
+		for _, ip := range eni.PrivateIpAddresses {
+			ipsByDescription[description] = append(ipsByDescription[description], aws.ToString(ip.PrivateIpAddress))
+		}
+	}
+
+	result := make([]LoadBalancer, 0, len(lbs))
+	for _, lb := range lbs {
+		item := LoadBalancer{
+			LoadBalancer: lb,
+			PrivateIPs:   ipsByDescription[LoadBalancerENIDescription(aws.ToString(lb.LoadBalancerArn))],
+		}
+		for _, id := range lb.SecurityGroups {
+			if sg, ok := sgMap[id]; ok {
+				item.SecurityGroups = append(item.SecurityGroups, sg)
+			}
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// LoadBalancerENIDescription returns the description ELBv2 gives the ENIs of a load balancer, such as
+// "ELB app/web/0123456789abcdef" for arn:aws:elasticloadbalancing:...:loadbalancer/app/web/0123456789abcdef
+func LoadBalancerENIDescription(arn string) string {
+	_, resource, _ := strings.Cut(arn, ":loadbalancer/")
+	return "ELB " + resource
+}
+
+func (c *Client) getENIsByDescriptions(ctx context.Context, descriptions []string, opts utils.BatchOptions) (map[string]types.NetworkInterface, error) {
+	return utils.RunBatchParallel(ctx, descriptions, opts, func(ctx context.Context, batch []string) (map[string]types.NetworkInterface, error) {
+		input := &ec2.DescribeNetworkInterfacesInput{
+			Filters: []types.Filter{
+				{
+					Name:   aws.String("description"),
+					Values: batch,
+				},
+			},
+		}
+
+		paginator := ec2.NewDescribeNetworkInterfacesPaginator(c.ec2Client, input)
+		result := make(map[string]types.NetworkInterface)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx, withoutSDKRetries)
+			if err != nil {
+				return nil, fmt.Errorf("failed to paginate DescribeNetworkInterfaces: %w", err)
+			}
+			for _, eni := range page.NetworkInterfaces {
+				result[aws.ToString(eni.NetworkInterfaceId)] = eni
+			}
+		}
+		return result, nil
+	})
+}
@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockELBv2Client struct {
+	mock.Mock
+}
+
+func (m *MockELBv2Client) DescribeLoadBalancers(ctx context.Context, params *elasticloadbalancingv2.DescribeLoadBalancersInput, optFns ...func(*elasticloadbalancingv2.Options)) (*elasticloadbalancingv2.DescribeLoadBalancersOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*elasticloadbalancingv2.DescribeLoadBalancersOutput), args.Error(1)
+}
+
+func TestLoadBalancerENIDescription(t *testing.T) {
+	assert.Equal(t, "ELB app/web/0123456789abcdef", LoadBalancerENIDescription("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/0123456789abcdef"))
+	assert.Equal(t, "ELB net/api/fedcba9876543210", LoadBalancerENIDescription("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/api/fedcba9876543210"))
+}
+
+func TestFetchLoadBalancers(t *testing.T) {
+	lbENI := func(id, description, ip string) types.NetworkInterface {
+		return types.NetworkInterface{
+			NetworkInterfaceId: aws.String(id),
+			Description:        aws.String(description),
+			PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String(ip)}},
+		}
+	}
+	api := NewStaticEC2API(
+		[]types.NetworkInterface{
+			lbENI("eni-1", "ELB app/web/1111", "10.0.0.10"),
+			lbENI("eni-2", "ELB app/web/1111", "10.0.1.10"),
+			lbENI("eni-3", "ELB app/other/2222", "10.0.2.10"),
+		},
+		[]types.SecurityGroup{{GroupId: aws.String("sg-alb"), GroupName: aws.String("alb")}},
+	)
+	elbv2 := new(MockELBv2Client)
+	elbv2.On("DescribeLoadBalancers", mock.Anything, mock.Anything).Return(&elasticloadbalancingv2.DescribeLoadBalancersOutput{
+		LoadBalancers: []elbv2types.LoadBalancer{
+			{
+				LoadBalancerArn:  aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/1111"),
+				LoadBalancerName: aws.String("web"),
+				DNSName:          aws.String("web-1111.us-east-1.elb.amazonaws.com"),
+				SecurityGroups:   []string{"sg-alb", "sg-deleted"},
+			},
+			{
+				LoadBalancerArn:  aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/other/2222"),
+				LoadBalancerName: aws.String("other"),
+				DNSName:          aws.String("other-2222.us-east-1.elb.amazonaws.com"),
+			},
+		},
+	}, nil)
+
+	client, err := NewClient(api, RequestOptions{})
+	require.NoError(t, err)
+	client.WithELBv2API(elbv2)
+
+	lbs, err := client.FetchLoadBalancers(context.Background(), []string{"WEB-1111.us-east-1.elb.amazonaws.com", "missing.elb.amazonaws.com"})
+	require.NoError(t, err)
+	require.Len(t, lbs, 1)
+
+	lb := lbs[0]
+	assert.Equal(t, "web", lb.Name())
+	assert.Equal(t, []string{"sg-alb", "sg-deleted"}, lb.SecurityGroupIDs())
+	require.Len(t, lb.SecurityGroups, 1)
+	assert.Equal(t, "alb", aws.ToString(lb.SecurityGroups[0].GroupName))
+	assert.ElementsMatch(t, []string{"10.0.0.10", "10.0.1.10"}, lb.PrivateIPs)
+}
+
+func TestFetchLoadBalancers_Errors(t *testing.T) {
+	client, err := NewClient(NewStaticEC2API(nil, nil), RequestOptions{})
+	require.NoError(t, err)
+
+	_, err = client.FetchLoadBalancers(context.Background(), []string{"web.elb.amazonaws.com"})
+	assert.ErrorContains(t, err, "no ELBv2 API")
+
+	elbv2 := new(MockELBv2Client)
+	elbv2.On("DescribeLoadBalancers", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("access denied"))
+	client.WithELBv2API(elbv2)
+
+	_, err = client.FetchLoadBalancers(context.Background(), []string{"web.elb.amazonaws.com"})
+	assert.ErrorContains(t, err, "access denied")
+}
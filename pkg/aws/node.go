@@ -0,0 +1,349 @@
+package aws
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NodeNetworkInterfaces represents the ENIs of the EC2 instance behind a node and the pods using them
+type NodeNetworkInterfaces struct {
+	Node       corev1.Node `json:"node" yaml:"node"`
+	InstanceID string      `json:"instanceID" yaml:"instanceID"`
+	ENIs       []NodeENI   `json:"enis" yaml:"enis"`
+	// UnmatchedPods are the running pods of the node whose IP is on none of its ENIs
+	UnmatchedPods []corev1.Pod `json:"unmatchedPods" yaml:"unmatchedPods"`
+	// Status tells whether the ENIs of the node could be found, and Reason explains any other status
+	Status string `json:"status" yaml:"status"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// NodeENI is an ENI of a node with its security groups and the pods using its addresses
+type NodeENI struct {
+	NetworkInterface types.NetworkInterface `json:"networkInterface" yaml:"networkInterface"`
+	Role             string                 `json:"role" yaml:"role"`
+	// Trunk is the ID of the trunk ENI a branch ENI is associated with
+	Trunk          string                `json:"trunk,omitempty" yaml:"trunk,omitempty"`
+	SecurityGroups []types.SecurityGroup `json:"securityGroups" yaml:"securityGroups"`
+	// BranchENIs is the number of branch ENIs of a trunk ENI used by pods of the node
+	BranchENIs int          `json:"branchENIs" yaml:"branchENIs"`
+	Pods       []corev1.Pod `json:"pods" yaml:"pods"`
+}
+
+// Roles of the ENIs of a node
+const (
+	// ENIRolePrimary is the ENI at device index 0, which carries the IP of the node
+	ENIRolePrimary = "primary"
+	// ENIRoleSecondary is an ENI attached by the VPC CNI to get more pod IPs
+	ENIRoleSecondary = "secondary"
+	// ENIRoleTrunk is the ENI the branch ENIs of security groups for pods are associated with
+	ENIRoleTrunk = "trunk"
+	// ENIRoleBranch is the ENI of a pod with security groups of its own
+	ENIRoleBranch = "branch"
+)
+
+// StatusNoInstance means the provider ID of the node names no EC2 instance, as for Fargate nodes
+const StatusNoInstance = "NoInstance"
+
+// ID returns the ID of the ENI
+func (e NodeENI) ID() string {
+	return aws.ToString(e.NetworkInterface.NetworkInterfaceId)
+}
+
+// AssignedIPs returns the number of IPv4 addresses assigned to the ENI, counting each delegated
+// /28 prefix as its 16 addresses
+func (e NodeENI) AssignedIPs() int {
+	n := len(e.NetworkInterface.PrivateIpAddresses)
+	for _, p := range e.NetworkInterface.Ipv4Prefixes {
+		if prefix, err := netip.ParsePrefix(aws.ToString(p.Ipv4Prefix)); err == nil {
+			n += 1 << (32 - prefix.Bits())
+		}
+	}
+	return n
+}
+
+// PodIPs returns the number of distinct pod IPs on the ENI. Pods using the host network share
+// the IP of their node.
+func (e NodeENI) PodIPs() int {
+	seen := make(map[string]struct{})
+	for _, pod := range e.Pods {
+		seen[pod.Status.PodIP] = struct{}{}
+	}
+	return len(seen)
+}
+
+// InstanceIDFromProviderID returns the EC2 instance ID of a node provider ID such as
+// aws:///us-east-1a/i-0123456789abcdef0, or "" if it names no instance
+func InstanceIDFromProviderID(providerID string) string {
+	if !strings.HasPrefix(providerID, "aws://") {
+		return ""
+	}
+	id := providerID[strings.LastIndex(providerID, "/")+1:]
+	if !strings.HasPrefix(id, "i-") {
+		return ""
+	}
+	return id
+}
+
+// FetchNodeNetworkInterfaces looks up the ENIs attached to the EC2 instance of each node, and the
+// branch ENIs of its pods, and maps the running pods of the nodes onto them. Every node is returned
+// in input order; failed EC2 lookups give the affected nodes StatusLookupFailed.
+func (c *Client) FetchNodeNetworkInterfaces(ctx context.Context, nodes []corev1.Node, pods []corev1.Pod) ([]NodeNetworkInterfaces, error) {
+	opts := c.requests.batchOptions()
+	opts.Partial = true
+
+	var instanceIDs []string
+	for _, node := range nodes {
+		if id := InstanceIDFromProviderID(node.Spec.ProviderID); id != "" {
+			instanceIDs = append(instanceIDs, id)
+		}
+	}
+
+	var attached map[string]types.NetworkInterface
+	var failedInstances map[string]error
+	if len(instanceIDs) > 0 {
+		var err error
+		attached, err = c.getENIsByInstanceIDs(ctx, instanceIDs, opts)
+		if failedInstances, err = partialFailures(ctx, err); err != nil {
+			return nil, fmt.Errorf("failed to describe ENIs: %w", err)
+		}
+	}
+
+	podsByNode := make(map[string][]corev1.Pod)
+	for _, pod := range pods {
+		if unresolvedStatus(pod) == "" && pod.Spec.NodeName != "" {
+			podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+		}
+	}
+
+	// Pods with security groups of their own use branch ENIs, which are not attached to the
+	// instance and can only be found by the IP of the pod
+	attachedIPs := newIPIndex(attached)
+	var branchIPs []string
+	for _, node := range nodes {
+		for _, pod := range podsByNode[node.Name] {
+			if attachedIPs.lookup(pod.Status.PodIP) == "" && !slices.Contains(branchIPs, pod.Status.PodIP) {
+				branchIPs = append(branchIPs, pod.Status.PodIP)
+			}
+		}
+	}
+	var branches map[string]types.NetworkInterface
+	var failures lookupFailures
+	if len(branchIPs) > 0 {
+		var err error
+		branches, err = c.getENIsByPrivateIPs(ctx, branchIPs, opts)
+		if failures.ips, err = partialFailures(ctx, err); err != nil {
+			return nil, fmt.Errorf("failed to describe ENIs: %w", err)
+		}
+		for id, eni := range branches {
+			if eni.InterfaceType != types.NetworkInterfaceTypeBranch {
+				delete(branches, id)
+			}
+		}
+	}
+
+	eniToSGIDs := make(map[string][]string)
+	for _, enis := range []map[string]types.NetworkInterface{attached, branches} {
+		for id, eni := range enis {
+			for _, group := range eni.Groups {
+				eniToSGIDs[id] = append(eniToSGIDs[id], aws.ToString(group.GroupId))
+			}
+		}
+	}
+	sgMap, err := c.getSecurityGroups(ctx, collectUniqueSGIDs(eniToSGIDs), opts)
+	if failures.sgs, err = partialFailures(ctx, err); err != nil {
+		return nil, err
+	}
+
+	byInstance := make(map[string][]types.NetworkInterface)
+	for _, eni := range attached {
+		if eni.Attachment != nil {
+			id := aws.ToString(eni.Attachment.InstanceId)
+			byInstance[id] = append(byInstance[id], eni)
+		}
+	}
+
+	result := make([]NodeNetworkInterfaces, 0, len(nodes))
+	for _, node := range nodes {
+		info := NodeNetworkInterfaces{Node: node, InstanceID: InstanceIDFromProviderID(node.Spec.ProviderID)}
+		switch {
+		case info.InstanceID == "":
+			info.Status, info.Reason = StatusNoInstance, fmt.Sprintf("provider ID %q names no EC2 instance", node.Spec.ProviderID)
+		case failedInstances[info.InstanceID] != nil:
+			info.Status, info.Reason = StatusLookupFailed, fmt.Sprintf("failed to look up ENIs of instance %s: %v", info.InstanceID, failedInstances[info.InstanceID])
+		case len(byInstance[info.InstanceID]) == 0:
+			info.Status, info.Reason = StatusENINotFound, fmt.Sprintf("no ENI is attached to instance %s", info.InstanceID)
+		default:
+			buildNodeENIs(&info, byInstance[info.InstanceID], branches, podsByNode[node.Name], sgMap, failures)
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// buildNodeENIs maps the pods of a node onto its attached and branch ENIs, and sets the status of the node
+func buildNodeENIs(
+	info *NodeNetworkInterfaces,
+	attached []types.NetworkInterface,
+	branches map[string]types.NetworkInterface,
+	pods []corev1.Pod,
+	sgMap map[string]types.SecurityGroup,
+	failures lookupFailures,
+) {
+	slices.SortFunc(attached, func(a, b types.NetworkInterface) int {
+		return cmp.Compare(aws.ToInt32(a.Attachment.DeviceIndex), aws.ToInt32(b.Attachment.DeviceIndex))
+	})
+
+	index := make(map[string]int)
+	trunk := ""
+	for _, eni := range attached {
+		role := ENIRoleSecondary
+		switch {
+		case eni.InterfaceType == types.NetworkInterfaceTypeTrunk:
+			role, trunk = ENIRoleTrunk, aws.ToString(eni.NetworkInterfaceId)
+		case aws.ToInt32(eni.Attachment.DeviceIndex) == 0:
+			role = ENIRolePrimary
+		}
+		index[aws.ToString(eni.NetworkInterfaceId)] = len(info.ENIs)
+		info.ENIs = append(info.ENIs, NodeENI{NetworkInterface: eni, Role: role})
+	}
+
+	attachedIPs := newIPIndex(nil)
+	for _, eni := range attached {
+		attachedIPs.add(eni)
+	}
+	branchIPs := newIPIndex(branches)
+
+	var lookupErr error
+	for _, pod := range pods {
+		ip := pod.Status.PodIP
+		eniID := attachedIPs.lookup(ip)
+		if eniID == "" {
+			eniID = branchIPs.lookup(ip)
+		}
+		if eniID == "" {
+			info.UnmatchedPods = append(info.UnmatchedPods, pod)
+			if err, failed := failures.ips[ip]; failed && lookupErr == nil {
+				lookupErr = fmt.Errorf("failed to look up ENI of IP %s: %v", ip, err)
+			}
+			continue
+		}
+		i, ok := index[eniID]
+		if !ok {
+			i = len(info.ENIs)
+			index[eniID] = i
+			info.ENIs = append(info.ENIs, NodeENI{NetworkInterface: branches[eniID], Role: ENIRoleBranch, Trunk: trunk})
+			if trunk != "" {
+				info.ENIs[index[trunk]].BranchENIs++
+			}
+		}
+		info.ENIs[i].Pods = append(info.ENIs[i].Pods, pod)
+	}
+
+	var missing []string
+	for i := range info.ENIs {
+		for _, group := range info.ENIs[i].NetworkInterface.Groups {
+			sgID := aws.ToString(group.GroupId)
+			if sg, ok := sgMap[sgID]; ok {
+				info.ENIs[i].SecurityGroups = append(info.ENIs[i].SecurityGroups, sg)
+				continue
+			}
+			if !slices.Contains(missing, sgID) {
+				missing = append(missing, sgID)
+			}
+			if err, failed := failures.sgs[sgID]; failed && lookupErr == nil {
+				lookupErr = fmt.Errorf("failed to look up security groups %s: %v", sgID, err)
+			}
+		}
+	}
+
+	switch {
+	case lookupErr != nil:
+		info.Status, info.Reason = StatusLookupFailed, lookupErr.Error()
+	case len(missing) > 0:
+		info.Status, info.Reason = StatusSGNotFound, fmt.Sprintf("security groups not found: %s", strings.Join(missing, ", "))
+	case len(info.UnmatchedPods) > 0:
+		info.Status, info.Reason = StatusENINotFound, fmt.Sprintf("no ENI has the IP of %d pods", len(info.UnmatchedPods))
+	default:
+		info.Status = StatusMapped
+	}
+}
+
+// ipIndex finds the ENI of an IP among the private IPs and delegated prefixes of a set of ENIs
+type ipIndex struct {
+	ips      map[string]string
+	prefixes map[netip.Prefix]string
+}
+
+func newIPIndex(enis map[string]types.NetworkInterface) *ipIndex {
+	idx := &ipIndex{ips: make(map[string]string), prefixes: make(map[netip.Prefix]string)}
+	for _, eni := range enis {
+		idx.add(eni)
+	}
+	return idx
+}
+
+func (idx *ipIndex) add(eni types.NetworkInterface) {
+	id := aws.ToString(eni.NetworkInterfaceId)
+	for _, ip := range eni.PrivateIpAddresses {
+		idx.ips[aws.ToString(ip.PrivateIpAddress)] = id
+	}
+	for _, p := range eni.Ipv4Prefixes {
+		if prefix, err := netip.ParsePrefix(aws.ToString(p.Ipv4Prefix)); err == nil {
+			idx.prefixes[prefix] = id
+		}
+	}
+}
+
+// lookup returns the ID of the ENI having the IP, or "" if none has
+func (idx *ipIndex) lookup(ip string) string {
+	if id, ok := idx.ips[ip]; ok {
+		return id
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	for prefix, id := range idx.prefixes {
+		if prefix.Contains(addr) {
+			return id
+		}
+	}
+	return ""
+}
+
+// getENIsByInstanceIDs retrieves the network interfaces attached to EC2 instances using batch processing
+func (c *Client) getENIsByInstanceIDs(ctx context.Context, instanceIDs []string, opts utils.BatchOptions) (map[string]types.NetworkInterface, error) {
+	return utils.RunBatchParallel(ctx, instanceIDs, opts, func(ctx context.Context, batch []string) (map[string]types.NetworkInterface, error) {
+		input := &ec2.DescribeNetworkInterfacesInput{
+			Filters: []types.Filter{
+				{
+					Name:   aws.String("attachment.instance-id"),
+					Values: batch,
+				},
+			},
+		}
+
+		paginator := ec2.NewDescribeNetworkInterfacesPaginator(c.ec2Client, input)
+		result := make(map[string]types.NetworkInterface)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx, withoutSDKRetries)
+			if err != nil {
+				return nil, fmt.Errorf("failed to paginate DescribeNetworkInterfaces: %w", err)
+			}
+			for _, eni := range page.NetworkInterfaces {
+				result[aws.ToString(eni.NetworkInterfaceId)] = eni
+			}
+		}
+		return result, nil
+	})
+}
@@ -0,0 +1,154 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInstanceIDFromProviderID(t *testing.T) {
+	testCases := map[string]string{
+		"aws:///us-east-1a/i-0123456789abcdef0":                     "i-0123456789abcdef0",
+		"aws:///us-east-1a/fargate-ip-10-0-1-10.ec2.internal":       "",
+		"aws:///us-east-1b/0123456789/fargate-ip-10-0-1-10.ec2.int": "",
+		"gce://project/zone/instance":                               "",
+		"":                                                          "",
+	}
+	for providerID, want := range testCases {
+		assert.Equal(t, want, InstanceIDFromProviderID(providerID), providerID)
+	}
+}
+
+func nodeENI(id, instanceID string, deviceIndex int32, interfaceType types.NetworkInterfaceType, sgID string, ips ...string) types.NetworkInterface {
+	eni := types.NetworkInterface{
+		NetworkInterfaceId: aws.String(id),
+		InterfaceType:      interfaceType,
+		SubnetId:           aws.String("subnet-1"),
+		Groups:             []types.GroupIdentifier{{GroupId: aws.String(sgID)}},
+	}
+	if instanceID != "" {
+		eni.Attachment = &types.NetworkInterfaceAttachment{InstanceId: aws.String(instanceID), DeviceIndex: aws.Int32(deviceIndex)}
+	}
+	for _, ip := range ips {
+		eni.PrivateIpAddresses = append(eni.PrivateIpAddresses, types.NetworkInterfacePrivateIpAddress{PrivateIpAddress: aws.String(ip)})
+	}
+	return eni
+}
+
+func nodePod(name, nodeName, ip string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ip},
+	}
+}
+
+func TestFetchNodeNetworkInterfaces(t *testing.T) {
+	prefixed := nodeENI("eni-secondary", "i-1", 1, types.NetworkInterfaceTypeInterface, "sg-node", "10.0.1.20")
+	prefixed.Ipv4Prefixes = []types.Ipv4PrefixSpecification{{Ipv4Prefix: aws.String("10.0.2.0/28")}}
+
+	api := NewStaticEC2API(
+		[]types.NetworkInterface{
+			prefixed,
+			nodeENI("eni-primary", "i-1", 0, types.NetworkInterfaceTypeInterface, "sg-node", "10.0.1.10", "10.0.1.11"),
+			nodeENI("eni-trunk", "i-1", 2, types.NetworkInterfaceTypeTrunk, "sg-node", "10.0.1.30"),
+			nodeENI("eni-branch", "", 0, types.NetworkInterfaceTypeBranch, "sg-web", "10.0.3.10"),
+			nodeENI("eni-other", "i-2", 0, types.NetworkInterfaceTypeInterface, "sg-node", "10.0.9.10"),
+		},
+		[]types.SecurityGroup{
+			{GroupId: aws.String("sg-node"), GroupName: aws.String("node")},
+			{GroupId: aws.String("sg-web"), GroupName: aws.String("web")},
+		},
+	)
+	client, err := NewClient(api, RequestOptions{})
+	require.NoError(t, err)
+
+	hostNetwork := nodePod("kube-proxy", "node-1", "10.0.1.10")
+	hostNetwork.Spec.HostNetwork = true
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "fargate"}, Spec: corev1.NodeSpec{ProviderID: "aws:///us-east-1a/fargate-ip-10-0-4-10"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-3"}, Spec: corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-3"}},
+	}
+	pods := []corev1.Pod{
+		hostNetwork,
+		nodePod("coredns", "node-1", "10.0.1.11"),
+		nodePod("api", "node-1", "10.0.2.5"),
+		nodePod("web", "node-1", "10.0.3.10"),
+		nodePod("lost", "node-1", "10.0.8.8"),
+		nodePod("other", "node-2", "10.0.9.11"),
+	}
+
+	result, err := client.FetchNodeNetworkInterfaces(context.Background(), nodes, pods)
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+
+	node := result[0]
+	assert.Equal(t, "i-1", node.InstanceID)
+	assert.Equal(t, StatusENINotFound, node.Status)
+	assert.Equal(t, "no ENI has the IP of 1 pods", node.Reason)
+	require.Len(t, node.UnmatchedPods, 1)
+	assert.Equal(t, "lost", node.UnmatchedPods[0].Name)
+
+	require.Len(t, node.ENIs, 4)
+	var roles, ids []string
+	for _, eni := range node.ENIs {
+		roles = append(roles, eni.Role)
+		ids = append(ids, eni.ID())
+	}
+	assert.Equal(t, []string{ENIRolePrimary, ENIRoleSecondary, ENIRoleTrunk, ENIRoleBranch}, roles)
+	assert.Equal(t, []string{"eni-primary", "eni-secondary", "eni-trunk", "eni-branch"}, ids)
+
+	primary := node.ENIs[0]
+	assert.Len(t, primary.Pods, 2)
+	assert.Equal(t, 2, primary.PodIPs())
+	assert.Equal(t, 2, primary.AssignedIPs())
+	assert.Equal(t, "node", aws.ToString(primary.SecurityGroups[0].GroupName))
+
+	secondary := node.ENIs[1]
+	assert.Equal(t, "api", secondary.Pods[0].Name)
+	assert.Equal(t, 17, secondary.AssignedIPs())
+
+	assert.Equal(t, 1, node.ENIs[2].BranchENIs)
+	branch := node.ENIs[3]
+	assert.Equal(t, "eni-trunk", branch.Trunk)
+	assert.Equal(t, "web", branch.Pods[0].Name)
+	assert.Equal(t, "web", aws.ToString(branch.SecurityGroups[0].GroupName))
+
+	assert.Equal(t, StatusNoInstance, result[1].Status)
+	assert.Empty(t, result[1].InstanceID)
+
+	assert.Equal(t, StatusENINotFound, result[2].Status)
+	assert.Equal(t, "no ENI is attached to instance i-3", result[2].Reason)
+}
+
+func TestFetchNodeNetworkInterfaces_LookupFailed(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, mock.MatchedBy(func(in *ec2.DescribeNetworkInterfacesInput) bool {
+		return aws.ToString(in.Filters[0].Name) == "attachment.instance-id"
+	})).Return(&ec2.DescribeNetworkInterfacesOutput{
+		NetworkInterfaces: []types.NetworkInterface{nodeENI("eni-primary", "i-1", 0, types.NetworkInterfaceTypeInterface, "sg-node", "10.0.1.10")},
+	}, nil)
+	mockClient.On("DescribeSecurityGroups", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("access denied"))
+
+	nodes := []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-1"}}}
+	result, err := client.FetchNodeNetworkInterfaces(context.Background(), nodes, []corev1.Pod{nodePod("web", "node-1", "10.0.1.10")})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	assert.Equal(t, StatusLookupFailed, result[0].Status)
+	assert.Contains(t, result[0].Reason, "failed to look up security groups sg-node")
+	require.Len(t, result[0].ENIs, 1)
+	assert.Len(t, result[0].ENIs[0].Pods, 1)
+}
@@ -0,0 +1,217 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Peers holds what the security group and prefix list peers of rules resolve to, by ID.
+// The zero value resolves nothing.
+type Peers struct {
+	SecurityGroups map[string]PeerSecurityGroup
+	PrefixLists    map[string]PrefixList
+}
+
+// PeerSecurityGroup is a security group referenced by a rule, with the pods and nodes using it
+type PeerSecurityGroup struct {
+	ID   string
+	Name string
+	// Pods are the namespace/name of the running pods with an IP on an ENI of the group
+	Pods []string
+	// Nodes are the names of the nodes whose instance has an ENI of the group attached
+	Nodes []string
+}
+
+// PrefixList is a managed prefix list referenced by a rule, with its CIDR entries
+type PrefixList struct {
+	ID    string
+	Name  string
+	CIDRs []string
+}
+
+// CrossAccount reports whether the group reference of a rule of sg names a group of another account
+func CrossAccount(sg types.SecurityGroup, pair types.UserIdGroupPair) bool {
+	owner := aws.ToString(pair.UserId)
+	return owner != "" && aws.ToString(sg.OwnerId) != "" && owner != aws.ToString(sg.OwnerId)
+}
+
+// referencedPeers returns the IDs of the security groups and prefix lists referenced by the rules of
+// the security groups, in order of appearance. Groups of other accounts are left out since they
+// cannot be described.
+func referencedPeers(securityGroups []types.SecurityGroup) ([]string, []string) {
+	var sgIDs, prefixListIDs []string
+	for _, sg := range securityGroups {
+		for _, p := range slices.Concat(sg.IpPermissions, sg.IpPermissionsEgress) {
+			for _, pair := range p.UserIdGroupPairs {
+				id := aws.ToString(pair.GroupId)
+				if id != "" && !CrossAccount(sg, pair) && !slices.Contains(sgIDs, id) {
+					sgIDs = append(sgIDs, id)
+				}
+			}
+			for _, pl := range p.PrefixListIds {
+				id := aws.ToString(pl.PrefixListId)
+				if id != "" && !slices.Contains(prefixListIDs, id) {
+					prefixListIDs = append(prefixListIDs, id)
+				}
+			}
+		}
+	}
+	return sgIDs, prefixListIDs
+}
+
+// ResolvePeers looks up the security groups and managed prefix lists referenced by the rules of the
+// security groups. Referenced groups are resolved to their name and to the pods and nodes using
+// them, and prefix lists to their name and CIDR entries. Peers that no longer exist are left out.
+func (c *Client) ResolvePeers(ctx context.Context, securityGroups []types.SecurityGroup, pods []corev1.Pod, nodes []corev1.Node) (Peers, error) {
+	sgIDs, prefixListIDs := referencedPeers(securityGroups)
+	peers := Peers{
+		SecurityGroups: make(map[string]PeerSecurityGroup, len(sgIDs)),
+		PrefixLists:    make(map[string]PrefixList, len(prefixListIDs)),
+	}
+
+	if len(sgIDs) > 0 {
+		groups, err := c.findSecurityGroups(ctx, sgIDs)
+		if err != nil {
+			return Peers{}, err
+		}
+		enis, err := c.GetENIsBySecurityGroups(ctx, sgIDs)
+		if err != nil {
+			return Peers{}, fmt.Errorf("failed to describe ENIs: %w", err)
+		}
+		for _, sg := range groups {
+			peers.SecurityGroups[aws.ToString(sg.GroupId)] = peerSecurityGroup(sg, enis, pods, nodes)
+		}
+	}
+
+	if len(prefixListIDs) > 0 {
+		lists, err := c.getPrefixLists(ctx, prefixListIDs)
+		if err != nil {
+			return Peers{}, err
+		}
+		peers.PrefixLists = lists
+	}
+	return peers, nil
+}
+
+// FetchPrefixLists looks up the managed prefix lists referenced by the rules of the security groups
+// with their CIDR entries, by ID. Prefix lists that no longer exist are left out.
+func (c *Client) FetchPrefixLists(ctx context.Context, securityGroups []types.SecurityGroup) (map[string]PrefixList, error) {
+	_, prefixListIDs := referencedPeers(securityGroups)
+	if len(prefixListIDs) == 0 {
+		return map[string]PrefixList{}, nil
+	}
+	return c.getPrefixLists(ctx, prefixListIDs)
+}
+
+// findSecurityGroups describes the security groups by a group-id filter, which unlike GroupIds does
+// not fail when some of the groups have been deleted
+func (c *Client) findSecurityGroups(ctx context.Context, sgIDs []string) ([]types.SecurityGroup, error) {
+	input := &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("group-id"),
+				Values: sgIDs,
+			},
+		},
+	}
+	var result []types.SecurityGroup
+	paginator := ec2.NewDescribeSecurityGroupsPaginator(c.ec2Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("DescribeSecurityGroups failed: %w", err)
+		}
+		result = append(result, page.SecurityGroups...)
+	}
+	return result, nil
+}
+
+// peerSecurityGroup maps the ENIs of a referenced security group back to the running pods with an
+// IP on them and the nodes they are attached to
+func peerSecurityGroup(sg types.SecurityGroup, enis map[string]types.NetworkInterface, pods []corev1.Pod, nodes []corev1.Node) PeerSecurityGroup {
+	sgID := aws.ToString(sg.GroupId)
+	ips := make(map[string]struct{})
+	instances := make(map[string]struct{})
+	for _, eni := range enis {
+		if !hasGroup(eni, sgID) {
+			continue
+		}
+		for _, ip := range eni.PrivateIpAddresses {
+			ips[aws.ToString(ip.PrivateIpAddress)] = struct{}{}
+		}
+		if eni.Attachment != nil && eni.Attachment.InstanceId != nil {
+			instances[aws.ToString(eni.Attachment.InstanceId)] = struct{}{}
+		}
+	}
+
+	peer := PeerSecurityGroup{ID: sgID, Name: aws.ToString(sg.GroupName)}
+	for _, pod := range pods {
+		if unresolvedStatus(pod) != "" {
+			continue
+		}
+		if _, ok := ips[pod.Status.PodIP]; ok {
+			peer.Pods = append(peer.Pods, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	for _, node := range nodes {
+		if _, ok := instances[InstanceIDFromProviderID(node.Spec.ProviderID)]; ok {
+			peer.Nodes = append(peer.Nodes, node.Name)
+		}
+	}
+	sort.Strings(peer.Pods)
+	sort.Strings(peer.Nodes)
+	return peer
+}
+
+// getPrefixLists describes the managed prefix lists by a prefix-list-id filter and fetches the
+// entries of each
+func (c *Client) getPrefixLists(ctx context.Context, ids []string) (map[string]PrefixList, error) {
+	input := &ec2.DescribeManagedPrefixListsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("prefix-list-id"),
+				Values: ids,
+			},
+		},
+	}
+	result := make(map[string]PrefixList, len(ids))
+	paginator := ec2.NewDescribeManagedPrefixListsPaginator(c.ec2Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("DescribeManagedPrefixLists failed: %w", err)
+		}
+		for _, pl := range page.PrefixLists {
+			id := aws.ToString(pl.PrefixListId)
+			cidrs, err := c.getPrefixListCIDRs(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			result[id] = PrefixList{ID: id, Name: aws.ToString(pl.PrefixListName), CIDRs: cidrs}
+		}
+	}
+	return result, nil
+}
+
+// getPrefixListCIDRs returns the CIDR entries of the current version of a managed prefix list
+func (c *Client) getPrefixListCIDRs(ctx context.Context, id string) ([]string, error) {
+	var cidrs []string
+	paginator := ec2.NewGetManagedPrefixListEntriesPaginator(c.ec2Client, &ec2.GetManagedPrefixListEntriesInput{PrefixListId: aws.String(id)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("GetManagedPrefixListEntries failed for %s: %w", id, err)
+		}
+		for _, entry := range page.Entries {
+			cidrs = append(cidrs, aws.ToString(entry.Cidr))
+		}
+	}
+	return cidrs, nil
+}
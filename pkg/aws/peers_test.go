@@ -0,0 +1,137 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolvePeers(t *testing.T) {
+	enis := []types.NetworkInterface{
+		{
+			NetworkInterfaceId: aws.String("eni-branch"),
+			InterfaceType:      types.NetworkInterfaceTypeBranch,
+			PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String("10.0.1.10")}},
+			Groups:             []types.GroupIdentifier{{GroupId: aws.String("sg-db")}},
+		},
+		{
+			NetworkInterfaceId: aws.String("eni-node"),
+			InterfaceType:      types.NetworkInterfaceTypeInterface,
+			PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{
+				{PrivateIpAddress: aws.String("10.0.2.10")},
+				{PrivateIpAddress: aws.String("10.0.2.11")},
+			},
+			Groups:     []types.GroupIdentifier{{GroupId: aws.String("sg-node")}},
+			Attachment: &types.NetworkInterfaceAttachment{InstanceId: aws.String("i-0123")},
+		},
+	}
+	sgs := []types.SecurityGroup{
+		{GroupId: aws.String("sg-db"), GroupName: aws.String("db")},
+		{GroupId: aws.String("sg-node"), GroupName: aws.String("node")},
+	}
+	prefixLists := []SnapshotPrefixList{{
+		ManagedPrefixList: types.ManagedPrefixList{PrefixListId: aws.String("pl-office"), PrefixListName: aws.String("office")},
+		Entries:           []types.PrefixListEntry{{Cidr: aws.String("192.0.2.0/24")}, {Cidr: aws.String("198.51.100.0/24")}},
+	}}
+	client, err := NewClient(NewStaticEC2API(enis, sgs).WithPrefixLists(prefixLists), RequestOptions{})
+	require.NoError(t, err)
+
+	web := types.SecurityGroup{
+		GroupId: aws.String("sg-web"),
+		OwnerId: aws.String("111111111111"),
+		IpPermissions: []types.IpPermission{{
+			UserIdGroupPairs: []types.UserIdGroupPair{
+				{GroupId: aws.String("sg-node"), UserId: aws.String("111111111111")},
+				{GroupId: aws.String("sg-deleted")},
+				{GroupId: aws.String("sg-partner"), UserId: aws.String("222222222222"), VpcPeeringConnectionId: aws.String("pcx-1")},
+			},
+			PrefixListIds: []types.PrefixListId{{PrefixListId: aws.String("pl-office")}},
+		}},
+		IpPermissionsEgress: []types.IpPermission{{
+			UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: aws.String("sg-db")}},
+		}},
+	}
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "data"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.1.10"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.2.11"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "done", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodSucceeded, PodIP: "10.0.2.10"},
+		},
+	}
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0123"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}, Spec: corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0456"}},
+	}
+
+	peers, err := client.ResolvePeers(context.Background(), []types.SecurityGroup{web}, pods, nodes)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]PeerSecurityGroup{
+		"sg-node": {ID: "sg-node", Name: "node", Pods: []string{"default/web"}, Nodes: []string{"node-1"}},
+		"sg-db":   {ID: "sg-db", Name: "db", Pods: []string{"data/db-0"}},
+	}, peers.SecurityGroups)
+	assert.Equal(t, map[string]PrefixList{
+		"pl-office": {ID: "pl-office", Name: "office", CIDRs: []string{"192.0.2.0/24", "198.51.100.0/24"}},
+	}, peers.PrefixLists)
+}
+
+func TestResolvePeers_NoPeers(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	sg := types.SecurityGroup{
+		GroupId: aws.String("sg-web"),
+		IpPermissions: []types.IpPermission{{
+			IpRanges: []types.IpRange{{CidrIp: aws.String("10.0.0.0/8")}},
+		}},
+	}
+	peers, err := client.ResolvePeers(context.Background(), []types.SecurityGroup{sg}, nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, peers.SecurityGroups)
+	assert.Empty(t, peers.PrefixLists)
+	mockClient.AssertExpectations(t)
+}
+
+func TestFetchPrefixLists(t *testing.T) {
+	prefixLists := []SnapshotPrefixList{{
+		ManagedPrefixList: types.ManagedPrefixList{PrefixListId: aws.String("pl-office"), PrefixListName: aws.String("office")},
+		Entries:           []types.PrefixListEntry{{Cidr: aws.String("192.0.2.0/24")}},
+	}}
+	client, err := NewClient(NewStaticEC2API(nil, nil).WithPrefixLists(prefixLists), RequestOptions{})
+	require.NoError(t, err)
+
+	web := types.SecurityGroup{
+		GroupId: aws.String("sg-web"),
+		IpPermissions: []types.IpPermission{{
+			UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: aws.String("sg-node")}},
+			PrefixListIds:    []types.PrefixListId{{PrefixListId: aws.String("pl-office")}, {PrefixListId: aws.String("pl-deleted")}},
+		}},
+	}
+	lists, err := client.FetchPrefixLists(context.Background(), []types.SecurityGroup{web})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]PrefixList{
+		"pl-office": {ID: "pl-office", Name: "office", CIDRs: []string{"192.0.2.0/24"}},
+	}, lists)
+}
+
+func TestCrossAccount(t *testing.T) {
+	sg := types.SecurityGroup{OwnerId: aws.String("111111111111")}
+
+	assert.False(t, CrossAccount(sg, types.UserIdGroupPair{UserId: aws.String("111111111111")}))
+	assert.True(t, CrossAccount(sg, types.UserIdGroupPair{UserId: aws.String("222222222222")}))
+	assert.False(t, CrossAccount(sg, types.UserIdGroupPair{}))
+	assert.False(t, CrossAccount(types.SecurityGroup{}, types.UserIdGroupPair{UserId: aws.String("222222222222")}))
+}
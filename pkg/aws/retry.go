@@ -0,0 +1,95 @@
+package aws
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/utils"
+)
+
+// Defaults of the EC2 request options. Lookups are split into batches of up to batchSize IDs or IPs,
+// the most a filter of the EC2 Describe APIs accepts.
+const (
+	DefaultConcurrency  = 5
+	DefaultMaxRetries   = 5
+	DefaultBatchTimeout = time.Minute
+	batchSize           = 200
+)
+
+// RequestOptions controls how EC2 lookups are batched, throttled and retried. Batches are retried
+// by RunBatchParallel only: the requests of a batch are sent with the SDK retries turned off.
+type RequestOptions struct {
+	// Concurrency is the maximum number of batches looked up at the same time, DefaultConcurrency when 0
+	Concurrency int
+	// RateLimit is the maximum number of batches started per second, 0 for no limit
+	RateLimit float64
+	// MaxRetries is how many times a throttled or failed batch is retried
+	MaxRetries int
+	// Timeout bounds each attempt of a batch, 0 for no timeout
+	Timeout time.Duration
+	// Stats counts the batches, retries and failures of the lookups when set
+	Stats *utils.BatchStats
+}
+
+// retryableErrorCodes are the EC2 error codes of throttled requests and transient server errors
+var retryableErrorCodes = map[string]struct{}{
+	"RequestLimitExceeded":      {},
+	"Throttling":                {},
+	"ThrottlingException":       {},
+	"RequestThrottled":          {},
+	"RequestThrottledException": {},
+	"TooManyRequestsException":  {},
+	"BandwidthLimitExceeded":    {},
+	"EC2ThrottledException":     {},
+	"PriorRequestNotComplete":   {},
+	"InternalError":             {},
+	"InternalFailure":           {},
+	"ServiceUnavailable":        {},
+	"Unavailable":               {},
+	"RequestTimeout":            {},
+	"RequestTimeoutException":   {},
+}
+
+// IsRetryableError reports whether an EC2 request failed because it was throttled or hit a
+// transient server error, and may succeed when retried
+func IsRetryableError(err error) bool {
+	var quotaErr ratelimit.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	_, ok := retryableErrorCodes[apiErr.ErrorCode()]
+	return ok
+}
+
+// withoutSDKRetries turns off the retries of the SDK for a request of a batch. RunBatchParallel
+// retries the batch with its own backoff, so SDK retries would multiply the attempts of MaxRetries,
+// be missing from Stats and spend the batch Timeout.
+func withoutSDKRetries(o *ec2.Options) {
+	o.Retryer = aws.NopRetryer{}
+}
+
+// batchOptions returns the options RunBatchParallel looks up EC2 resources with
+func (o RequestOptions) batchOptions() utils.BatchOptions {
+	concurrency := o.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	return utils.BatchOptions{
+		BatchSize:   batchSize,
+		MaxParallel: concurrency,
+		RateLimit:   o.RateLimit,
+		MaxRetries:  o.MaxRetries,
+		Timeout:     o.Timeout,
+		Retryable:   IsRetryableError,
+		Stats:       o.Stats,
+	}
+}
@@ -0,0 +1,143 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/utils"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"request limit exceeded", &smithy.GenericAPIError{Code: "RequestLimitExceeded"}, true},
+		{"wrapped throttling", fmt.Errorf("DescribeSecurityGroups failed: %w", &smithy.GenericAPIError{Code: "Throttling"}), true},
+		{"server error", &smithy.GenericAPIError{Code: "InternalError"}, true},
+		{"sdk retry quota exceeded", fmt.Errorf("failed to get rate limit token, %w", ratelimit.QuotaExceededError{Available: 0, Requested: 5}), true},
+		{"unauthorized", &smithy.GenericAPIError{Code: "UnauthorizedOperation"}, false},
+		{"not an api error", fmt.Errorf("aws error"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryableError(tt.err))
+		})
+	}
+}
+
+func TestRequestOptions_BatchOptions(t *testing.T) {
+	opts := RequestOptions{}.batchOptions()
+	assert.Equal(t, batchSize, opts.BatchSize)
+	assert.Equal(t, DefaultConcurrency, opts.MaxParallel)
+	assert.Equal(t, 0, opts.MaxRetries)
+
+	opts = RequestOptions{Concurrency: 2, RateLimit: 10, MaxRetries: 3}.batchOptions()
+	assert.Equal(t, 2, opts.MaxParallel)
+	assert.Equal(t, 10.0, opts.RateLimit)
+	assert.Equal(t, 3, opts.MaxRetries)
+}
+
+func TestGetSecurityGroupsParallel_RetriesThrottling(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	stats := &utils.BatchStats{}
+	client := &Client{ec2Client: mockClient, requests: RequestOptions{MaxRetries: 2, Stats: stats}}
+
+	mockClient.On("DescribeSecurityGroups", mock.Anything, mock.Anything).Return(
+		nil, &smithy.GenericAPIError{Code: "RequestLimitExceeded"},
+	).Once()
+	mockClient.On("DescribeSecurityGroups", mock.Anything, mock.Anything).Return(
+		&ec2.DescribeSecurityGroupsOutput{
+			SecurityGroups: []types.SecurityGroup{{GroupId: aws.String("sg-1")}},
+		}, nil,
+	).Once()
+
+	sgMap, err := client.GetSecurityGroupsParallel(context.Background(), []string{"sg-1"})
+
+	assert.NoError(t, err)
+	assert.Len(t, sgMap, 1)
+	assert.Equal(t, int64(1), stats.Retries.Load())
+	assert.Equal(t, int64(0), stats.Failures.Load())
+	mockClient.AssertExpectations(t)
+}
+
+func TestGetSecurityGroups_BatchLayerOwnsRetries(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `<Response><Errors><Error><Code>RequestLimitExceeded</Code><Message>Request limit exceeded.</Message></Error></Errors><RequestID>1</RequestID></Response>`)
+	}))
+	defer server.Close()
+
+	// The client keeps the standard SDK retryer, as ec2.NewFromConfig sets it up
+	api := ec2.New(ec2.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	})
+	stats := &utils.BatchStats{}
+	client := &Client{ec2Client: api}
+
+	_, err := client.getSecurityGroups(context.Background(), []string{"sg-1"}, utils.BatchOptions{
+		BatchSize:  batchSize,
+		MaxRetries: 2,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   time.Millisecond,
+		Retryable:  IsRetryableError,
+		Stats:      stats,
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, int64(3), requests.Load(), "one request per batch attempt")
+	assert.Equal(t, int64(2), stats.Retries.Load())
+}
+
+func TestGetSecurityGroupsByNames_BatchLayerOwnsRetries(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `<Response><Errors><Error><Code>RequestLimitExceeded</Code><Message>Request limit exceeded.</Message></Error></Errors><RequestID>1</RequestID></Response>`)
+	}))
+	defer server.Close()
+
+	api := ec2.New(ec2.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	})
+	stats := &utils.BatchStats{}
+	client := &Client{ec2Client: api}
+
+	_, err := client.getSecurityGroupsByNames(context.Background(), []string{"web"}, utils.BatchOptions{
+		BatchSize:  batchSize,
+		MaxRetries: 2,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   time.Millisecond,
+		Retryable:  IsRetryableError,
+		Stats:      stats,
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, int64(3), requests.Load(), "one request per batch attempt")
+	assert.Equal(t, int64(2), stats.Retries.Load())
+}
@@ -0,0 +1,54 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/utils"
+)
+
+// FetchSecurityGroupRules retrieves the rules of the security groups, which unlike the permissions of
+// DescribeSecurityGroups carry the ID of each rule. Rules are returned in order of rule ID.
+func (c *Client) FetchSecurityGroupRules(ctx context.Context, sgIDs []string) ([]types.SecurityGroupRule, error) {
+	if len(sgIDs) == 0 {
+		return nil, nil
+	}
+
+	rules, err := utils.RunBatchParallel(ctx, sgIDs, c.requests.batchOptions(), func(ctx context.Context, batch []string) (map[string]types.SecurityGroupRule, error) {
+		input := &ec2.DescribeSecurityGroupRulesInput{
+			Filters: []types.Filter{
+				{
+					Name:   aws.String("group-id"),
+					Values: batch,
+				},
+			},
+		}
+
+		paginator := ec2.NewDescribeSecurityGroupRulesPaginator(c.ec2Client, input)
+		result := make(map[string]types.SecurityGroupRule)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx, withoutSDKRetries)
+			if err != nil {
+				return nil, fmt.Errorf("failed to paginate DescribeSecurityGroupRules: %w", err)
+			}
+			for _, rule := range page.SecurityGroupRules {
+				result[aws.ToString(rule.SecurityGroupRuleId)] = rule
+			}
+		}
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]types.SecurityGroupRule, 0, len(rules))
+	for _, id := range slices.Sorted(maps.Keys(rules)) {
+		result = append(result, rules[id])
+	}
+	return result, nil
+}
@@ -0,0 +1,56 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchSecurityGroupRules(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	mockClient.On("DescribeSecurityGroupRules", mock.Anything, mock.MatchedBy(func(input *ec2.DescribeSecurityGroupRulesInput) bool {
+		return len(input.Filters) == 1 && aws.ToString(input.Filters[0].Name) == "group-id" && len(input.Filters[0].Values) == 2
+	})).Return(&ec2.DescribeSecurityGroupRulesOutput{
+		SecurityGroupRules: []types.SecurityGroupRule{
+			{SecurityGroupRuleId: aws.String("sgr-2"), GroupId: aws.String("sg-db")},
+			{SecurityGroupRuleId: aws.String("sgr-1"), GroupId: aws.String("sg-web"), Description: aws.String("allow from payments API")},
+		},
+	}, nil)
+
+	rules, err := client.FetchSecurityGroupRules(context.Background(), []string{"sg-web", "sg-db"})
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "sgr-1", aws.ToString(rules[0].SecurityGroupRuleId))
+	assert.Equal(t, "allow from payments API", aws.ToString(rules[0].Description))
+	assert.Equal(t, "sgr-2", aws.ToString(rules[1].SecurityGroupRuleId))
+	mockClient.AssertExpectations(t)
+}
+
+func TestFetchSecurityGroupRules_Empty(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	rules, err := client.FetchSecurityGroupRules(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+	mockClient.AssertExpectations(t)
+}
+
+func TestFetchSecurityGroupRules_Error(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	mockClient.On("DescribeSecurityGroupRules", mock.Anything, mock.Anything).Return(nil, errors.New("UnauthorizedOperation"))
+
+	_, err := client.FetchSecurityGroupRules(context.Background(), []string{"sg-web"})
+	assert.ErrorContains(t, err, "UnauthorizedOperation")
+}
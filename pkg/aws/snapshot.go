@@ -0,0 +1,408 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Snapshot file names. The files have the same shape as the output of
+// `aws ec2 describe-network-interfaces`, `aws ec2 describe-security-groups` and
+// `aws ec2 describe-managed-prefix-lists` and `aws ec2 describe-security-group-rules`, the prefix
+// lists carrying their Entries as well.
+const (
+	NetworkInterfacesFile  = "network-interfaces.json"
+	SecurityGroupsFile     = "security-groups.json"
+	PrefixListsFile        = "prefix-lists.json"
+	SecurityGroupRulesFile = "security-group-rules.json"
+)
+
+// networkInterfacesSnapshot is the content of the network interfaces snapshot file
+type networkInterfacesSnapshot struct {
+	NetworkInterfaces []types.NetworkInterface
+}
+
+// securityGroupsSnapshot is the content of the security groups snapshot file
+type securityGroupsSnapshot struct {
+	SecurityGroups []types.SecurityGroup
+}
+
+// prefixListsSnapshot is the content of the prefix lists snapshot file
+type prefixListsSnapshot struct {
+	PrefixLists []SnapshotPrefixList
+}
+
+// securityGroupRulesSnapshot is the content of the security group rules snapshot file
+type securityGroupRulesSnapshot struct {
+	SecurityGroupRules []types.SecurityGroupRule
+}
+
+// SnapshotPrefixList is a managed prefix list saved together with its entries
+type SnapshotPrefixList struct {
+	types.ManagedPrefixList
+	Entries []types.PrefixListEntry
+}
+
+// SnapshotEC2API is a file-backed EC2API serving network interfaces, security groups, managed
+// prefix lists and security group rules saved in a snapshot directory
+type SnapshotEC2API struct {
+	networkInterfaces  []types.NetworkInterface
+	securityGroups     []types.SecurityGroup
+	prefixLists        []SnapshotPrefixList
+	securityGroupRules []types.SecurityGroupRule
+}
+
+var _ EC2API = (*SnapshotEC2API)(nil)
+
+// NewSnapshotEC2API loads the network interfaces, security groups, prefix lists and security group
+// rules of a snapshot directory. The prefix lists and rules files are optional since older snapshots
+// do not have them.
+func NewSnapshotEC2API(dir string) (*SnapshotEC2API, error) {
+	var enis networkInterfacesSnapshot
+	if err := readSnapshotFile(dir, NetworkInterfacesFile, &enis); err != nil {
+		return nil, err
+	}
+	var sgs securityGroupsSnapshot
+	if err := readSnapshotFile(dir, SecurityGroupsFile, &sgs); err != nil {
+		return nil, err
+	}
+	var prefixLists prefixListsSnapshot
+	if _, err := os.Stat(filepath.Join(dir, PrefixListsFile)); err == nil {
+		if err := readSnapshotFile(dir, PrefixListsFile, &prefixLists); err != nil {
+			return nil, err
+		}
+	}
+	var rules securityGroupRulesSnapshot
+	if _, err := os.Stat(filepath.Join(dir, SecurityGroupRulesFile)); err == nil {
+		if err := readSnapshotFile(dir, SecurityGroupRulesFile, &rules); err != nil {
+			return nil, err
+		}
+	}
+	return &SnapshotEC2API{
+		networkInterfaces:  enis.NetworkInterfaces,
+		securityGroups:     sgs.SecurityGroups,
+		prefixLists:        prefixLists.PrefixLists,
+		securityGroupRules: rules.SecurityGroupRules,
+	}, nil
+}
+
+// NewStaticEC2API serves the given network interfaces and security groups the way a snapshot does
+func NewStaticEC2API(networkInterfaces []types.NetworkInterface, securityGroups []types.SecurityGroup) *SnapshotEC2API {
+	return &SnapshotEC2API{
+		networkInterfaces: networkInterfaces,
+		securityGroups:    securityGroups,
+	}
+}
+
+// WithPrefixLists sets the managed prefix lists the API serves and returns the API
+func (s *SnapshotEC2API) WithPrefixLists(prefixLists []SnapshotPrefixList) *SnapshotEC2API {
+	s.prefixLists = prefixLists
+	return s
+}
+
+// WithSecurityGroupRules sets the security group rules the API serves and returns the API
+func (s *SnapshotEC2API) WithSecurityGroupRules(rules []types.SecurityGroupRule) *SnapshotEC2API {
+	s.securityGroupRules = rules
+	return s
+}
+
+func readSnapshotFile(dir, name string, out any) error {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot file %s: %w", name, err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to decode snapshot file %s: %w", name, err)
+	}
+	return nil
+}
+
+// networkInterfaceFilters maps the supported DescribeNetworkInterfaces filter names to the ENI values they match
+var networkInterfaceFilters = map[string]func(types.NetworkInterface) []string{
+	"addresses.private-ip-address": func(eni types.NetworkInterface) []string {
+		var ips []string
+		for _, ip := range eni.PrivateIpAddresses {
+			ips = append(ips, aws.ToString(ip.PrivateIpAddress))
+		}
+		return ips
+	},
+	"attachment.instance-id": func(eni types.NetworkInterface) []string {
+		if eni.Attachment == nil {
+			return nil
+		}
+		return []string{aws.ToString(eni.Attachment.InstanceId)}
+	},
+	"description": func(eni types.NetworkInterface) []string {
+		return []string{aws.ToString(eni.Description)}
+	},
+	"group-id": func(eni types.NetworkInterface) []string {
+		var ids []string
+		for _, g := range eni.Groups {
+			ids = append(ids, aws.ToString(g.GroupId))
+		}
+		return ids
+	},
+	"network-interface-id": func(eni types.NetworkInterface) []string {
+		return []string{aws.ToString(eni.NetworkInterfaceId)}
+	},
+	"vpc-id": func(eni types.NetworkInterface) []string {
+		return []string{aws.ToString(eni.VpcId)}
+	},
+}
+
+// securityGroupFilters maps the supported DescribeSecurityGroups filter names to the security group values they match
+var securityGroupFilters = map[string]func(types.SecurityGroup) []string{
+	"group-id": func(sg types.SecurityGroup) []string {
+		return []string{aws.ToString(sg.GroupId)}
+	},
+	"group-name": func(sg types.SecurityGroup) []string {
+		return []string{aws.ToString(sg.GroupName)}
+	},
+	"vpc-id": func(sg types.SecurityGroup) []string {
+		return []string{aws.ToString(sg.VpcId)}
+	},
+}
+
+// prefixListFilters maps the supported DescribeManagedPrefixLists filter names to the prefix list values they match
+var prefixListFilters = map[string]func(SnapshotPrefixList) []string{
+	"owner-id": func(pl SnapshotPrefixList) []string {
+		return []string{aws.ToString(pl.OwnerId)}
+	},
+	"prefix-list-id": func(pl SnapshotPrefixList) []string {
+		return []string{aws.ToString(pl.PrefixListId)}
+	},
+	"prefix-list-name": func(pl SnapshotPrefixList) []string {
+		return []string{aws.ToString(pl.PrefixListName)}
+	},
+}
+
+// securityGroupRuleFilters maps the supported DescribeSecurityGroupRules filter names to the rule values they match
+var securityGroupRuleFilters = map[string]func(types.SecurityGroupRule) []string{
+	"group-id": func(rule types.SecurityGroupRule) []string {
+		return []string{aws.ToString(rule.GroupId)}
+	},
+	"security-group-rule-id": func(rule types.SecurityGroupRule) []string {
+		return []string{aws.ToString(rule.SecurityGroupRuleId)}
+	},
+}
+
+// DescribeNetworkInterfaces returns the saved network interfaces matching the input in a single page
+func (s *SnapshotEC2API) DescribeNetworkInterfaces(_ context.Context, params *ec2.DescribeNetworkInterfacesInput, _ ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	var ids []string
+	var filters []types.Filter
+	if params != nil {
+		ids = params.NetworkInterfaceIds
+		filters = params.Filters
+	}
+
+	var result []types.NetworkInterface
+	for _, eni := range s.networkInterfaces {
+		if len(ids) > 0 && !slices.Contains(ids, aws.ToString(eni.NetworkInterfaceId)) {
+			continue
+		}
+		ok, err := matchFilters(eni, filters, networkInterfaceFilters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, eni)
+		}
+	}
+	return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: result}, nil
+}
+
+// DescribeSecurityGroups returns the saved security groups matching the input in a single page
+func (s *SnapshotEC2API) DescribeSecurityGroups(_ context.Context, params *ec2.DescribeSecurityGroupsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	var ids, names []string
+	var filters []types.Filter
+	if params != nil {
+		ids = params.GroupIds
+		names = params.GroupNames
+		filters = params.Filters
+	}
+
+	var result []types.SecurityGroup
+	for _, sg := range s.securityGroups {
+		if len(ids) > 0 && !slices.Contains(ids, aws.ToString(sg.GroupId)) {
+			continue
+		}
+		if len(names) > 0 && !slices.Contains(names, aws.ToString(sg.GroupName)) {
+			continue
+		}
+		ok, err := matchFilters(sg, filters, securityGroupFilters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, sg)
+		}
+	}
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: result}, nil
+}
+
+// DescribeManagedPrefixLists returns the saved prefix lists matching the input in a single page
+func (s *SnapshotEC2API) DescribeManagedPrefixLists(_ context.Context, params *ec2.DescribeManagedPrefixListsInput, _ ...func(*ec2.Options)) (*ec2.DescribeManagedPrefixListsOutput, error) {
+	var ids []string
+	var filters []types.Filter
+	if params != nil {
+		ids = params.PrefixListIds
+		filters = params.Filters
+	}
+
+	var result []types.ManagedPrefixList
+	for _, pl := range s.prefixLists {
+		if len(ids) > 0 && !slices.Contains(ids, aws.ToString(pl.PrefixListId)) {
+			continue
+		}
+		ok, err := matchFilters(pl, filters, prefixListFilters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, pl.ManagedPrefixList)
+		}
+	}
+	return &ec2.DescribeManagedPrefixListsOutput{PrefixLists: result}, nil
+}
+
+// GetManagedPrefixListEntries returns the saved entries of a prefix list in a single page
+func (s *SnapshotEC2API) GetManagedPrefixListEntries(_ context.Context, params *ec2.GetManagedPrefixListEntriesInput, _ ...func(*ec2.Options)) (*ec2.GetManagedPrefixListEntriesOutput, error) {
+	var id string
+	if params != nil {
+		id = aws.ToString(params.PrefixListId)
+	}
+	for _, pl := range s.prefixLists {
+		if aws.ToString(pl.PrefixListId) == id {
+			return &ec2.GetManagedPrefixListEntriesOutput{Entries: pl.Entries}, nil
+		}
+	}
+	return nil, fmt.Errorf("prefix list %s not found in snapshot", id)
+}
+
+// DescribeSecurityGroupRules returns the saved security group rules matching the input in a single page
+func (s *SnapshotEC2API) DescribeSecurityGroupRules(_ context.Context, params *ec2.DescribeSecurityGroupRulesInput, _ ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	var ids []string
+	var filters []types.Filter
+	if params != nil {
+		ids = params.SecurityGroupRuleIds
+		filters = params.Filters
+	}
+
+	var result []types.SecurityGroupRule
+	for _, rule := range s.securityGroupRules {
+		if len(ids) > 0 && !slices.Contains(ids, aws.ToString(rule.SecurityGroupRuleId)) {
+			continue
+		}
+		ok, err := matchFilters(rule, filters, securityGroupRuleFilters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, rule)
+		}
+	}
+	return &ec2.DescribeSecurityGroupRulesOutput{SecurityGroupRules: result}, nil
+}
+
+// matchFilters reports whether the item matches every filter. An item matches a filter when
+// any of its values equals any of the filter values.
+func matchFilters[T any](item T, filters []types.Filter, supported map[string]func(T) []string) (bool, error) {
+	for _, f := range filters {
+		name := aws.ToString(f.Name)
+		values, ok := supported[name]
+		if !ok {
+			return false, fmt.Errorf("filter %s is not supported by snapshots", name)
+		}
+		if !slices.ContainsFunc(values(item), func(v string) bool { return slices.Contains(f.Values, v) }) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// WriteSnapshot saves every network interface, security group, managed prefix list and security group
+// rule visible to the API into the directory
+func WriteSnapshot(ctx context.Context, api EC2API, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory %s: %w", dir, err)
+	}
+
+	var enis networkInterfacesSnapshot
+	eniPaginator := ec2.NewDescribeNetworkInterfacesPaginator(api, &ec2.DescribeNetworkInterfacesInput{})
+	for eniPaginator.HasMorePages() {
+		page, err := eniPaginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to paginate DescribeNetworkInterfaces: %w", err)
+		}
+		enis.NetworkInterfaces = append(enis.NetworkInterfaces, page.NetworkInterfaces...)
+	}
+	if err := writeSnapshotFile(dir, NetworkInterfacesFile, enis); err != nil {
+		return err
+	}
+
+	var sgs securityGroupsSnapshot
+	sgPaginator := ec2.NewDescribeSecurityGroupsPaginator(api, &ec2.DescribeSecurityGroupsInput{})
+	for sgPaginator.HasMorePages() {
+		page, err := sgPaginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to paginate DescribeSecurityGroups: %w", err)
+		}
+		sgs.SecurityGroups = append(sgs.SecurityGroups, page.SecurityGroups...)
+	}
+	if err := writeSnapshotFile(dir, SecurityGroupsFile, sgs); err != nil {
+		return err
+	}
+
+	var prefixLists prefixListsSnapshot
+	plPaginator := ec2.NewDescribeManagedPrefixListsPaginator(api, &ec2.DescribeManagedPrefixListsInput{})
+	for plPaginator.HasMorePages() {
+		page, err := plPaginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to paginate DescribeManagedPrefixLists: %w", err)
+		}
+		for _, pl := range page.PrefixLists {
+			saved := SnapshotPrefixList{ManagedPrefixList: pl}
+			entryPaginator := ec2.NewGetManagedPrefixListEntriesPaginator(api, &ec2.GetManagedPrefixListEntriesInput{PrefixListId: pl.PrefixListId})
+			for entryPaginator.HasMorePages() {
+				entries, err := entryPaginator.NextPage(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to paginate GetManagedPrefixListEntries: %w", err)
+				}
+				saved.Entries = append(saved.Entries, entries.Entries...)
+			}
+			prefixLists.PrefixLists = append(prefixLists.PrefixLists, saved)
+		}
+	}
+	if err := writeSnapshotFile(dir, PrefixListsFile, prefixLists); err != nil {
+		return err
+	}
+
+	var rules securityGroupRulesSnapshot
+	rulePaginator := ec2.NewDescribeSecurityGroupRulesPaginator(api, &ec2.DescribeSecurityGroupRulesInput{})
+	for rulePaginator.HasMorePages() {
+		page, err := rulePaginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to paginate DescribeSecurityGroupRules: %w", err)
+		}
+		rules.SecurityGroupRules = append(rules.SecurityGroupRules, page.SecurityGroupRules...)
+	}
+	return writeSnapshotFile(dir, SecurityGroupRulesFile, rules)
+}
+
+func writeSnapshotFile(dir, name string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot file %s: %w", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot file %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSnapshotEC2API_RoundTrip(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, mock.Anything).Return(
+		&ec2.DescribeNetworkInterfacesOutput{
+			NetworkInterfaces: []types.NetworkInterface{
+				{
+					NetworkInterfaceId: aws.String("eni-1"),
+					InterfaceType:      types.NetworkInterfaceTypeBranch,
+					PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String("10.0.0.1")}},
+					Groups:             []types.GroupIdentifier{{GroupId: aws.String("sg-1")}},
+				},
+				{
+					NetworkInterfaceId: aws.String("eni-2"),
+					PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String("10.0.0.2")}},
+					Groups:             []types.GroupIdentifier{{GroupId: aws.String("sg-2")}},
+				},
+			},
+		}, nil)
+	mockClient.On("DescribeSecurityGroups", mock.Anything, mock.Anything).Return(
+		&ec2.DescribeSecurityGroupsOutput{
+			SecurityGroups: []types.SecurityGroup{
+				{GroupId: aws.String("sg-1"), GroupName: aws.String("web")},
+				{GroupId: aws.String("sg-2"), GroupName: aws.String("db")},
+			},
+		}, nil)
+	mockClient.On("DescribeManagedPrefixLists", mock.Anything, mock.Anything).Return(
+		&ec2.DescribeManagedPrefixListsOutput{
+			PrefixLists: []types.ManagedPrefixList{{PrefixListId: aws.String("pl-1"), PrefixListName: aws.String("office")}},
+		}, nil)
+	mockClient.On("GetManagedPrefixListEntries", mock.Anything, mock.Anything).Return(
+		&ec2.GetManagedPrefixListEntriesOutput{
+			Entries: []types.PrefixListEntry{{Cidr: aws.String("192.0.2.0/24")}},
+		}, nil)
+	mockClient.On("DescribeSecurityGroupRules", mock.Anything, mock.Anything).Return(
+		&ec2.DescribeSecurityGroupRulesOutput{
+			SecurityGroupRules: []types.SecurityGroupRule{
+				{SecurityGroupRuleId: aws.String("sgr-1"), GroupId: aws.String("sg-1")},
+				{SecurityGroupRuleId: aws.String("sgr-2"), GroupId: aws.String("sg-2")},
+			},
+		}, nil)
+
+	dir := t.TempDir()
+	assert.NoError(t, WriteSnapshot(context.Background(), mockClient, dir))
+
+	api, err := NewSnapshotEC2API(dir)
+	assert.NoError(t, err)
+	client, err := NewClient(api, RequestOptions{})
+	assert.NoError(t, err)
+
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"},
+		},
+	}
+	result, err := client.FetchSecurityGroupsByPods(context.Background(), pods)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, "eni-1", result[0].ENI)
+	assert.Equal(t, "pod", result[0].AttachmentLevel)
+	assert.Equal(t, "web", aws.ToString(result[0].SecurityGroups[0].GroupName))
+
+	usage, err := client.FetchPodsBySecurityGroups(context.Background(), []string{"db"}, pods)
+	assert.NoError(t, err)
+	assert.Len(t, usage, 1)
+	assert.Len(t, usage[0].UnmatchedENIs, 1)
+
+	peers, err := client.ResolvePeers(context.Background(), []types.SecurityGroup{{
+		GroupId: aws.String("sg-2"),
+		IpPermissions: []types.IpPermission{{
+			UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: aws.String("sg-1")}},
+			PrefixListIds:    []types.PrefixListId{{PrefixListId: aws.String("pl-1")}},
+		}},
+	}}, pods, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default/web"}, peers.SecurityGroups["sg-1"].Pods)
+	assert.Equal(t, PrefixList{ID: "pl-1", Name: "office", CIDRs: []string{"192.0.2.0/24"}}, peers.PrefixLists["pl-1"])
+
+	rules, err := client.FetchSecurityGroupRules(context.Background(), []string{"sg-2"})
+	assert.NoError(t, err)
+	assert.Len(t, rules, 1)
+	assert.Equal(t, "sgr-2", aws.ToString(rules[0].SecurityGroupRuleId))
+}
+
+func TestNewSnapshotEC2API_CLIOutput(t *testing.T) {
+	dir := t.TempDir()
+	enis := `{"NetworkInterfaces": [{"NetworkInterfaceId": "eni-1", "InterfaceType": "interface", "Description": "aws-K8S-i-0123", "PrivateIpAddresses": [{"PrivateIpAddress": "10.0.0.5", "Primary": true}], "Groups": [{"GroupId": "sg-1", "GroupName": "node"}], "Attachment": {"AttachTime": "2024-01-01T00:00:00+00:00", "InstanceId": "i-0123"}}]}`
+	sgs := `{"SecurityGroups": [{"GroupId": "sg-1", "GroupName": "node", "IpPermissions": [{"IpProtocol": "tcp", "FromPort": 443, "ToPort": 443, "IpRanges": [{"CidrIp": "10.0.0.0/8"}]}]}]}`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, NetworkInterfacesFile), []byte(enis), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, SecurityGroupsFile), []byte(sgs), 0o644))
+
+	api, err := NewSnapshotEC2API(dir)
+	assert.NoError(t, err)
+
+	out, err := api.DescribeNetworkInterfaces(context.Background(), &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{{Name: aws.String("addresses.private-ip-address"), Values: []string{"10.0.0.5"}}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, out.NetworkInterfaces, 1)
+	assert.Equal(t, "i-0123", aws.ToString(out.NetworkInterfaces[0].Attachment.InstanceId))
+
+	sgOut, err := api.DescribeSecurityGroups(context.Background(), &ec2.DescribeSecurityGroupsInput{GroupIds: []string{"sg-1"}})
+	assert.NoError(t, err)
+	assert.Len(t, sgOut.SecurityGroups, 1)
+	assert.Equal(t, int32(443), aws.ToInt32(sgOut.SecurityGroups[0].IpPermissions[0].FromPort))
+
+	_, err = api.DescribeNetworkInterfaces(context.Background(), &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{{Name: aws.String("unsupported"), Values: []string{"x"}}},
+	})
+	assert.Error(t, err)
+}
+
+func TestNewSnapshotEC2API_MissingFile(t *testing.T) {
+	_, err := NewSnapshotEC2API(t.TempDir())
+	assert.Error(t, err)
+}
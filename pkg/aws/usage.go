@@ -0,0 +1,284 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SecurityGroupUsage represents the pods and ENIs that use a security group
+type SecurityGroupUsage struct {
+	SecurityGroup types.SecurityGroup      `json:"securityGroup" yaml:"securityGroup"`
+	Pods          []PodSecurityGroupInfo   `json:"pods" yaml:"pods"`
+	UnmatchedENIs []types.NetworkInterface `json:"unmatchedENIs" yaml:"unmatchedENIs"`
+}
+
+// FetchPodsBySecurityGroups finds every ENI attached to the given security groups and maps the ENIs'
+// private IPs back to pods. The security groups may be given by ID or by name.
+func (c *Client) FetchPodsBySecurityGroups(ctx context.Context, sgRefs []string, pods []corev1.Pod) ([]SecurityGroupUsage, error) {
+	sgs, err := c.ResolveSecurityGroups(ctx, sgRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	sgIDs := make([]string, 0, len(sgs))
+	for _, sg := range sgs {
+		sgIDs = append(sgIDs, aws.ToString(sg.GroupId))
+	}
+
+	eniMap, err := c.GetENIsBySecurityGroups(ctx, sgIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe ENIs: %w", err)
+	}
+
+	eniToSGIDs := make(map[string][]string, len(eniMap))
+	for id, eni := range eniMap {
+		for _, group := range eni.Groups {
+			eniToSGIDs[id] = append(eniToSGIDs[id], aws.ToString(group.GroupId))
+		}
+	}
+	sgMap, err := c.GetSecurityGroupsParallel(ctx, collectUniqueSGIDs(eniToSGIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	ipToPods := make(map[string][]corev1.Pod)
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		ipToPods[pod.Status.PodIP] = append(ipToPods[pod.Status.PodIP], pod)
+	}
+
+	result := make([]SecurityGroupUsage, 0, len(sgs))
+	for _, sg := range sgs {
+		usage := SecurityGroupUsage{SecurityGroup: sg}
+		sgID := aws.ToString(sg.GroupId)
+		for _, eniID := range sortedENIIDs(eniMap) {
+			eni := eniMap[eniID]
+			if !hasGroup(eni, sgID) {
+				continue
+			}
+			matched := false
+			for _, ip := range eni.PrivateIpAddresses {
+				for _, pod := range ipToPods[aws.ToString(ip.PrivateIpAddress)] {
+					matched = true
+					var groups []types.SecurityGroup
+					var missing []string
+					for _, id := range eniToSGIDs[eniID] {
+						if group, ok := sgMap[id]; ok {
+							groups = append(groups, group)
+						} else {
+							missing = append(missing, id)
+						}
+					}
+					info := PodSecurityGroupInfo{
+						Pod:             pod,
+						ENI:             eniID,
+						SecurityGroups:  groups,
+						AttachmentLevel: determineAttachmentLevel(eni),
+					}
+					if isFargatePod(pod) {
+						info.AttachmentLevel = "pod"
+					}
+					info.Status, info.Reason = mappedStatus(pod, missing)
+					usage.Pods = append(usage.Pods, info)
+				}
+			}
+			if !matched {
+				usage.UnmatchedENIs = append(usage.UnmatchedENIs, eni)
+			}
+		}
+		sort.SliceStable(usage.Pods, func(i, j int) bool {
+			if usage.Pods[i].Pod.Namespace != usage.Pods[j].Pod.Namespace {
+				return usage.Pods[i].Pod.Namespace < usage.Pods[j].Pod.Namespace
+			}
+			return usage.Pods[i].Pod.Name < usage.Pods[j].Pod.Name
+		})
+		result = append(result, usage)
+	}
+	return result, nil
+}
+
+// ResolveSecurityGroups looks up security groups by ID (sg-...), by name, or by VPC and name
+// (vpc-.../name). Names are only unique within a VPC, so a name matching security groups of several
+// VPCs is an error listing them, to be resolved with an ID or a VPC qualified name.
+// It returns an error if any of the references does not resolve to a security group.
+func (c *Client) ResolveSecurityGroups(ctx context.Context, refs []string) ([]types.SecurityGroup, error) {
+	var ids, names []string
+	for _, ref := range refs {
+		if strings.HasPrefix(ref, "sg-") {
+			ids = append(ids, ref)
+		} else {
+			_, name := splitSecurityGroupName(ref)
+			names = append(names, name)
+		}
+	}
+
+	byID, err := c.GetSecurityGroupsParallel(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byName, err := c.getSecurityGroupsByNames(ctx, names, c.requests.batchOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	var result []types.SecurityGroup
+	for _, ref := range refs {
+		sg, ok := byID[ref]
+		if !ok && !strings.HasPrefix(ref, "sg-") {
+			sg, ok, err = lookupSecurityGroupName(byName, ref)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("security group not found: %s", ref)
+		}
+		id := aws.ToString(sg.GroupId)
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, sg)
+	}
+	return result, nil
+}
+
+// getSecurityGroupsByNames looks up the security groups with the names in batches, grouped by name,
+// with the rate limit and retries of the batch options
+func (c *Client) getSecurityGroupsByNames(ctx context.Context, names []string, opts utils.BatchOptions) (map[string][]types.SecurityGroup, error) {
+	byName := make(map[string][]types.SecurityGroup)
+	if len(names) == 0 {
+		return byName, nil
+	}
+
+	byID, err := utils.RunBatchParallel(ctx, names, opts, func(ctx context.Context, batch []string) (map[string]types.SecurityGroup, error) {
+		input := &ec2.DescribeSecurityGroupsInput{
+			Filters: []types.Filter{
+				{
+					Name:   aws.String("group-name"),
+					Values: batch,
+				},
+			},
+		}
+		paginator := ec2.NewDescribeSecurityGroupsPaginator(c.ec2Client, input)
+		result := make(map[string]types.SecurityGroup)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx, withoutSDKRetries)
+			if err != nil {
+				return nil, fmt.Errorf("DescribeSecurityGroups failed: %w", err)
+			}
+			for _, sg := range page.SecurityGroups {
+				result[aws.ToString(sg.GroupId)] = sg
+			}
+		}
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sg := range byID {
+		name := aws.ToString(sg.GroupName)
+		byName[name] = append(byName[name], sg)
+	}
+	return byName, nil
+}
+
+// splitSecurityGroupName splits a vpc-.../name reference into its VPC and name. The VPC is empty
+// for a plain name.
+func splitSecurityGroupName(ref string) (string, string) {
+	if vpc, name, ok := strings.Cut(ref, "/"); ok && strings.HasPrefix(vpc, "vpc-") {
+		return vpc, name
+	}
+	return "", ref
+}
+
+// lookupSecurityGroupName returns the security group of a name or VPC qualified name among the
+// security groups found by name
+func lookupSecurityGroupName(byName map[string][]types.SecurityGroup, ref string) (types.SecurityGroup, bool, error) {
+	vpc, name := splitSecurityGroupName(ref)
+	var matches []types.SecurityGroup
+	for _, sg := range byName[name] {
+		if vpc == "" || aws.ToString(sg.VpcId) == vpc {
+			matches = append(matches, sg)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return types.SecurityGroup{}, false, nil
+	case 1:
+		return matches[0], true, nil
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return aws.ToString(matches[i].GroupId) < aws.ToString(matches[j].GroupId)
+	})
+	candidates := make([]string, 0, len(matches))
+	for _, sg := range matches {
+		candidates = append(candidates, fmt.Sprintf("%s (%s)", aws.ToString(sg.GroupId), aws.ToString(sg.VpcId)))
+	}
+	return types.SecurityGroup{}, false, fmt.Errorf("security group name %s is ambiguous, it matches %s; use the ID or vpc-id/name", ref, strings.Join(candidates, ", "))
+}
+
+// GetENIsBySecurityGroups retrieves all network interfaces attached to any of the given security groups
+func (c *Client) GetENIsBySecurityGroups(ctx context.Context, sgIDs []string) (map[string]types.NetworkInterface, error) {
+	if len(sgIDs) == 0 {
+		return nil, fmt.Errorf("input list of security group IDs is empty")
+	}
+
+	return utils.RunBatchParallel(ctx, sgIDs, c.requests.batchOptions(), func(ctx context.Context, batch []string) (map[string]types.NetworkInterface, error) {
+		input := &ec2.DescribeNetworkInterfacesInput{
+			Filters: []types.Filter{
+				{
+					Name:   aws.String("group-id"),
+					Values: batch,
+				},
+			},
+		}
+
+		paginator := ec2.NewDescribeNetworkInterfacesPaginator(c.ec2Client, input)
+		result := make(map[string]types.NetworkInterface)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx, withoutSDKRetries)
+			if err != nil {
+				return nil, fmt.Errorf("failed to paginate DescribeNetworkInterfaces: %w", err)
+			}
+			for _, eni := range page.NetworkInterfaces {
+				result[aws.ToString(eni.NetworkInterfaceId)] = eni
+			}
+		}
+		return result, nil
+	})
+}
+
+// hasGroup reports whether the ENI is attached to the security group
+func hasGroup(eni types.NetworkInterface, sgID string) bool {
+	for _, group := range eni.Groups {
+		if aws.ToString(group.GroupId) == sgID {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedENIIDs returns the ENI IDs of the map in sorted order
+func sortedENIIDs(eniMap map[string]types.NetworkInterface) []string {
+	ids := make([]string, 0, len(eniMap))
+	for id := range eniMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,140 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFetchPodsBySecurityGroups_Success(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.9"},
+		},
+	}
+
+	mockClient.On("DescribeSecurityGroups", mock.Anything, mock.MatchedBy(func(in *ec2.DescribeSecurityGroupsInput) bool {
+		return len(in.Filters) == 1 && aws.ToString(in.Filters[0].Name) == "group-name"
+	})).Return(&ec2.DescribeSecurityGroupsOutput{
+		SecurityGroups: []types.SecurityGroup{{GroupId: aws.String("sg-1"), GroupName: aws.String("web")}},
+	}, nil).Once()
+
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, mock.MatchedBy(func(in *ec2.DescribeNetworkInterfacesInput) bool {
+		return aws.ToString(in.Filters[0].Name) == "group-id" && in.Filters[0].Values[0] == "sg-1"
+	})).Return(&ec2.DescribeNetworkInterfacesOutput{
+		NetworkInterfaces: []types.NetworkInterface{
+			{
+				NetworkInterfaceId: aws.String("eni-1"),
+				InterfaceType:      "branch",
+				Groups:             []types.GroupIdentifier{{GroupId: aws.String("sg-1")}, {GroupId: aws.String("sg-2")}},
+				PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String("10.0.0.1")}},
+			},
+			{
+				NetworkInterfaceId: aws.String("eni-2"),
+				Groups:             []types.GroupIdentifier{{GroupId: aws.String("sg-1")}},
+				PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String("10.0.0.50")}},
+			},
+		},
+	}, nil)
+
+	mockClient.On("DescribeSecurityGroups", mock.Anything, mock.MatchedBy(func(in *ec2.DescribeSecurityGroupsInput) bool {
+		return len(in.GroupIds) > 0
+	})).Return(&ec2.DescribeSecurityGroupsOutput{
+		SecurityGroups: []types.SecurityGroup{
+			{GroupId: aws.String("sg-1"), GroupName: aws.String("web")},
+			{GroupId: aws.String("sg-2"), GroupName: aws.String("common")},
+		},
+	}, nil)
+
+	result, err := client.FetchPodsBySecurityGroups(context.Background(), []string{"web"}, pods)
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, "sg-1", aws.ToString(result[0].SecurityGroup.GroupId))
+	assert.Len(t, result[0].Pods, 1)
+	assert.Equal(t, "pod1", result[0].Pods[0].Pod.Name)
+	assert.Equal(t, "eni-1", result[0].Pods[0].ENI)
+	assert.Equal(t, "pod", result[0].Pods[0].AttachmentLevel)
+	assert.Len(t, result[0].Pods[0].SecurityGroups, 2)
+	assert.Len(t, result[0].UnmatchedENIs, 1)
+	assert.Equal(t, "eni-2", aws.ToString(result[0].UnmatchedENIs[0].NetworkInterfaceId))
+}
+
+func TestResolveSecurityGroups_NotFound(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	mockClient.On("DescribeSecurityGroups", mock.Anything, mock.Anything).Return(
+		&ec2.DescribeSecurityGroupsOutput{}, nil,
+	)
+
+	_, err := client.ResolveSecurityGroups(context.Background(), []string{"missing"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "security group not found: missing")
+}
+
+func TestResolveSecurityGroups_NameInSeveralVPCs(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	mockClient.On("DescribeSecurityGroups", mock.Anything, mock.MatchedBy(func(in *ec2.DescribeSecurityGroupsInput) bool {
+		return len(in.Filters) == 1 && assert.ObjectsAreEqual([]string{"default"}, in.Filters[0].Values)
+	})).Return(&ec2.DescribeSecurityGroupsOutput{
+		SecurityGroups: []types.SecurityGroup{
+			{GroupId: aws.String("sg-2"), GroupName: aws.String("default"), VpcId: aws.String("vpc-b")},
+			{GroupId: aws.String("sg-1"), GroupName: aws.String("default"), VpcId: aws.String("vpc-a")},
+		},
+	}, nil)
+
+	_, err := client.ResolveSecurityGroups(context.Background(), []string{"default"})
+	assert.EqualError(t, err, "security group name default is ambiguous, it matches sg-1 (vpc-a), sg-2 (vpc-b); use the ID or vpc-id/name")
+
+	sgs, err := client.ResolveSecurityGroups(context.Background(), []string{"vpc-b/default"})
+	assert.NoError(t, err)
+	assert.Len(t, sgs, 1)
+	assert.Equal(t, "sg-2", aws.ToString(sgs[0].GroupId))
+
+	_, err = client.ResolveSecurityGroups(context.Background(), []string{"vpc-c/default"})
+	assert.EqualError(t, err, "security group not found: vpc-c/default")
+}
+
+func TestGetENIsBySecurityGroups_Error(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	mockClient.On("DescribeNetworkInterfaces", mock.Anything, mock.Anything).Return(
+		nil, fmt.Errorf("aws error"),
+	)
+
+	_, err := client.GetENIsBySecurityGroups(context.Background(), []string{"sg-1"})
+
+	assert.Error(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestGetENIsBySecurityGroups_Empty(t *testing.T) {
+	mockClient := new(MockEC2Client)
+	client := &Client{ec2Client: mockClient}
+
+	_, err := client.GetENIsBySecurityGroups(context.Background(), nil)
+
+	assert.Error(t, err)
+	mockClient.AssertNotCalled(t, "DescribeNetworkInterfaces")
+}
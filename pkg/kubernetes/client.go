@@ -5,21 +5,58 @@ import (
 	"context"
 	"fmt"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
 // Interface defines the methods for interacting with Kubernetes.
 type Interface interface {
 	GetPod(ctx context.Context, name, namespace string) (*corev1.Pod, error)
-	ListPods(ctx context.Context, namespace string) ([]corev1.Pod, error)
+	ListPods(ctx context.Context, namespace string, selector PodSelector) ([]corev1.Pod, error)
+	WatchPods(ctx context.Context, namespace, resourceVersion string, selector PodSelector) (watch.Interface, error)
+	ListReplicaSets(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error)
+	GetDeployment(ctx context.Context, name, namespace string) (*appsv1.Deployment, error)
+	ListDeployments(ctx context.Context, namespace string) ([]appsv1.Deployment, error)
+	GetStatefulSet(ctx context.Context, name, namespace string) (*appsv1.StatefulSet, error)
+	ListStatefulSets(ctx context.Context, namespace string) ([]appsv1.StatefulSet, error)
+	GetDaemonSet(ctx context.Context, name, namespace string) (*appsv1.DaemonSet, error)
+	ListDaemonSets(ctx context.Context, namespace string) ([]appsv1.DaemonSet, error)
+	GetJob(ctx context.Context, name, namespace string) (*batchv1.Job, error)
+	ListJobs(ctx context.Context, namespace string) ([]batchv1.Job, error)
+	GetCronJob(ctx context.Context, name, namespace string) (*batchv1.CronJob, error)
+	ListCronJobs(ctx context.Context, namespace string) ([]batchv1.CronJob, error)
+	ListServiceAccounts(ctx context.Context, namespace string) ([]corev1.ServiceAccount, error)
+	ListSecurityGroupPolicies(ctx context.Context, namespace string) ([]SecurityGroupPolicy, error)
+	GetNode(ctx context.Context, name string) (*corev1.Node, error)
+	ListNodes(ctx context.Context) ([]corev1.Node, error)
+	GetService(ctx context.Context, name, namespace string) (*corev1.Service, error)
+	ListServices(ctx context.Context, namespace string) ([]corev1.Service, error)
+	ListEndpointSlices(ctx context.Context, namespace string) ([]discoveryv1.EndpointSlice, error)
+	ListIngresses(ctx context.Context, namespace string) ([]networkingv1.Ingress, error)
+	ListIngressClasses(ctx context.Context) ([]networkingv1.IngressClass, error)
+	ListNetworkPolicies(ctx context.Context, namespace string) ([]networkingv1.NetworkPolicy, error)
+	ListNamespaces(ctx context.Context) ([]corev1.Namespace, error)
+}
+
+// PodSelector narrows down the pods returned by ListPods and WatchPods with kubectl style label
+// and field selectors. The zero value selects every pod.
+type PodSelector struct {
+	Label string
+	Field string
 }
 
 // Client is a client for interacting with the Kubernetes API.
 type Client struct {
 	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
 }
 
 // NewClient creates a new Kubernetes client from the given config flags.
@@ -34,7 +71,18 @@ func NewClient(configFlags *genericclioptions.ConfigFlags) (*Client, error) {
 		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
 
-	return &Client{clientset: clientset}, nil
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	return &Client{clientset: clientset, dynamic: dynamicClient}, nil
+}
+
+// NewClientFromClientsets creates a new Kubernetes client from existing clientsets, such as the
+// fake clientsets of client-go in tests.
+func NewClientFromClientsets(clientset kubernetes.Interface, dynamicClient dynamic.Interface) *Client {
+	return &Client{clientset: clientset, dynamic: dynamicClient}
 }
 
 // GetPod gets a pod by name in a namespace.
@@ -46,11 +94,218 @@ func (c *Client) GetPod(ctx context.Context, name, namespace string) (*corev1.Po
 	return pod, nil
 }
 
-// ListPods lists all pods in a namespace.
-func (c *Client) ListPods(ctx context.Context, namespace string) ([]corev1.Pod, error) {
-	podList, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+// ListPods lists the pods in a namespace that match the selector.
+func (c *Client) ListPods(ctx context.Context, namespace string, selector PodSelector) ([]corev1.Pod, error) {
+	podList, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.Label,
+		FieldSelector: selector.Field,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list pods in namespace %s: %w", namespace, err)
 	}
 	return podList.Items, nil
 }
+
+// WatchPods watches the pods in a namespace that match the selector. An empty resource version
+// starts with an ADDED event for every existing pod.
+func (c *Client) WatchPods(ctx context.Context, namespace, resourceVersion string, selector PodSelector) (watch.Interface, error) {
+	w, err := c.clientset.CoreV1().Pods(namespace).Watch(ctx, metav1.ListOptions{
+		LabelSelector:       selector.Label,
+		FieldSelector:       selector.Field,
+		ResourceVersion:     resourceVersion,
+		AllowWatchBookmarks: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch pods in namespace %s: %w", namespace, err)
+	}
+	return w, nil
+}
+
+// ListReplicaSets lists all replica sets in a namespace.
+func (c *Client) ListReplicaSets(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error) {
+	list, err := c.clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicasets in namespace %s: %w", namespace, err)
+	}
+	return list.Items, nil
+}
+
+// GetDeployment gets a deployment by name in a namespace.
+func (c *Client) GetDeployment(ctx context.Context, name, namespace string) (*appsv1.Deployment, error) {
+	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s in namespace %s: %w", name, namespace, err)
+	}
+	return deployment, nil
+}
+
+// ListDeployments lists all deployments in a namespace.
+func (c *Client) ListDeployments(ctx context.Context, namespace string) ([]appsv1.Deployment, error) {
+	list, err := c.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments in namespace %s: %w", namespace, err)
+	}
+	return list.Items, nil
+}
+
+// GetStatefulSet gets a stateful set by name in a namespace.
+func (c *Client) GetStatefulSet(ctx context.Context, name, namespace string) (*appsv1.StatefulSet, error) {
+	statefulSet, err := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statefulset %s in namespace %s: %w", name, namespace, err)
+	}
+	return statefulSet, nil
+}
+
+// ListStatefulSets lists all stateful sets in a namespace.
+func (c *Client) ListStatefulSets(ctx context.Context, namespace string) ([]appsv1.StatefulSet, error) {
+	list, err := c.clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets in namespace %s: %w", namespace, err)
+	}
+	return list.Items, nil
+}
+
+// GetDaemonSet gets a daemon set by name in a namespace.
+func (c *Client) GetDaemonSet(ctx context.Context, name, namespace string) (*appsv1.DaemonSet, error) {
+	daemonSet, err := c.clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daemonset %s in namespace %s: %w", name, namespace, err)
+	}
+	return daemonSet, nil
+}
+
+// ListDaemonSets lists all daemon sets in a namespace.
+func (c *Client) ListDaemonSets(ctx context.Context, namespace string) ([]appsv1.DaemonSet, error) {
+	list, err := c.clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets in namespace %s: %w", namespace, err)
+	}
+	return list.Items, nil
+}
+
+// GetJob gets a job by name in a namespace.
+func (c *Client) GetJob(ctx context.Context, name, namespace string) (*batchv1.Job, error) {
+	job, err := c.clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job %s in namespace %s: %w", name, namespace, err)
+	}
+	return job, nil
+}
+
+// ListJobs lists all jobs in a namespace.
+func (c *Client) ListJobs(ctx context.Context, namespace string) ([]batchv1.Job, error) {
+	list, err := c.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs in namespace %s: %w", namespace, err)
+	}
+	return list.Items, nil
+}
+
+// GetCronJob gets a cron job by name in a namespace.
+func (c *Client) GetCronJob(ctx context.Context, name, namespace string) (*batchv1.CronJob, error) {
+	cronJob, err := c.clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cronjob %s in namespace %s: %w", name, namespace, err)
+	}
+	return cronJob, nil
+}
+
+// ListCronJobs lists all cron jobs in a namespace.
+func (c *Client) ListCronJobs(ctx context.Context, namespace string) ([]batchv1.CronJob, error) {
+	list, err := c.clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs in namespace %s: %w", namespace, err)
+	}
+	return list.Items, nil
+}
+
+// ListServiceAccounts lists all service accounts in a namespace.
+func (c *Client) ListServiceAccounts(ctx context.Context, namespace string) ([]corev1.ServiceAccount, error) {
+	list, err := c.clientset.CoreV1().ServiceAccounts(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list serviceaccounts in namespace %s: %w", namespace, err)
+	}
+	return list.Items, nil
+}
+
+// GetNode gets a node by name.
+func (c *Client) GetNode(ctx context.Context, name string) (*corev1.Node, error) {
+	node, err := c.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+	return node, nil
+}
+
+// ListNodes lists all nodes of the cluster.
+func (c *Client) ListNodes(ctx context.Context) ([]corev1.Node, error) {
+	list, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	return list.Items, nil
+}
+
+// GetService gets a service by name in a namespace.
+func (c *Client) GetService(ctx context.Context, name, namespace string) (*corev1.Service, error) {
+	service, err := c.clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service %s in namespace %s: %w", name, namespace, err)
+	}
+	return service, nil
+}
+
+// ListServices lists all services in a namespace.
+func (c *Client) ListServices(ctx context.Context, namespace string) ([]corev1.Service, error) {
+	list, err := c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services in namespace %s: %w", namespace, err)
+	}
+	return list.Items, nil
+}
+
+// ListEndpointSlices lists all endpoint slices in a namespace.
+func (c *Client) ListEndpointSlices(ctx context.Context, namespace string) ([]discoveryv1.EndpointSlice, error) {
+	list, err := c.clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list endpointslices in namespace %s: %w", namespace, err)
+	}
+	return list.Items, nil
+}
+
+// ListIngresses lists all ingresses in a namespace.
+func (c *Client) ListIngresses(ctx context.Context, namespace string) ([]networkingv1.Ingress, error) {
+	list, err := c.clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingresses in namespace %s: %w", namespace, err)
+	}
+	return list.Items, nil
+}
+
+// ListIngressClasses lists all ingress classes of the cluster.
+func (c *Client) ListIngressClasses(ctx context.Context) ([]networkingv1.IngressClass, error) {
+	list, err := c.clientset.NetworkingV1().IngressClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingressclasses: %w", err)
+	}
+	return list.Items, nil
+}
+
+// ListNetworkPolicies lists all network policies in a namespace.
+func (c *Client) ListNetworkPolicies(ctx context.Context, namespace string) ([]networkingv1.NetworkPolicy, error) {
+	list, err := c.clientset.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networkpolicies in namespace %s: %w", namespace, err)
+	}
+	return list.Items, nil
+}
+
+// ListNamespaces lists all namespaces of the cluster.
+func (c *Client) ListNamespaces(ctx context.Context) ([]corev1.Namespace, error) {
+	list, err := c.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	return list.Items, nil
+}
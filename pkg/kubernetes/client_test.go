@@ -6,9 +6,14 @@ import (
 	"fmt"
 	"testing"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/kubernetes/fake"
 	k8stesting "k8s.io/client-go/testing"
@@ -64,7 +69,7 @@ func TestClient_ListPods(t *testing.T) {
 	)
 	client := &Client{clientset: clientset}
 
-	pods, err := client.ListPods(context.Background(), "default")
+	pods, err := client.ListPods(context.Background(), "default", PodSelector{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -74,11 +79,28 @@ func TestClient_ListPods(t *testing.T) {
 	}
 }
 
+func TestClient_ListPods_LabelSelector(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Labels: map[string]string{"app": "db"}}},
+	)
+	client := &Client{clientset: clientset}
+
+	pods, err := client.ListPods(context.Background(), "default", PodSelector{Label: "app=web"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pods) != 1 || pods[0].Name != "web" {
+		t.Errorf("expected only pod 'web', got %v", pods)
+	}
+}
+
 func TestClient_ListPods_Empty(t *testing.T) {
 	clientset := fake.NewSimpleClientset()
 	client := &Client{clientset: clientset}
 
-	pods, err := client.ListPods(context.Background(), "default")
+	pods, err := client.ListPods(context.Background(), "default", PodSelector{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -108,8 +130,217 @@ func TestClient_ListPods_Error(t *testing.T) {
 	})
 	client := &Client{clientset: clientset}
 
-	_, err := client.ListPods(context.Background(), "default")
+	_, err := client.ListPods(context.Background(), "default", PodSelector{})
+	if err == nil {
+		t.Fatal("expected an error, but got nil")
+	}
+}
+
+func TestClient_GetDeployment(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+	})
+	client := &Client{clientset: clientset}
+
+	deployment, err := client.GetDeployment(context.Background(), "web", "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if deployment.Name != "web" {
+		t.Errorf("expected deployment name to be 'web', got '%s'", deployment.Name)
+	}
+}
+
+func TestClient_GetDeployment_NotFound(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	client := &Client{clientset: clientset}
+
+	_, err := client.GetDeployment(context.Background(), "web", "default")
+	if err == nil {
+		t.Fatal("expected an error, but got nil")
+	}
+}
+
+func TestClient_GetNode(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+	)
+	client := &Client{clientset: clientset}
+
+	node, err := client.GetNode(context.Background(), "node-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if node.Name != "node-1" {
+		t.Errorf("expected node name to be 'node-1', got '%s'", node.Name)
+	}
+
+	nodes, err := client.ListNodes(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Errorf("expected 2 nodes, got %d", len(nodes))
+	}
+
+	if _, err := client.GetNode(context.Background(), "node-3"); err == nil {
+		t.Fatal("expected an error, but got nil")
+	}
+}
+
+func TestClient_Services(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "data"}},
+		&discoveryv1.EndpointSlice{ObjectMeta: metav1.ObjectMeta{Name: "web-abcde", Namespace: "default"}},
+	)
+	client := &Client{clientset: clientset}
+
+	service, err := client.GetService(context.Background(), "web", "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if service.Name != "web" {
+		t.Errorf("expected service name to be 'web', got '%s'", service.Name)
+	}
+
+	services, err := client.ListServices(context.Background(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(services) != 2 {
+		t.Errorf("expected 2 services, got %d", len(services))
+	}
+
+	slices, err := client.ListEndpointSlices(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slices) != 1 {
+		t.Errorf("expected 1 endpoint slice, got %d", len(slices))
+	}
+}
+
+func TestClient_Ingresses(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "admin", Namespace: "ops"}},
+		&networkingv1.IngressClass{ObjectMeta: metav1.ObjectMeta{Name: "alb"}},
+	)
+	client := &Client{clientset: clientset}
+
+	ingresses, err := client.ListIngresses(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ingresses) != 1 || ingresses[0].Name != "web" {
+		t.Errorf("expected only ingress 'web' in namespace default, got %v", ingresses)
+	}
+
+	classes, err := client.ListIngressClasses(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(classes) != 1 {
+		t.Errorf("expected 1 ingress class, got %d", len(classes))
+	}
+}
+
+func TestClient_NetworkPolicies(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "deny-all", Namespace: "default"}},
+		&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "allow-dns", Namespace: "ops"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ops"}},
+	)
+	client := &Client{clientset: clientset}
+
+	policies, err := client.ListNetworkPolicies(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policies) != 1 || policies[0].Name != "deny-all" {
+		t.Errorf("expected only network policy 'deny-all' in namespace default, got %v", policies)
+	}
+
+	namespaces, err := client.ListNamespaces(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(namespaces) != 2 {
+		t.Errorf("expected 2 namespaces, got %d", len(namespaces))
+	}
+}
+
+func TestClient_ListReplicaSets(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "other"}},
+	)
+	client := &Client{clientset: clientset}
+
+	replicaSets, err := client.ListReplicaSets(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(replicaSets) != 1 {
+		t.Errorf("expected 1 replicaset, got %d", len(replicaSets))
+	}
+}
+
+func TestClient_ListJobs(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job-1", Namespace: "default"}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job-2", Namespace: "default"}},
+	)
+	client := &Client{clientset: clientset}
+
+	jobs, err := client.ListJobs(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(jobs) != 2 {
+		t.Errorf("expected 2 jobs, got %d", len(jobs))
+	}
+}
+
+func TestClient_ListCronJobs_Error(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "cronjobs", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, fmt.Errorf("failed to list cronjobs")
+	})
+	client := &Client{clientset: clientset}
+
+	_, err := client.ListCronJobs(context.Background(), "default")
 	if err == nil {
 		t.Fatal("expected an error, but got nil")
 	}
 }
+
+func TestClient_WatchPods(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	client := &Client{clientset: clientset}
+
+	w, err := client.WatchPods(context.Background(), "default", "", PodSelector{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"}}
+	if _, err := clientset.CoreV1().Pods("default").Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	event := <-w.ResultChan()
+	if event.Type != watch.Added {
+		t.Errorf("expected ADDED event, got %s", event.Type)
+	}
+	if got := event.Object.(*corev1.Pod).Name; got != "pod-1" {
+		t.Errorf("expected pod-1, got %s", got)
+	}
+}
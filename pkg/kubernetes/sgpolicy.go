@@ -0,0 +1,63 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// SecurityGroupPolicyGVR is the resource of the EKS Security Groups for Pods SecurityGroupPolicy CRD.
+var SecurityGroupPolicyGVR = schema.GroupVersionResource{
+	Group:    "vpcresources.k8s.aws",
+	Version:  "v1beta1",
+	Resource: "securitygrouppolicies",
+}
+
+// SecurityGroupPolicy is a vpcresources.k8s.aws/v1beta1 SecurityGroupPolicy object.
+type SecurityGroupPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              SecurityGroupPolicySpec `json:"spec"`
+}
+
+// SecurityGroupPolicySpec selects pods and the security groups their branch ENIs should carry.
+type SecurityGroupPolicySpec struct {
+	PodSelector            *metav1.LabelSelector `json:"podSelector,omitempty"`
+	ServiceAccountSelector *metav1.LabelSelector `json:"serviceAccountSelector,omitempty"`
+	SecurityGroups         GroupIDs              `json:"securityGroups,omitempty"`
+}
+
+// GroupIDs lists security group IDs.
+type GroupIDs struct {
+	Groups []string `json:"groupIds,omitempty"`
+}
+
+// ListSecurityGroupPolicies lists all SecurityGroupPolicies in a namespace.
+// It returns an empty list when the CRD is not installed in the cluster.
+func (c *Client) ListSecurityGroupPolicies(ctx context.Context, namespace string) ([]SecurityGroupPolicy, error) {
+	if c.dynamic == nil {
+		return nil, fmt.Errorf("dynamic client is not configured")
+	}
+
+	list, err := c.dynamic.Resource(SecurityGroupPolicyGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list securitygrouppolicies in namespace %s: %w", namespace, err)
+	}
+
+	policies := make([]SecurityGroupPolicy, 0, len(list.Items))
+	for _, item := range list.Items {
+		var policy SecurityGroupPolicy
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), &policy); err != nil {
+			return nil, fmt.Errorf("failed to decode securitygrouppolicy %s/%s: %w", item.GetNamespace(), item.GetName(), err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
@@ -0,0 +1,59 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newSecurityGroupPolicy(name string, podLabels map[string]any, groupIDs ...any) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "vpcresources.k8s.aws/v1beta1",
+		"kind":       "SecurityGroupPolicy",
+		"metadata":   map[string]any{"name": name, "namespace": "default"},
+		"spec": map[string]any{
+			"podSelector":    map[string]any{"matchLabels": podLabels},
+			"securityGroups": map[string]any{"groupIds": groupIDs},
+		},
+	}}
+}
+
+func TestClient_ListSecurityGroupPolicies(t *testing.T) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{SecurityGroupPolicyGVR: "SecurityGroupPolicyList"},
+		newSecurityGroupPolicy("web", map[string]any{"app": "web"}, "sg-1", "sg-2"),
+	)
+	client := &Client{dynamic: dynamicClient}
+
+	policies, err := client.ListSecurityGroupPolicies(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(policies) != 1 {
+		t.Fatalf("expected 1 policy, got %d", len(policies))
+	}
+	if policies[0].Name != "web" {
+		t.Errorf("expected policy name to be 'web', got '%s'", policies[0].Name)
+	}
+	if got := policies[0].Spec.PodSelector.MatchLabels["app"]; got != "web" {
+		t.Errorf("expected pod selector app=web, got '%s'", got)
+	}
+	if len(policies[0].Spec.SecurityGroups.Groups) != 2 {
+		t.Errorf("expected 2 security groups, got %v", policies[0].Spec.SecurityGroups.Groups)
+	}
+}
+
+func TestClient_ListSecurityGroupPolicies_NoDynamicClient(t *testing.T) {
+	client := &Client{}
+
+	_, err := client.ListSecurityGroupPolicies(context.Background(), "default")
+	if err == nil {
+		t.Fatal("expected an error, but got nil")
+	}
+}
@@ -0,0 +1,469 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Snapshot file names. Each file holds a Kubernetes List, as written by `kubectl get <resource> -A -o json`.
+const (
+	PodsFile                  = "pods.json"
+	ReplicaSetsFile           = "replicasets.json"
+	DeploymentsFile           = "deployments.json"
+	StatefulSetsFile          = "statefulsets.json"
+	DaemonSetsFile            = "daemonsets.json"
+	JobsFile                  = "jobs.json"
+	CronJobsFile              = "cronjobs.json"
+	ServiceAccountsFile       = "serviceaccounts.json"
+	SecurityGroupPoliciesFile = "securitygrouppolicies.json"
+	NodesFile                 = "nodes.json"
+	ServicesFile              = "services.json"
+	EndpointSlicesFile        = "endpointslices.json"
+	IngressesFile             = "ingresses.json"
+	IngressClassesFile        = "ingressclasses.json"
+	NetworkPoliciesFile       = "networkpolicies.json"
+	NamespacesFile            = "namespaces.json"
+)
+
+// SnapshotClient serves Kubernetes objects from files saved in a snapshot directory.
+type SnapshotClient struct {
+	pods                  []corev1.Pod
+	replicaSets           []appsv1.ReplicaSet
+	deployments           []appsv1.Deployment
+	statefulSets          []appsv1.StatefulSet
+	daemonSets            []appsv1.DaemonSet
+	jobs                  []batchv1.Job
+	cronJobs              []batchv1.CronJob
+	serviceAccounts       []corev1.ServiceAccount
+	securityGroupPolicies []SecurityGroupPolicy
+	nodes                 []corev1.Node
+	services              []corev1.Service
+	endpointSlices        []discoveryv1.EndpointSlice
+	ingresses             []networkingv1.Ingress
+	ingressClasses        []networkingv1.IngressClass
+	networkPolicies       []networkingv1.NetworkPolicy
+	namespaces            []corev1.Namespace
+}
+
+var _ Interface = (*SnapshotClient)(nil)
+
+// list is the subset of a Kubernetes List read from and written to snapshot files.
+type list[T any] struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Items      []T    `json:"items"`
+}
+
+// NewSnapshotClient loads a snapshot directory. The pods file is required; the other files are
+// optional and treated as empty lists when missing.
+func NewSnapshotClient(dir string) (*SnapshotClient, error) {
+	c := &SnapshotClient{}
+	if _, err := os.Stat(filepath.Join(dir, PodsFile)); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", dir, err)
+	}
+
+	loaders := []error{
+		readList(dir, PodsFile, &c.pods),
+		readList(dir, ReplicaSetsFile, &c.replicaSets),
+		readList(dir, DeploymentsFile, &c.deployments),
+		readList(dir, StatefulSetsFile, &c.statefulSets),
+		readList(dir, DaemonSetsFile, &c.daemonSets),
+		readList(dir, JobsFile, &c.jobs),
+		readList(dir, CronJobsFile, &c.cronJobs),
+		readList(dir, ServiceAccountsFile, &c.serviceAccounts),
+		readList(dir, SecurityGroupPoliciesFile, &c.securityGroupPolicies),
+		readList(dir, NodesFile, &c.nodes),
+		readList(dir, ServicesFile, &c.services),
+		readList(dir, EndpointSlicesFile, &c.endpointSlices),
+		readList(dir, IngressesFile, &c.ingresses),
+		readList(dir, IngressClassesFile, &c.ingressClasses),
+		readList(dir, NetworkPoliciesFile, &c.networkPolicies),
+		readList(dir, NamespacesFile, &c.namespaces),
+	}
+	if err := errors.Join(loaders...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readList decodes the items of a List file into out. A missing file leaves out empty.
+func readList[T any](dir, name string, out *[]T) error {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot file %s: %w", name, err)
+	}
+
+	var l list[T]
+	if err := json.Unmarshal(b, &l); err != nil {
+		return fmt.Errorf("failed to decode snapshot file %s: %w", name, err)
+	}
+	*out = l.Items
+	return nil
+}
+
+// inNamespace returns the objects in the namespace, or all objects for an empty namespace.
+func inNamespace[T any, PT interface {
+	*T
+	metav1.Object
+}](items []T, namespace string) []T {
+	var result []T
+	for i := range items {
+		if namespace == "" || PT(&items[i]).GetNamespace() == namespace {
+			result = append(result, items[i])
+		}
+	}
+	return result
+}
+
+// getByName returns the named object in the namespace or a NotFound error.
+func getByName[T any, PT interface {
+	*T
+	metav1.Object
+}](items []T, resource, name, namespace string) (*T, error) {
+	for i := range items {
+		if PT(&items[i]).GetName() == name && PT(&items[i]).GetNamespace() == namespace {
+			item := items[i]
+			return &item, nil
+		}
+	}
+	err := apierrors.NewNotFound(schema.GroupResource{Resource: resource}, name)
+	return nil, fmt.Errorf("failed to get %s %s in namespace %s: %w", resource[:len(resource)-1], name, namespace, err)
+}
+
+// GetPod gets a pod by name in a namespace.
+func (c *SnapshotClient) GetPod(_ context.Context, name, namespace string) (*corev1.Pod, error) {
+	return getByName(c.pods, "pods", name, namespace)
+}
+
+// ListPods lists the pods in a namespace that match the selector.
+func (c *SnapshotClient) ListPods(_ context.Context, namespace string, selector PodSelector) ([]corev1.Pod, error) {
+	return selectPods(inNamespace(c.pods, namespace), selector)
+}
+
+// selectPods returns the pods matching the label and field selectors the way the API server
+// evaluates them, for the fields pods support in field selectors.
+func selectPods(pods []corev1.Pod, selector PodSelector) ([]corev1.Pod, error) {
+	labelSelector, err := labels.Parse(selector.Label)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %w", selector.Label, err)
+	}
+	fieldSelector, err := fields.ParseSelector(selector.Field)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field selector %q: %w", selector.Field, err)
+	}
+
+	var result []corev1.Pod
+	for _, pod := range pods {
+		if labelSelector.Matches(labels.Set(pod.Labels)) && fieldSelector.Matches(podFields(pod)) {
+			result = append(result, pod)
+		}
+	}
+	return result, nil
+}
+
+// podFields returns the fields of a pod that can be used in field selectors
+func podFields(pod corev1.Pod) fields.Set {
+	return fields.Set{
+		"metadata.name":            pod.Name,
+		"metadata.namespace":       pod.Namespace,
+		"spec.nodeName":            pod.Spec.NodeName,
+		"spec.restartPolicy":       string(pod.Spec.RestartPolicy),
+		"spec.schedulerName":       pod.Spec.SchedulerName,
+		"spec.serviceAccountName":  pod.Spec.ServiceAccountName,
+		"spec.hostNetwork":         strconv.FormatBool(pod.Spec.HostNetwork),
+		"status.phase":             string(pod.Status.Phase),
+		"status.podIP":             pod.Status.PodIP,
+		"status.nominatedNodeName": pod.Status.NominatedNodeName,
+	}
+}
+
+// WatchPods is not supported by snapshots, which never change.
+func (c *SnapshotClient) WatchPods(_ context.Context, _, _ string, _ PodSelector) (watch.Interface, error) {
+	return nil, fmt.Errorf("watching pods is not supported by snapshots")
+}
+
+// ListReplicaSets lists all replica sets in a namespace.
+func (c *SnapshotClient) ListReplicaSets(_ context.Context, namespace string) ([]appsv1.ReplicaSet, error) {
+	return inNamespace(c.replicaSets, namespace), nil
+}
+
+// GetDeployment gets a deployment by name in a namespace.
+func (c *SnapshotClient) GetDeployment(_ context.Context, name, namespace string) (*appsv1.Deployment, error) {
+	return getByName(c.deployments, "deployments", name, namespace)
+}
+
+// ListDeployments lists all deployments in a namespace.
+func (c *SnapshotClient) ListDeployments(_ context.Context, namespace string) ([]appsv1.Deployment, error) {
+	return inNamespace(c.deployments, namespace), nil
+}
+
+// GetStatefulSet gets a stateful set by name in a namespace.
+func (c *SnapshotClient) GetStatefulSet(_ context.Context, name, namespace string) (*appsv1.StatefulSet, error) {
+	return getByName(c.statefulSets, "statefulsets", name, namespace)
+}
+
+// ListStatefulSets lists all stateful sets in a namespace.
+func (c *SnapshotClient) ListStatefulSets(_ context.Context, namespace string) ([]appsv1.StatefulSet, error) {
+	return inNamespace(c.statefulSets, namespace), nil
+}
+
+// GetDaemonSet gets a daemon set by name in a namespace.
+func (c *SnapshotClient) GetDaemonSet(_ context.Context, name, namespace string) (*appsv1.DaemonSet, error) {
+	return getByName(c.daemonSets, "daemonsets", name, namespace)
+}
+
+// ListDaemonSets lists all daemon sets in a namespace.
+func (c *SnapshotClient) ListDaemonSets(_ context.Context, namespace string) ([]appsv1.DaemonSet, error) {
+	return inNamespace(c.daemonSets, namespace), nil
+}
+
+// GetJob gets a job by name in a namespace.
+func (c *SnapshotClient) GetJob(_ context.Context, name, namespace string) (*batchv1.Job, error) {
+	return getByName(c.jobs, "jobs", name, namespace)
+}
+
+// ListJobs lists all jobs in a namespace.
+func (c *SnapshotClient) ListJobs(_ context.Context, namespace string) ([]batchv1.Job, error) {
+	return inNamespace(c.jobs, namespace), nil
+}
+
+// GetCronJob gets a cron job by name in a namespace.
+func (c *SnapshotClient) GetCronJob(_ context.Context, name, namespace string) (*batchv1.CronJob, error) {
+	return getByName(c.cronJobs, "cronjobs", name, namespace)
+}
+
+// ListCronJobs lists all cron jobs in a namespace.
+func (c *SnapshotClient) ListCronJobs(_ context.Context, namespace string) ([]batchv1.CronJob, error) {
+	return inNamespace(c.cronJobs, namespace), nil
+}
+
+// ListServiceAccounts lists all service accounts in a namespace.
+func (c *SnapshotClient) ListServiceAccounts(_ context.Context, namespace string) ([]corev1.ServiceAccount, error) {
+	return inNamespace(c.serviceAccounts, namespace), nil
+}
+
+// ListSecurityGroupPolicies lists all SecurityGroupPolicies in a namespace.
+func (c *SnapshotClient) ListSecurityGroupPolicies(_ context.Context, namespace string) ([]SecurityGroupPolicy, error) {
+	return inNamespace(c.securityGroupPolicies, namespace), nil
+}
+
+// GetNode gets a node by name.
+func (c *SnapshotClient) GetNode(_ context.Context, name string) (*corev1.Node, error) {
+	for i := range c.nodes {
+		if c.nodes[i].Name == name {
+			node := c.nodes[i]
+			return &node, nil
+		}
+	}
+	err := apierrors.NewNotFound(schema.GroupResource{Resource: "nodes"}, name)
+	return nil, fmt.Errorf("failed to get node %s: %w", name, err)
+}
+
+// ListNodes lists all nodes of the cluster.
+func (c *SnapshotClient) ListNodes(_ context.Context) ([]corev1.Node, error) {
+	return c.nodes, nil
+}
+
+// GetService gets a service by name in a namespace.
+func (c *SnapshotClient) GetService(_ context.Context, name, namespace string) (*corev1.Service, error) {
+	return getByName(c.services, "services", name, namespace)
+}
+
+// ListServices lists all services in a namespace.
+func (c *SnapshotClient) ListServices(_ context.Context, namespace string) ([]corev1.Service, error) {
+	return inNamespace(c.services, namespace), nil
+}
+
+// ListEndpointSlices lists all endpoint slices in a namespace.
+func (c *SnapshotClient) ListEndpointSlices(_ context.Context, namespace string) ([]discoveryv1.EndpointSlice, error) {
+	return inNamespace(c.endpointSlices, namespace), nil
+}
+
+// ListIngresses lists all ingresses in a namespace.
+func (c *SnapshotClient) ListIngresses(_ context.Context, namespace string) ([]networkingv1.Ingress, error) {
+	return inNamespace(c.ingresses, namespace), nil
+}
+
+// ListIngressClasses lists all ingress classes of the cluster.
+func (c *SnapshotClient) ListIngressClasses(_ context.Context) ([]networkingv1.IngressClass, error) {
+	return c.ingressClasses, nil
+}
+
+// ListNetworkPolicies lists all network policies in a namespace.
+func (c *SnapshotClient) ListNetworkPolicies(_ context.Context, namespace string) ([]networkingv1.NetworkPolicy, error) {
+	return inNamespace(c.networkPolicies, namespace), nil
+}
+
+// ListNamespaces lists all namespaces of the cluster.
+func (c *SnapshotClient) ListNamespaces(_ context.Context) ([]corev1.Namespace, error) {
+	return c.namespaces, nil
+}
+
+// WriteSnapshot saves every object sgmap reads, across all namespaces, into the directory.
+func WriteSnapshot(ctx context.Context, client Interface, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory %s: %w", dir, err)
+	}
+
+	pods, err := client.ListPods(ctx, "", PodSelector{})
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, PodsFile, pods); err != nil {
+		return err
+	}
+
+	replicaSets, err := client.ListReplicaSets(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, ReplicaSetsFile, replicaSets); err != nil {
+		return err
+	}
+
+	deployments, err := client.ListDeployments(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, DeploymentsFile, deployments); err != nil {
+		return err
+	}
+
+	statefulSets, err := client.ListStatefulSets(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, StatefulSetsFile, statefulSets); err != nil {
+		return err
+	}
+
+	daemonSets, err := client.ListDaemonSets(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, DaemonSetsFile, daemonSets); err != nil {
+		return err
+	}
+
+	jobs, err := client.ListJobs(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, JobsFile, jobs); err != nil {
+		return err
+	}
+
+	cronJobs, err := client.ListCronJobs(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, CronJobsFile, cronJobs); err != nil {
+		return err
+	}
+
+	serviceAccounts, err := client.ListServiceAccounts(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, ServiceAccountsFile, serviceAccounts); err != nil {
+		return err
+	}
+
+	policies, err := client.ListSecurityGroupPolicies(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, SecurityGroupPoliciesFile, policies); err != nil {
+		return err
+	}
+
+	nodes, err := client.ListNodes(ctx)
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, NodesFile, nodes); err != nil {
+		return err
+	}
+
+	services, err := client.ListServices(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, ServicesFile, services); err != nil {
+		return err
+	}
+
+	endpointSlices, err := client.ListEndpointSlices(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, EndpointSlicesFile, endpointSlices); err != nil {
+		return err
+	}
+
+	ingresses, err := client.ListIngresses(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, IngressesFile, ingresses); err != nil {
+		return err
+	}
+
+	ingressClasses, err := client.ListIngressClasses(ctx)
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, IngressClassesFile, ingressClasses); err != nil {
+		return err
+	}
+
+	networkPolicies, err := client.ListNetworkPolicies(ctx, "")
+	if err != nil {
+		return err
+	}
+	if err := writeList(dir, NetworkPoliciesFile, networkPolicies); err != nil {
+		return err
+	}
+
+	namespaces, err := client.ListNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+	return writeList(dir, NamespacesFile, namespaces)
+}
+
+// writeList writes the items as a Kubernetes List file.
+func writeList[T any](dir, name string, items []T) error {
+	if items == nil {
+		items = []T{}
+	}
+	b, err := json.MarshalIndent(list[T]{APIVersion: "v1", Kind: "List", Items: items}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot file %s: %w", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot file %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package kubernetes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSnapshotClient_RoundTrip(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "data"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		&discoveryv1.EndpointSlice{ObjectMeta: metav1.ObjectMeta{Name: "web-abcde", Namespace: "default"}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		&networkingv1.IngressClass{ObjectMeta: metav1.ObjectMeta{Name: "alb"}},
+		&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "deny-all", Namespace: "data"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "data"}},
+	)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{SecurityGroupPolicyGVR: "SecurityGroupPolicyList"},
+		newSecurityGroupPolicy("web", map[string]any{"app": "web"}, "sg-1"),
+	)
+	client := &Client{clientset: clientset, dynamic: dynamicClient}
+
+	dir := t.TempDir()
+	if err := WriteSnapshot(context.Background(), client, dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	snapshot, err := NewSnapshotClient(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pods, _ := snapshot.ListPods(context.Background(), "", PodSelector{})
+	if len(pods) != 2 {
+		t.Errorf("expected 2 pods across all namespaces, got %d", len(pods))
+	}
+	pods, _ = snapshot.ListPods(context.Background(), "default", PodSelector{})
+	if len(pods) != 1 || pods[0].Name != "web" {
+		t.Errorf("expected only pod 'web' in namespace default, got %v", pods)
+	}
+
+	if _, err := snapshot.GetDeployment(context.Background(), "web", "default"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := snapshot.GetPod(context.Background(), "web", "data"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected a NotFound error, got %v", err)
+	}
+
+	policies, _ := snapshot.ListSecurityGroupPolicies(context.Background(), "default")
+	if len(policies) != 1 || policies[0].Spec.SecurityGroups.Groups[0] != "sg-1" {
+		t.Errorf("unexpected policies: %v", policies)
+	}
+
+	if _, err := snapshot.GetNode(context.Background(), "node-1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := snapshot.GetNode(context.Background(), "node-2"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected a NotFound error, got %v", err)
+	}
+
+	if _, err := snapshot.GetService(context.Background(), "web", "default"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	slices, _ := snapshot.ListEndpointSlices(context.Background(), "")
+	if len(slices) != 1 || slices[0].Name != "web-abcde" {
+		t.Errorf("unexpected endpoint slices: %v", slices)
+	}
+
+	ingresses, _ := snapshot.ListIngresses(context.Background(), "default")
+	if len(ingresses) != 1 || ingresses[0].Name != "web" {
+		t.Errorf("unexpected ingresses: %v", ingresses)
+	}
+	classes, _ := snapshot.ListIngressClasses(context.Background())
+	if len(classes) != 1 || classes[0].Name != "alb" {
+		t.Errorf("unexpected ingress classes: %v", classes)
+	}
+
+	networkPolicies, _ := snapshot.ListNetworkPolicies(context.Background(), "data")
+	if len(networkPolicies) != 1 || networkPolicies[0].Name != "deny-all" {
+		t.Errorf("unexpected network policies: %v", networkPolicies)
+	}
+	namespaces, _ := snapshot.ListNamespaces(context.Background())
+	if len(namespaces) != 1 || namespaces[0].Name != "data" {
+		t.Errorf("unexpected namespaces: %v", namespaces)
+	}
+}
+
+func TestNewSnapshotClient_KubectlOutput(t *testing.T) {
+	dir := t.TempDir()
+	pods := `{"apiVersion": "v1", "kind": "List", "items": [{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web", "namespace": "default"}, "status": {"phase": "Running", "podIP": "10.0.0.1"}}], "metadata": {"resourceVersion": ""}}`
+	if err := os.WriteFile(filepath.Join(dir, PodsFile), []byte(pods), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := NewSnapshotClient(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pod, err := snapshot.GetPod(context.Background(), "web", "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pod.Status.PodIP != "10.0.0.1" {
+		t.Errorf("expected pod IP 10.0.0.1, got %s", pod.Status.PodIP)
+	}
+
+	jobs, err := snapshot.ListJobs(context.Background(), "")
+	if err != nil || len(jobs) != 0 {
+		t.Errorf("expected no jobs for a missing file, got %v, %v", jobs, err)
+	}
+}
+
+func TestNewSnapshotClient_MissingPods(t *testing.T) {
+	if _, err := NewSnapshotClient(t.TempDir()); err == nil {
+		t.Fatal("expected an error, but got nil")
+	}
+}
+
+func TestSelectPods(t *testing.T) {
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{NodeName: "node-1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-pending", Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Labels: map[string]string{"app": "db"}},
+			Spec:       corev1.PodSpec{NodeName: "node-1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		selector PodSelector
+		expected []string
+		wantErr  bool
+	}{
+		{name: "no selector", expected: []string{"web", "web-pending", "db"}},
+		{name: "label selector", selector: PodSelector{Label: "app=web"}, expected: []string{"web", "web-pending"}},
+		{name: "field selector", selector: PodSelector{Field: "spec.nodeName=node-1"}, expected: []string{"web", "db"}},
+		{name: "both selectors", selector: PodSelector{Label: "app in (web)", Field: "status.phase!=Pending"}, expected: []string{"web"}},
+		{name: "invalid label selector", selector: PodSelector{Label: "app in web"}, wantErr: true},
+		{name: "invalid field selector", selector: PodSelector{Field: "spec.nodeName"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := selectPods(pods, tc.selector)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("selectPods() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			var names []string
+			for _, pod := range result {
+				names = append(names, pod.Name)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("selectPods() = %v, want %v", names, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package lint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"gopkg.in/yaml.v3"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+)
+
+// Defaults used when a rule does not set its own value
+var (
+	DefaultAllowedPorts      = []int32{80, 443}
+	DefaultMaxSecurityGroups = 5
+)
+
+// Config enables, disables and tunes the built-in rules. Rules missing from the config run with their defaults.
+//
+//	rules:
+//	  SG001:
+//	    allowedPorts: [80, 443, 8443]
+//	  SG002:
+//	    severity: high
+//	    includeEgress: true
+//	  SG003:
+//	    namespaces: [payments]
+//	  SG004:
+//	    enabled: false
+//	  SG005:
+//	    maxSecurityGroups: 3
+type Config struct {
+	Rules map[string]RuleConfig `yaml:"rules"`
+}
+
+// RuleConfig holds the settings of a single rule. Settings that do not apply to the rule are ignored.
+type RuleConfig struct {
+	// Enabled turns the rule off when set to false
+	Enabled *bool `yaml:"enabled"`
+	// Severity overrides the rule's default severity
+	Severity string `yaml:"severity"`
+	// AllowedPorts are the ports SG001 accepts open to the internet
+	AllowedPorts []int32 `yaml:"allowedPorts"`
+	// IncludeEgress makes SG002 check outbound rules as well
+	IncludeEgress bool `yaml:"includeEgress"`
+	// Namespaces limits SG003 to namespaces expected to use security groups for pods
+	Namespaces []string `yaml:"namespaces"`
+	// MaxSecurityGroups is the number of security groups above which SG005 reports a pod
+	MaxSecurityGroups int `yaml:"maxSecurityGroups"`
+}
+
+// LoadConfig reads a YAML config file
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read lint config %s: %w", path, err)
+	}
+
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("failed to decode lint config %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid lint config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate returns an error for unknown rule IDs and severities
+func (c Config) Validate() error {
+	for id, rc := range c.Rules {
+		if _, ok := ruleByID(id); !ok {
+			return fmt.Errorf("unknown rule %s", id)
+		}
+		switch rc.Severity {
+		case "", SeverityHigh, SeverityMedium, SeverityLow:
+		default:
+			return fmt.Errorf("invalid severity %s for rule %s, valid severities are: high, medium, low", rc.Severity, id)
+		}
+	}
+	return nil
+}
+
+func ruleByID(id string) (Rule, bool) {
+	for _, r := range Rules() {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+func (c Config) enabled(id string) bool {
+	rc := c.Rules[id]
+	return rc.Enabled == nil || *rc.Enabled
+}
+
+func (c Config) severity(id string) string {
+	if s := c.Rules[id].Severity; s != "" {
+		return s
+	}
+	r, _ := ruleByID(id)
+	return r.Severity
+}
+
+func (c Config) includeEgress(id string) bool {
+	return c.Rules[id].IncludeEgress
+}
+
+func (c Config) expectsPodSecurityGroups(namespace string) bool {
+	namespaces := c.Rules[RuleNodeLevel].Namespaces
+	return len(namespaces) == 0 || slices.Contains(namespaces, namespace)
+}
+
+func (c Config) maxSecurityGroups() int {
+	if m := c.Rules[RuleTooManyGroups].MaxSecurityGroups; m > 0 {
+		return m
+	}
+	return DefaultMaxSecurityGroups
+}
+
+// onlyAllowedPorts reports whether every port of a TCP or UDP permission is allowed to be open to the internet
+func (c Config) onlyAllowedPorts(p types.IpPermission) bool {
+	switch rules.NormalizeProtocol(awsSDK.ToString(p.IpProtocol)) {
+	case "tcp", "udp":
+	default:
+		return false
+	}
+	ports := c.Rules[RuleOpenIngress].AllowedPorts
+	if ports == nil {
+		ports = DefaultAllowedPorts
+	}
+	from, to := portRange(p)
+	return containsAll(ports, from, to)
+}
@@ -0,0 +1,227 @@
+// Package lint checks pod security groups for risky exposure.
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+)
+
+// Severities
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// Built-in rule IDs
+const (
+	RuleOpenIngress   = "SG001"
+	RuleAllProtocols  = "SG002"
+	RuleNodeLevel     = "SG003"
+	RuleEmptyGroup    = "SG004"
+	RuleTooManyGroups = "SG005"
+)
+
+const (
+	worldIPv4 = "0.0.0.0/0"
+	worldIPv6 = "::/0"
+)
+
+// Rule describes a built-in check
+type Rule struct {
+	ID          string
+	Name        string
+	Description string
+	Severity    string
+}
+
+// Rules returns the built-in checks in rule ID order
+func Rules() []Rule {
+	return []Rule{
+		{RuleOpenIngress, "open-ingress", "Inbound rule open to 0.0.0.0/0 or ::/0 on a port other than the allowed HTTP ports", SeverityHigh},
+		{RuleAllProtocols, "all-protocols", "Rule allowing all protocols and ports", SeverityMedium},
+		{RuleNodeLevel, "node-level-security-groups", "Pod relies on the node's security groups instead of security groups for pods", SeverityMedium},
+		{RuleEmptyGroup, "empty-security-group", "Security group without any inbound or outbound rules", SeverityLow},
+		{RuleTooManyGroups, "too-many-security-groups", "Pod with more security groups than the configured maximum", SeverityLow},
+	}
+}
+
+// Finding is a single violation of a check
+type Finding struct {
+	RuleID        string
+	Severity      string
+	Namespace     string
+	Pod           string
+	SecurityGroup string
+	Message       string
+}
+
+// PodRef identifies a checked pod
+type PodRef struct {
+	Namespace string
+	Name      string
+}
+
+// Report is the result of a lint run
+type Report struct {
+	// Pods are the checked pods sorted by namespace and name
+	Pods []PodRef
+	// Rules are the enabled rules with their configured severity
+	Rules []Rule
+	// Findings are sorted by namespace, pod, rule and security group
+	Findings []Finding
+}
+
+// Run evaluates the enabled checks against the pods, leaving out pods whose EC2 lookup failed
+func Run(infos []aws.PodSecurityGroupInfo, cfg Config) Report {
+	report := Report{Pods: []PodRef{}, Rules: []Rule{}}
+	for _, r := range Rules() {
+		if cfg.enabled(r.ID) {
+			r.Severity = cfg.severity(r.ID)
+			report.Rules = append(report.Rules, r)
+		}
+	}
+
+	var findings []Finding
+	for _, info := range infos {
+		// A pod whose EC2 lookup failed was not checked, so it is neither a finding nor a pass
+		if info.Status == aws.StatusLookupFailed {
+			continue
+		}
+		report.Pods = append(report.Pods, PodRef{Namespace: info.Pod.Namespace, Name: info.Pod.Name})
+		add := func(ruleID, sgID, message string) {
+			findings = append(findings, Finding{
+				RuleID:        ruleID,
+				Severity:      cfg.severity(ruleID),
+				Namespace:     info.Pod.Namespace,
+				Pod:           info.Pod.Name,
+				SecurityGroup: sgID,
+				Message:       message,
+			})
+		}
+
+		if cfg.enabled(RuleNodeLevel) && info.AttachmentLevel == "node" && cfg.expectsPodSecurityGroups(info.Pod.Namespace) {
+			add(RuleNodeLevel, "", "pod uses the node's security groups")
+		}
+
+		if limit := cfg.maxSecurityGroups(); cfg.enabled(RuleTooManyGroups) && len(info.SecurityGroups) > limit {
+			add(RuleTooManyGroups, "", fmt.Sprintf("pod has %d security groups, more than %d", len(info.SecurityGroups), limit))
+		}
+
+		for _, sg := range info.SecurityGroups {
+			sgID := awsSDK.ToString(sg.GroupId)
+
+			if cfg.enabled(RuleEmptyGroup) && len(sg.IpPermissions) == 0 && len(sg.IpPermissionsEgress) == 0 {
+				add(RuleEmptyGroup, sgID, "security group has no rules")
+			}
+
+			for _, p := range sg.IpPermissions {
+				if cfg.enabled(RuleOpenIngress) && openToWorld(p) && !cfg.onlyAllowedPorts(p) {
+					add(RuleOpenIngress, sgID, fmt.Sprintf("inbound %s open to the internet", formatTraffic(p)))
+				}
+				if cfg.enabled(RuleAllProtocols) && isAllProtocols(p) {
+					add(RuleAllProtocols, sgID, "inbound rule allows all traffic")
+				}
+			}
+			if cfg.enabled(RuleAllProtocols) && cfg.includeEgress(RuleAllProtocols) {
+				for _, p := range sg.IpPermissionsEgress {
+					if isAllProtocols(p) {
+						add(RuleAllProtocols, sgID, "outbound rule allows all traffic")
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Pod != b.Pod {
+			return a.Pod < b.Pod
+		}
+		if a.RuleID != b.RuleID {
+			return a.RuleID < b.RuleID
+		}
+		return a.SecurityGroup < b.SecurityGroup
+	})
+	sort.Slice(report.Pods, func(i, j int) bool {
+		if report.Pods[i].Namespace != report.Pods[j].Namespace {
+			return report.Pods[i].Namespace < report.Pods[j].Namespace
+		}
+		return report.Pods[i].Name < report.Pods[j].Name
+	})
+	report.Findings = findings
+	return report
+}
+
+// openToWorld reports whether a TCP, UDP or all-protocol permission has 0.0.0.0/0 or ::/0 as a peer
+func openToWorld(p types.IpPermission) bool {
+	switch rules.NormalizeProtocol(awsSDK.ToString(p.IpProtocol)) {
+	case "icmp", "icmpv6":
+		return false
+	}
+	for _, r := range p.IpRanges {
+		if awsSDK.ToString(r.CidrIp) == worldIPv4 {
+			return true
+		}
+	}
+	for _, r := range p.Ipv6Ranges {
+		if awsSDK.ToString(r.CidrIpv6) == worldIPv6 {
+			return true
+		}
+	}
+	return false
+}
+
+func isAllProtocols(p types.IpPermission) bool {
+	return rules.NormalizeProtocol(awsSDK.ToString(p.IpProtocol)) == "all"
+}
+
+// portRange returns the ports covered by a TCP or UDP permission
+func portRange(p types.IpPermission) (int32, int32) {
+	if p.FromPort == nil || p.ToPort == nil || *p.FromPort == -1 {
+		return 0, 65535
+	}
+	return *p.FromPort, *p.ToPort
+}
+
+// formatTraffic renders the protocol and ports of a permission, such as "tcp/22" or "all traffic"
+func formatTraffic(p types.IpPermission) string {
+	protocol := rules.NormalizeProtocol(awsSDK.ToString(p.IpProtocol))
+	switch protocol {
+	case "all":
+		return "all traffic"
+	case "icmp", "icmpv6":
+		return protocol
+	}
+	from, to := portRange(p)
+	if from == 0 && to == 65535 {
+		return protocol + "/all"
+	}
+	if from == to {
+		return fmt.Sprintf("%s/%d", protocol, from)
+	}
+	return fmt.Sprintf("%s/%d-%d", protocol, from, to)
+}
+
+// containsAll reports whether every port of the range is in the list
+func containsAll(ports []int32, from, to int32) bool {
+	if int(to-from)+1 > len(ports) {
+		return false
+	}
+	for port := from; port <= to; port++ {
+		if !slices.Contains(ports, port) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+func newInfo(namespace, name, attachment string, sgs ...types.SecurityGroup) aws.PodSecurityGroupInfo {
+	return aws.PodSecurityGroupInfo{
+		Pod:             corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}},
+		AttachmentLevel: attachment,
+		SecurityGroups:  sgs,
+	}
+}
+
+func worldRule(protocol string, from, to int32) types.IpPermission {
+	return types.IpPermission{
+		IpProtocol: awsSDK.String(protocol),
+		FromPort:   awsSDK.Int32(from),
+		ToPort:     awsSDK.Int32(to),
+		IpRanges:   []types.IpRange{{CidrIp: awsSDK.String("0.0.0.0/0")}},
+	}
+}
+
+func ruleIDs(findings []Finding) []string {
+	ids := []string{}
+	for _, f := range findings {
+		ids = append(ids, f.RuleID)
+	}
+	return ids
+}
+
+func TestRun(t *testing.T) {
+	egressAll := []types.IpPermission{{IpProtocol: awsSDK.String("-1"), IpRanges: []types.IpRange{{CidrIp: awsSDK.String("0.0.0.0/0")}}}}
+
+	testCases := []struct {
+		name     string
+		info     aws.PodSecurityGroupInfo
+		cfg      Config
+		expected []string
+	}{
+		{
+			name:     "https open to the internet is allowed",
+			info:     newInfo("default", "web", "pod", types.SecurityGroup{GroupId: awsSDK.String("sg-1"), IpPermissions: []types.IpPermission{worldRule("tcp", 443, 443)}, IpPermissionsEgress: egressAll}),
+			expected: []string{},
+		},
+		{
+			name:     "ssh open to the internet",
+			info:     newInfo("default", "web", "pod", types.SecurityGroup{GroupId: awsSDK.String("sg-1"), IpPermissions: []types.IpPermission{worldRule("tcp", 22, 22)}}),
+			expected: []string{RuleOpenIngress},
+		},
+		{
+			name: "ipv6 port range open to the internet",
+			info: newInfo("default", "web", "pod", types.SecurityGroup{GroupId: awsSDK.String("sg-1"), IpPermissions: []types.IpPermission{
+				{IpProtocol: awsSDK.String("tcp"), FromPort: awsSDK.Int32(80), ToPort: awsSDK.Int32(443), Ipv6Ranges: []types.Ipv6Range{{CidrIpv6: awsSDK.String("::/0")}}},
+			}}),
+			expected: []string{RuleOpenIngress},
+		},
+		{
+			name:     "all traffic open to the internet",
+			info:     newInfo("default", "web", "pod", types.SecurityGroup{GroupId: awsSDK.String("sg-1"), IpPermissions: egressAll}),
+			expected: []string{RuleOpenIngress, RuleAllProtocols},
+		},
+		{
+			name:     "egress all protocols only when enabled",
+			info:     newInfo("default", "web", "pod", types.SecurityGroup{GroupId: awsSDK.String("sg-1"), IpPermissionsEgress: egressAll}),
+			cfg:      Config{Rules: map[string]RuleConfig{RuleAllProtocols: {IncludeEgress: true}}},
+			expected: []string{RuleAllProtocols},
+		},
+		{
+			name:     "node level and empty security group",
+			info:     newInfo("default", "web", "node", types.SecurityGroup{GroupId: awsSDK.String("sg-1")}),
+			expected: []string{RuleNodeLevel, RuleEmptyGroup},
+		},
+		{
+			name:     "node level outside the expected namespaces",
+			info:     newInfo("kube-system", "coredns", "node", types.SecurityGroup{GroupId: awsSDK.String("sg-1"), IpPermissionsEgress: egressAll}),
+			cfg:      Config{Rules: map[string]RuleConfig{RuleNodeLevel: {Namespaces: []string{"default"}}}},
+			expected: []string{},
+		},
+		{
+			name: "too many security groups with a tuned threshold",
+			info: newInfo("default", "web", "pod",
+				types.SecurityGroup{GroupId: awsSDK.String("sg-1"), IpPermissionsEgress: egressAll},
+				types.SecurityGroup{GroupId: awsSDK.String("sg-2"), IpPermissionsEgress: egressAll},
+			),
+			cfg:      Config{Rules: map[string]RuleConfig{RuleTooManyGroups: {MaxSecurityGroups: 1}}},
+			expected: []string{RuleTooManyGroups},
+		},
+		{
+			name:     "disabled rule",
+			info:     newInfo("default", "web", "pod", types.SecurityGroup{GroupId: awsSDK.String("sg-1"), IpPermissions: []types.IpPermission{worldRule("tcp", 22, 22)}}),
+			cfg:      Config{Rules: map[string]RuleConfig{RuleOpenIngress: {Enabled: awsSDK.Bool(false)}, RuleEmptyGroup: {Enabled: awsSDK.Bool(false)}}},
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := Run([]aws.PodSecurityGroupInfo{tc.info}, tc.cfg)
+			assert.Equal(t, tc.expected, ruleIDs(report.Findings))
+		})
+	}
+}
+
+func TestRun_Finding(t *testing.T) {
+	info := newInfo("default", "web", "pod", types.SecurityGroup{GroupId: awsSDK.String("sg-1"), IpPermissions: []types.IpPermission{worldRule("tcp", 22, 22)}})
+	cfg := Config{Rules: map[string]RuleConfig{RuleOpenIngress: {Severity: SeverityLow}}}
+
+	report := Run([]aws.PodSecurityGroupInfo{info}, cfg)
+
+	assert.Equal(t, []PodRef{{Namespace: "default", Name: "web"}}, report.Pods)
+	assert.Len(t, report.Rules, len(Rules()))
+	assert.Equal(t, SeverityLow, report.Rules[0].Severity)
+	assert.Equal(t, []Finding{{
+		RuleID:        RuleOpenIngress,
+		Severity:      SeverityLow,
+		Namespace:     "default",
+		Pod:           "web",
+		SecurityGroup: "sg-1",
+		Message:       "inbound tcp/22 open to the internet",
+	}}, report.Findings)
+}
+
+func TestRun_LookupFailed(t *testing.T) {
+	info := newInfo("default", "web", "pod", types.SecurityGroup{GroupId: awsSDK.String("sg-1"), IpPermissions: []types.IpPermission{worldRule("tcp", 22, 22)}})
+	info.Status = aws.StatusLookupFailed
+
+	report := Run([]aws.PodSecurityGroupInfo{info}, Config{})
+
+	assert.Empty(t, report.Pods)
+	assert.Empty(t, report.Findings)
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	cfg, err := LoadConfig(write("valid.yaml", "rules:\n  SG001:\n    allowedPorts: [443, 8443]\n  SG004:\n    enabled: false\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{443, 8443}, cfg.Rules[RuleOpenIngress].AllowedPorts)
+	assert.False(t, cfg.enabled(RuleEmptyGroup))
+	assert.True(t, cfg.enabled(RuleNodeLevel))
+
+	_, err = LoadConfig(write("empty.yaml", ""))
+	assert.NoError(t, err)
+
+	_, err = LoadConfig(write("unknown-rule.yaml", "rules:\n  SG999: {}\n"))
+	assert.Error(t, err)
+
+	_, err = LoadConfig(write("unknown-field.yaml", "rules:\n  SG001:\n    allowedPort: [22]\n"))
+	assert.Error(t, err)
+
+	_, err = LoadConfig(write("bad-severity.yaml", "rules:\n  SG001:\n    severity: critical\n"))
+	assert.Error(t, err)
+}
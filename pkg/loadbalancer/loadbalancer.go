@@ -0,0 +1,367 @@
+// Package loadbalancer finds the Services and Ingresses exposed through AWS load balancers and checks
+// that the ingress rules of the security groups of their targets admit traffic from the load balancers.
+package loadbalancer
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/service"
+)
+
+// Kinds of the objects a load balancer is provisioned for
+const (
+	KindService = "Service"
+	KindIngress = "Ingress"
+)
+
+// Target types of the AWS Load Balancer Controller. Instance targets receive the traffic on the
+// NodePort of their node, IP targets directly on the pod.
+const (
+	TargetTypeInstance = "instance"
+	TargetTypeIP       = "ip"
+)
+
+// Annotations and names read from Services, Ingresses and IngressClasses
+const (
+	// ALBController is the controller of IngressClasses handled by the AWS Load Balancer Controller
+	ALBController = "ingress.k8s.aws/alb"
+	// IngressClassAnnotation is the legacy way of selecting the class of an Ingress
+	IngressClassAnnotation = "kubernetes.io/ingress.class"
+	// DefaultIngressClassAnnotation marks the IngressClass used by Ingresses without a class
+	DefaultIngressClassAnnotation = "ingressclass.kubernetes.io/is-default-class"
+	// ALBTargetTypeAnnotation selects the target type of the load balancer of an Ingress
+	ALBTargetTypeAnnotation = "alb.ingress.kubernetes.io/target-type"
+	// NLBTargetTypeAnnotation selects the target type of the load balancer of a Service
+	NLBTargetTypeAnnotation = "service.beta.kubernetes.io/aws-load-balancer-nlb-target-type"
+	// NLBTypeAnnotation set to "nlb-ip" is the legacy way of requesting IP targets for a Service
+	NLBTypeAnnotation = "service.beta.kubernetes.io/aws-load-balancer-type"
+)
+
+// Statuses of a path
+const (
+	// StatusOK means the ingress rules of the target permit the traffic from every IP of the load balancer
+	StatusOK = "OK"
+	// StatusBlocked means no ingress rule of the target permits the traffic from some IP of the load balancer
+	StatusBlocked = "BLOCKED"
+	// StatusNoLoadBalancer means no ELBv2 load balancer has a hostname in the status of the object
+	StatusNoLoadBalancer = "NoLoadBalancer"
+	// StatusNoEndpoints means the backend Services have no ready endpoint
+	StatusNoEndpoints = "NoEndpoints"
+	// StatusUnresolved means the ENI or security groups of the target could not be found
+	StatusUnresolved = "Unresolved"
+)
+
+// Backend is a port of a Service in the namespace of the frontend that a load balancer sends traffic to
+type Backend struct {
+	Service string
+	Port    networkingv1.ServiceBackendPort
+}
+
+// Frontend is a Service of type LoadBalancer or an Ingress of the AWS Load Balancer Controller
+type Frontend struct {
+	Kind       string
+	Namespace  string
+	Name       string
+	Hostnames  []string
+	TargetType string
+	Backends   []Backend
+}
+
+// Frontends returns the Services of type LoadBalancer and the Ingresses whose class is handled by the
+// AWS Load Balancer Controller, Services first
+func Frontends(services []corev1.Service, ingresses []networkingv1.Ingress, classes []networkingv1.IngressClass) []Frontend {
+	var frontends []Frontend
+	for _, svc := range services {
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		f := Frontend{
+			Kind:       KindService,
+			Namespace:  svc.Namespace,
+			Name:       svc.Name,
+			TargetType: serviceTargetType(svc),
+		}
+		for _, lb := range svc.Status.LoadBalancer.Ingress {
+			if lb.Hostname != "" {
+				f.Hostnames = append(f.Hostnames, lb.Hostname)
+			}
+		}
+		for _, p := range svc.Spec.Ports {
+			f.Backends = append(f.Backends, Backend{Service: svc.Name, Port: networkingv1.ServiceBackendPort{Number: p.Port}})
+		}
+		frontends = append(frontends, f)
+	}
+
+	for _, ing := range ingresses {
+		if !isALBIngress(ing, classes) {
+			continue
+		}
+		f := Frontend{
+			Kind:       KindIngress,
+			Namespace:  ing.Namespace,
+			Name:       ing.Name,
+			TargetType: TargetTypeInstance,
+		}
+		if ing.Annotations[ALBTargetTypeAnnotation] == TargetTypeIP {
+			f.TargetType = TargetTypeIP
+		}
+		for _, lb := range ing.Status.LoadBalancer.Ingress {
+			if lb.Hostname != "" {
+				f.Hostnames = append(f.Hostnames, lb.Hostname)
+			}
+		}
+		f.Backends = ingressBackends(ing)
+		frontends = append(frontends, f)
+	}
+	return frontends
+}
+
+// serviceTargetType returns the target type of the load balancer of a Service
+func serviceTargetType(svc corev1.Service) string {
+	if svc.Annotations[NLBTargetTypeAnnotation] == TargetTypeIP || svc.Annotations[NLBTypeAnnotation] == "nlb-ip" {
+		return TargetTypeIP
+	}
+	return TargetTypeInstance
+}
+
+// isALBIngress reports whether the class of the Ingress is handled by the AWS Load Balancer Controller
+func isALBIngress(ing networkingv1.Ingress, classes []networkingv1.IngressClass) bool {
+	if ing.Spec.IngressClassName == nil {
+		if class, ok := ing.Annotations[IngressClassAnnotation]; ok {
+			return class == "alb"
+		}
+	}
+	for _, class := range classes {
+		if class.Spec.Controller != ALBController {
+			continue
+		}
+		if ing.Spec.IngressClassName != nil {
+			if class.Name == *ing.Spec.IngressClassName {
+				return true
+			}
+		} else if class.Annotations[DefaultIngressClassAnnotation] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// ingressBackends returns the distinct Service backends of the default backend and rules of an Ingress
+func ingressBackends(ing networkingv1.Ingress) []Backend {
+	var backends []Backend
+	add := func(b *networkingv1.IngressBackend) {
+		if b == nil || b.Service == nil {
+			return
+		}
+		backend := Backend{Service: b.Service.Name, Port: b.Service.Port}
+		if !slices.Contains(backends, backend) {
+			backends = append(backends, backend)
+		}
+	}
+	add(ing.Spec.DefaultBackend)
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			add(&path.Backend)
+		}
+	}
+	return backends
+}
+
+// Target is a pod, or for instance targets a node, that a load balancer sends traffic to. The pod of a
+// node target is named after the node and has its internal IP, so that it can be looked up by IP.
+type Target struct {
+	Pod  corev1.Pod
+	Node string
+	Port service.Port
+}
+
+// Key returns the key the security group information of the target is looked up with
+func (t Target) Key() string {
+	return t.Pod.Namespace + "/" + t.Pod.Name
+}
+
+// Targets returns the targets of the backends of a frontend. IP targets are the ready endpoints of the
+// backend Services on their target port; instance targets are the nodes running those endpoints on
+// the NodePort of the Service.
+func Targets(f Frontend, services []corev1.Service, endpointSlices []discoveryv1.EndpointSlice, pods []corev1.Pod, nodes []corev1.Node) []Target {
+	var targets []Target
+	for _, backend := range f.Backends {
+		i := slices.IndexFunc(services, func(svc corev1.Service) bool {
+			return svc.Namespace == f.Namespace && svc.Name == backend.Service
+		})
+		if i < 0 {
+			continue
+		}
+		svc := services[i]
+		port, ok := servicePort(svc, backend.Port)
+		if !ok {
+			continue
+		}
+		protocol := string(port.Protocol)
+		if protocol == "" {
+			protocol = string(corev1.ProtocolTCP)
+		}
+
+		for _, ep := range service.ReadyEndpoints(svc, endpointSlices, pods) {
+			var target Target
+			if f.TargetType == TargetTypeIP {
+				j := slices.IndexFunc(ep.Ports, func(p service.Port) bool {
+					return p.Name == port.Name && p.Protocol == protocol
+				})
+				if j < 0 {
+					continue
+				}
+				target = Target{Pod: ep.Pod, Port: ep.Ports[j]}
+			} else {
+				node, ok := nodeTarget(ep.Pod.Spec.NodeName, nodes)
+				if !ok || port.NodePort == 0 {
+					continue
+				}
+				target = Target{Pod: node, Node: node.Name, Port: service.Port{Name: port.Name, Protocol: protocol, Port: port.NodePort}}
+			}
+			if !slices.ContainsFunc(targets, func(t Target) bool { return t.Key() == target.Key() && t.Port == target.Port }) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+// servicePort returns the port of the Service a backend refers to by number or name
+func servicePort(svc corev1.Service, ref networkingv1.ServiceBackendPort) (corev1.ServicePort, bool) {
+	for _, p := range svc.Spec.Ports {
+		if (ref.Name != "" && p.Name == ref.Name) || (ref.Name == "" && p.Port == ref.Number) {
+			return p, true
+		}
+	}
+	return corev1.ServicePort{}, false
+}
+
+// nodeTarget returns a running pod named after the node with its internal IP
+func nodeTarget(name string, nodes []corev1.Node) (corev1.Pod, bool) {
+	for _, node := range nodes {
+		if node.Name != name {
+			continue
+		}
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == corev1.NodeInternalIP {
+				pod := corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: addr.Address}}
+				pod.Name = node.Name
+				pod.Spec.NodeName = node.Name
+				return pod, true
+			}
+		}
+	}
+	return corev1.Pod{}, false
+}
+
+// Path is the traffic from the load balancer of a frontend to one of its targets
+type Path struct {
+	Frontend     Frontend
+	LoadBalancer *aws.LoadBalancer
+	Target       Target
+	// Info holds the security groups of the target
+	Info aws.PodSecurityGroupInfo
+	// Rules are the ingress rules of the target that permit the traffic from the load balancer
+	Rules  []rules.Match
+	Status string
+	Reason string
+}
+
+// Paths checks the traffic from the load balancer of a frontend to each of its targets. lb is nil when
+// no load balancer was found for the frontend, infos holds the security group information of the
+// targets by Target.Key, and prefixLists the prefix lists referenced by their rules by ID. The traffic
+// comes from the security groups of the load balancer and the private IPs of its ENIs, and is
+// permitted when every IP is permitted.
+func Paths(f Frontend, lb *aws.LoadBalancer, targets []Target, infos map[string]aws.PodSecurityGroupInfo, prefixLists map[string]aws.PrefixList) []Path {
+	if lb == nil {
+		reason := "the status has no load balancer hostname"
+		if len(f.Hostnames) > 0 {
+			reason = fmt.Sprintf("no ELBv2 load balancer has the hostname %s", strings.Join(f.Hostnames, ", "))
+		}
+		return []Path{{Frontend: f, Status: StatusNoLoadBalancer, Reason: reason}}
+	}
+	if len(targets) == 0 {
+		return []Path{{Frontend: f, LoadBalancer: lb, Status: StatusNoEndpoints, Reason: "the backend services have no ready endpoint"}}
+	}
+
+	paths := make([]Path, 0, len(targets))
+	for _, target := range targets {
+		path := Path{Frontend: f, LoadBalancer: lb, Target: target, Info: infos[target.Key()]}
+		if !path.Info.Mapped() {
+			path.Status, path.Reason = StatusUnresolved, path.Info.Reason
+			if path.Reason == "" {
+				path.Reason = "the target was not looked up"
+			}
+			paths = append(paths, path)
+			continue
+		}
+		path.Rules, path.Status, path.Reason = check(*lb, path.Info, target.Port, prefixLists)
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// check evaluates the ingress rules of the target against the traffic from each IP of the load balancer
+func check(lb aws.LoadBalancer, info aws.PodSecurityGroupInfo, port service.Port, prefixLists map[string]aws.PrefixList) ([]rules.Match, string, string) {
+	peers := []rules.Peer{{SecurityGroupIDs: lb.SecurityGroupIDs()}}
+	if len(lb.PrivateIPs) > 0 {
+		peers = peers[:0]
+		for _, ip := range lb.PrivateIPs {
+			peers = append(peers, rules.Peer{IP: net.ParseIP(ip), SecurityGroupIDs: lb.SecurityGroupIDs()})
+		}
+	}
+
+	var matches []rules.Match
+	var blocked []string
+	for _, peer := range peers {
+		found := rules.Evaluate(info.SecurityGroups, rules.Ingress, port.Protocol, port.Port, peer, prefixLists)
+		if len(found) == 0 {
+			blocked = append(blocked, peerString(peer))
+		}
+		for _, m := range found {
+			if !slices.ContainsFunc(matches, func(existing rules.Match) bool {
+				return awsSDK.ToString(existing.SecurityGroup.GroupId) == awsSDK.ToString(m.SecurityGroup.GroupId) && existing.Peer == m.Peer
+			}) {
+				matches = append(matches, m)
+			}
+		}
+	}
+
+	if len(blocked) > 0 {
+		return matches, StatusBlocked, fmt.Sprintf("no ingress rule permits %s/%d from %s",
+			strings.ToLower(port.Protocol), port.Port, strings.Join(blocked, ", "))
+	}
+	return matches, StatusOK, ""
+}
+
+// peerString describes the source of the traffic of a load balancer
+func peerString(peer rules.Peer) string {
+	var parts []string
+	if peer.IP != nil {
+		parts = append(parts, peer.IP.String())
+	}
+	if len(peer.SecurityGroupIDs) > 0 {
+		parts = append(parts, strings.Join(peer.SecurityGroupIDs, ", "))
+	}
+	if len(parts) == 0 {
+		return "the load balancer"
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return fmt.Sprintf("%s (%s)", parts[0], parts[1])
+}
@@ -0,0 +1,179 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/service"
+)
+
+func TestFrontends(t *testing.T) {
+	alb := "alb"
+	nginx := "nginx"
+	services := []corev1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", Annotations: map[string]string{NLBTargetTypeAnnotation: "ip"}},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{{Name: "grpc", Port: 443}}},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{Hostname: "api.elb.amazonaws.com"}},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP},
+		},
+	}
+	backend := func(name string, port int32) networkingv1.IngressBackend {
+		return networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: name, Port: networkingv1.ServiceBackendPort{Number: port}}}
+	}
+	paths := func(backends ...networkingv1.IngressBackend) []networkingv1.IngressRule {
+		var rule networkingv1.IngressRule
+		rule.HTTP = &networkingv1.HTTPIngressRuleValue{}
+		for _, b := range backends {
+			rule.HTTP.Paths = append(rule.HTTP.Paths, networkingv1.HTTPIngressPath{Backend: b})
+		}
+		return []networkingv1.IngressRule{rule}
+	}
+	ingresses := []networkingv1.Ingress{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: networkingv1.IngressSpec{
+				IngressClassName: &alb,
+				Rules:            paths(backend("web", 80), backend("web", 80), backend("admin", 8080)),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "default", Annotations: map[string]string{IngressClassAnnotation: "alb", ALBTargetTypeAnnotation: "ip"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-class", Namespace: "default"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+			Spec:       networkingv1.IngressSpec{IngressClassName: &nginx},
+		},
+	}
+	classes := []networkingv1.IngressClass{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "alb", Annotations: map[string]string{DefaultIngressClassAnnotation: "true"}},
+			Spec:       networkingv1.IngressClassSpec{Controller: ALBController},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Name: "nginx"}, Spec: networkingv1.IngressClassSpec{Controller: "k8s.io/ingress-nginx"}},
+	}
+
+	frontends := Frontends(services, ingresses, classes)
+	require.Len(t, frontends, 4)
+
+	api := frontends[0]
+	assert.Equal(t, KindService, api.Kind)
+	assert.Equal(t, TargetTypeIP, api.TargetType)
+	assert.Equal(t, []string{"api.elb.amazonaws.com"}, api.Hostnames)
+	assert.Equal(t, []Backend{{Service: "api", Port: networkingv1.ServiceBackendPort{Number: 443}}}, api.Backends)
+
+	web := frontends[1]
+	assert.Equal(t, KindIngress, web.Kind)
+	assert.Equal(t, TargetTypeInstance, web.TargetType)
+	assert.Len(t, web.Backends, 2, "duplicate backends are merged")
+
+	assert.Equal(t, "legacy", frontends[2].Name)
+	assert.Equal(t, TargetTypeIP, frontends[2].TargetType)
+	assert.Equal(t, "default-class", frontends[3].Name)
+}
+
+func TestTargets(t *testing.T) {
+	ready := true
+	port, protocol, nodeName := int32(8080), corev1.ProtocolTCP, "node-1"
+	portName := "http"
+	services := []corev1.Service{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 30080}}},
+	}}
+	endpointSlices := []discoveryv1.EndpointSlice{{
+		ObjectMeta:  metav1.ObjectMeta{Name: "web-abcde", Namespace: "default", Labels: map[string]string{discoveryv1.LabelServiceName: "web"}},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       []discoveryv1.EndpointPort{{Name: &portName, Port: &port, Protocol: &protocol}},
+		Endpoints: []discoveryv1.Endpoint{
+			{Addresses: []string{"10.0.1.10"}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}, NodeName: &nodeName},
+			{Addresses: []string{"10.0.1.11"}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}, NodeName: &nodeName},
+		},
+	}}
+	nodes := []corev1.Node{{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.2.10"}}},
+	}}
+	f := Frontend{Kind: KindIngress, Namespace: "default", Name: "web", Backends: []Backend{
+		{Service: "web", Port: networkingv1.ServiceBackendPort{Name: "http"}},
+		{Service: "missing", Port: networkingv1.ServiceBackendPort{Number: 80}},
+	}}
+
+	f.TargetType = TargetTypeIP
+	targets := Targets(f, services, endpointSlices, nil, nodes)
+	require.Len(t, targets, 2)
+	assert.Equal(t, "10.0.1.10", targets[0].Pod.Status.PodIP)
+	assert.Equal(t, service.Port{Name: "http", Protocol: "TCP", Port: 8080}, targets[0].Port)
+	assert.Empty(t, targets[0].Node)
+
+	f.TargetType = TargetTypeInstance
+	targets = Targets(f, services, endpointSlices, nil, nodes)
+	require.Len(t, targets, 1, "endpoints on the same node share a target")
+	assert.Equal(t, "node-1", targets[0].Node)
+	assert.Equal(t, "10.0.2.10", targets[0].Pod.Status.PodIP)
+	assert.Equal(t, int32(30080), targets[0].Port.Port)
+}
+
+func TestPaths(t *testing.T) {
+	rule := func(protocol string, port int32) types.IpPermission {
+		return types.IpPermission{IpProtocol: awsSDK.String(protocol), FromPort: awsSDK.Int32(port), ToPort: awsSDK.Int32(port)}
+	}
+	fromALB := rule("tcp", 8080)
+	fromALB.UserIdGroupPairs = []types.UserIdGroupPair{{GroupId: awsSDK.String("sg-alb")}}
+	fromSubnet := rule("tcp", 9090)
+	fromSubnet.IpRanges = []types.IpRange{{CidrIp: awsSDK.String("10.0.0.0/24")}}
+
+	lb := &aws.LoadBalancer{
+		LoadBalancer: elbv2types.LoadBalancer{LoadBalancerName: awsSDK.String("web"), SecurityGroups: []string{"sg-alb"}},
+		PrivateIPs:   []string{"10.0.0.10", "10.0.1.10"},
+	}
+	target := func(name string, port int32) Target {
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		return Target{Pod: pod, Port: service.Port{Protocol: "TCP", Port: port}}
+	}
+	infos := map[string]aws.PodSecurityGroupInfo{
+		"default/web-1": {ENI: "eni-1", SecurityGroups: []types.SecurityGroup{{GroupId: awsSDK.String("sg-web"), IpPermissions: []types.IpPermission{fromALB, fromSubnet}}}},
+		"default/web-2": {Status: aws.StatusENINotFound, Reason: "no ENI has IP 10.0.1.12"},
+	}
+	f := Frontend{Kind: KindIngress, Namespace: "default", Name: "web", Hostnames: []string{"web.elb.amazonaws.com"}}
+
+	paths := Paths(f, lb, []Target{target("web-1", 8080), target("web-1", 9090), target("web-2", 8080)}, infos, nil)
+	require.Len(t, paths, 3)
+
+	assert.Equal(t, StatusOK, paths[0].Status)
+	require.Len(t, paths[0].Rules, 1, "the same rule permitting every IP is listed once")
+	assert.Equal(t, "sg-alb", paths[0].Rules[0].Peer)
+
+	assert.Equal(t, StatusBlocked, paths[1].Status)
+	assert.Equal(t, "no ingress rule permits tcp/9090 from 10.0.1.10 (sg-alb)", paths[1].Reason)
+	assert.Len(t, paths[1].Rules, 1)
+
+	assert.Equal(t, StatusUnresolved, paths[2].Status)
+	assert.Equal(t, "no ENI has IP 10.0.1.12", paths[2].Reason)
+
+	paths = Paths(f, nil, nil, infos, nil)
+	require.Len(t, paths, 1)
+	assert.Equal(t, StatusNoLoadBalancer, paths[0].Status)
+	assert.Equal(t, "no ELBv2 load balancer has the hostname web.elb.amazonaws.com", paths[0].Reason)
+
+	paths = Paths(f, lb, nil, infos, nil)
+	require.Len(t, paths, 1)
+	assert.Equal(t, StatusNoEndpoints, paths[0].Status)
+}
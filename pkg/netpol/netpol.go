@@ -0,0 +1,737 @@
+// Package netpol evaluates Kubernetes NetworkPolicies against pods and compares the traffic they allow
+// with the traffic allowed by the security groups of the pods. With security groups for pods, traffic
+// must be allowed by both layers, so a rule of one layer is only effective where the other agrees.
+package netpol
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+)
+
+// Layers a rule comes from
+const (
+	LayerSecurityGroup = "SecurityGroup"
+	LayerNetworkPolicy = "NetworkPolicy"
+)
+
+// Traffic statuses
+const (
+	// StatusAllowed means the other layer allows the traffic too
+	StatusAllowed = "allowed"
+	// StatusBlockedBySecurityGroup means a NetworkPolicy allows the traffic but the security groups do not
+	StatusBlockedBySecurityGroup = "blocked-by-sg"
+	// StatusBlockedByNetworkPolicy means a security group allows the traffic but the NetworkPolicies do not
+	StatusBlockedByNetworkPolicy = "blocked-by-netpol"
+)
+
+// Traffic is the traffic allowed by a single rule peer of one layer, with the verdict of the other layer
+type Traffic struct {
+	// Direction is rules.Ingress or rules.Egress
+	Direction string
+	Layer     string
+	// Rule is the security group ID or the NetworkPolicy name
+	Rule string
+	// Protocol is a normalized protocol name; all ports are allowed when FromPort and ToPort are nil
+	Protocol string
+	FromPort *int32
+	ToPort   *int32
+	// PortName is the named port of a NetworkPolicy rule, resolved against the pod serving it
+	PortName string
+	Peer     string
+	Status   string
+}
+
+// Result is the combined view of the NetworkPolicies and security groups of a pod
+type Result struct {
+	Info aws.PodSecurityGroupInfo
+	// Policies are the names of the NetworkPolicies selecting the pod
+	Policies        []string
+	IngressIsolated bool
+	EgressIsolated  bool
+	Traffic         []Traffic
+}
+
+// Mismatches returns the number of rules allowing traffic the other layer blocks
+func (r Result) Mismatches() int {
+	n := 0
+	for _, t := range r.Traffic {
+		if t.Status != StatusAllowed {
+			n++
+		}
+	}
+	return n
+}
+
+// Cluster is the cluster state NetworkPolicy peers are resolved against
+type Cluster struct {
+	Pods       []corev1.Pod
+	Namespaces []corev1.Namespace
+	// SecurityGroups are the security group lookups of pods by namespace/name
+	SecurityGroups map[string]aws.PodSecurityGroupInfo
+}
+
+// Selecting returns the NetworkPolicies that select the pod
+func Selecting(policies []networkingv1.NetworkPolicy, pod *corev1.Pod) []networkingv1.NetworkPolicy {
+	var selecting []networkingv1.NetworkPolicy
+	for _, p := range policies {
+		if p.Namespace == pod.Namespace && selectorMatches(&p.Spec.PodSelector, pod.Labels) {
+			selecting = append(selecting, p)
+		}
+	}
+	sort.Slice(selecting, func(i, j int) bool { return selecting[i].Name < selecting[j].Name })
+	return selecting
+}
+
+// SelectsOtherNamespaces reports whether a rule of the policies has a namespace selector, so that its
+// peers are resolved against the pods and namespace labels of the whole cluster
+func SelectsOtherNamespaces(policies []networkingv1.NetworkPolicy) bool {
+	for _, p := range policies {
+		for _, r := range p.Spec.Ingress {
+			if hasNamespaceSelector(r.From) {
+				return true
+			}
+		}
+		for _, r := range p.Spec.Egress {
+			if hasNamespaceSelector(r.To) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasNamespaceSelector(peers []networkingv1.NetworkPolicyPeer) bool {
+	for _, p := range peers {
+		if p.NamespaceSelector != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// PeerPods returns the pods selected by the rule peers of the NetworkPolicies selecting the given pods,
+// whose security groups are needed to evaluate the NetworkPolicy rules against the security groups
+func PeerPods(pods []corev1.Pod, policies []networkingv1.NetworkPolicy, c Cluster) []corev1.Pod {
+	var peers []corev1.Pod
+	seen := make(map[string]struct{})
+	for i := range pods {
+		for _, p := range Selecting(policies, &pods[i]) {
+			var rulePeers []networkingv1.NetworkPolicyPeer
+			for _, r := range p.Spec.Ingress {
+				rulePeers = append(rulePeers, r.From...)
+			}
+			for _, r := range p.Spec.Egress {
+				rulePeers = append(rulePeers, r.To...)
+			}
+			for _, rp := range rulePeers {
+				for _, pod := range c.selectedPods(p.Namespace, rp) {
+					key := pod.Namespace + "/" + pod.Name
+					if _, ok := seen[key]; !ok {
+						seen[key] = struct{}{}
+						peers = append(peers, pod)
+					}
+				}
+			}
+		}
+	}
+	return peers
+}
+
+// Evaluate builds the combined view of each pod. Every security group rule peer is checked against the
+// NetworkPolicies selecting the pod, and every NetworkPolicy rule peer against the security groups.
+func Evaluate(infos []aws.PodSecurityGroupInfo, policies []networkingv1.NetworkPolicy, c Cluster) []Result {
+	results := make([]Result, 0, len(infos))
+	for _, info := range infos {
+		selecting := Selecting(policies, &info.Pod)
+		result := Result{
+			Info:            info,
+			IngressIsolated: len(isolating(selecting, rules.Ingress)) > 0,
+			EgressIsolated:  len(isolating(selecting, rules.Egress)) > 0,
+		}
+		for _, p := range selecting {
+			result.Policies = append(result.Policies, p.Name)
+		}
+		for _, direction := range []string{rules.Ingress, rules.Egress} {
+			result.Traffic = append(result.Traffic, c.securityGroupTraffic(info, selecting, direction)...)
+			result.Traffic = append(result.Traffic, c.networkPolicyTraffic(info, selecting, direction)...)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// securityGroupTraffic returns the traffic allowed by each security group rule peer of the pod in the
+// direction, checked against the NetworkPolicies
+func (c Cluster) securityGroupTraffic(info aws.PodSecurityGroupInfo, policies []networkingv1.NetworkPolicy, direction string) []Traffic {
+	var traffic []Traffic
+	for _, sg := range info.SecurityGroups {
+		permissions := sg.IpPermissions
+		if direction == rules.Egress {
+			permissions = sg.IpPermissionsEgress
+		}
+		for _, p := range permissions {
+			t := Traffic{
+				Direction: direction,
+				Layer:     LayerSecurityGroup,
+				Rule:      awsSDK.ToString(sg.GroupId),
+				Protocol:  rules.NormalizeProtocol(awsSDK.ToString(p.IpProtocol)),
+			}
+			if p.FromPort != nil && p.ToPort != nil && *p.FromPort != -1 {
+				t.FromPort, t.ToPort = p.FromPort, p.ToPort
+			}
+			for _, peer := range c.securityGroupPeers(p) {
+				t.Peer = peer.name
+				t.Status = StatusAllowed
+				if !c.policiesAllow(policies, &info.Pod, t, peer.peers) {
+					t.Status = StatusBlockedByNetworkPolicy
+				}
+				traffic = append(traffic, t)
+			}
+		}
+	}
+	return traffic
+}
+
+// namedPeers is a rule peer of a security group with the peers it stands for in NetworkPolicy terms
+type namedPeers struct {
+	name  string
+	peers []peer
+}
+
+// peer is the remote end of traffic: a pod, or a CIDR outside the cluster. A peer with neither, such as
+// a security group no known pod uses, only matches NetworkPolicy rules allowing every peer.
+type peer struct {
+	pod  *corev1.Pod
+	cidr *net.IPNet
+}
+
+// securityGroupPeers flattens the peers of a permission. A security group peer stands for the known pods
+// using it.
+func (c Cluster) securityGroupPeers(p types.IpPermission) []namedPeers {
+	var out []namedPeers
+	cidr := func(s string) namedPeers {
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return namedPeers{name: s, peers: []peer{{}}}
+		}
+		return namedPeers{name: s, peers: []peer{{cidr: network}}}
+	}
+	for _, r := range p.IpRanges {
+		out = append(out, cidr(awsSDK.ToString(r.CidrIp)))
+	}
+	for _, r := range p.Ipv6Ranges {
+		out = append(out, cidr(awsSDK.ToString(r.CidrIpv6)))
+	}
+	for _, g := range p.UserIdGroupPairs {
+		id := awsSDK.ToString(g.GroupId)
+		var peers []peer
+		for _, info := range c.SecurityGroups {
+			if usesSecurityGroup(info, id) && info.Pod.Status.PodIP != "" {
+				pod := info.Pod
+				peers = append(peers, peer{pod: &pod})
+			}
+		}
+		if len(peers) == 0 {
+			peers = []peer{{}}
+		}
+		out = append(out, namedPeers{name: id, peers: peers})
+	}
+	for _, pl := range p.PrefixListIds {
+		out = append(out, namedPeers{name: awsSDK.ToString(pl.PrefixListId), peers: []peer{{}}})
+	}
+	return out
+}
+
+// policiesAllow reports whether the NetworkPolicies selecting the pod allow the traffic with every peer
+func (c Cluster) policiesAllow(policies []networkingv1.NetworkPolicy, pod *corev1.Pod, t Traffic, peers []peer) bool {
+	isolated := isolating(policies, t.Direction)
+	if len(isolated) == 0 {
+		return true
+	}
+	for _, p := range peers {
+		if !c.policiesAllowPeer(isolated, pod, t, p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Cluster) policiesAllowPeer(policies []networkingv1.NetworkPolicy, pod *corev1.Pod, t Traffic, p peer) bool {
+	// Named ports are the ports of the pod receiving the traffic
+	portPod := pod
+	if t.Direction == rules.Egress {
+		portPod = p.pod
+	}
+	for _, np := range policies {
+		for _, r := range policyRules(np, t.Direction) {
+			if portsCover(r.ports, portPod, t) && c.peersMatch(np.Namespace, r.peers, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// networkPolicyTraffic returns the traffic allowed by each NetworkPolicy rule peer of the pod in the
+// direction, checked against the security groups
+func (c Cluster) networkPolicyTraffic(info aws.PodSecurityGroupInfo, policies []networkingv1.NetworkPolicy, direction string) []Traffic {
+	var traffic []Traffic
+	for _, np := range isolating(policies, direction) {
+		for _, r := range policyRules(np, direction) {
+			ports := r.ports
+			if len(ports) == 0 {
+				ports = []networkingv1.NetworkPolicyPort{{}}
+			}
+			rulePeers := r.peers
+			if len(rulePeers) == 0 {
+				rulePeers = []networkingv1.NetworkPolicyPeer{{}}
+			}
+			for _, port := range ports {
+				for _, rp := range rulePeers {
+					t := portTraffic(port)
+					t.Direction = direction
+					t.Layer = LayerNetworkPolicy
+					t.Rule = np.Name
+					t.Peer = describePeer(rp)
+					t.Status = StatusAllowed
+					if !c.securityGroupsAllow(info, np.Namespace, t, rp) {
+						t.Status = StatusBlockedBySecurityGroup
+					}
+					traffic = append(traffic, t)
+				}
+			}
+		}
+	}
+	return traffic
+}
+
+// securityGroupsAllow reports whether the security groups of the pod allow the traffic with every peer
+// the NetworkPolicy peer stands for. A selector peer selecting no pods allows no traffic, so nothing
+// is blocked.
+func (c Cluster) securityGroupsAllow(info aws.PodSecurityGroupInfo, namespace string, t Traffic, rp networkingv1.NetworkPolicyPeer) bool {
+	if rp.IPBlock != nil || (rp.PodSelector == nil && rp.NamespaceSelector == nil) {
+		port := portOf(t, &info.Pod)
+		if t.PortName != "" && (t.Direction == rules.Egress || port == nil) {
+			// A named port that does not resolve, such as one of a peer outside the cluster, matches nothing
+			return true
+		}
+		if rp.IPBlock == nil {
+			return permits(info.SecurityGroups, t, port, func(sgPeer string, group bool) bool {
+				return sgPeer == "0.0.0.0/0" || sgPeer == "::/0"
+			})
+		}
+		_, network, err := net.ParseCIDR(rp.IPBlock.CIDR)
+		if err != nil {
+			return false
+		}
+		return permits(info.SecurityGroups, t, port, func(sgPeer string, group bool) bool {
+			return !group && cidrCovers(sgPeer, network)
+		})
+	}
+
+	for _, pod := range c.selectedPods(namespace, rp) {
+		portPod := &info.Pod
+		if t.Direction == rules.Egress {
+			portPod = &pod
+		}
+		port := portOf(t, portPod)
+		if t.PortName != "" && port == nil {
+			continue
+		}
+		ip := net.ParseIP(pod.Status.PodIP)
+		sgIDs := c.securityGroupIDs(pod)
+		allowed := permits(info.SecurityGroups, t, port, func(sgPeer string, group bool) bool {
+			if group {
+				return contains(sgIDs, sgPeer)
+			}
+			return cidrContains(sgPeer, ip)
+		})
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// permits reports whether a permission of the security groups in the direction of the traffic covers its
+// protocol and ports and has a peer accepted by match. port overrides the ports of the traffic when a
+// named port was resolved.
+func permits(sgs []types.SecurityGroup, t Traffic, port *int32, match func(sgPeer string, group bool) bool) bool {
+	from, to := t.FromPort, t.ToPort
+	if port != nil {
+		from, to = port, port
+	}
+	for _, sg := range sgs {
+		permissions := sg.IpPermissions
+		if t.Direction == rules.Egress {
+			permissions = sg.IpPermissionsEgress
+		}
+		for _, p := range permissions {
+			if !permissionCovers(p, t.Protocol, from, to) {
+				continue
+			}
+			for _, r := range p.IpRanges {
+				if match(awsSDK.ToString(r.CidrIp), false) {
+					return true
+				}
+			}
+			for _, r := range p.Ipv6Ranges {
+				if match(awsSDK.ToString(r.CidrIpv6), false) {
+					return true
+				}
+			}
+			for _, g := range p.UserIdGroupPairs {
+				if match(awsSDK.ToString(g.GroupId), true) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// permissionCovers reports whether the permission covers the protocol and every port of the range.
+// Nil ports stand for all ports.
+func permissionCovers(p types.IpPermission, protocol string, from, to *int32) bool {
+	ruleProtocol := rules.NormalizeProtocol(awsSDK.ToString(p.IpProtocol))
+	if ruleProtocol == "all" {
+		return true
+	}
+	if ruleProtocol != protocol {
+		return false
+	}
+	if ruleProtocol == "icmp" || ruleProtocol == "icmpv6" {
+		return true
+	}
+	if p.FromPort == nil || p.ToPort == nil || *p.FromPort == -1 || (*p.FromPort == 0 && *p.ToPort == 65535) {
+		return true
+	}
+	if from == nil || to == nil {
+		return false
+	}
+	return *p.FromPort <= *from && *to <= *p.ToPort
+}
+
+// rule is an ingress or egress rule of a NetworkPolicy
+type rule struct {
+	ports []networkingv1.NetworkPolicyPort
+	peers []networkingv1.NetworkPolicyPeer
+}
+
+func policyRules(p networkingv1.NetworkPolicy, direction string) []rule {
+	var out []rule
+	if direction == rules.Egress {
+		for _, r := range p.Spec.Egress {
+			out = append(out, rule{r.Ports, r.To})
+		}
+		return out
+	}
+	for _, r := range p.Spec.Ingress {
+		out = append(out, rule{r.Ports, r.From})
+	}
+	return out
+}
+
+// isolating returns the policies that isolate the pod in the direction. Policies without policyTypes
+// always isolate ingress, and isolate egress when they have egress rules.
+func isolating(policies []networkingv1.NetworkPolicy, direction string) []networkingv1.NetworkPolicy {
+	policyType := networkingv1.PolicyTypeIngress
+	if direction == rules.Egress {
+		policyType = networkingv1.PolicyTypeEgress
+	}
+	var out []networkingv1.NetworkPolicy
+	for _, p := range policies {
+		policyTypes := p.Spec.PolicyTypes
+		if len(policyTypes) == 0 {
+			policyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+			if len(p.Spec.Egress) > 0 {
+				policyTypes = append(policyTypes, networkingv1.PolicyTypeEgress)
+			}
+		}
+		for _, t := range policyTypes {
+			if t == policyType {
+				out = append(out, p)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// portTraffic returns the protocol and ports of a NetworkPolicy port. An empty port allows every port
+// of every protocol.
+func portTraffic(port networkingv1.NetworkPolicyPort) Traffic {
+	if port.Protocol == nil && port.Port == nil && port.EndPort == nil {
+		return Traffic{Protocol: "all"}
+	}
+	t := Traffic{Protocol: policyProtocol(port)}
+	switch {
+	case port.Port == nil:
+	case port.Port.Type == intstr.String:
+		t.PortName = port.Port.StrVal
+	default:
+		from, to := port.Port.IntVal, port.Port.IntVal
+		if port.EndPort != nil {
+			to = *port.EndPort
+		}
+		t.FromPort, t.ToPort = &from, &to
+	}
+	return t
+}
+
+// portsCover reports whether a port of a NetworkPolicy rule covers the protocol and every port of the
+// traffic. Named ports are resolved against the container ports of portPod.
+func portsCover(ports []networkingv1.NetworkPolicyPort, portPod *corev1.Pod, t Traffic) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	if t.Protocol == "all" {
+		return false
+	}
+	for _, port := range ports {
+		if policyProtocol(port) != t.Protocol {
+			continue
+		}
+		if port.Port == nil {
+			return true
+		}
+		from, to := port.Port.IntVal, port.Port.IntVal
+		if port.Port.Type == intstr.String {
+			resolved := namedPort(portPod, port.Port.StrVal, t.Protocol)
+			if resolved == nil {
+				continue
+			}
+			from, to = *resolved, *resolved
+		} else if port.EndPort != nil {
+			to = *port.EndPort
+		}
+		if t.FromPort != nil && t.ToPort != nil && from <= *t.FromPort && *t.ToPort <= to {
+			return true
+		}
+	}
+	return false
+}
+
+// policyProtocol returns the normalized protocol of a NetworkPolicy port, TCP by default
+func policyProtocol(port networkingv1.NetworkPolicyPort) string {
+	if port.Protocol == nil {
+		return "tcp"
+	}
+	return strings.ToLower(string(*port.Protocol))
+}
+
+// portOf resolves the named port of the traffic against the pod, returning nil for numbered ports
+func portOf(t Traffic, pod *corev1.Pod) *int32 {
+	if t.PortName == "" {
+		return nil
+	}
+	return namedPort(pod, t.PortName, t.Protocol)
+}
+
+// namedPort returns the number of the named container port of the pod with the protocol, or nil
+func namedPort(pod *corev1.Pod, name, protocol string) *int32 {
+	if pod == nil {
+		return nil
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			portProtocol := strings.ToLower(string(p.Protocol))
+			if portProtocol == "" {
+				portProtocol = "tcp"
+			}
+			if p.Name == name && portProtocol == protocol {
+				n := p.ContainerPort
+				return &n
+			}
+		}
+	}
+	return nil
+}
+
+// peersMatch reports whether one of the peers of a NetworkPolicy rule in the namespace matches the peer.
+// A rule without peers matches every peer.
+func (c Cluster) peersMatch(namespace string, rulePeers []networkingv1.NetworkPolicyPeer, p peer) bool {
+	if len(rulePeers) == 0 {
+		return true
+	}
+	for _, rp := range rulePeers {
+		if rp.IPBlock != nil {
+			if ipBlockMatches(rp.IPBlock, p) {
+				return true
+			}
+			continue
+		}
+		if p.pod != nil && c.selects(namespace, rp, p.pod) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipBlockMatches reports whether the IP block contains the pod IP or the whole CIDR of the peer,
+// outside its exceptions
+func ipBlockMatches(block *networkingv1.IPBlock, p peer) bool {
+	_, network, err := net.ParseCIDR(block.CIDR)
+	if err != nil {
+		return false
+	}
+	if p.pod != nil {
+		ip := net.ParseIP(p.pod.Status.PodIP)
+		if ip == nil || !network.Contains(ip) {
+			return false
+		}
+		for _, except := range block.Except {
+			if cidrContains(except, ip) {
+				return false
+			}
+		}
+		return true
+	}
+	if p.cidr == nil || !cidrCovers(block.CIDR, p.cidr) {
+		return false
+	}
+	for _, except := range block.Except {
+		if _, e, err := net.ParseCIDR(except); err == nil && (e.Contains(p.cidr.IP) || p.cidr.Contains(e.IP)) {
+			return false
+		}
+	}
+	return true
+}
+
+// selects reports whether the selector peer of a NetworkPolicy in the namespace selects the pod
+func (c Cluster) selects(namespace string, rp networkingv1.NetworkPolicyPeer, pod *corev1.Pod) bool {
+	if rp.PodSelector == nil && rp.NamespaceSelector == nil {
+		return false
+	}
+	if rp.NamespaceSelector == nil {
+		if pod.Namespace != namespace {
+			return false
+		}
+	} else if !selectorMatches(rp.NamespaceSelector, c.namespaceLabels(pod.Namespace)) {
+		return false
+	}
+	return rp.PodSelector == nil || selectorMatches(rp.PodSelector, pod.Labels)
+}
+
+// selectedPods returns the pods with an IP selected by the selector peer of a NetworkPolicy in the namespace
+func (c Cluster) selectedPods(namespace string, rp networkingv1.NetworkPolicyPeer) []corev1.Pod {
+	var pods []corev1.Pod
+	for i := range c.Pods {
+		if c.Pods[i].Status.PodIP != "" && c.selects(namespace, rp, &c.Pods[i]) {
+			pods = append(pods, c.Pods[i])
+		}
+	}
+	return pods
+}
+
+// namespaceLabels returns the labels of the namespace. Namespaces that were not listed only carry the
+// kubernetes.io/metadata.name label every namespace has.
+func (c Cluster) namespaceLabels(name string) map[string]string {
+	for _, ns := range c.Namespaces {
+		if ns.Name == name {
+			return ns.Labels
+		}
+	}
+	return map[string]string{corev1.LabelMetadataName: name}
+}
+
+// securityGroupIDs returns the IDs of the security groups of the pod, when it was looked up
+func (c Cluster) securityGroupIDs(pod corev1.Pod) []string {
+	var ids []string
+	for _, sg := range c.SecurityGroups[pod.Namespace+"/"+pod.Name].SecurityGroups {
+		ids = append(ids, awsSDK.ToString(sg.GroupId))
+	}
+	return ids
+}
+
+// describePeer returns a short description of a NetworkPolicy peer, such as
+// "pods(app=web) in namespaces(team=a)" or "10.0.0.0/8 except 10.0.1.0/24"
+func describePeer(rp networkingv1.NetworkPolicyPeer) string {
+	switch {
+	case rp.IPBlock != nil:
+		if len(rp.IPBlock.Except) > 0 {
+			return fmt.Sprintf("%s except %s", rp.IPBlock.CIDR, strings.Join(rp.IPBlock.Except, ","))
+		}
+		return rp.IPBlock.CIDR
+	case rp.PodSelector != nil && rp.NamespaceSelector != nil:
+		return fmt.Sprintf("pods(%s) in namespaces(%s)", describeSelector(rp.PodSelector), describeSelector(rp.NamespaceSelector))
+	case rp.PodSelector != nil:
+		return fmt.Sprintf("pods(%s)", describeSelector(rp.PodSelector))
+	case rp.NamespaceSelector != nil:
+		return fmt.Sprintf("namespaces(%s)", describeSelector(rp.NamespaceSelector))
+	default:
+		return "all"
+	}
+}
+
+func describeSelector(selector *metav1.LabelSelector) string {
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		return "all"
+	}
+	return metav1.FormatLabelSelector(selector)
+}
+
+// selectorMatches reports whether the label selector matches the labels; invalid selectors match nothing
+func selectorMatches(selector *metav1.LabelSelector, set map[string]string) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(labels.Set(set))
+}
+
+// usesSecurityGroup reports whether the pod uses the security group
+func usesSecurityGroup(info aws.PodSecurityGroupInfo, id string) bool {
+	for _, sg := range info.SecurityGroups {
+		if awsSDK.ToString(sg.GroupId) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// cidrContains reports whether the CIDR contains the IP
+func cidrContains(cidr string, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	return network.Contains(ip)
+}
+
+// cidrCovers reports whether the CIDR contains the whole network
+func cidrCovers(cidr string, network *net.IPNet) bool {
+	_, outer, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	outerOnes, outerBits := outer.Mask.Size()
+	ones, bits := network.Mask.Size()
+	return outerBits == bits && outerOnes <= ones && outer.Contains(network.IP)
+}
+
+// contains reports whether the slice contains the value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package netpol
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+func newPod(name, namespace, ip string, podLabels map[string]string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: podLabels},
+		Status:     corev1.PodStatus{PodIP: ip},
+	}
+}
+
+func newInfo(pod corev1.Pod, sgs ...types.SecurityGroup) aws.PodSecurityGroupInfo {
+	return aws.PodSecurityGroupInfo{Pod: pod, ENI: "eni-" + pod.Name, AttachmentLevel: "pod", SecurityGroups: sgs}
+}
+
+func permission(protocol string, from, to int32) types.IpPermission {
+	return types.IpPermission{IpProtocol: awsSDK.String(protocol), FromPort: awsSDK.Int32(from), ToPort: awsSDK.Int32(to)}
+}
+
+func tcpPort(port intstr.IntOrString) networkingv1.NetworkPolicyPort {
+	return networkingv1.NetworkPolicyPort{Port: &port}
+}
+
+func TestSelecting(t *testing.T) {
+	pod := newPod("web", "default", "10.0.1.10", map[string]string{"app": "web"})
+	policies := []networkingv1.NetworkPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: networkingv1.NetworkPolicySpec{PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "deny-all", Namespace: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}, Spec: networkingv1.NetworkPolicySpec{PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "other"}},
+	}
+
+	var names []string
+	for _, p := range Selecting(policies, &pod) {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"deny-all", "web"}, names)
+}
+
+func TestIsolating(t *testing.T) {
+	ingressOnly := networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "ingress"}}
+	withEgress := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "egress-rules"},
+		Spec:       networkingv1.NetworkPolicySpec{Egress: []networkingv1.NetworkPolicyEgressRule{{}}},
+	}
+	egressType := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "egress-type"},
+		Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}},
+	}
+
+	assert.Len(t, isolating([]networkingv1.NetworkPolicy{ingressOnly}, "ingress"), 1)
+	assert.Empty(t, isolating([]networkingv1.NetworkPolicy{ingressOnly}, "egress"))
+	assert.Len(t, isolating([]networkingv1.NetworkPolicy{withEgress}, "egress"), 1)
+	assert.Empty(t, isolating([]networkingv1.NetworkPolicy{egressType}, "ingress"))
+	assert.Len(t, isolating([]networkingv1.NetworkPolicy{egressType}, "egress"), 1)
+}
+
+func TestEvaluate(t *testing.T) {
+	web := newPod("web", "default", "10.0.1.10", map[string]string{"app": "web"})
+	web.Spec.Containers = []corev1.Container{{Ports: []corev1.ContainerPort{
+		{Name: "http", ContainerPort: 8080},
+		{Name: "metrics", ContainerPort: 9090},
+	}}}
+	front := newPod("front", "default", "10.0.1.20", map[string]string{"app": "front"})
+	db := newPod("db", "data", "10.0.2.10", map[string]string{"app": "db"})
+
+	fromVPC := permission("tcp", 8080, 8080)
+	fromVPC.IpRanges = []types.IpRange{{CidrIp: awsSDK.String("10.0.0.0/16")}}
+	fromFront := permission("tcp", 9090, 9090)
+	fromFront.UserIdGroupPairs = []types.UserIdGroupPair{{GroupId: awsSDK.String("sg-front")}}
+	toAll := types.IpPermission{IpProtocol: awsSDK.String("-1"), IpRanges: []types.IpRange{{CidrIp: awsSDK.String("0.0.0.0/0")}}}
+	sgWeb := types.SecurityGroup{GroupId: awsSDK.String("sg-web"), IpPermissions: []types.IpPermission{fromVPC, fromFront}, IpPermissionsEgress: []types.IpPermission{toAll}}
+
+	postgres := networkingv1.NetworkPolicyPort{Port: &intstr.IntOrString{IntVal: 5432}}
+	policy := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					Ports: []networkingv1.NetworkPolicyPort{tcpPort(intstr.FromInt32(8080)), tcpPort(intstr.FromString("metrics"))},
+					From:  []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "front"}}}},
+				},
+				{
+					Ports: []networkingv1.NetworkPolicyPort{tcpPort(intstr.FromInt32(8080))},
+					From:  []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "192.168.0.0/16"}}},
+				},
+			},
+			Egress: []networkingv1.NetworkPolicyEgressRule{{
+				Ports: []networkingv1.NetworkPolicyPort{postgres},
+				To:    []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "data"}}}},
+			}},
+		},
+	}
+	policies := []networkingv1.NetworkPolicy{policy}
+
+	c := Cluster{
+		Pods:       []corev1.Pod{web, front, db},
+		Namespaces: []corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: "data", Labels: map[string]string{"team": "data"}}}},
+	}
+	assert.True(t, SelectsOtherNamespaces(policies))
+
+	peers := PeerPods([]corev1.Pod{web}, policies, c)
+	var peerNames []string
+	for _, p := range peers {
+		peerNames = append(peerNames, p.Name)
+	}
+	assert.Equal(t, []string{"front", "db"}, peerNames)
+
+	c.SecurityGroups = map[string]aws.PodSecurityGroupInfo{
+		"default/web":   newInfo(web, sgWeb),
+		"default/front": newInfo(front, types.SecurityGroup{GroupId: awsSDK.String("sg-front")}),
+		"data/db":       newInfo(db, types.SecurityGroup{GroupId: awsSDK.String("sg-db")}),
+	}
+	results := Evaluate([]aws.PodSecurityGroupInfo{newInfo(web, sgWeb)}, policies, c)
+	require.Len(t, results, 1)
+
+	r := results[0]
+	assert.Equal(t, []string{"web"}, r.Policies)
+	assert.True(t, r.IngressIsolated)
+	assert.True(t, r.EgressIsolated)
+
+	var got []string
+	for _, tr := range r.Traffic {
+		port := tr.PortName
+		if tr.FromPort != nil {
+			port = fmt.Sprintf("%d-%d", *tr.FromPort, *tr.ToPort)
+		}
+		got = append(got, fmt.Sprintf("%s %s %s %s %s %s %s", tr.Direction, tr.Layer, tr.Rule, tr.Protocol, port, tr.Peer, tr.Status))
+	}
+	assert.Equal(t, []string{
+		"ingress SecurityGroup sg-web tcp 8080-8080 10.0.0.0/16 blocked-by-netpol",
+		"ingress SecurityGroup sg-web tcp 9090-9090 sg-front allowed",
+		"ingress NetworkPolicy web tcp 8080-8080 pods(app=front) allowed",
+		"ingress NetworkPolicy web tcp metrics pods(app=front) allowed",
+		"ingress NetworkPolicy web tcp 8080-8080 192.168.0.0/16 blocked-by-sg",
+		"egress SecurityGroup sg-web all  0.0.0.0/0 blocked-by-netpol",
+		"egress NetworkPolicy web tcp 5432-5432 namespaces(team=data) allowed",
+	}, got)
+	assert.Equal(t, 3, r.Mismatches())
+}
+
+func TestEvaluate_NotIsolated(t *testing.T) {
+	web := newPod("web", "default", "10.0.1.10", map[string]string{"app": "web"})
+	fromAll := permission("tcp", 443, 443)
+	fromAll.IpRanges = []types.IpRange{{CidrIp: awsSDK.String("0.0.0.0/0")}}
+	info := newInfo(web, types.SecurityGroup{GroupId: awsSDK.String("sg-web"), IpPermissions: []types.IpPermission{fromAll}})
+
+	results := Evaluate([]aws.PodSecurityGroupInfo{info}, nil, Cluster{Pods: []corev1.Pod{web}})
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Policies)
+	assert.False(t, results[0].IngressIsolated)
+	require.Len(t, results[0].Traffic, 1)
+	assert.Equal(t, StatusAllowed, results[0].Traffic[0].Status)
+}
+
+func TestIPBlockMatches(t *testing.T) {
+	block := &networkingv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16"}}
+	cidr := func(s string) peer {
+		_, network, err := net.ParseCIDR(s)
+		require.NoError(t, err)
+		return peer{cidr: network}
+	}
+	pod := func(ip string) peer {
+		p := newPod("web", "default", ip, nil)
+		return peer{pod: &p}
+	}
+
+	assert.True(t, ipBlockMatches(block, cidr("10.2.0.0/16")))
+	assert.False(t, ipBlockMatches(block, cidr("10.1.2.0/24")), "inside an exception")
+	assert.False(t, ipBlockMatches(block, cidr("10.0.0.0/7")), "wider than the block")
+	assert.False(t, ipBlockMatches(block, cidr("0.0.0.0/0")), "contains an exception")
+	assert.True(t, ipBlockMatches(block, pod("10.2.3.4")))
+	assert.False(t, ipBlockMatches(block, pod("10.1.3.4")))
+	assert.False(t, ipBlockMatches(block, peer{}))
+}
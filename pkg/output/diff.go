@@ -0,0 +1,304 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+)
+
+// Pod changes
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Empty reports whether the diff found no differences
+func (d DiffOutput) Empty() bool {
+	return len(d.Pods) == 0 && len(d.SecurityGroups) == 0
+}
+
+// DiffPodOutputs compares two pod security group mappings keyed by context, namespace and pod. Rule
+// changes are reported once per security group present in both mappings.
+func DiffPodOutputs(oldPods, newPods []PodOutput) DiffOutput {
+	oldByKey := podOutputsByKey(oldPods)
+	newByKey := podOutputsByKey(newPods)
+
+	keys := make([]string, 0, len(oldByKey)+len(newByKey))
+	for k := range oldByKey {
+		keys = append(keys, k)
+	}
+	for k := range newByKey {
+		if _, ok := oldByKey[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	result := DiffOutput{Pods: []PodDiffOutput{}, SecurityGroups: []SecurityGroupDiffOutput{}}
+	for _, k := range keys {
+		o, inOld := oldByKey[k]
+		n, inNew := newByKey[k]
+		switch {
+		case !inOld:
+			result.Pods = append(result.Pods, PodDiffOutput{
+				Context:             n.Context,
+				Namespace:           n.Namespace,
+				PodName:             n.PodName,
+				Change:              ChangeAdded,
+				NewENI:              n.ENI,
+				NewAttachmentLevel:  n.AttachmentLevel,
+				AddedSecurityGroups: securityGroupIDs(n.SecurityGroups),
+			})
+		case !inNew:
+			result.Pods = append(result.Pods, PodDiffOutput{
+				Context:               o.Context,
+				Namespace:             o.Namespace,
+				PodName:               o.PodName,
+				Change:                ChangeRemoved,
+				OldENI:                o.ENI,
+				OldAttachmentLevel:    o.AttachmentLevel,
+				RemovedSecurityGroups: securityGroupIDs(o.SecurityGroups),
+			})
+		default:
+			if d, changed := diffPod(o, n); changed {
+				result.Pods = append(result.Pods, d)
+			}
+		}
+	}
+
+	oldGroups := securityGroupsByID(oldPods)
+	newGroups := securityGroupsByID(newPods)
+	ids := make([]string, 0, len(oldGroups))
+	for id := range oldGroups {
+		if _, ok := newGroups[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		added, removed := diffStrings(ruleStrings(oldGroups[id]), ruleStrings(newGroups[id]))
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		name := ""
+		if newGroups[id].Name != nil {
+			name = *newGroups[id].Name
+		}
+		result.SecurityGroups = append(result.SecurityGroups, SecurityGroupDiffOutput{
+			ID:           id,
+			Name:         name,
+			AddedRules:   added,
+			RemovedRules: removed,
+		})
+	}
+
+	return result
+}
+
+// diffPod compares a pod present in both mappings
+func diffPod(o, n PodOutput) (PodDiffOutput, bool) {
+	d := PodDiffOutput{Context: n.Context, Namespace: n.Namespace, PodName: n.PodName, Change: ChangeChanged}
+	changed := false
+	if o.ENI != n.ENI {
+		d.OldENI, d.NewENI = o.ENI, n.ENI
+		changed = true
+	}
+	if o.AttachmentLevel != n.AttachmentLevel {
+		d.OldAttachmentLevel, d.NewAttachmentLevel = o.AttachmentLevel, n.AttachmentLevel
+		changed = true
+	}
+	if o.Status != n.Status {
+		d.OldStatus, d.NewStatus = o.Status, n.Status
+		changed = true
+	}
+	d.AddedSecurityGroups, d.RemovedSecurityGroups = diffStrings(securityGroupIDs(o.SecurityGroups), securityGroupIDs(n.SecurityGroups))
+	if len(d.AddedSecurityGroups) > 0 || len(d.RemovedSecurityGroups) > 0 {
+		changed = true
+	}
+	return d, changed
+}
+
+// podOutputsByKey indexes the pods by context/namespace/pod, so that pods of the same name in
+// several clusters are kept apart
+func podOutputsByKey(pods []PodOutput) map[string]PodOutput {
+	m := make(map[string]PodOutput, len(pods))
+	for _, p := range pods {
+		m[p.Context+"/"+p.Namespace+"/"+p.PodName] = p
+	}
+	return m
+}
+
+func securityGroupsByID(pods []PodOutput) map[string]SecurityGroupOutput {
+	m := make(map[string]SecurityGroupOutput)
+	for _, p := range pods {
+		for _, sg := range p.SecurityGroups {
+			if _, ok := m[sg.ID]; !ok {
+				m[sg.ID] = sg
+			}
+		}
+	}
+	return m
+}
+
+func securityGroupIDs(sgs []SecurityGroupOutput) []string {
+	ids := make([]string, 0, len(sgs))
+	for _, sg := range sgs {
+		ids = append(ids, sg.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ruleStrings renders each rule peer of a security group as a single comparable line
+func ruleStrings(sg SecurityGroupOutput) []string {
+	var lines []string
+	for _, r := range sg.InboundRules {
+		lines = append(lines, ruleLines(DirectionInbound, "from", r, r.Sources)...)
+	}
+	for _, r := range sg.OutboundRules {
+		lines = append(lines, ruleLines(DirectionOutbound, "to", r, r.Destinations)...)
+	}
+	sort.Strings(lines)
+	return slices.Compact(lines)
+}
+
+func ruleLines(direction, preposition string, r RuleOutput, peers []string) []string {
+	rule := fmt.Sprintf("%s %s %s", direction, rules.NormalizeProtocol(r.Protocol), FormatPortRange(r.Protocol, r.FromPort, r.ToPort))
+	if len(peers) == 0 {
+		return []string{rule}
+	}
+	lines := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		lines = append(lines, fmt.Sprintf("%s %s %s", rule, preposition, peer))
+	}
+	return lines
+}
+
+// diffStrings returns the sorted values only in b (added) and only in a (removed)
+func diffStrings(a, b []string) ([]string, []string) {
+	var added, removed []string
+	for _, v := range b {
+		if !slices.Contains(a, v) {
+			added = append(added, v)
+		}
+	}
+	for _, v := range a {
+		if !slices.Contains(b, v) {
+			removed = append(removed, v)
+		}
+	}
+	return added, removed
+}
+
+// OutputDiff formats and outputs the differences between two pod security group mappings
+func OutputDiff(w io.Writer, d DiffOutput, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(d)
+	case "json-minimal":
+		b, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "yaml":
+		b, err := yaml.Marshal(d)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, string(b))
+		return err
+	default:
+		return outputDiffTable(w, d)
+	}
+}
+
+// outputDiffTable outputs the pod changes followed by the rule changes, with + and - marking added and removed entries
+func outputDiffTable(w io.Writer, d DiffOutput) error {
+	if d.Empty() {
+		_, err := fmt.Fprintln(w, "No differences found")
+		return err
+	}
+
+	if len(d.Pods) > 0 {
+		withContext := slices.ContainsFunc(d.Pods, func(p PodDiffOutput) bool { return p.Context != "" })
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		if withContext {
+			fmt.Fprint(tw, "CONTEXT\t")
+		}
+		fmt.Fprintln(tw, "NAMESPACE\tPOD NAME\tCHANGE\tDETAILS")
+		for _, p := range d.Pods {
+			if withContext {
+				fmt.Fprintf(tw, "%s\t", p.Context)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Namespace, p.PodName, p.Change, podDiffDetails(p))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(d.SecurityGroups) > 0 {
+		if len(d.Pods) > 0 {
+			fmt.Fprintln(w)
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "SECURITY GROUP\tCHANGE\tRULE")
+		for _, sg := range d.SecurityGroups {
+			label := sg.ID
+			if sg.Name != "" {
+				label = fmt.Sprintf("%s (%s)", sg.ID, sg.Name)
+			}
+			for _, r := range sg.AddedRules {
+				fmt.Fprintf(tw, "%s\t+\t%s\n", label, r)
+			}
+			for _, r := range sg.RemovedRules {
+				fmt.Fprintf(tw, "%s\t-\t%s\n", label, r)
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func podDiffDetails(p PodDiffOutput) string {
+	var details []string
+	switch p.Change {
+	case ChangeAdded:
+		details = append(details, fmt.Sprintf("eni: %s", p.NewENI))
+	case ChangeRemoved:
+		details = append(details, fmt.Sprintf("eni: %s", p.OldENI))
+	default:
+		if p.OldENI != p.NewENI {
+			details = append(details, fmt.Sprintf("eni: %s -> %s", p.OldENI, p.NewENI))
+		}
+		if p.OldAttachmentLevel != p.NewAttachmentLevel {
+			details = append(details, fmt.Sprintf("attachment: %s -> %s", p.OldAttachmentLevel, p.NewAttachmentLevel))
+		}
+		if p.OldStatus != p.NewStatus {
+			details = append(details, fmt.Sprintf("status: %s -> %s", p.OldStatus, p.NewStatus))
+		}
+	}
+	for _, id := range p.AddedSecurityGroups {
+		details = append(details, "+"+id)
+	}
+	for _, id := range p.RemovedSecurityGroups {
+		details = append(details, "-"+id)
+	}
+	return strings.Join(details, " ")
+}
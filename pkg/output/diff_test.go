@@ -0,0 +1,117 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffPodOutputs(t *testing.T) {
+	web := SecurityGroupOutput{
+		ID:           "sg-web",
+		Name:         strPtr("web"),
+		InboundRules: []RuleOutput{{Protocol: "tcp", FromPort: int32Ptr(443), ToPort: int32Ptr(443), Sources: []string{"10.0.0.0/8"}}},
+	}
+	webChanged := web
+	webChanged.InboundRules = []RuleOutput{{Protocol: "tcp", FromPort: int32Ptr(443), ToPort: int32Ptr(443), Sources: []string{"10.0.0.0/8", "192.168.0.0/16"}}}
+	db := SecurityGroupOutput{ID: "sg-db"}
+
+	oldPods := []PodOutput{
+		{Namespace: "default", PodName: "api", ENI: "eni-1", AttachmentLevel: "pod", SecurityGroups: []SecurityGroupOutput{web}},
+		{Namespace: "default", PodName: "gone", ENI: "eni-2", AttachmentLevel: "node", SecurityGroups: []SecurityGroupOutput{db}},
+		{Namespace: "default", PodName: "same", ENI: "eni-3", AttachmentLevel: "pod", SecurityGroups: []SecurityGroupOutput{web}},
+	}
+	newPods := []PodOutput{
+		{Namespace: "default", PodName: "api", ENI: "eni-9", AttachmentLevel: "pod", SecurityGroups: []SecurityGroupOutput{webChanged, db}},
+		{Namespace: "default", PodName: "new", ENI: "eni-4", AttachmentLevel: "pod", SecurityGroups: []SecurityGroupOutput{db}},
+		{Namespace: "default", PodName: "same", ENI: "eni-3", AttachmentLevel: "pod", SecurityGroups: []SecurityGroupOutput{webChanged}},
+	}
+
+	d := DiffPodOutputs(oldPods, newPods)
+
+	assert.Equal(t, []PodDiffOutput{
+		{Namespace: "default", PodName: "api", Change: ChangeChanged, OldENI: "eni-1", NewENI: "eni-9", AddedSecurityGroups: []string{"sg-db"}},
+		{Namespace: "default", PodName: "gone", Change: ChangeRemoved, OldENI: "eni-2", OldAttachmentLevel: "node", RemovedSecurityGroups: []string{"sg-db"}},
+		{Namespace: "default", PodName: "new", Change: ChangeAdded, NewENI: "eni-4", NewAttachmentLevel: "pod", AddedSecurityGroups: []string{"sg-db"}},
+	}, d.Pods)
+	assert.Equal(t, []SecurityGroupDiffOutput{
+		{ID: "sg-web", Name: "web", AddedRules: []string{"inbound tcp 443 from 192.168.0.0/16"}},
+	}, d.SecurityGroups)
+	assert.False(t, d.Empty())
+
+	assert.True(t, DiffPodOutputs(oldPods, oldPods).Empty())
+}
+
+func TestDiffPodOutputs_ContextsAndStatus(t *testing.T) {
+	sg := SecurityGroupOutput{ID: "sg-web"}
+	oldPods := []PodOutput{
+		{Context: "prod-eu", Namespace: "default", PodName: "web", ENI: "eni-1", AttachmentLevel: "pod", Status: "Mapped", SecurityGroups: []SecurityGroupOutput{sg}},
+		{Context: "prod-us", Namespace: "default", PodName: "web", ENI: "eni-2", AttachmentLevel: "pod", Status: "Mapped", SecurityGroups: []SecurityGroupOutput{sg}},
+	}
+	newPods := []PodOutput{
+		{Context: "prod-eu", Namespace: "default", PodName: "web", ENI: "eni-1", AttachmentLevel: "pod", Status: "Mapped", SecurityGroups: []SecurityGroupOutput{sg}},
+		{Context: "prod-us", Namespace: "default", PodName: "web", ENI: "eni-2", AttachmentLevel: "pod", Status: "LookupFailed", SecurityGroups: []SecurityGroupOutput{}},
+	}
+
+	d := DiffPodOutputs(oldPods, newPods)
+
+	assert.Equal(t, []PodDiffOutput{
+		{Context: "prod-us", Namespace: "default", PodName: "web", Change: ChangeChanged, OldStatus: "Mapped", NewStatus: "LookupFailed", RemovedSecurityGroups: []string{"sg-web"}},
+	}, d.Pods)
+
+	var buf bytes.Buffer
+	assert.NoError(t, OutputDiff(&buf, d, "table"))
+	assert.Equal(t, "CONTEXT  NAMESPACE  POD NAME  CHANGE   DETAILS\n"+
+		"prod-us  default    web       changed  status: Mapped -> LookupFailed -sg-web\n", buf.String())
+}
+
+func TestOutputDiff(t *testing.T) {
+	d := DiffOutput{
+		Pods: []PodDiffOutput{
+			{Namespace: "default", PodName: "api", Change: ChangeChanged, OldENI: "eni-1", NewENI: "eni-9", AddedSecurityGroups: []string{"sg-db"}},
+		},
+		SecurityGroups: []SecurityGroupDiffOutput{
+			{ID: "sg-web", Name: "web", RemovedRules: []string{"inbound tcp 22 from 0.0.0.0/0"}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		data     DiffOutput
+		format   string
+		expected string
+	}{
+		{
+			name:     "table output",
+			data:     d,
+			format:   "table",
+			expected: "NAMESPACE  POD NAME  CHANGE   DETAILS\ndefault    api       changed  eni: eni-1 -> eni-9 +sg-db\n\nSECURITY GROUP  CHANGE  RULE\nsg-web (web)    -       inbound tcp 22 from 0.0.0.0/0\n",
+		},
+		{
+			name:     "no differences",
+			data:     DiffOutput{},
+			format:   "table",
+			expected: "No differences found\n",
+		},
+		{
+			name:     "json-minimal output",
+			data:     d,
+			format:   "json-minimal",
+			expected: `{"pods":[{"namespace":"default","podName":"api","change":"changed","oldENI":"eni-1","newENI":"eni-9","addedSecurityGroups":["sg-db"]}],"securityGroups":[{"id":"sg-web","name":"web","removedRules":["inbound tcp 22 from 0.0.0.0/0"]}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputDiff(&buf, tc.data, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("unexpected output: got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
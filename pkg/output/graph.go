@@ -0,0 +1,283 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/workload"
+)
+
+// Graph node kinds
+const (
+	NodePod           = "pod"
+	NodeWorkload      = "workload"
+	NodeENI           = "eni"
+	NodeSecurityGroup = "security-group"
+	NodeCIDR          = "cidr"
+)
+
+// Graph edge kinds
+const (
+	EdgeENI        = "eni"
+	EdgeAttachment = "attachment"
+	EdgeIngress    = "ingress"
+	EdgeEgress     = "egress"
+)
+
+// Graph output formats
+const (
+	FormatDOT       = "dot"
+	FormatMermaid   = "mermaid"
+	FormatGraphJSON = "graph-json"
+)
+
+// IsGraphFormat reports whether the output format renders a graph
+func IsGraphFormat(format string) bool {
+	switch format {
+	case FormatDOT, FormatMermaid, FormatGraphJSON:
+		return true
+	}
+	return false
+}
+
+// GraphOptions controls which nodes are added to the graph
+type GraphOptions struct {
+	// Owners collapses pods into their top-level controller when set. ENIs are omitted and the
+	// controller is linked to the security groups of its pods directly.
+	Owners *workload.Resolver
+	// CIDRPeers adds the CIDR peers of security group rules as nodes
+	CIDRPeers bool
+}
+
+// OutputGraph formats and outputs the pods and their security groups as a graph
+func OutputGraph(w io.Writer, data []aws.PodSecurityGroupInfo, format string, opts GraphOptions) error {
+	graph := BuildGraph(data, opts)
+	switch format {
+	case FormatDOT:
+		return outputDOT(w, graph)
+	case FormatMermaid:
+		return outputMermaid(w, graph)
+	case FormatGraphJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(graph)
+	default:
+		return fmt.Errorf("unsupported graph format: %s", format)
+	}
+}
+
+// graphBuilder collects unique nodes and edges
+type graphBuilder struct {
+	nodes map[string]GraphNodeOutput
+	edges map[GraphEdgeOutput]struct{}
+}
+
+func (b *graphBuilder) addNode(id, kind, label string) string {
+	if _, ok := b.nodes[id]; !ok {
+		b.nodes[id] = GraphNodeOutput{ID: id, Kind: kind, Label: label}
+	}
+	return id
+}
+
+func (b *graphBuilder) addEdge(from, to, kind, label string) {
+	b.edges[GraphEdgeOutput{From: from, To: to, Kind: kind, Label: label}] = struct{}{}
+}
+
+// BuildGraph returns pods (or their controllers), ENIs and security groups as nodes with edges
+// for ENI attachment, security group attachment and security group rules referencing other
+// security groups or, with CIDRPeers, CIDRs. Nodes and edges are sorted by ID.
+func BuildGraph(data []aws.PodSecurityGroupInfo, opts GraphOptions) GraphOutput {
+	b := &graphBuilder{
+		nodes: make(map[string]GraphNodeOutput),
+		edges: make(map[GraphEdgeOutput]struct{}),
+	}
+
+	// Count the pods of each controller first so that the controller label carries the total
+	replicas := make(map[workload.Ref]int)
+	if opts.Owners != nil {
+		for i := range data {
+			if ref, ok := opts.Owners.OwnerOf(&data[i].Pod); ok {
+				replicas[ref]++
+			}
+		}
+	}
+
+	var securityGroups []types.SecurityGroup
+	for i := range data {
+		d := &data[i]
+		var from string
+		ref, owned := workload.Ref{}, false
+		if opts.Owners != nil {
+			ref, owned = opts.Owners.OwnerOf(&d.Pod)
+		}
+		if owned {
+			from = b.addNode("workload:"+ref.String(), NodeWorkload, fmt.Sprintf("%s %s/%s (%d pods)", ref.Kind, ref.Namespace, ref.Name, replicas[ref]))
+		} else {
+			from = b.addNode("pod:"+d.Pod.Namespace+"/"+d.Pod.Name, NodePod, d.Pod.Namespace+"/"+d.Pod.Name)
+			if d.ENI != "" {
+				eni := b.addNode("eni:"+d.ENI, NodeENI, d.ENI)
+				b.addEdge(from, eni, EdgeENI, d.AttachmentLevel)
+				from = eni
+			}
+		}
+
+		for _, sg := range d.SecurityGroups {
+			sgNode := b.addNode("sg:"+awsSDK.ToString(sg.GroupId), NodeSecurityGroup, formatSecurityGroups([]types.SecurityGroup{sg}))
+			b.addEdge(from, sgNode, EdgeAttachment, "")
+			securityGroups = append(securityGroups, sg)
+		}
+	}
+
+	for _, sg := range securityGroups {
+		sgNode := "sg:" + awsSDK.ToString(sg.GroupId)
+		for _, p := range sg.IpPermissions {
+			for _, peer := range b.rulePeerNodes(p, opts.CIDRPeers) {
+				b.addEdge(peer, sgNode, EdgeIngress, formatTrafficLabel(p))
+			}
+		}
+		for _, p := range sg.IpPermissionsEgress {
+			for _, peer := range b.rulePeerNodes(p, opts.CIDRPeers) {
+				b.addEdge(sgNode, peer, EdgeEgress, formatTrafficLabel(p))
+			}
+		}
+	}
+
+	graph := GraphOutput{
+		Nodes: make([]GraphNodeOutput, 0, len(b.nodes)),
+		Edges: make([]GraphEdgeOutput, 0, len(b.edges)),
+	}
+	for _, n := range b.nodes {
+		graph.Nodes = append(graph.Nodes, n)
+	}
+	for e := range b.edges {
+		graph.Edges = append(graph.Edges, e)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		a, c := graph.Edges[i], graph.Edges[j]
+		if a.From != c.From {
+			return a.From < c.From
+		}
+		if a.To != c.To {
+			return a.To < c.To
+		}
+		if a.Kind != c.Kind {
+			return a.Kind < c.Kind
+		}
+		return a.Label < c.Label
+	})
+	return graph
+}
+
+// rulePeerNodes adds the security group peers of a permission, and its CIDR peers when requested, as nodes
+func (b *graphBuilder) rulePeerNodes(p types.IpPermission, cidrPeers bool) []string {
+	var ids []string
+	for _, g := range p.UserIdGroupPairs {
+		id := awsSDK.ToString(g.GroupId)
+		ids = append(ids, b.addNode("sg:"+id, NodeSecurityGroup, id))
+	}
+	if !cidrPeers {
+		return ids
+	}
+	for _, r := range p.IpRanges {
+		cidr := awsSDK.ToString(r.CidrIp)
+		ids = append(ids, b.addNode("cidr:"+cidr, NodeCIDR, cidr))
+	}
+	for _, r := range p.Ipv6Ranges {
+		cidr := awsSDK.ToString(r.CidrIpv6)
+		ids = append(ids, b.addNode("cidr:"+cidr, NodeCIDR, cidr))
+	}
+	return ids
+}
+
+// formatTrafficLabel renders the protocol and ports of a permission, such as "tcp 443" or "all"
+func formatTrafficLabel(p types.IpPermission) string {
+	protocol := awsSDK.ToString(p.IpProtocol)
+	if rules.NormalizeProtocol(protocol) == "all" {
+		return "all"
+	}
+	return rules.NormalizeProtocol(protocol) + " " + FormatPortRange(protocol, p.FromPort, p.ToPort)
+}
+
+// dotShapes maps node kinds to Graphviz shapes
+var dotShapes = map[string]string{
+	NodePod:           "box",
+	NodeWorkload:      "box3d",
+	NodeENI:           "ellipse",
+	NodeSecurityGroup: "hexagon",
+	NodeCIDR:          "note",
+}
+
+// outputDOT outputs the graph in Graphviz DOT format
+func outputDOT(w io.Writer, graph GraphOutput) error {
+	var sb strings.Builder
+	sb.WriteString("digraph sgmap {\n  rankdir=LR;\n")
+	for _, n := range graph.Nodes {
+		fmt.Fprintf(&sb, "  %s [label=%s, shape=%s];\n", dotQuote(n.ID), dotQuote(n.Label), dotShapes[n.Kind])
+	}
+	for _, e := range graph.Edges {
+		attrs := ""
+		switch e.Kind {
+		case EdgeIngress, EdgeEgress:
+			attrs = fmt.Sprintf(" [label=%s, style=dashed]", dotQuote(e.Label))
+		default:
+			if e.Label != "" {
+				attrs = fmt.Sprintf(" [label=%s]", dotQuote(e.Label))
+			}
+		}
+		fmt.Fprintf(&sb, "  %s -> %s%s;\n", dotQuote(e.From), dotQuote(e.To), attrs)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// outputMermaid outputs the graph as a Mermaid flowchart. Node IDs are replaced by n0, n1, ...
+// because Mermaid IDs cannot contain the slashes and colons of graph node IDs.
+func outputMermaid(w io.Writer, graph GraphOutput) error {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(graph.Nodes))
+	for i, n := range graph.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+		label := `"` + strings.ReplaceAll(n.Label, `"`, "#quot;") + `"`
+		switch n.Kind {
+		case NodeWorkload:
+			fmt.Fprintf(&sb, "  %s[[%s]]\n", id, label)
+		case NodeENI:
+			fmt.Fprintf(&sb, "  %s([%s])\n", id, label)
+		case NodeSecurityGroup:
+			fmt.Fprintf(&sb, "  %s{{%s}}\n", id, label)
+		case NodeCIDR:
+			fmt.Fprintf(&sb, "  %s>%s]\n", id, label)
+		default:
+			fmt.Fprintf(&sb, "  %s[%s]\n", id, label)
+		}
+	}
+	for _, e := range graph.Edges {
+		arrow := "-->"
+		if e.Kind == EdgeIngress || e.Kind == EdgeEgress {
+			arrow = "-.->"
+		}
+		if e.Label != "" {
+			fmt.Fprintf(&sb, "  %s %s|%s| %s\n", ids[e.From], arrow, strings.ReplaceAll(e.Label, `"`, "#quot;"), ids[e.To])
+		} else {
+			fmt.Fprintf(&sb, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
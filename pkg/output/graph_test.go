@@ -0,0 +1,133 @@
+package output
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+func graphTestData() []aws.PodSecurityGroupInfo {
+	return []aws.PodSecurityGroupInfo{
+		{
+			Pod:             corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			ENI:             "eni-1",
+			AttachmentLevel: "pod",
+			SecurityGroups: []types.SecurityGroup{
+				{
+					GroupId:   awsSDK.String("sg-web"),
+					GroupName: awsSDK.String("web"),
+					IpPermissions: []types.IpPermission{
+						{
+							IpProtocol:       awsSDK.String("tcp"),
+							FromPort:         awsSDK.Int32(443),
+							ToPort:           awsSDK.Int32(443),
+							UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: awsSDK.String("sg-lb")}},
+							IpRanges:         []types.IpRange{{CidrIp: awsSDK.String("10.0.0.0/8")}},
+						},
+					},
+					IpPermissionsEgress: []types.IpPermission{
+						{IpProtocol: awsSDK.String("-1"), IpRanges: []types.IpRange{{CidrIp: awsSDK.String("0.0.0.0/0")}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestBuildGraph(t *testing.T) {
+	t.Run("security group peers only", func(t *testing.T) {
+		graph := BuildGraph(graphTestData(), GraphOptions{})
+
+		expectedNodes := []GraphNodeOutput{
+			{ID: "eni:eni-1", Kind: NodeENI, Label: "eni-1"},
+			{ID: "pod:default/web", Kind: NodePod, Label: "default/web"},
+			{ID: "sg:sg-lb", Kind: NodeSecurityGroup, Label: "sg-lb"},
+			{ID: "sg:sg-web", Kind: NodeSecurityGroup, Label: "sg-web (web)"},
+		}
+		expectedEdges := []GraphEdgeOutput{
+			{From: "eni:eni-1", To: "sg:sg-web", Kind: EdgeAttachment},
+			{From: "pod:default/web", To: "eni:eni-1", Kind: EdgeENI, Label: "pod"},
+			{From: "sg:sg-lb", To: "sg:sg-web", Kind: EdgeIngress, Label: "tcp 443"},
+		}
+		if got := graph.Nodes; !slices.Equal(got, expectedNodes) {
+			t.Errorf("nodes = %+v, want %+v", got, expectedNodes)
+		}
+		if got := graph.Edges; !slices.Equal(got, expectedEdges) {
+			t.Errorf("edges = %+v, want %+v", got, expectedEdges)
+		}
+	})
+
+	t.Run("with CIDR peers", func(t *testing.T) {
+		graph := BuildGraph(graphTestData(), GraphOptions{CIDRPeers: true})
+
+		if len(graph.Nodes) != 6 {
+			t.Errorf("expected 6 nodes, got %+v", graph.Nodes)
+		}
+		expected := GraphEdgeOutput{From: "sg:sg-web", To: "cidr:0.0.0.0/0", Kind: EdgeEgress, Label: "all"}
+		found := false
+		for _, e := range graph.Edges {
+			if e == expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected edge %+v in %+v", expected, graph.Edges)
+		}
+	})
+}
+
+func TestOutputGraph(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:   "dot output",
+			format: "dot",
+			expected: `digraph sgmap {
+  rankdir=LR;
+  "eni:eni-1" [label="eni-1", shape=ellipse];
+  "pod:default/web" [label="default/web", shape=box];
+  "sg:sg-lb" [label="sg-lb", shape=hexagon];
+  "sg:sg-web" [label="sg-web (web)", shape=hexagon];
+  "eni:eni-1" -> "sg:sg-web";
+  "pod:default/web" -> "eni:eni-1" [label="pod"];
+  "sg:sg-lb" -> "sg:sg-web" [label="tcp 443", style=dashed];
+}
+`,
+		},
+		{
+			name:   "mermaid output",
+			format: "mermaid",
+			expected: `flowchart LR
+  n0(["eni-1"])
+  n1["default/web"]
+  n2{{"sg-lb"}}
+  n3{{"sg-web (web)"}}
+  n0 --> n3
+  n1 -->|pod| n0
+  n2 -.->|tcp 443| n3
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputGraph(&buf, graphTestData(), tc.format, GraphOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/lint"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// outputJUnit outputs a JUnit XML report with one test suite per rule and one test case per pod,
+// failing with the severity as the failure type when the rule reported findings for the pod
+func outputJUnit(w io.Writer, report lint.Report) error {
+	suites := make(map[string]*junitTestSuite, len(report.Rules))
+	result := junitTestSuites{Name: toolName + " lint", Suites: make([]junitTestSuite, 0, len(report.Rules))}
+	for _, r := range report.Rules {
+		result.Suites = append(result.Suites, junitTestSuite{Name: fmt.Sprintf("%s %s", r.ID, r.Name), TestCases: []junitTestCase{}})
+	}
+	for i, r := range report.Rules {
+		suites[r.ID] = &result.Suites[i]
+	}
+
+	for _, c := range lintChecks(report) {
+		suite := suites[c.rule.ID]
+		tc := junitTestCase{Name: c.pod.Namespace + "/" + c.pod.Name, ClassName: suite.Name}
+		if len(c.findings) > 0 {
+			tc.Failure = &junitFailure{
+				Message: c.message(),
+				Type:    c.findings[0].Severity,
+				Text:    c.rule.Description,
+			}
+			suite.Failures++
+			result.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+		suite.Tests++
+		result.Tests++
+	}
+
+	if _, err := fmt.Fprint(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
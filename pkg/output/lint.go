@@ -0,0 +1,121 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/lint"
+)
+
+// OutputLintReport formats and outputs the findings of a lint run
+func OutputLintReport(w io.Writer, report lint.Report, format string) error {
+	findings := report.Findings
+	switch format {
+	case "sarif":
+		return outputSARIF(w, report)
+	case "junit":
+		return outputJUnit(w, report)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(toLintFindingOutputs(findings))
+	case "json-minimal":
+		b, err := json.Marshal(toLintFindingOutputs(findings))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "yaml":
+		b, err := yaml.Marshal(toLintFindingOutputs(findings))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, string(b))
+		return err
+	default:
+		return outputLintTable(w, findings)
+	}
+}
+
+func toLintFindingOutputs(findings []lint.Finding) []LintFindingOutput {
+	names := make(map[string]string)
+	for _, r := range lint.Rules() {
+		names[r.ID] = r.Name
+	}
+
+	out := make([]LintFindingOutput, 0, len(findings))
+	for _, f := range findings {
+		out = append(out, LintFindingOutput{
+			RuleID:        f.RuleID,
+			RuleName:      names[f.RuleID],
+			Severity:      f.Severity,
+			Namespace:     f.Namespace,
+			PodName:       f.Pod,
+			SecurityGroup: f.SecurityGroup,
+			Message:       f.Message,
+		})
+	}
+	return out
+}
+
+// outputLintTable outputs one row per finding
+func outputLintTable(w io.Writer, findings []lint.Finding) error {
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, "No findings")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tRULE\tNAMESPACE\tPOD NAME\tSECURITY GROUP\tMESSAGE")
+	for _, f := range findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", f.Severity, f.RuleID, f.Namespace, f.Pod, f.SecurityGroup, f.Message)
+	}
+	return tw.Flush()
+}
+
+// lintCheck is a pod-and-rule pair and the findings reported for it
+type lintCheck struct {
+	pod      lint.PodRef
+	rule     lint.Rule
+	findings []lint.Finding
+}
+
+// lintChecks returns every pod-and-rule pair of the report ordered by pod and rule, with the findings reported for each
+func lintChecks(report lint.Report) []lintCheck {
+	byPair := make(map[string][]lint.Finding)
+	for _, f := range report.Findings {
+		key := f.Namespace + "/" + f.Pod + "/" + f.RuleID
+		byPair[key] = append(byPair[key], f)
+	}
+
+	checks := make([]lintCheck, 0, len(report.Pods)*len(report.Rules))
+	for _, pod := range report.Pods {
+		for _, rule := range report.Rules {
+			checks = append(checks, lintCheck{
+				pod:      pod,
+				rule:     rule,
+				findings: byPair[pod.Namespace+"/"+pod.Name+"/"+rule.ID],
+			})
+		}
+	}
+	return checks
+}
+
+// message joins the findings of a check, prefixing each with its security group
+func (c lintCheck) message() string {
+	messages := make([]string, 0, len(c.findings))
+	for _, f := range c.findings {
+		if f.SecurityGroup != "" {
+			messages = append(messages, fmt.Sprintf("%s: %s", f.SecurityGroup, f.Message))
+		} else {
+			messages = append(messages, f.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
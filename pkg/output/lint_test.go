@@ -0,0 +1,128 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/lint"
+)
+
+func TestOutputLintReport(t *testing.T) {
+	findings := []lint.Finding{
+		{RuleID: lint.RuleOpenIngress, Severity: lint.SeverityHigh, Namespace: "default", Pod: "web", SecurityGroup: "sg-1", Message: "inbound tcp/22 open to the internet"},
+		{RuleID: lint.RuleNodeLevel, Severity: lint.SeverityMedium, Namespace: "default", Pod: "web", Message: "pod uses the node's security groups"},
+	}
+
+	testCases := []struct {
+		name     string
+		data     []lint.Finding
+		format   string
+		expected string
+	}{
+		{
+			name:     "table output",
+			data:     findings,
+			format:   "table",
+			expected: "SEVERITY  RULE   NAMESPACE  POD NAME  SECURITY GROUP  MESSAGE\nhigh      SG001  default    web       sg-1            inbound tcp/22 open to the internet\nmedium    SG003  default    web                       pod uses the node's security groups\n",
+		},
+		{
+			name:     "no findings",
+			format:   "table",
+			expected: "No findings\n",
+		},
+		{
+			name:     "json-minimal output",
+			data:     findings[:1],
+			format:   "json-minimal",
+			expected: `[{"ruleId":"SG001","ruleName":"open-ingress","severity":"high","namespace":"default","podName":"web","securityGroup":"sg-1","message":"inbound tcp/22 open to the internet"}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputLintReport(&buf, lint.Report{Findings: tc.data}, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("unexpected output: got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func lintTestReport() lint.Report {
+	return lint.Report{
+		Pods: []lint.PodRef{{Namespace: "default", Name: "api"}, {Namespace: "default", Name: "web"}},
+		Rules: []lint.Rule{
+			{ID: lint.RuleOpenIngress, Name: "open-ingress", Description: "open ingress", Severity: lint.SeverityHigh},
+			{ID: lint.RuleEmptyGroup, Name: "empty-security-group", Description: "empty group", Severity: lint.SeverityLow},
+		},
+		Findings: []lint.Finding{
+			{RuleID: lint.RuleOpenIngress, Severity: lint.SeverityHigh, Namespace: "default", Pod: "web", SecurityGroup: "sg-1", Message: "inbound tcp/22 open to the internet"},
+			{RuleID: lint.RuleOpenIngress, Severity: lint.SeverityHigh, Namespace: "default", Pod: "web", SecurityGroup: "sg-2", Message: "inbound tcp/3306 open to the internet"},
+			{RuleID: lint.RuleEmptyGroup, Severity: lint.SeverityLow, Namespace: "default", Pod: "api", SecurityGroup: "sg-3", Message: "security group has no rules"},
+		},
+	}
+}
+
+func TestOutputLintReportSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := OutputLintReport(&buf, lintTestReport(), "sarif"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF: %v", err)
+	}
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "kubectl-sgmap", run.Tool.Driver.Name)
+	require.Len(t, run.Tool.Driver.Rules, 2)
+	assert.Equal(t, "error", run.Tool.Driver.Rules[0].DefaultConfiguration.Level)
+	assert.Equal(t, "note", run.Tool.Driver.Rules[1].DefaultConfiguration.Level)
+
+	require.Len(t, run.Results, 2)
+	assert.Equal(t, lint.RuleEmptyGroup, run.Results[0].RuleID)
+	assert.Equal(t, 1, run.Results[0].RuleIndex)
+	assert.Equal(t, "note", run.Results[0].Level)
+	assert.Equal(t, "default/api", run.Results[0].Locations[0].LogicalLocations[0].FullyQualifiedName)
+	assert.Equal(t, lint.RuleOpenIngress, run.Results[1].RuleID)
+	assert.Equal(t, "error", run.Results[1].Level)
+	assert.Equal(t, "sg-1: inbound tcp/22 open to the internet; sg-2: inbound tcp/3306 open to the internet", run.Results[1].Message.Text)
+	assert.Equal(t, "namespaces/default/pods/web", run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+}
+
+func TestOutputLintReportJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := OutputLintReport(&buf, lintTestReport(), "junit"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.True(t, strings.HasPrefix(buf.String(), xml.Header))
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid JUnit XML: %v", err)
+	}
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 2, suites.Failures)
+	require.Len(t, suites.Suites, 2)
+
+	openIngress := suites.Suites[0]
+	assert.Equal(t, "SG001 open-ingress", openIngress.Name)
+	require.Len(t, openIngress.TestCases, 2)
+	assert.Equal(t, "default/api", openIngress.TestCases[0].Name)
+	assert.Nil(t, openIngress.TestCases[0].Failure)
+	require.NotNil(t, openIngress.TestCases[1].Failure)
+	assert.Equal(t, lint.SeverityHigh, openIngress.TestCases[1].Failure.Type)
+	assert.Equal(t, 1, suites.Suites[1].Failures)
+}
@@ -0,0 +1,127 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/loadbalancer"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+)
+
+// OutputLoadBalancerPaths formats and outputs the paths from load balancers to their targets
+func OutputLoadBalancerPaths(w io.Writer, data []loadbalancer.Path, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(toLoadBalancerPathOutput(data))
+	case "json-minimal":
+		b, err := json.Marshal(toLoadBalancerPathOutput(data))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "yaml":
+		b, err := yaml.Marshal(toLoadBalancerPathOutput(data))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, string(b))
+		return err
+	default:
+		return outputLoadBalancerPathTable(w, data)
+	}
+}
+
+// toLoadBalancerPathOutput converts load balancer paths into their output representation
+func toLoadBalancerPathOutput(data []loadbalancer.Path) []LoadBalancerPathOutput {
+	output := make([]LoadBalancerPathOutput, 0, len(data))
+	for _, p := range data {
+		out := LoadBalancerPathOutput{
+			Namespace:                  p.Frontend.Namespace,
+			Kind:                       p.Frontend.Kind,
+			Name:                       p.Frontend.Name,
+			TargetType:                 p.Frontend.TargetType,
+			LoadBalancerSecurityGroups: []SecurityGroupOutput{},
+			LoadBalancerIPs:            []string{},
+			Target:                     targetName(p.Target),
+			TargetIP:                   p.Target.Pod.Status.PodIP,
+			TargetSecurityGroups:       toSecurityGroupOutputs(p.Info.SecurityGroups, SecurityGroupOptions{}),
+			Status:                     p.Status,
+			Reason:                     p.Reason,
+			Rules:                      toMatchRuleOutputs(p.Rules),
+		}
+		if lb := p.LoadBalancer; lb != nil {
+			out.LoadBalancer = lb.Name()
+			out.DNSName = lb.DNSName()
+			out.Type = string(lb.LoadBalancer.Type)
+			out.LoadBalancerSecurityGroups = toSecurityGroupOutputs(lb.SecurityGroups, SecurityGroupOptions{})
+			if len(lb.PrivateIPs) > 0 {
+				out.LoadBalancerIPs = lb.PrivateIPs
+			}
+		}
+		if p.Target.Port.Port != 0 {
+			out.Protocol = rules.NormalizeProtocol(p.Target.Port.Protocol)
+			out.Port = p.Target.Port.Port
+		}
+		output = append(output, out)
+	}
+	return output
+}
+
+// outputLoadBalancerPathTable outputs one row per path, with the rules that permit it or the reason it
+// is not permitted
+func outputLoadBalancerPathTable(w io.Writer, data []loadbalancer.Path) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tSOURCE\tLOAD BALANCER\tTARGET\tPORT\tSTATUS\tRULE")
+
+	for _, p := range data {
+		lbName := "<none>"
+		if p.LoadBalancer != nil {
+			lbName = p.LoadBalancer.Name()
+		}
+		port := ""
+		if p.Target.Port.Port != 0 {
+			port = formatTraffic(rules.NormalizeProtocol(p.Target.Port.Protocol), p.Target.Port.Port)
+		}
+		rule := p.Reason
+		if p.Status == loadbalancer.StatusOK {
+			rule = formatMatchRules(p.Rules)
+		}
+		fmt.Fprintf(tw, "%s\t%s/%s\t%s\t%s\t%s\t%s\t%s\n",
+			p.Frontend.Namespace,
+			strings.ToLower(p.Frontend.Kind),
+			p.Frontend.Name,
+			lbName,
+			targetName(p.Target),
+			port,
+			p.Status,
+			rule,
+		)
+	}
+
+	return tw.Flush()
+}
+
+// targetName returns the name of a path target, the pod name or node/NAME for instance targets
+func targetName(t loadbalancer.Target) string {
+	if t.Node != "" {
+		return "node/" + t.Node
+	}
+	return t.Pod.Name
+}
+
+// formatMatchRules formats the permitting rules of a path, such as "sg-1 tcp 8080 from sg-2"
+func formatMatchRules(matches []rules.Match) string {
+	var out []string
+	for _, m := range toMatchRuleOutputs(matches) {
+		out = append(out, fmt.Sprintf("%s %s %s from %s", m.SecurityGroupID, m.Protocol, m.PortRange, m.Peer))
+	}
+	return strings.Join(out, ", ")
+}
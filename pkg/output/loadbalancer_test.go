@@ -0,0 +1,90 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/loadbalancer"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/service"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOutputLoadBalancerPaths(t *testing.T) {
+	ingress := loadbalancer.Frontend{Kind: loadbalancer.KindIngress, Namespace: "default", Name: "web", TargetType: loadbalancer.TargetTypeIP}
+	lb := &aws.LoadBalancer{
+		LoadBalancer: elbv2types.LoadBalancer{
+			LoadBalancerName: strPtr("web"),
+			DNSName:          strPtr("web.elb.amazonaws.com"),
+			Type:             elbv2types.LoadBalancerTypeEnumApplication,
+		},
+		SecurityGroups: []awsSDK.SecurityGroup{{GroupId: strPtr("sg-alb"), GroupName: strPtr("alb")}},
+		PrivateIPs:     []string{"10.0.0.10"},
+	}
+	pod := func(name, ip string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}, Status: corev1.PodStatus{PodIP: ip}}
+	}
+	http := service.Port{Name: "http", Protocol: "TCP", Port: 8080}
+	data := []loadbalancer.Path{
+		{
+			Frontend:     ingress,
+			LoadBalancer: lb,
+			Target:       loadbalancer.Target{Pod: pod("web-1", "10.0.1.10"), Port: http},
+			Info:         aws.PodSecurityGroupInfo{SecurityGroups: []awsSDK.SecurityGroup{{GroupId: strPtr("sg-web"), GroupName: strPtr("web")}}},
+			Rules: []rules.Match{{
+				SecurityGroup: awsSDK.SecurityGroup{GroupId: strPtr("sg-web")},
+				Permission:    awsSDK.IpPermission{IpProtocol: strPtr("tcp"), FromPort: int32Ptr(8080), ToPort: int32Ptr(8080)},
+				Peer:          "sg-alb",
+			}},
+			Status: loadbalancer.StatusOK,
+		},
+		{
+			Frontend:     ingress,
+			LoadBalancer: lb,
+			Target:       loadbalancer.Target{Pod: pod("web-2", "10.0.1.11"), Port: http},
+			Status:       loadbalancer.StatusBlocked,
+			Reason:       "no ingress rule permits tcp/8080 from 10.0.0.10 (sg-alb)",
+		},
+		{
+			Frontend: loadbalancer.Frontend{Kind: loadbalancer.KindService, Namespace: "default", Name: "api", TargetType: loadbalancer.TargetTypeInstance},
+			Status:   loadbalancer.StatusNoLoadBalancer,
+			Reason:   "no ELBv2 load balancer has the hostname api.elb.amazonaws.com",
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:   "table output",
+			format: "table",
+			expected: "NAMESPACE  SOURCE       LOAD BALANCER  TARGET  PORT      STATUS          RULE\n" +
+				"default    ingress/web  web            web-1   tcp/8080  OK              sg-web tcp 8080 from sg-alb\n" +
+				"default    ingress/web  web            web-2   tcp/8080  BLOCKED         no ingress rule permits tcp/8080 from 10.0.0.10 (sg-alb)\n" +
+				"default    service/api  <none>                           NoLoadBalancer  no ELBv2 load balancer has the hostname api.elb.amazonaws.com\n",
+		},
+		{
+			name:     "json-minimal output",
+			format:   "json-minimal",
+			expected: `[{"namespace":"default","kind":"Ingress","name":"web","loadBalancer":"web","dnsName":"web.elb.amazonaws.com","type":"application","targetType":"ip","loadBalancerSecurityGroups":[{"id":"sg-alb","name":"alb"}],"loadBalancerIPs":["10.0.0.10"],"target":"web-1","targetIP":"10.0.1.10","protocol":"tcp","port":8080,"targetSecurityGroups":[{"id":"sg-web","name":"web"}],"status":"OK","rules":[{"securityGroupId":"sg-web","protocol":"tcp","portRange":"8080","peer":"sg-alb"}]},{"namespace":"default","kind":"Ingress","name":"web","loadBalancer":"web","dnsName":"web.elb.amazonaws.com","type":"application","targetType":"ip","loadBalancerSecurityGroups":[{"id":"sg-alb","name":"alb"}],"loadBalancerIPs":["10.0.0.10"],"target":"web-2","targetIP":"10.0.1.11","protocol":"tcp","port":8080,"targetSecurityGroups":[],"status":"BLOCKED","reason":"no ingress rule permits tcp/8080 from 10.0.0.10 (sg-alb)","rules":[]},{"namespace":"default","kind":"Service","name":"api","targetType":"instance","loadBalancerSecurityGroups":[],"loadBalancerIPs":[],"targetSecurityGroups":[],"status":"NoLoadBalancer","reason":"no ELBv2 load balancer has the hostname api.elb.amazonaws.com","rules":[]}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputLoadBalancerPaths(&buf, data, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tc.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tc.expected, buf.String())
+			}
+		})
+	}
+}
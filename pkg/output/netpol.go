@@ -0,0 +1,107 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/netpol"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+)
+
+// OutputNetworkPolicies formats and outputs the combined NetworkPolicy and security group view of each pod
+func OutputNetworkPolicies(w io.Writer, results []netpol.Result, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(toNetworkPolicyViewOutputs(results))
+	case "json-minimal":
+		b, err := json.Marshal(toNetworkPolicyViewOutputs(results))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "yaml":
+		b, err := yaml.Marshal(toNetworkPolicyViewOutputs(results))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, string(b))
+		return err
+	default:
+		return outputNetworkPolicyTable(w, results)
+	}
+}
+
+func toNetworkPolicyViewOutputs(results []netpol.Result) []NetworkPolicyViewOutput {
+	out := make([]NetworkPolicyViewOutput, 0, len(results))
+	for _, r := range results {
+		traffic := make([]TrafficOutput, 0, len(r.Traffic))
+		for _, t := range r.Traffic {
+			traffic = append(traffic, TrafficOutput{
+				Direction: t.Direction,
+				Layer:     t.Layer,
+				Rule:      t.Rule,
+				Protocol:  rules.NormalizeProtocol(t.Protocol),
+				PortRange: formatTrafficPorts(t),
+				Peer:      t.Peer,
+				Status:    t.Status,
+			})
+		}
+		out = append(out, NetworkPolicyViewOutput{
+			PodName:         r.Info.Pod.Name,
+			Namespace:       r.Info.Pod.Namespace,
+			PodIP:           r.Info.Pod.Status.PodIP,
+			AttachmentLevel: r.Info.AttachmentLevel,
+			NetworkPolicies: nonNil(r.Policies),
+			IngressIsolated: r.IngressIsolated,
+			EgressIsolated:  r.EgressIsolated,
+			SecurityGroups:  toSecurityGroupOutputs(r.Info.SecurityGroups, SecurityGroupOptions{}),
+			Traffic:         traffic,
+			Mismatches:      r.Mismatches(),
+		})
+	}
+	return out
+}
+
+// outputNetworkPolicyTable outputs one row per rule peer of either layer, with the verdict of the other
+// layer in upper case so that traffic blocked by one layer stands out
+func outputNetworkPolicyTable(w io.Writer, results []netpol.Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POD NAME\tDIRECTION\tLAYER\tRULE\tPROTOCOL\tPORT RANGE\tPEER\tSTATUS")
+
+	for _, r := range results {
+		if len(r.Traffic) == 0 {
+			fmt.Fprintf(tw, "%s\t<none>\t\t\t\t\t\t\n", r.Info.Pod.Name)
+			continue
+		}
+		for _, t := range r.Traffic {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.Info.Pod.Name,
+				t.Direction,
+				t.Layer,
+				t.Rule,
+				rules.NormalizeProtocol(t.Protocol),
+				formatTrafficPorts(t),
+				t.Peer,
+				strings.ToUpper(t.Status),
+			)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// formatTrafficPorts returns the port range of the traffic, or the named port of a NetworkPolicy rule
+func formatTrafficPorts(t netpol.Traffic) string {
+	if t.PortName != "" {
+		return t.PortName
+	}
+	return FormatPortRange(t.Protocol, t.FromPort, t.ToPort)
+}
@@ -0,0 +1,68 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/netpol"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOutputNetworkPolicies(t *testing.T) {
+	pod := func(name string) aws.PodSecurityGroupInfo {
+		return aws.PodSecurityGroupInfo{
+			Pod:             corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}, Status: corev1.PodStatus{PodIP: "10.0.1.10"}},
+			AttachmentLevel: "pod",
+			SecurityGroups:  []awsSDK.SecurityGroup{{GroupId: strPtr("sg-web"), GroupName: strPtr("web")}},
+		}
+	}
+	results := []netpol.Result{
+		{
+			Info:            pod("web"),
+			Policies:        []string{"web"},
+			IngressIsolated: true,
+			Traffic: []netpol.Traffic{
+				{Direction: "ingress", Layer: netpol.LayerSecurityGroup, Rule: "sg-web", Protocol: "tcp", FromPort: int32Ptr(8080), ToPort: int32Ptr(8080), Peer: "10.0.0.0/16", Status: netpol.StatusBlockedByNetworkPolicy},
+				{Direction: "ingress", Layer: netpol.LayerNetworkPolicy, Rule: "web", Protocol: "tcp", PortName: "metrics", Peer: "pods(app=front)", Status: netpol.StatusAllowed},
+				{Direction: "egress", Layer: netpol.LayerNetworkPolicy, Rule: "web", Protocol: "all", Peer: "all", Status: netpol.StatusBlockedBySecurityGroup},
+			},
+		},
+		{Info: pod("idle")},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:   "table output",
+			format: "table",
+			expected: "POD NAME  DIRECTION  LAYER          RULE    PROTOCOL  PORT RANGE  PEER             STATUS\n" +
+				"web       ingress    SecurityGroup  sg-web  tcp       8080        10.0.0.0/16      BLOCKED-BY-NETPOL\n" +
+				"web       ingress    NetworkPolicy  web     tcp       metrics     pods(app=front)  ALLOWED\n" +
+				"web       egress     NetworkPolicy  web     all       all         all              BLOCKED-BY-SG\n" +
+				"idle      <none>                                                                   \n",
+		},
+		{
+			name:     "json-minimal output",
+			format:   "json-minimal",
+			expected: `[{"podName":"web","namespace":"default","podIP":"10.0.1.10","attachmentLevel":"pod","networkPolicies":["web"],"ingressIsolated":true,"egressIsolated":false,"securityGroups":[{"id":"sg-web","name":"web"}],"traffic":[{"direction":"ingress","layer":"SecurityGroup","rule":"sg-web","protocol":"tcp","portRange":"8080","peer":"10.0.0.0/16","status":"blocked-by-netpol"},{"direction":"ingress","layer":"NetworkPolicy","rule":"web","protocol":"tcp","portRange":"metrics","peer":"pods(app=front)","status":"allowed"},{"direction":"egress","layer":"NetworkPolicy","rule":"web","protocol":"all","portRange":"all","peer":"all","status":"blocked-by-sg"}],"mismatches":2},{"podName":"idle","namespace":"default","podIP":"10.0.1.10","attachmentLevel":"pod","networkPolicies":[],"ingressIsolated":false,"egressIsolated":false,"securityGroups":[{"id":"sg-web","name":"web"}],"traffic":[],"mismatches":0}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputNetworkPolicies(&buf, results, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tc.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tc.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+// OutputNodeNetworkInterfaces formats and outputs the ENIs of each node and the pods using them
+func OutputNodeNetworkInterfaces(w io.Writer, data []aws.NodeNetworkInterfaces, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(toNodeOutput(data))
+	case "json-minimal":
+		b, err := json.Marshal(toNodeOutput(data))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "yaml":
+		b, err := yaml.Marshal(toNodeOutput(data))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, string(b))
+		return err
+	default:
+		return outputNodeTable(w, data)
+	}
+}
+
+// toNodeOutput converts the ENIs of nodes into their output representation
+func toNodeOutput(data []aws.NodeNetworkInterfaces) []NodeOutput {
+	output := make([]NodeOutput, 0, len(data))
+	for _, d := range data {
+		enis := make([]NodeENIOutput, 0, len(d.ENIs))
+		for _, eni := range d.ENIs {
+			enis = append(enis, NodeENIOutput{
+				ID:             eni.ID(),
+				Role:           eni.Role,
+				Trunk:          eni.Trunk,
+				SubnetID:       awsSDK.ToString(eni.NetworkInterface.SubnetId),
+				AssignedIPs:    eni.AssignedIPs(),
+				PodIPs:         eni.PodIPs(),
+				BranchENIs:     eni.BranchENIs,
+				Pods:           podNames(eni.Pods),
+				SecurityGroups: toSecurityGroupOutputs(eni.SecurityGroups, SecurityGroupOptions{}),
+			})
+		}
+		output = append(output, NodeOutput{
+			Node:          d.Node.Name,
+			InstanceID:    d.InstanceID,
+			Status:        d.Status,
+			Reason:        d.Reason,
+			ENIs:          enis,
+			UnmatchedPods: podNames(d.UnmatchedPods),
+		})
+	}
+	return output
+}
+
+// podNames returns the namespace/name of each pod
+func podNames(pods []corev1.Pod) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Namespace+"/"+pod.Name)
+	}
+	return names
+}
+
+// outputNodeTable outputs one row per ENI of each node. IPS is the number of pod IPs out of the
+// IPs assigned to the ENI, and BRANCHES the number of branch ENIs of a trunk ENI.
+func outputNodeTable(w io.Writer, data []aws.NodeNetworkInterfaces) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tINSTANCE\tENI ID\tROLE\tIPS\tBRANCHES\tSECURITY GROUPS\tPODS")
+
+	for _, d := range data {
+		if len(d.ENIs) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t<none>\t\t\t\t\t%s\n", d.Node.Name, d.InstanceID, d.Reason)
+			continue
+		}
+		for i, eni := range d.ENIs {
+			node, instance := d.Node.Name, d.InstanceID
+			if i > 0 {
+				node, instance = "", ""
+			}
+			branches := ""
+			if eni.Role == aws.ENIRoleTrunk {
+				branches = fmt.Sprint(eni.BranchENIs)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d/%d\t%s\t%s\t%s\n",
+				node,
+				instance,
+				eni.ID(),
+				eni.Role,
+				eni.PodIPs(),
+				eni.AssignedIPs(),
+				branches,
+				formatSecurityGroups(eni.SecurityGroups),
+				strings.Join(podNames(eni.Pods), ", "),
+			)
+		}
+		if len(d.UnmatchedPods) > 0 {
+			fmt.Fprintf(tw, "\t\t<none>\t\t\t\t\t%s\n", strings.Join(podNames(d.UnmatchedPods), ", "))
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOutputNodeNetworkInterfaces(t *testing.T) {
+	pod := func(name, ip string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     corev1.PodStatus{PodIP: ip},
+		}
+	}
+	sg := awsSDK.SecurityGroup{GroupId: strPtr("sg-1"), GroupName: strPtr("node")}
+	data := []aws.NodeNetworkInterfaces{
+		{
+			Node:       corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+			InstanceID: "i-1",
+			Status:     aws.StatusMapped,
+			ENIs: []aws.NodeENI{
+				{
+					NetworkInterface: awsSDK.NetworkInterface{
+						NetworkInterfaceId: strPtr("eni-1"),
+						SubnetId:           strPtr("subnet-1"),
+						PrivateIpAddresses: []awsSDK.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: strPtr("10.0.0.1")}, {PrivateIpAddress: strPtr("10.0.0.2")}},
+					},
+					Role:           aws.ENIRolePrimary,
+					SecurityGroups: []awsSDK.SecurityGroup{sg},
+					Pods:           []corev1.Pod{pod("web", "10.0.0.2")},
+				},
+				{
+					NetworkInterface: awsSDK.NetworkInterface{NetworkInterfaceId: strPtr("eni-2"), SubnetId: strPtr("subnet-1")},
+					Role:             aws.ENIRoleTrunk,
+					SecurityGroups:   []awsSDK.SecurityGroup{sg},
+				},
+			},
+		},
+		{
+			Node:   corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "fargate"}},
+			Status: aws.StatusNoInstance,
+			Reason: "no instance",
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:   "table output",
+			format: "table",
+			expected: "NODE     INSTANCE  ENI ID  ROLE     IPS  BRANCHES  SECURITY GROUPS  PODS\n" +
+				"node-1   i-1       eni-1   primary  1/2            sg-1 (node)      default/web\n" +
+				"                   eni-2   trunk    0/0  0         sg-1 (node)      \n" +
+				"fargate            <none>                                           no instance\n",
+		},
+		{
+			name:     "json-minimal output",
+			format:   "json-minimal",
+			expected: `[{"node":"node-1","instanceID":"i-1","status":"Mapped","enis":[{"id":"eni-1","role":"primary","subnetId":"subnet-1","assignedIPs":2,"podIPs":1,"branchENIs":0,"pods":["default/web"],"securityGroups":[{"id":"sg-1","name":"node"}]},{"id":"eni-2","role":"trunk","subnetId":"subnet-1","assignedIPs":0,"podIPs":0,"branchENIs":0,"pods":[],"securityGroups":[{"id":"sg-1","name":"node"}]}]},{"node":"fargate","status":"NoInstance","reason":"no instance","enis":[]}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputNodeNetworkInterfaces(&buf, data, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tc.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tc.expected, buf.String())
+			}
+		})
+	}
+}
@@ -18,9 +18,24 @@ import (
 	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
 )
 
-// OutputPodSecurityGroups formats and outputs pod security group information
-func OutputPodSecurityGroups(w io.Writer, data []aws.PodSecurityGroupInfo, format string, sortField string) error {
+// IsStructuredFormat reports whether the output format is JSON or YAML, which carry every field of
+// the security groups and their rules
+func IsStructuredFormat(format string) bool {
+	switch format {
+	case "json", "json-minimal", "yaml":
+		return true
+	}
+	return false
+}
+
+// OutputPodSecurityGroups formats and outputs pod security group information. The security group and
+// prefix list peers of rules are described with what they resolved to in opts.Peers, and rules with
+// their IDs in opts.Rules, both of which may be empty.
+func OutputPodSecurityGroups(w io.Writer, data []aws.PodSecurityGroupInfo, format string, sortField string, opts SecurityGroupOptions) error {
 	sort.SliceStable(data, func(i, j int) bool {
+		if data[i].Context != data[j].Context {
+			return data[i].Context < data[j].Context
+		}
 		switch sortField {
 		case "ip":
 			ipA := net.ParseIP(data[i].Pod.Status.PodIP)
@@ -52,27 +67,29 @@ func OutputPodSecurityGroups(w io.Writer, data []aws.PodSecurityGroupInfo, forma
 
 	switch format {
 	case "json":
-		return outputJSON(w, data)
+		return outputJSON(w, data, opts)
 	case "json-minimal":
-		return outputJSONMinimal(w, data)
+		return outputJSONMinimal(w, data, opts)
 	case "yaml":
-		return outputYAML(w, data)
+		return outputYAML(w, data, opts)
+	case "wide":
+		return outputRulesTable(w, data, opts)
 	default:
-		return outputTable(w, data)
+		return outputTable(w, data, opts.ShowTags)
 	}
 }
 
 // outputJSON outputs the data in JSON format
-func outputJSON(w io.Writer, data []aws.PodSecurityGroupInfo) error {
-	outputData := toMinimalOutput(data)
+func outputJSON(w io.Writer, data []aws.PodSecurityGroupInfo, opts SecurityGroupOptions) error {
+	outputData := toMinimalOutput(data, opts)
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
 	return encoder.Encode(outputData)
 }
 
 // outputJSONMinimal outputs the data in minimal JSON format
-func outputJSONMinimal(w io.Writer, data []aws.PodSecurityGroupInfo) error {
-	outputData := toMinimalOutput(data)
+func outputJSONMinimal(w io.Writer, data []aws.PodSecurityGroupInfo, opts SecurityGroupOptions) error {
+	outputData := toMinimalOutput(data, opts)
 	b, err := json.Marshal(outputData)
 	if err != nil {
 		return err
@@ -81,38 +98,64 @@ func outputJSONMinimal(w io.Writer, data []aws.PodSecurityGroupInfo) error {
 	return err
 }
 
+// ToPodOutputs converts pod security group information into the representation written by -o json
+func ToPodOutputs(data []aws.PodSecurityGroupInfo) []PodOutput {
+	return toMinimalOutput(data, SecurityGroupOptions{})
+}
+
 // toMinimalOutput converts the full pod security group info into a minimal structure for output
-func toMinimalOutput(data []aws.PodSecurityGroupInfo) []PodOutput {
+func toMinimalOutput(data []aws.PodSecurityGroupInfo, opts SecurityGroupOptions) []PodOutput {
 	output := make([]PodOutput, 0, len(data))
+	ids := indexRuleIDs(opts.Rules)
 	for _, d := range data {
-		sgs := make([]SecurityGroupOutput, 0, len(d.SecurityGroups))
-		for _, sg := range d.SecurityGroups {
-			sgs = append(sgs, SecurityGroupOutput{
-				ID:            awsSDK.ToString(sg.GroupId),
-				Name:          sg.GroupName,
-				InboundRules:  toRuleOutput(sg.IpPermissions, true),
-				OutboundRules: toRuleOutput(sg.IpPermissionsEgress, false),
-			})
-		}
 		output = append(output, PodOutput{
+			Context:         d.Context,
 			PodName:         d.Pod.Name,
 			Namespace:       d.Pod.Namespace,
 			PodIP:           d.Pod.Status.PodIP,
 			ENI:             d.ENI,
 			AttachmentLevel: d.AttachmentLevel,
-			SecurityGroups:  sgs,
+			Status:          d.Status,
+			Reason:          d.Reason,
+			SecurityGroups:  toSecurityGroupsWithIDs(d.SecurityGroups, opts.Peers, ids),
 		})
 	}
 	return output
 }
 
-func toRuleOutput(permissions []types.IpPermission, isInbound bool) []RuleOutput {
+// toSecurityGroupOutputs converts security groups into their output representation including rules,
+// describing rule peers with what they resolved to and rules with their IDs
+func toSecurityGroupOutputs(securityGroups []types.SecurityGroup, opts SecurityGroupOptions) []SecurityGroupOutput {
+	return toSecurityGroupsWithIDs(securityGroups, opts.Peers, indexRuleIDs(opts.Rules))
+}
+
+// toSecurityGroupsWithIDs converts security groups with rule IDs indexed once for all the pods
+func toSecurityGroupsWithIDs(securityGroups []types.SecurityGroup, peers aws.Peers, ids ruleIDs) []SecurityGroupOutput {
+	sgs := make([]SecurityGroupOutput, 0, len(securityGroups))
+	for _, sg := range securityGroups {
+		sgs = append(sgs, SecurityGroupOutput{
+			ID:            awsSDK.ToString(sg.GroupId),
+			Name:          sg.GroupName,
+			Description:   awsSDK.ToString(sg.Description),
+			VpcID:         awsSDK.ToString(sg.VpcId),
+			OwnerID:       awsSDK.ToString(sg.OwnerId),
+			Tags:          tagMap(sg.Tags),
+			InboundRules:  toRuleOutput(sg, sg.IpPermissions, true, peers, ids),
+			OutboundRules: toRuleOutput(sg, sg.IpPermissionsEgress, false, peers, ids),
+		})
+	}
+	return sgs
+}
+
+func toRuleOutput(sg types.SecurityGroup, permissions []types.IpPermission, isInbound bool, peers aws.Peers, ids ruleIDs) []RuleOutput {
 	rules := make([]RuleOutput, 0, len(permissions))
 	for _, p := range permissions {
 		rule := RuleOutput{
 			Protocol: awsSDK.ToString(p.IpProtocol),
 			FromPort: p.FromPort,
 			ToPort:   p.ToPort,
+			Peers:    toPeerOutputs(sg, p, peers),
+			Entries:  toRuleEntryOutputs(sg, p, isInbound, ids),
 		}
 		if isInbound {
 			for _, ipRange := range p.IpRanges {
@@ -124,6 +167,9 @@ func toRuleOutput(permissions []types.IpPermission, isInbound bool) []RuleOutput
 			for _, userGroup := range p.UserIdGroupPairs {
 				rule.Sources = append(rule.Sources, awsSDK.ToString(userGroup.GroupId))
 			}
+			for _, pl := range p.PrefixListIds {
+				rule.Sources = append(rule.Sources, awsSDK.ToString(pl.PrefixListId))
+			}
 		} else {
 			for _, ipRange := range p.IpRanges {
 				rule.Destinations = append(rule.Destinations, awsSDK.ToString(ipRange.CidrIp))
@@ -134,41 +180,63 @@ func toRuleOutput(permissions []types.IpPermission, isInbound bool) []RuleOutput
 			for _, userGroup := range p.UserIdGroupPairs {
 				rule.Destinations = append(rule.Destinations, awsSDK.ToString(userGroup.GroupId))
 			}
+			for _, pl := range p.PrefixListIds {
+				rule.Destinations = append(rule.Destinations, awsSDK.ToString(pl.PrefixListId))
+			}
 		}
 		rules = append(rules, rule)
 	}
 	return rules
 }
 
-// outputYAML outputs the data in YAML format
-func outputYAML(w io.Writer, data []aws.PodSecurityGroupInfo) error {
+// outputYAML outputs the data in YAML format. The security groups keep their groupId and groupName
+// keys and carry the other fields of JSON output next to them.
+func outputYAML(w io.Writer, data []aws.PodSecurityGroupInfo, opts SecurityGroupOptions) error {
 	type sg struct {
-		GroupID   string `yaml:"groupId"`
-		GroupName string `yaml:"groupName"`
+		GroupID       string            `yaml:"groupId"`
+		GroupName     string            `yaml:"groupName"`
+		Description   string            `yaml:"description,omitempty"`
+		VpcID         string            `yaml:"vpcId,omitempty"`
+		OwnerID       string            `yaml:"ownerId,omitempty"`
+		Tags          map[string]string `yaml:"tags,omitempty"`
+		InboundRules  []RuleOutput      `yaml:"inboundRules,omitempty"`
+		OutboundRules []RuleOutput      `yaml:"outboundRules,omitempty"`
 	}
 
 	type out struct {
+		Context         string `yaml:"context,omitempty"`
 		PodName         string `yaml:"podName"`
 		Namespace       string `yaml:"namespace"`
 		ENI             string `yaml:"eni"`
 		AttachmentLevel string `yaml:"attachmentLevel"`
+		Status          string `yaml:"status,omitempty"`
+		Reason          string `yaml:"reason,omitempty"`
 		SecurityGroups  []sg   `yaml:"securityGroups"`
 	}
 
 	var converted []out
-	for _, d := range data {
+	for _, d := range toMinimalOutput(data, opts) {
 		var groups []sg
 		for _, g := range d.SecurityGroups {
 			groups = append(groups, sg{
-				GroupID:   awsSDK.ToString(g.GroupId),
-				GroupName: awsSDK.ToString(g.GroupName),
+				GroupID:       g.ID,
+				GroupName:     awsSDK.ToString(g.Name),
+				Description:   g.Description,
+				VpcID:         g.VpcID,
+				OwnerID:       g.OwnerID,
+				Tags:          g.Tags,
+				InboundRules:  g.InboundRules,
+				OutboundRules: g.OutboundRules,
 			})
 		}
 		converted = append(converted, out{
-			PodName:         d.Pod.Name,
-			Namespace:       d.Pod.Namespace,
+			Context:         d.Context,
+			PodName:         d.PodName,
+			Namespace:       d.Namespace,
 			ENI:             d.ENI,
 			AttachmentLevel: d.AttachmentLevel,
+			Status:          d.Status,
+			Reason:          d.Reason,
 			SecurityGroups:  groups,
 		})
 	}
@@ -181,34 +249,65 @@ func outputYAML(w io.Writer, data []aws.PodSecurityGroupInfo) error {
 	return err
 }
 
-// outputTable outputs the data in table format
-func outputTable(w io.Writer, results []aws.PodSecurityGroupInfo) error {
+// outputTable outputs the data in table format, with the tags of the security groups when showTags is set
+func outputTable(w io.Writer, results []aws.PodSecurityGroupInfo, showTags bool) error {
 	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(tw, "POD NAME\tIP ADDRESS\tENI ID\tATTACHMENT\tSECURITY GROUPS")
+	withContext := hasContext(results)
+	if withContext {
+		fmt.Fprint(tw, "CONTEXT\t")
+	}
+	fmt.Fprint(tw, "POD NAME\tSTATUS\tIP ADDRESS\tENI ID\tATTACHMENT\tSECURITY GROUPS")
+	if showTags {
+		fmt.Fprint(tw, "\tTAGS")
+	}
+	fmt.Fprintln(tw)
 
 	for _, r := range results {
-		var sgs []string
-		for _, sg := range r.SecurityGroups {
-			sgID := awsSDK.ToString(sg.GroupId)
-			sgName := awsSDK.ToString(sg.GroupName)
-			if sgName != "" {
-				sgs = append(sgs, fmt.Sprintf("%s (%s)", sgID, sgName))
-			} else {
-				sgs = append(sgs, sgID)
-			}
+		if withContext {
+			fmt.Fprintf(tw, "%s\t", r.Context)
 		}
 		podIP := ""
 		if r.Pod.Status.PodIP != "" {
 			podIP = r.Pod.Status.PodIP
 		}
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s",
 			r.Pod.Name,
+			r.Status,
 			podIP,
 			r.ENI,
 			r.AttachmentLevel,
-			strings.Join(sgs, ", "),
+			formatSecurityGroups(r.SecurityGroups),
 		)
+		if showTags {
+			fmt.Fprintf(tw, "\t%s", formatSecurityGroupTags(r.SecurityGroups))
+		}
+		fmt.Fprintln(tw)
 	}
 
 	return tw.Flush()
 }
+
+// formatSecurityGroups renders security groups as a comma separated list of "id (name)" entries
+func formatSecurityGroups(securityGroups []types.SecurityGroup) string {
+	var sgs []string
+	for _, sg := range securityGroups {
+		sgID := awsSDK.ToString(sg.GroupId)
+		sgName := awsSDK.ToString(sg.GroupName)
+		if sgName != "" {
+			sgs = append(sgs, fmt.Sprintf("%s (%s)", sgID, sgName))
+		} else {
+			sgs = append(sgs, sgID)
+		}
+	}
+	return strings.Join(sgs, ", ")
+}
+
+// hasContext reports whether the pods were read from several kubeconfig contexts
+func hasContext(data []aws.PodSecurityGroupInfo) bool {
+	for _, d := range data {
+		if d.Context != "" {
+			return true
+		}
+	}
+	return false
+}
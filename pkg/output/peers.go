@@ -0,0 +1,89 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+// Peer types
+const (
+	PeerTypeSecurityGroup = "securityGroup"
+	PeerTypePrefixList    = "prefixList"
+)
+
+// toPeerOutputs converts the security group and prefix list peers of a permission of sg, adding
+// what the peers resolved to
+func toPeerOutputs(sg types.SecurityGroup, p types.IpPermission, peers aws.Peers) []PeerOutput {
+	var out []PeerOutput
+	for _, pair := range p.UserIdGroupPairs {
+		out = append(out, toGroupPeerOutput(sg, pair, peers))
+	}
+	for _, pl := range p.PrefixListIds {
+		out = append(out, toPrefixListPeerOutput(pl, peers))
+	}
+	return out
+}
+
+// toGroupPeerOutput converts a group reference. Groups of another account are labelled with the
+// account since they cannot be resolved.
+func toGroupPeerOutput(sg types.SecurityGroup, pair types.UserIdGroupPair, peers aws.Peers) PeerOutput {
+	out := PeerOutput{
+		Type:                   PeerTypeSecurityGroup,
+		ID:                     awsSDK.ToString(pair.GroupId),
+		Name:                   awsSDK.ToString(pair.GroupName),
+		VpcPeeringConnectionID: awsSDK.ToString(pair.VpcPeeringConnectionId),
+	}
+	if aws.CrossAccount(sg, pair) {
+		out.OwnerID = awsSDK.ToString(pair.UserId)
+		return out
+	}
+	if resolved, ok := peers.SecurityGroups[out.ID]; ok {
+		out.Name = resolved.Name
+		out.Pods = resolved.Pods
+		out.Nodes = resolved.Nodes
+	}
+	return out
+}
+
+// toPrefixListPeerOutput converts a prefix list reference
+func toPrefixListPeerOutput(pl types.PrefixListId, peers aws.Peers) PeerOutput {
+	out := PeerOutput{Type: PeerTypePrefixList, ID: awsSDK.ToString(pl.PrefixListId)}
+	if resolved, ok := peers.PrefixLists[out.ID]; ok {
+		out.Name = resolved.Name
+		out.CIDRs = resolved.CIDRs
+	}
+	return out
+}
+
+// formatPeer renders a peer with what it resolved to, such as "sg-0123 (db; pods: data/db-0)",
+// "pl-0123 (office; cidrs: 192.0.2.0/24)" or "sg-0456 (account 111122223333; via pcx-0789)"
+func formatPeer(p PeerOutput) string {
+	var details []string
+	if p.Name != "" {
+		details = append(details, p.Name)
+	}
+	if p.OwnerID != "" {
+		details = append(details, "account "+p.OwnerID)
+	}
+	if p.VpcPeeringConnectionID != "" {
+		details = append(details, "via "+p.VpcPeeringConnectionID)
+	}
+	if len(p.Pods) > 0 {
+		details = append(details, "pods: "+strings.Join(p.Pods, ", "))
+	}
+	if len(p.Nodes) > 0 {
+		details = append(details, "nodes: "+strings.Join(p.Nodes, ", "))
+	}
+	if len(p.CIDRs) > 0 {
+		details = append(details, "cidrs: "+strings.Join(p.CIDRs, ", "))
+	}
+	if len(details) == 0 {
+		return p.ID
+	}
+	return fmt.Sprintf("%s (%s)", p.ID, strings.Join(details, "; "))
+}
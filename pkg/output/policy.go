@@ -0,0 +1,93 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/policy"
+)
+
+// OutputPolicyDrift formats and outputs the expected and actual security groups of each pod
+func OutputPolicyDrift(w io.Writer, results []policy.Result, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(toPolicyDriftOutputs(results))
+	case "json-minimal":
+		b, err := json.Marshal(toPolicyDriftOutputs(results))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "yaml":
+		b, err := yaml.Marshal(toPolicyDriftOutputs(results))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, string(b))
+		return err
+	default:
+		return outputPolicyDriftTable(w, results)
+	}
+}
+
+func toPolicyDriftOutputs(results []policy.Result) []PolicyDriftOutput {
+	out := make([]PolicyDriftOutput, 0, len(results))
+	for _, r := range results {
+		out = append(out, PolicyDriftOutput{
+			PodName:          r.Info.Pod.Name,
+			Namespace:        r.Info.Pod.Namespace,
+			AttachmentLevel:  r.Info.AttachmentLevel,
+			Policies:         nonNil(r.Policies),
+			MultiplePolicies: r.MultiplePolicies(),
+			Expected:         nonNil(r.Expected),
+			Actual:           nonNil(r.Actual),
+			Status:           r.Status,
+		})
+	}
+	return out
+}
+
+// outputPolicyDriftTable outputs one row per pod; pods selected by more than one policy are marked with a trailing *
+func outputPolicyDriftTable(w io.Writer, results []policy.Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POD NAME\tATTACHMENT\tPOLICIES\tEXPECTED\tACTUAL\tSTATUS")
+
+	for _, r := range results {
+		policies := joinOrNone(r.Policies)
+		if r.MultiplePolicies() {
+			policies += " *"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Info.Pod.Name,
+			r.Info.AttachmentLevel,
+			policies,
+			joinOrNone(r.Expected),
+			joinOrNone(r.Actual),
+			strings.ToUpper(r.Status),
+		)
+	}
+
+	return tw.Flush()
+}
+
+func joinOrNone(values []string) string {
+	if len(values) == 0 {
+		return "<none>"
+	}
+	return strings.Join(values, ",")
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
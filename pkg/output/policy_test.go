@@ -0,0 +1,59 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/policy"
+)
+
+func TestOutputPolicyDrift(t *testing.T) {
+	data := []policy.Result{
+		{
+			Info:     aws.PodSecurityGroupInfo{Pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"}}, AttachmentLevel: "pod"},
+			Policies: []string{"api", "payments"},
+			Expected: []string{"sg-a", "sg-b"},
+			Actual:   []string{"sg-a"},
+			Status:   policy.StatusDrift,
+		},
+		{
+			Info:   aws.PodSecurityGroupInfo{Pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}, AttachmentLevel: "node"},
+			Actual: []string{"sg-node"},
+			Status: policy.StatusNone,
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:     "table output",
+			format:   "table",
+			expected: "POD NAME  ATTACHMENT  POLICIES        EXPECTED   ACTUAL   STATUS\napi       pod         api,payments *  sg-a,sg-b  sg-a     DRIFT\nweb       node        <none>          <none>     sg-node  NONE\n",
+		},
+		{
+			name:     "json-minimal output",
+			format:   "json-minimal",
+			expected: `[{"podName":"api","namespace":"default","attachmentLevel":"pod","policies":["api","payments"],"multiplePolicies":true,"expectedSecurityGroups":["sg-a","sg-b"],"actualSecurityGroups":["sg-a"],"status":"drift"},{"podName":"web","namespace":"default","attachmentLevel":"node","policies":[],"multiplePolicies":false,"expectedSecurityGroups":[],"actualSecurityGroups":["sg-node"],"status":"none"}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputPolicyDrift(&buf, data, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("unexpected output: got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
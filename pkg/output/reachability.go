@@ -0,0 +1,129 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"gopkg.in/yaml.v3"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+)
+
+// OutputReachability formats and outputs the result of a pod-to-pod reachability check
+func OutputReachability(w io.Writer, r rules.Reachability, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(toReachabilityOutput(r))
+	case "json-minimal":
+		b, err := json.Marshal(toReachabilityOutput(r))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "yaml":
+		b, err := yaml.Marshal(toReachabilityOutput(r))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, string(b))
+		return err
+	default:
+		return outputReachabilityTable(w, r)
+	}
+}
+
+// toReachabilityOutput converts a reachability result into its output representation
+func toReachabilityOutput(r rules.Reachability) ReachabilityOutput {
+	return ReachabilityOutput{
+		Source:      toEndpointOutput(r.Source),
+		Destination: toEndpointOutput(r.Destination),
+		Protocol:    r.Protocol,
+		Port:        r.Port,
+		Allowed:     r.Allowed(),
+		Egress:      DirectionOutput{Allowed: r.EgressAllowed(), Rules: toMatchRuleOutputs(r.Egress)},
+		Ingress:     DirectionOutput{Allowed: r.IngressAllowed(), Rules: toMatchRuleOutputs(r.Ingress)},
+	}
+}
+
+func toEndpointOutput(info aws.PodSecurityGroupInfo) EndpointOutput {
+	sgs := make([]string, 0, len(info.SecurityGroups))
+	for _, sg := range info.SecurityGroups {
+		sgs = append(sgs, awsSDK.ToString(sg.GroupId))
+	}
+	return EndpointOutput{
+		PodName:        info.Pod.Name,
+		Namespace:      info.Pod.Namespace,
+		PodIP:          info.Pod.Status.PodIP,
+		SecurityGroups: sgs,
+	}
+}
+
+func toMatchRuleOutputs(matches []rules.Match) []MatchRuleOutput {
+	out := make([]MatchRuleOutput, 0, len(matches))
+	for _, m := range matches {
+		protocol := awsSDK.ToString(m.Permission.IpProtocol)
+		out = append(out, MatchRuleOutput{
+			SecurityGroupID: awsSDK.ToString(m.SecurityGroup.GroupId),
+			Protocol:        rules.NormalizeProtocol(protocol),
+			PortRange:       FormatPortRange(protocol, m.Permission.FromPort, m.Permission.ToPort),
+			Peer:            m.Peer,
+			Description:     m.Description,
+		})
+	}
+	return out
+}
+
+// outputReachabilityTable outputs the verdict for each direction followed by the rules that permit it
+func outputReachabilityTable(w io.Writer, r rules.Reachability) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "SOURCE\t%s/%s (%s)\t%s\n", r.Source.Pod.Namespace, r.Source.Pod.Name, r.Source.Pod.Status.PodIP, formatSecurityGroups(r.Source.SecurityGroups))
+	fmt.Fprintf(tw, "DESTINATION\t%s/%s (%s)\t%s\n", r.Destination.Pod.Namespace, r.Destination.Pod.Name, r.Destination.Pod.Status.PodIP, formatSecurityGroups(r.Destination.SecurityGroups))
+	fmt.Fprintf(tw, "TRAFFIC\t%s\t\n", formatTraffic(r.Protocol, r.Port))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DIRECTION\tVERDICT\tSECURITY GROUP\tPROTOCOL\tPORT RANGE\tPEER\tDESCRIPTION")
+	writeDirectionRows(tw, "egress", r.Egress, r.Source)
+	writeDirectionRows(tw, "ingress", r.Ingress, r.Destination)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nRESULT: %s\n", verdict(r.Allowed()))
+	return nil
+}
+
+// writeDirectionRows writes the permitting rules of one direction, or a single DENIED row listing the evaluated groups
+func writeDirectionRows(w io.Writer, direction string, matches []rules.Match, endpoint aws.PodSecurityGroupInfo) {
+	if len(matches) == 0 {
+		fmt.Fprintf(w, "%s\t%s\t%s\t\t\t\tno rule permits the traffic\n", direction, verdict(false), formatSecurityGroups(endpoint.SecurityGroups))
+		return
+	}
+	for _, m := range toMatchRuleOutputs(matches) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", direction, verdict(true), m.SecurityGroupID, m.Protocol, m.PortRange, m.Peer, m.Description)
+	}
+}
+
+func formatTraffic(protocol string, port int32) string {
+	if protocol == "all" || protocol == "icmp" || protocol == "icmpv6" {
+		return protocol
+	}
+	return fmt.Sprintf("%s/%d", protocol, port)
+}
+
+func verdict(allowed bool) string {
+	if allowed {
+		return "ALLOWED"
+	}
+	return "DENIED"
+}
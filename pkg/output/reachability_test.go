@@ -0,0 +1,69 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOutputReachability(t *testing.T) {
+	permission := awsSDK.IpPermission{IpProtocol: strPtr("tcp"), FromPort: int32Ptr(8080), ToPort: int32Ptr(8080)}
+	r := rules.Reachability{
+		Source: aws.PodSecurityGroupInfo{
+			Pod:            corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"}, Status: corev1.PodStatus{PodIP: "10.0.1.5"}},
+			SecurityGroups: []awsSDK.SecurityGroup{{GroupId: strPtr("sg-a")}},
+		},
+		Destination: aws.PodSecurityGroupInfo{
+			Pod:            corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default"}, Status: corev1.PodStatus{PodIP: "10.0.2.7"}},
+			SecurityGroups: []awsSDK.SecurityGroup{{GroupId: strPtr("sg-b")}},
+		},
+		Protocol: "tcp",
+		Port:     8080,
+		Ingress: []rules.Match{
+			{SecurityGroup: awsSDK.SecurityGroup{GroupId: strPtr("sg-b")}, Permission: permission, Peer: "sg-a", Description: "from frontend"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:   "table output",
+			format: "table",
+			expected: "SOURCE       default/frontend (10.0.1.5)  sg-a\n" +
+				"DESTINATION  default/backend (10.0.2.7)   sg-b\n" +
+				"TRAFFIC      tcp/8080                     \n" +
+				"\n" +
+				"DIRECTION  VERDICT  SECURITY GROUP  PROTOCOL  PORT RANGE  PEER  DESCRIPTION\n" +
+				"egress     DENIED   sg-a                                        no rule permits the traffic\n" +
+				"ingress    ALLOWED  sg-b            tcp       8080        sg-a  from frontend\n" +
+				"\n" +
+				"RESULT: DENIED\n",
+		},
+		{
+			name:     "json-minimal output",
+			format:   "json-minimal",
+			expected: `{"source":{"podName":"frontend","namespace":"default","podIP":"10.0.1.5","securityGroups":["sg-a"]},"destination":{"podName":"backend","namespace":"default","podIP":"10.0.2.7","securityGroups":["sg-b"]},"protocol":"tcp","port":8080,"allowed":false,"egress":{"allowed":false,"rules":[]},"ingress":{"allowed":true,"rules":[{"securityGroupId":"sg-b","protocol":"tcp","portRange":"8080","peer":"sg-a","description":"from frontend"}]}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputReachability(&buf, r, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("unexpected output: got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
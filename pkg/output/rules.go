@@ -0,0 +1,124 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+)
+
+// Rule directions
+const (
+	DirectionInbound  = "inbound"
+	DirectionOutbound = "outbound"
+)
+
+// rulePeer is a single peer (CIDR, security group or prefix list) of a security group rule
+type rulePeer struct {
+	peer        string
+	description string
+}
+
+// outputRulesTable outputs one row per pod, security group, rule and peer, with the tags of the
+// security group when opts.ShowTags is set
+func outputRulesTable(w io.Writer, results []aws.PodSecurityGroupInfo, opts SecurityGroupOptions) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	withContext := hasContext(results)
+	if withContext {
+		fmt.Fprint(tw, "CONTEXT\t")
+	}
+	fmt.Fprint(tw, "POD NAME\tSECURITY GROUP\tDIRECTION\tPROTOCOL\tPORT RANGE\tPEER\tDESCRIPTION")
+	if opts.ShowTags {
+		fmt.Fprint(tw, "\tTAGS")
+	}
+	fmt.Fprintln(tw)
+
+	for _, r := range results {
+		// The context column is written as part of the leading cell so rule rows stay aligned
+		leading := r.Pod.Name
+		if withContext {
+			leading = r.Context + "\t" + r.Pod.Name
+		}
+		for _, sg := range r.SecurityGroups {
+			sgLabel := formatSecurityGroups([]types.SecurityGroup{sg})
+			trailing := ""
+			if opts.ShowTags {
+				trailing = "\t" + formatTags(sg.Tags)
+			}
+			writeRuleRows(tw, leading, sgLabel, trailing, DirectionInbound, sg, sg.IpPermissions, opts.Peers)
+			writeRuleRows(tw, leading, sgLabel, trailing, DirectionOutbound, sg, sg.IpPermissionsEgress, opts.Peers)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// writeRuleRows writes a row for each peer of each permission, starting with the leading pod columns
+// and ending with the trailing columns
+func writeRuleRows(w io.Writer, leading, sgLabel, trailing, direction string, sg types.SecurityGroup, permissions []types.IpPermission, peers aws.Peers) {
+	for _, p := range permissions {
+		protocol := rules.NormalizeProtocol(awsSDK.ToString(p.IpProtocol))
+		portRange := FormatPortRange(awsSDK.ToString(p.IpProtocol), p.FromPort, p.ToPort)
+		for _, peer := range rulePeers(sg, p, peers) {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n",
+				leading,
+				sgLabel,
+				direction,
+				protocol,
+				portRange,
+				peer.peer,
+				peer.description,
+				trailing,
+			)
+		}
+	}
+}
+
+// rulePeers flattens the CIDR, security group and prefix list peers of a permission of sg, the
+// latter two with what they resolved to
+func rulePeers(sg types.SecurityGroup, p types.IpPermission, peers aws.Peers) []rulePeer {
+	var result []rulePeer
+	for _, r := range p.IpRanges {
+		result = append(result, rulePeer{awsSDK.ToString(r.CidrIp), awsSDK.ToString(r.Description)})
+	}
+	for _, r := range p.Ipv6Ranges {
+		result = append(result, rulePeer{awsSDK.ToString(r.CidrIpv6), awsSDK.ToString(r.Description)})
+	}
+	for _, g := range p.UserIdGroupPairs {
+		result = append(result, rulePeer{formatPeer(toGroupPeerOutput(sg, g, peers)), awsSDK.ToString(g.Description)})
+	}
+	for _, pl := range p.PrefixListIds {
+		result = append(result, rulePeer{formatPeer(toPrefixListPeerOutput(pl, peers)), awsSDK.ToString(pl.Description)})
+	}
+	return result
+}
+
+// FormatPortRange returns a human-readable port range for a permission.
+// For ICMP the from and to ports carry the ICMP type and code.
+func FormatPortRange(protocol string, from, to *int32) string {
+	switch rules.NormalizeProtocol(protocol) {
+	case "all":
+		return "all"
+	case "icmp", "icmpv6":
+		if from == nil || *from == -1 {
+			return "all"
+		}
+		if to == nil || *to == -1 {
+			return fmt.Sprintf("type %d", *from)
+		}
+		return fmt.Sprintf("type %d code %d", *from, *to)
+	}
+
+	if from == nil || to == nil || *from == -1 || (*from == 0 && *to == 65535) {
+		return "all"
+	}
+	if *from == *to {
+		return fmt.Sprintf("%d", *from)
+	}
+	return fmt.Sprintf("%d-%d", *from, *to)
+}
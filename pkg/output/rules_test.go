@@ -0,0 +1,215 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFormatPortRange(t *testing.T) {
+	testCases := []struct {
+		name     string
+		protocol string
+		from     *int32
+		to       *int32
+		expected string
+	}{
+		{"all protocols", "-1", nil, nil, "all"},
+		{"single port", "tcp", int32Ptr(443), int32Ptr(443), "443"},
+		{"port range", "udp", int32Ptr(1024), int32Ptr(65535), "1024-65535"},
+		{"full tcp range", "tcp", int32Ptr(0), int32Ptr(65535), "all"},
+		{"icmp all", "icmp", int32Ptr(-1), int32Ptr(-1), "all"},
+		{"icmp type", "icmp", int32Ptr(8), int32Ptr(-1), "type 8"},
+		{"icmp type and code", "1", int32Ptr(3), int32Ptr(4), "type 3 code 4"},
+		{"numeric tcp", "6", int32Ptr(22), int32Ptr(22), "22"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := FormatPortRange(tc.protocol, tc.from, tc.to); got != tc.expected {
+				t.Errorf("FormatPortRange() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestOutputPodSecurityGroups_Wide(t *testing.T) {
+	data := []aws.PodSecurityGroupInfo{
+		{
+			Pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1"},
+				Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+			},
+			ENI:             "eni-1",
+			AttachmentLevel: "pod",
+			SecurityGroups: []awsSDK.SecurityGroup{
+				{
+					GroupId:   strPtr("sg-1"),
+					GroupName: strPtr("web"),
+					IpPermissions: []awsSDK.IpPermission{
+						{
+							IpProtocol:       strPtr("tcp"),
+							FromPort:         int32Ptr(443),
+							ToPort:           int32Ptr(443),
+							IpRanges:         []awsSDK.IpRange{{CidrIp: strPtr("10.0.0.0/8"), Description: strPtr("vpc")}},
+							UserIdGroupPairs: []awsSDK.UserIdGroupPair{{GroupId: strPtr("sg-lb")}},
+						},
+						{
+							IpProtocol:    strPtr("icmp"),
+							FromPort:      int32Ptr(8),
+							ToPort:        int32Ptr(-1),
+							PrefixListIds: []awsSDK.PrefixListId{{PrefixListId: strPtr("pl-1")}},
+						},
+					},
+					IpPermissionsEgress: []awsSDK.IpPermission{
+						{
+							IpProtocol: strPtr("-1"),
+							Ipv6Ranges: []awsSDK.Ipv6Range{{CidrIpv6: strPtr("::/0")}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	expected := "POD NAME  SECURITY GROUP  DIRECTION  PROTOCOL  PORT RANGE  PEER        DESCRIPTION\n" +
+		"pod1      sg-1 (web)      inbound    tcp       443         10.0.0.0/8  vpc\n" +
+		"pod1      sg-1 (web)      inbound    tcp       443         sg-lb       \n" +
+		"pod1      sg-1 (web)      inbound    icmp      type 8      pl-1        \n" +
+		"pod1      sg-1 (web)      outbound   all       all         ::/0        \n"
+
+	var buf bytes.Buffer
+	if err := OutputPodSecurityGroups(&buf, data, "wide", "pod", SecurityGroupOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := buf.String(); got != expected {
+		t.Errorf("unexpected output: got %q, want %q", got, expected)
+	}
+}
+
+func TestOutputPodSecurityGroups_Contexts(t *testing.T) {
+	data := []aws.PodSecurityGroupInfo{
+		{
+			Context: "staging",
+			Pod:     corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			ENI:     "eni-2",
+			SecurityGroups: []awsSDK.SecurityGroup{{
+				GroupId:       strPtr("sg-2"),
+				IpPermissions: []awsSDK.IpPermission{{IpProtocol: strPtr("tcp"), FromPort: int32Ptr(443), ToPort: int32Ptr(443), IpRanges: []awsSDK.IpRange{{CidrIp: strPtr("10.0.0.0/8")}}}},
+			}},
+		},
+		{
+			Context: "prod",
+			Pod:     corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			ENI:     "eni-1",
+			SecurityGroups: []awsSDK.SecurityGroup{{
+				GroupId:       strPtr("sg-1"),
+				IpPermissions: []awsSDK.IpPermission{{IpProtocol: strPtr("tcp"), FromPort: int32Ptr(443), ToPort: int32Ptr(443), IpRanges: []awsSDK.IpRange{{CidrIp: strPtr("10.0.0.0/8")}}}},
+			}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:   "wide output",
+			format: "wide",
+			expected: "CONTEXT  POD NAME  SECURITY GROUP  DIRECTION  PROTOCOL  PORT RANGE  PEER        DESCRIPTION\n" +
+				"prod     web       sg-1            inbound    tcp       443         10.0.0.0/8  \n" +
+				"staging  web       sg-2            inbound    tcp       443         10.0.0.0/8  \n",
+		},
+		{
+			name:     "json-minimal output",
+			format:   "json-minimal",
+			expected: `[{"context":"prod","podName":"web","namespace":"default","podIP":"","eni":"eni-1","attachmentLevel":"","securityGroups":[{"id":"sg-1","inboundRules":[{"protocol":"tcp","fromPort":443,"toPort":443,"sources":["10.0.0.0/8"],"entries":[{"peer":"10.0.0.0/8"}]}]}]},{"context":"staging","podName":"web","namespace":"default","podIP":"","eni":"eni-2","attachmentLevel":"","securityGroups":[{"id":"sg-2","inboundRules":[{"protocol":"tcp","fromPort":443,"toPort":443,"sources":["10.0.0.0/8"],"entries":[{"peer":"10.0.0.0/8"}]}]}]}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputPodSecurityGroups(&buf, data, tc.format, "pod", SecurityGroupOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("unexpected output: got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestOutputPodSecurityGroups_ResolvedPeers(t *testing.T) {
+	data := []aws.PodSecurityGroupInfo{
+		{
+			Pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			ENI: "eni-1",
+			SecurityGroups: []awsSDK.SecurityGroup{{
+				GroupId: strPtr("sg-1"),
+				OwnerId: strPtr("111111111111"),
+				IpPermissions: []awsSDK.IpPermission{{
+					IpProtocol: strPtr("tcp"),
+					FromPort:   int32Ptr(443),
+					ToPort:     int32Ptr(443),
+					UserIdGroupPairs: []awsSDK.UserIdGroupPair{
+						{GroupId: strPtr("sg-lb"), UserId: strPtr("111111111111")},
+						{GroupId: strPtr("sg-ext"), UserId: strPtr("222222222222"), VpcPeeringConnectionId: strPtr("pcx-1")},
+					},
+				}},
+				IpPermissionsEgress: []awsSDK.IpPermission{{
+					IpProtocol:    strPtr("tcp"),
+					FromPort:      int32Ptr(443),
+					ToPort:        int32Ptr(443),
+					PrefixListIds: []awsSDK.PrefixListId{{PrefixListId: strPtr("pl-1"), Description: strPtr("office")}},
+				}},
+			}},
+		},
+	}
+	peers := aws.Peers{
+		SecurityGroups: map[string]aws.PeerSecurityGroup{
+			"sg-lb": {ID: "sg-lb", Name: "lb", Pods: []string{"ingress/lb-0"}, Nodes: []string{"node-1"}},
+		},
+		PrefixLists: map[string]aws.PrefixList{
+			"pl-1": {ID: "pl-1", Name: "office", CIDRs: []string{"192.0.2.0/24", "198.51.100.0/24"}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:   "wide output",
+			format: "wide",
+			expected: "POD NAME  SECURITY GROUP  DIRECTION  PROTOCOL  PORT RANGE  PEER                                                 DESCRIPTION\n" +
+				"web       sg-1            inbound    tcp       443         sg-lb (lb; pods: ingress/lb-0; nodes: node-1)        \n" +
+				"web       sg-1            inbound    tcp       443         sg-ext (account 222222222222; via pcx-1)             \n" +
+				"web       sg-1            outbound   tcp       443         pl-1 (office; cidrs: 192.0.2.0/24, 198.51.100.0/24)  office\n",
+		},
+		{
+			name:     "json-minimal output",
+			format:   "json-minimal",
+			expected: `[{"podName":"web","namespace":"default","podIP":"","eni":"eni-1","attachmentLevel":"","securityGroups":[{"id":"sg-1","ownerId":"111111111111","inboundRules":[{"protocol":"tcp","fromPort":443,"toPort":443,"sources":["sg-lb","sg-ext"],"peers":[{"type":"securityGroup","id":"sg-lb","name":"lb","pods":["ingress/lb-0"],"nodes":["node-1"]},{"type":"securityGroup","id":"sg-ext","ownerId":"222222222222","vpcPeeringConnectionId":"pcx-1"}],"entries":[{"peer":"sg-lb"},{"peer":"sg-ext"}]}],"outboundRules":[{"protocol":"tcp","fromPort":443,"toPort":443,"destinations":["pl-1"],"peers":[{"type":"prefixList","id":"pl-1","name":"office","cidrs":["192.0.2.0/24","198.51.100.0/24"]}],"entries":[{"peer":"pl-1","description":"office"}]}]}]}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputPodSecurityGroups(&buf, data, tc.format, "pod", SecurityGroupOptions{Peers: peers}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("unexpected output:\ngot  %s\nwant %s", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/lint"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "kubectl-sgmap"
+	toolURI      = "https://github.com/naka-gawa/kubectl-sgmap"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevel maps a lint severity to a SARIF result level
+func sarifLevel(severity string) string {
+	switch severity {
+	case lint.SeverityHigh:
+		return "error"
+	case lint.SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// outputSARIF outputs a SARIF 2.1.0 log with one result per pod-and-rule pair with findings.
+// Pods have no source file, so the physical location is a namespaces/NS/pods/NAME path that code
+// scanning tools can display, and the pod is also given as a logical location.
+func outputSARIF(w io.Writer, report lint.Report) error {
+	driver := sarifDriver{Name: toolName, InformationURI: toolURI, Rules: []sarifRule{}}
+	ruleIndex := make(map[string]int, len(report.Rules))
+	for i, r := range report.Rules {
+		ruleIndex[r.ID] = i
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   r.ID,
+			Name:                 r.Name,
+			ShortDescription:     sarifMessage{Text: r.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(r.Severity)},
+		})
+	}
+
+	results := []sarifResult{}
+	for _, c := range lintChecks(report) {
+		if len(c.findings) == 0 {
+			continue
+		}
+		results = append(results, sarifResult{
+			RuleID:    c.rule.ID,
+			RuleIndex: ruleIndex[c.rule.ID],
+			Level:     sarifLevel(c.findings[0].Severity),
+			Message:   sarifMessage{Text: c.message()},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: "namespaces/" + c.pod.Namespace + "/pods/" + c.pod.Name},
+				},
+				LogicalLocations: []sarifLogicalLocation{{
+					Name:               c.pod.Name,
+					FullyQualifiedName: c.pod.Namespace + "/" + c.pod.Name,
+					Kind:               "object",
+				}},
+			}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
@@ -0,0 +1,142 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+// SecurityGroupOptions controls what is added to the security groups of pods
+type SecurityGroupOptions struct {
+	// Peers describes the security group and prefix list peers of rules with what they resolved to
+	Peers aws.Peers
+	// Rules are the security group rules as listed by DescribeSecurityGroupRules, used to add the ID
+	// of the rule behind each peer of a permission
+	Rules []types.SecurityGroupRule
+	// ShowTags adds the tags of the security groups to table output
+	ShowTags bool
+}
+
+// ruleKey identifies a single security group rule by what it allows
+type ruleKey struct {
+	groupID  string
+	egress   bool
+	protocol string
+	fromPort int32
+	toPort   int32
+	peer     string
+}
+
+// ruleIDs maps security group rules to their IDs
+type ruleIDs map[ruleKey]string
+
+// indexRuleIDs indexes the IDs of the rules by what they allow. A permission without ports matches
+// the -1 ports of the rule DescribeSecurityGroupRules lists for it.
+func indexRuleIDs(rules []types.SecurityGroupRule) ruleIDs {
+	ids := make(ruleIDs, len(rules))
+	for _, r := range rules {
+		peer := awsSDK.ToString(r.CidrIpv4)
+		switch {
+		case r.CidrIpv6 != nil:
+			peer = awsSDK.ToString(r.CidrIpv6)
+		case r.PrefixListId != nil:
+			peer = awsSDK.ToString(r.PrefixListId)
+		case r.ReferencedGroupInfo != nil:
+			peer = awsSDK.ToString(r.ReferencedGroupInfo.GroupId)
+		}
+		ids[ruleKey{
+			groupID:  awsSDK.ToString(r.GroupId),
+			egress:   awsSDK.ToBool(r.IsEgress),
+			protocol: awsSDK.ToString(r.IpProtocol),
+			fromPort: portOrAll(r.FromPort),
+			toPort:   portOrAll(r.ToPort),
+			peer:     peer,
+		}] = awsSDK.ToString(r.SecurityGroupRuleId)
+	}
+	return ids
+}
+
+// lookup returns the ID of the rule of sg allowing the peer of the permission, or an empty string
+// when the rules were not looked up
+func (ids ruleIDs) lookup(sg types.SecurityGroup, p types.IpPermission, isInbound bool, peer string) string {
+	return ids[ruleKey{
+		groupID:  awsSDK.ToString(sg.GroupId),
+		egress:   !isInbound,
+		protocol: awsSDK.ToString(p.IpProtocol),
+		fromPort: portOrAll(p.FromPort),
+		toPort:   portOrAll(p.ToPort),
+		peer:     peer,
+	}]
+}
+
+func portOrAll(port *int32) int32 {
+	if port == nil {
+		return -1
+	}
+	return *port
+}
+
+// toRuleEntryOutputs converts each peer of a permission of sg into a rule entry with its description
+// and, when found in ids, its rule ID
+func toRuleEntryOutputs(sg types.SecurityGroup, p types.IpPermission, isInbound bool, ids ruleIDs) []RuleEntryOutput {
+	var out []RuleEntryOutput
+	add := func(peer string, description *string) {
+		out = append(out, RuleEntryOutput{
+			ID:          ids.lookup(sg, p, isInbound, peer),
+			Peer:        peer,
+			Description: awsSDK.ToString(description),
+		})
+	}
+	for _, r := range p.IpRanges {
+		add(awsSDK.ToString(r.CidrIp), r.Description)
+	}
+	for _, r := range p.Ipv6Ranges {
+		add(awsSDK.ToString(r.CidrIpv6), r.Description)
+	}
+	for _, g := range p.UserIdGroupPairs {
+		add(awsSDK.ToString(g.GroupId), g.Description)
+	}
+	for _, pl := range p.PrefixListIds {
+		add(awsSDK.ToString(pl.PrefixListId), pl.Description)
+	}
+	return out
+}
+
+// tagMap returns the tags as a map, or nil when there are none
+func tagMap(tags []types.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[awsSDK.ToString(t.Key)] = awsSDK.ToString(t.Value)
+	}
+	return m
+}
+
+// formatTags renders tags as comma separated key=value pairs sorted by key
+func formatTags(tags []types.Tag) string {
+	pairs := make([]string, 0, len(tags))
+	for _, t := range tags {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", awsSDK.ToString(t.Key), awsSDK.ToString(t.Value)))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// formatSecurityGroupTags renders the tags of each tagged security group, such as
+// "sg-0123: team=payments; sg-0456: team=platform"
+func formatSecurityGroupTags(securityGroups []types.SecurityGroup) string {
+	var groups []string
+	for _, sg := range securityGroups {
+		if len(sg.Tags) > 0 {
+			groups = append(groups, fmt.Sprintf("%s: %s", awsSDK.ToString(sg.GroupId), formatTags(sg.Tags)))
+		}
+	}
+	return strings.Join(groups, "; ")
+}
@@ -0,0 +1,144 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOutputPodSecurityGroups_Details(t *testing.T) {
+	boolPtr := func(b bool) *bool { return &b }
+	data := []aws.PodSecurityGroupInfo{
+		{
+			Pod:             corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			ENI:             "eni-1",
+			AttachmentLevel: "pod",
+			Status:          aws.StatusMapped,
+			SecurityGroups: []awsSDK.SecurityGroup{
+				{
+					GroupId:     strPtr("sg-1"),
+					GroupName:   strPtr("web"),
+					Description: strPtr("web pods"),
+					VpcId:       strPtr("vpc-1"),
+					OwnerId:     strPtr("111111111111"),
+					Tags: []awsSDK.Tag{
+						{Key: strPtr("team"), Value: strPtr("payments")},
+						{Key: strPtr("module"), Value: strPtr("web-sg")},
+					},
+					IpPermissions: []awsSDK.IpPermission{{
+						IpProtocol:       strPtr("tcp"),
+						FromPort:         int32Ptr(443),
+						ToPort:           int32Ptr(443),
+						IpRanges:         []awsSDK.IpRange{{CidrIp: strPtr("10.0.0.0/8"), Description: strPtr("allow from payments API")}},
+						UserIdGroupPairs: []awsSDK.UserIdGroupPair{{GroupId: strPtr("sg-lb")}},
+					}},
+					IpPermissionsEgress: []awsSDK.IpPermission{{
+						IpProtocol: strPtr("-1"),
+						IpRanges:   []awsSDK.IpRange{{CidrIp: strPtr("0.0.0.0/0")}},
+					}},
+				},
+				{GroupId: strPtr("sg-2")},
+			},
+		},
+	}
+	rules := []awsSDK.SecurityGroupRule{
+		{SecurityGroupRuleId: strPtr("sgr-1"), GroupId: strPtr("sg-1"), IsEgress: boolPtr(false), IpProtocol: strPtr("tcp"), FromPort: int32Ptr(443), ToPort: int32Ptr(443), CidrIpv4: strPtr("10.0.0.0/8")},
+		{SecurityGroupRuleId: strPtr("sgr-2"), GroupId: strPtr("sg-1"), IsEgress: boolPtr(false), IpProtocol: strPtr("tcp"), FromPort: int32Ptr(443), ToPort: int32Ptr(443), ReferencedGroupInfo: &awsSDK.ReferencedSecurityGroup{GroupId: strPtr("sg-lb")}},
+		{SecurityGroupRuleId: strPtr("sgr-3"), GroupId: strPtr("sg-1"), IsEgress: boolPtr(true), IpProtocol: strPtr("-1"), FromPort: int32Ptr(-1), ToPort: int32Ptr(-1), CidrIpv4: strPtr("0.0.0.0/0")},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		opts     SecurityGroupOptions
+		expected string
+	}{
+		{
+			name:   "table output with tags",
+			format: "table",
+			opts:   SecurityGroupOptions{ShowTags: true},
+			expected: "POD NAME  STATUS  IP ADDRESS  ENI ID  ATTACHMENT  SECURITY GROUPS   TAGS\n" +
+				"web       Mapped              eni-1   pod         sg-1 (web), sg-2  sg-1: module=web-sg,team=payments\n",
+		},
+		{
+			name:   "wide output with tags",
+			format: "wide",
+			opts:   SecurityGroupOptions{ShowTags: true},
+			expected: "POD NAME  SECURITY GROUP  DIRECTION  PROTOCOL  PORT RANGE  PEER        DESCRIPTION              TAGS\n" +
+				"web       sg-1 (web)      inbound    tcp       443         10.0.0.0/8  allow from payments API  module=web-sg,team=payments\n" +
+				"web       sg-1 (web)      inbound    tcp       443         sg-lb                                module=web-sg,team=payments\n" +
+				"web       sg-1 (web)      outbound   all       all         0.0.0.0/0                            module=web-sg,team=payments\n",
+		},
+		{
+			name:     "json-minimal output with rule IDs",
+			format:   "json-minimal",
+			opts:     SecurityGroupOptions{Rules: rules},
+			expected: `[{"podName":"web","namespace":"default","podIP":"","eni":"eni-1","attachmentLevel":"pod","status":"Mapped","securityGroups":[{"id":"sg-1","name":"web","description":"web pods","vpcId":"vpc-1","ownerId":"111111111111","tags":{"module":"web-sg","team":"payments"},"inboundRules":[{"protocol":"tcp","fromPort":443,"toPort":443,"sources":["10.0.0.0/8","sg-lb"],"peers":[{"type":"securityGroup","id":"sg-lb"}],"entries":[{"id":"sgr-1","peer":"10.0.0.0/8","description":"allow from payments API"},{"id":"sgr-2","peer":"sg-lb"}]}],"outboundRules":[{"protocol":"-1","destinations":["0.0.0.0/0"],"entries":[{"id":"sgr-3","peer":"0.0.0.0/0"}]}]},{"id":"sg-2"}]}]`,
+		},
+		{
+			name:   "yaml output with rule IDs",
+			format: "yaml",
+			opts:   SecurityGroupOptions{Rules: rules},
+			expected: "- podName: web\n" +
+				"  namespace: default\n" +
+				"  eni: eni-1\n" +
+				"  attachmentLevel: pod\n" +
+				"  status: Mapped\n" +
+				"  securityGroups:\n" +
+				"    - groupId: sg-1\n" +
+				"      groupName: web\n" +
+				"      description: web pods\n" +
+				"      vpcId: vpc-1\n" +
+				"      ownerId: \"111111111111\"\n" +
+				"      tags:\n" +
+				"        module: web-sg\n" +
+				"        team: payments\n" +
+				"      inboundRules:\n" +
+				"        - protocol: tcp\n" +
+				"          fromPort: 443\n" +
+				"          toPort: 443\n" +
+				"          sources:\n" +
+				"            - 10.0.0.0/8\n" +
+				"            - sg-lb\n" +
+				"          peers:\n" +
+				"            - type: securityGroup\n" +
+				"              id: sg-lb\n" +
+				"          entries:\n" +
+				"            - id: sgr-1\n" +
+				"              peer: 10.0.0.0/8\n" +
+				"              description: allow from payments API\n" +
+				"            - id: sgr-2\n" +
+				"              peer: sg-lb\n" +
+				"      outboundRules:\n" +
+				"        - protocol: \"-1\"\n" +
+				"          destinations:\n" +
+				"            - 0.0.0.0/0\n" +
+				"          entries:\n" +
+				"            - id: sgr-3\n" +
+				"              peer: 0.0.0.0/0\n" +
+				"    - groupId: sg-2\n" +
+				"      groupName: \"\"\n",
+		},
+		{
+			name:     "json-minimal output without rule IDs",
+			format:   "json-minimal",
+			expected: `[{"podName":"web","namespace":"default","podIP":"","eni":"eni-1","attachmentLevel":"pod","status":"Mapped","securityGroups":[{"id":"sg-1","name":"web","description":"web pods","vpcId":"vpc-1","ownerId":"111111111111","tags":{"module":"web-sg","team":"payments"},"inboundRules":[{"protocol":"tcp","fromPort":443,"toPort":443,"sources":["10.0.0.0/8","sg-lb"],"peers":[{"type":"securityGroup","id":"sg-lb"}],"entries":[{"peer":"10.0.0.0/8","description":"allow from payments API"},{"peer":"sg-lb"}]}],"outboundRules":[{"protocol":"-1","destinations":["0.0.0.0/0"],"entries":[{"peer":"0.0.0.0/0"}]}]},{"id":"sg-2"}]}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputPodSecurityGroups(&buf, data, tc.format, "pod", tc.opts); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("unexpected output:\ngot  %s\nwant %s", got, tc.expected)
+			}
+		})
+	}
+}
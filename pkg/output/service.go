@@ -0,0 +1,122 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/service"
+)
+
+// OutputServiceSecurityGroups formats and outputs the security groups behind the endpoints of each Service
+func OutputServiceSecurityGroups(w io.Writer, data []service.SecurityGroupInfo, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(toServiceOutput(data))
+	case "json-minimal":
+		b, err := json.Marshal(toServiceOutput(data))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "yaml":
+		b, err := yaml.Marshal(toServiceOutput(data))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, string(b))
+		return err
+	default:
+		return outputServiceTable(w, data)
+	}
+}
+
+// toServiceOutput converts the security groups of Services into their output representation
+func toServiceOutput(data []service.SecurityGroupInfo) []ServiceOutput {
+	output := make([]ServiceOutput, 0, len(data))
+	for _, d := range data {
+		sets := make([]ServiceSecurityGroupSetOutput, 0, len(d.SecurityGroupSets))
+		for _, set := range d.SecurityGroupSets {
+			ports := make([]TargetPortOutput, 0, len(set.Ports))
+			for _, c := range set.Ports {
+				ports = append(ports, TargetPortOutput{
+					Name:     c.Port.Name,
+					Protocol: rules.NormalizeProtocol(c.Port.Protocol),
+					Port:     c.Port.Port,
+					Allowed:  c.Allowed(),
+					Rules:    toMatchRuleOutputs(c.Rules),
+				})
+			}
+			sets = append(sets, ServiceSecurityGroupSetOutput{
+				AttachmentLevel: set.AttachmentLevel,
+				Endpoints:       set.Endpoints,
+				SecurityGroups:  toSecurityGroupOutputs(set.SecurityGroups, SecurityGroupOptions{}),
+				TargetPorts:     ports,
+			})
+		}
+		output = append(output, ServiceOutput{
+			Namespace:         d.Service.Namespace,
+			Name:              d.Service.Name,
+			Type:              string(d.Service.Spec.Type),
+			Endpoints:         d.Endpoints(),
+			Consistent:        d.Consistent(),
+			Blocked:           d.Blocked(),
+			SecurityGroupSets: sets,
+			Unresolved:        toMinimalOutput(d.Unresolved, SecurityGroupOptions{}),
+		})
+	}
+	return output
+}
+
+// outputServiceTable outputs one row per distinct security group set of each Service, with the verdict
+// of its ingress rules for each target port
+func outputServiceTable(w io.Writer, data []service.SecurityGroupInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tNAME\tENDPOINTS\tATTACHMENT\tSECURITY GROUPS\tTARGET PORTS\tCONSISTENT")
+
+	for _, d := range data {
+		consistent := "yes"
+		if !d.Consistent() {
+			consistent = "NO"
+		}
+		if d.Endpoints() == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t0\t\t\t\t%s\n", d.Service.Namespace, d.Service.Name, consistent)
+			continue
+		}
+		namespace, name, status := d.Service.Namespace, d.Service.Name, consistent
+		for _, set := range d.SecurityGroupSets {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+				namespace,
+				name,
+				len(set.Endpoints),
+				set.AttachmentLevel,
+				formatSecurityGroups(set.SecurityGroups),
+				formatPortChecks(set.Ports),
+				status,
+			)
+			namespace, name, status = "", "", ""
+		}
+		if len(d.Unresolved) > 0 {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t\t<unresolved>\t\t%s\n", namespace, name, len(d.Unresolved), status)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// formatPortChecks formats the verdict of each target port, such as "tcp/8080 ALLOWED, tcp/9090 DENIED"
+func formatPortChecks(checks []service.PortCheck) string {
+	var ports []string
+	for _, c := range checks {
+		ports = append(ports, fmt.Sprintf("%s %s", formatTraffic(rules.NormalizeProtocol(c.Port.Protocol), c.Port.Port), verdict(c.Allowed())))
+	}
+	return strings.Join(ports, ", ")
+}
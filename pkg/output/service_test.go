@@ -0,0 +1,82 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/service"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOutputServiceSecurityGroups(t *testing.T) {
+	http := service.Port{Name: "http", Protocol: "TCP", Port: 8080}
+	data := []service.SecurityGroupInfo{
+		{
+			Service: corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			SecurityGroupSets: []service.SecurityGroupSet{
+				{
+					SecurityGroups:  []awsSDK.SecurityGroup{{GroupId: strPtr("sg-1"), GroupName: strPtr("web")}},
+					AttachmentLevel: "pod",
+					Endpoints:       []string{"web-1", "web-2"},
+					Ports: []service.PortCheck{{
+						Port: http,
+						Rules: []rules.Match{{
+							SecurityGroup: awsSDK.SecurityGroup{GroupId: strPtr("sg-1")},
+							Permission:    awsSDK.IpPermission{IpProtocol: strPtr("tcp"), FromPort: int32Ptr(8080), ToPort: int32Ptr(8080)},
+							Peer:          "10.0.0.0/8",
+						}},
+					}},
+				},
+				{
+					SecurityGroups:  []awsSDK.SecurityGroup{{GroupId: strPtr("sg-2"), GroupName: strPtr("node")}},
+					AttachmentLevel: "node",
+					Endpoints:       []string{"web-3"},
+					Ports:           []service.PortCheck{{Port: http}},
+				},
+			},
+			Unresolved: []aws.PodSecurityGroupInfo{
+				{Pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-4", Namespace: "default"}}, Status: aws.StatusENINotFound},
+			},
+		},
+		{
+			Service: corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "idle", Namespace: "default"}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:   "table output",
+			format: "table",
+			expected: "NAMESPACE  NAME  ENDPOINTS  ATTACHMENT  SECURITY GROUPS  TARGET PORTS      CONSISTENT\n" +
+				"default    web   2          pod         sg-1 (web)       tcp/8080 ALLOWED  NO\n" +
+				"                 1          node        sg-2 (node)      tcp/8080 DENIED   \n" +
+				"                 1                      <unresolved>                       \n" +
+				"default    idle  0                                                         yes\n",
+		},
+		{
+			name:     "json-minimal output",
+			format:   "json-minimal",
+			expected: `[{"namespace":"default","name":"web","type":"","endpoints":4,"consistent":false,"blocked":true,"securityGroupSets":[{"attachmentLevel":"pod","endpoints":["web-1","web-2"],"securityGroups":[{"id":"sg-1","name":"web"}],"targetPorts":[{"name":"http","protocol":"tcp","port":8080,"allowed":true,"rules":[{"securityGroupId":"sg-1","protocol":"tcp","portRange":"8080","peer":"10.0.0.0/8"}]}]},{"attachmentLevel":"node","endpoints":["web-3"],"securityGroups":[{"id":"sg-2","name":"node"}],"targetPorts":[{"name":"http","protocol":"tcp","port":8080,"allowed":false,"rules":[]}]}],"unresolved":[{"podName":"web-4","namespace":"default","podIP":"","eni":"","attachmentLevel":"","status":"ENINotFound","securityGroups":[]}]},{"namespace":"default","name":"idle","type":"","endpoints":0,"consistent":true,"blocked":false,"securityGroupSets":[]}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputServiceSecurityGroups(&buf, data, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tc.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tc.expected, buf.String())
+			}
+		})
+	}
+}
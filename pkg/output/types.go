@@ -3,27 +3,306 @@ package output
 
 // PodOutput is a slimmed-down representation of a pod's security information for JSON output.
 type PodOutput struct {
-	PodName         string                `json:"podName"`
-	Namespace       string                `json:"namespace"`
-	PodIP           string                `json:"podIP"`
-	ENI             string                `json:"eni"`
-	AttachmentLevel string                `json:"attachmentLevel"`
-	SecurityGroups  []SecurityGroupOutput `json:"securityGroups"`
+	Context         string                `json:"context,omitempty" yaml:"context,omitempty"`
+	PodName         string                `json:"podName" yaml:"podName"`
+	Namespace       string                `json:"namespace" yaml:"namespace"`
+	PodIP           string                `json:"podIP" yaml:"podIP"`
+	ENI             string                `json:"eni" yaml:"eni"`
+	AttachmentLevel string                `json:"attachmentLevel" yaml:"attachmentLevel"`
+	Status          string                `json:"status,omitempty" yaml:"status,omitempty"`
+	Reason          string                `json:"reason,omitempty" yaml:"reason,omitempty"`
+	SecurityGroups  []SecurityGroupOutput `json:"securityGroups" yaml:"securityGroups"`
 }
 
 // SecurityGroupOutput is a slimmed-down representation of a security group for JSON output.
 type SecurityGroupOutput struct {
-	ID            string       `json:"id"`
-	Name          *string      `json:"name,omitempty"`
-	InboundRules  []RuleOutput `json:"inboundRules,omitempty"`
-	OutboundRules []RuleOutput `json:"outboundRules,omitempty"`
+	ID            string            `json:"id" yaml:"id"`
+	Name          *string           `json:"name,omitempty" yaml:"name,omitempty"`
+	Description   string            `json:"description,omitempty" yaml:"description,omitempty"`
+	VpcID         string            `json:"vpcId,omitempty" yaml:"vpcId,omitempty"`
+	OwnerID       string            `json:"ownerId,omitempty" yaml:"ownerId,omitempty"`
+	Tags          map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	InboundRules  []RuleOutput      `json:"inboundRules,omitempty" yaml:"inboundRules,omitempty"`
+	OutboundRules []RuleOutput      `json:"outboundRules,omitempty" yaml:"outboundRules,omitempty"`
 }
 
 // RuleOutput represents a simplified security group rule.
 type RuleOutput struct {
-	Protocol     string   `json:"protocol"`
-	FromPort     *int32   `json:"fromPort,omitempty"`
-	ToPort       *int32   `json:"toPort,omitempty"`
-	Sources      []string `json:"sources,omitempty"`
-	Destinations []string `json:"destinations,omitempty"`
+	Protocol     string   `json:"protocol" yaml:"protocol"`
+	FromPort     *int32   `json:"fromPort,omitempty" yaml:"fromPort,omitempty"`
+	ToPort       *int32   `json:"toPort,omitempty" yaml:"toPort,omitempty"`
+	Sources      []string `json:"sources,omitempty" yaml:"sources,omitempty"`
+	Destinations []string `json:"destinations,omitempty" yaml:"destinations,omitempty"`
+	// Peers describes the security group and prefix list sources or destinations
+	Peers []PeerOutput `json:"peers,omitempty" yaml:"peers,omitempty"`
+	// Entries are the individual security group rules of the permission, one per source or destination
+	Entries []RuleEntryOutput `json:"entries,omitempty" yaml:"entries,omitempty"`
+}
+
+// RuleEntryOutput is a single security group rule, allowing one source or destination. ID is only set
+// when the rules were looked up with DescribeSecurityGroupRules.
+type RuleEntryOutput struct {
+	ID          string `json:"id,omitempty" yaml:"id,omitempty"`
+	Peer        string `json:"peer" yaml:"peer"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// PeerOutput describes a security group or managed prefix list peer of a rule, with what it resolved to.
+type PeerOutput struct {
+	Type string `json:"type" yaml:"type"`
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// OwnerID is the account of a group of another account, reached through VpcPeeringConnectionID
+	// when the group is in a peered VPC
+	OwnerID                string   `json:"ownerId,omitempty" yaml:"ownerId,omitempty"`
+	VpcPeeringConnectionID string   `json:"vpcPeeringConnectionId,omitempty" yaml:"vpcPeeringConnectionId,omitempty"`
+	Pods                   []string `json:"pods,omitempty" yaml:"pods,omitempty"`
+	Nodes                  []string `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+	CIDRs                  []string `json:"cidrs,omitempty" yaml:"cidrs,omitempty"`
+}
+
+// WorkloadOutput represents the security groups carried by the replicas of a workload controller.
+type WorkloadOutput struct {
+	Kind              string                   `json:"kind" yaml:"kind"`
+	Namespace         string                   `json:"namespace" yaml:"namespace"`
+	Name              string                   `json:"name" yaml:"name"`
+	Replicas          int                      `json:"replicas" yaml:"replicas"`
+	Consistent        bool                     `json:"consistent" yaml:"consistent"`
+	SecurityGroupSets []SecurityGroupSetOutput `json:"securityGroupSets" yaml:"securityGroupSets"`
+	Unresolved        []PodOutput              `json:"unresolved,omitempty" yaml:"unresolved,omitempty"`
+}
+
+// SecurityGroupSetOutput represents a distinct set of security groups and the replicas carrying it.
+type SecurityGroupSetOutput struct {
+	Replicas        int                   `json:"replicas" yaml:"replicas"`
+	AttachmentLevel string                `json:"attachmentLevel" yaml:"attachmentLevel"`
+	Pods            []string              `json:"pods" yaml:"pods"`
+	SecurityGroups  []SecurityGroupOutput `json:"securityGroups" yaml:"securityGroups"`
+}
+
+// SecurityGroupUsageOutput represents the pods and unmatched ENIs that use a security group.
+type SecurityGroupUsageOutput struct {
+	ID            string      `json:"id" yaml:"id"`
+	Name          string      `json:"name" yaml:"name"`
+	Pods          []PodOutput `json:"pods" yaml:"pods"`
+	UnmatchedENIs []ENIOutput `json:"unmatchedENIs" yaml:"unmatchedENIs"`
+}
+
+// ENIOutput is a slimmed-down representation of a network interface.
+type ENIOutput struct {
+	ID            string   `json:"id" yaml:"id"`
+	InterfaceType string   `json:"interfaceType" yaml:"interfaceType"`
+	Description   string   `json:"description,omitempty" yaml:"description,omitempty"`
+	PrivateIPs    []string `json:"privateIPs" yaml:"privateIPs"`
+}
+
+// ReachabilityOutput represents the result of a pod-to-pod reachability check.
+type ReachabilityOutput struct {
+	Source      EndpointOutput  `json:"source" yaml:"source"`
+	Destination EndpointOutput  `json:"destination" yaml:"destination"`
+	Protocol    string          `json:"protocol" yaml:"protocol"`
+	Port        int32           `json:"port" yaml:"port"`
+	Allowed     bool            `json:"allowed" yaml:"allowed"`
+	Egress      DirectionOutput `json:"egress" yaml:"egress"`
+	Ingress     DirectionOutput `json:"ingress" yaml:"ingress"`
+}
+
+// EndpointOutput identifies a pod and the security groups evaluated for it.
+type EndpointOutput struct {
+	PodName        string   `json:"podName" yaml:"podName"`
+	Namespace      string   `json:"namespace" yaml:"namespace"`
+	PodIP          string   `json:"podIP" yaml:"podIP"`
+	SecurityGroups []string `json:"securityGroups" yaml:"securityGroups"`
+}
+
+// DirectionOutput lists the rules that permit traffic in one direction.
+type DirectionOutput struct {
+	Allowed bool              `json:"allowed" yaml:"allowed"`
+	Rules   []MatchRuleOutput `json:"rules" yaml:"rules"`
+}
+
+// MatchRuleOutput is a single security group rule peer that permits traffic.
+type MatchRuleOutput struct {
+	SecurityGroupID string `json:"securityGroupId" yaml:"securityGroupId"`
+	Protocol        string `json:"protocol" yaml:"protocol"`
+	PortRange       string `json:"portRange" yaml:"portRange"`
+	Peer            string `json:"peer" yaml:"peer"`
+	Description     string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// PolicyDriftOutput compares the security groups a pod should have according to its
+// SecurityGroupPolicies with the security groups attached to its ENI.
+type PolicyDriftOutput struct {
+	PodName          string   `json:"podName" yaml:"podName"`
+	Namespace        string   `json:"namespace" yaml:"namespace"`
+	AttachmentLevel  string   `json:"attachmentLevel" yaml:"attachmentLevel"`
+	Policies         []string `json:"policies" yaml:"policies"`
+	MultiplePolicies bool     `json:"multiplePolicies" yaml:"multiplePolicies"`
+	Expected         []string `json:"expectedSecurityGroups" yaml:"expectedSecurityGroups"`
+	Actual           []string `json:"actualSecurityGroups" yaml:"actualSecurityGroups"`
+	Status           string   `json:"status" yaml:"status"`
+}
+
+// NetworkPolicyViewOutput combines the NetworkPolicies selecting a pod with its security groups. Each
+// traffic entry is a rule of one layer checked against the other.
+type NetworkPolicyViewOutput struct {
+	PodName         string                `json:"podName" yaml:"podName"`
+	Namespace       string                `json:"namespace" yaml:"namespace"`
+	PodIP           string                `json:"podIP" yaml:"podIP"`
+	AttachmentLevel string                `json:"attachmentLevel" yaml:"attachmentLevel"`
+	NetworkPolicies []string              `json:"networkPolicies" yaml:"networkPolicies"`
+	IngressIsolated bool                  `json:"ingressIsolated" yaml:"ingressIsolated"`
+	EgressIsolated  bool                  `json:"egressIsolated" yaml:"egressIsolated"`
+	SecurityGroups  []SecurityGroupOutput `json:"securityGroups" yaml:"securityGroups"`
+	Traffic         []TrafficOutput       `json:"traffic" yaml:"traffic"`
+	Mismatches      int                   `json:"mismatches" yaml:"mismatches"`
+}
+
+// TrafficOutput represents the traffic allowed by a rule peer of a security group or NetworkPolicy and
+// whether the other layer allows it too.
+type TrafficOutput struct {
+	Direction string `json:"direction" yaml:"direction"`
+	Layer     string `json:"layer" yaml:"layer"`
+	Rule      string `json:"rule" yaml:"rule"`
+	Protocol  string `json:"protocol" yaml:"protocol"`
+	PortRange string `json:"portRange" yaml:"portRange"`
+	Peer      string `json:"peer" yaml:"peer"`
+	Status    string `json:"status" yaml:"status"`
+}
+
+// DiffOutput lists the differences between two pod security group mappings.
+type DiffOutput struct {
+	Pods           []PodDiffOutput           `json:"pods" yaml:"pods"`
+	SecurityGroups []SecurityGroupDiffOutput `json:"securityGroups" yaml:"securityGroups"`
+}
+
+// PodDiffOutput represents a pod that was added, removed or whose ENI or security groups changed.
+type PodDiffOutput struct {
+	Context               string   `json:"context,omitempty" yaml:"context,omitempty"`
+	Namespace             string   `json:"namespace" yaml:"namespace"`
+	PodName               string   `json:"podName" yaml:"podName"`
+	Change                string   `json:"change" yaml:"change"`
+	OldENI                string   `json:"oldENI,omitempty" yaml:"oldENI,omitempty"`
+	NewENI                string   `json:"newENI,omitempty" yaml:"newENI,omitempty"`
+	OldAttachmentLevel    string   `json:"oldAttachmentLevel,omitempty" yaml:"oldAttachmentLevel,omitempty"`
+	NewAttachmentLevel    string   `json:"newAttachmentLevel,omitempty" yaml:"newAttachmentLevel,omitempty"`
+	OldStatus             string   `json:"oldStatus,omitempty" yaml:"oldStatus,omitempty"`
+	NewStatus             string   `json:"newStatus,omitempty" yaml:"newStatus,omitempty"`
+	AddedSecurityGroups   []string `json:"addedSecurityGroups,omitempty" yaml:"addedSecurityGroups,omitempty"`
+	RemovedSecurityGroups []string `json:"removedSecurityGroups,omitempty" yaml:"removedSecurityGroups,omitempty"`
+}
+
+// SecurityGroupDiffOutput represents the rules added to and removed from a security group.
+type SecurityGroupDiffOutput struct {
+	ID           string   `json:"id" yaml:"id"`
+	Name         string   `json:"name,omitempty" yaml:"name,omitempty"`
+	AddedRules   []string `json:"addedRules,omitempty" yaml:"addedRules,omitempty"`
+	RemovedRules []string `json:"removedRules,omitempty" yaml:"removedRules,omitempty"`
+}
+
+// LintFindingOutput represents a single lint finding.
+type LintFindingOutput struct {
+	RuleID        string `json:"ruleId" yaml:"ruleId"`
+	RuleName      string `json:"ruleName" yaml:"ruleName"`
+	Severity      string `json:"severity" yaml:"severity"`
+	Namespace     string `json:"namespace" yaml:"namespace"`
+	PodName       string `json:"podName" yaml:"podName"`
+	SecurityGroup string `json:"securityGroup,omitempty" yaml:"securityGroup,omitempty"`
+	Message       string `json:"message" yaml:"message"`
+}
+
+// GraphOutput represents pods, ENIs, security groups and rule peers as nodes with edges between them.
+type GraphOutput struct {
+	Nodes []GraphNodeOutput `json:"nodes" yaml:"nodes"`
+	Edges []GraphEdgeOutput `json:"edges" yaml:"edges"`
+}
+
+// GraphNodeOutput represents a single node of the graph.
+type GraphNodeOutput struct {
+	ID    string `json:"id" yaml:"id"`
+	Kind  string `json:"kind" yaml:"kind"`
+	Label string `json:"label" yaml:"label"`
+}
+
+// GraphEdgeOutput represents a directed edge of the graph.
+type GraphEdgeOutput struct {
+	From  string `json:"from" yaml:"from"`
+	To    string `json:"to" yaml:"to"`
+	Kind  string `json:"kind" yaml:"kind"`
+	Label string `json:"label,omitempty" yaml:"label,omitempty"`
+}
+
+// NodeOutput represents the ENIs of the EC2 instance behind a node and the pods using them.
+type NodeOutput struct {
+	Node          string          `json:"node" yaml:"node"`
+	InstanceID    string          `json:"instanceID,omitempty" yaml:"instanceID,omitempty"`
+	Status        string          `json:"status" yaml:"status"`
+	Reason        string          `json:"reason,omitempty" yaml:"reason,omitempty"`
+	ENIs          []NodeENIOutput `json:"enis" yaml:"enis"`
+	UnmatchedPods []string        `json:"unmatchedPods,omitempty" yaml:"unmatchedPods,omitempty"`
+}
+
+// NodeENIOutput represents an ENI of a node, its security groups and the pods using its addresses.
+type NodeENIOutput struct {
+	ID             string                `json:"id" yaml:"id"`
+	Role           string                `json:"role" yaml:"role"`
+	Trunk          string                `json:"trunk,omitempty" yaml:"trunk,omitempty"`
+	SubnetID       string                `json:"subnetId" yaml:"subnetId"`
+	AssignedIPs    int                   `json:"assignedIPs" yaml:"assignedIPs"`
+	PodIPs         int                   `json:"podIPs" yaml:"podIPs"`
+	BranchENIs     int                   `json:"branchENIs" yaml:"branchENIs"`
+	Pods           []string              `json:"pods" yaml:"pods"`
+	SecurityGroups []SecurityGroupOutput `json:"securityGroups" yaml:"securityGroups"`
+}
+
+// ServiceOutput represents the security groups behind the ready endpoints of a Service.
+type ServiceOutput struct {
+	Namespace         string                          `json:"namespace" yaml:"namespace"`
+	Name              string                          `json:"name" yaml:"name"`
+	Type              string                          `json:"type" yaml:"type"`
+	Endpoints         int                             `json:"endpoints" yaml:"endpoints"`
+	Consistent        bool                            `json:"consistent" yaml:"consistent"`
+	Blocked           bool                            `json:"blocked" yaml:"blocked"`
+	SecurityGroupSets []ServiceSecurityGroupSetOutput `json:"securityGroupSets" yaml:"securityGroupSets"`
+	Unresolved        []PodOutput                     `json:"unresolved,omitempty" yaml:"unresolved,omitempty"`
+}
+
+// ServiceSecurityGroupSetOutput represents a distinct set of security groups shared by endpoints of a
+// Service and whether its ingress rules permit each target port.
+type ServiceSecurityGroupSetOutput struct {
+	AttachmentLevel string                `json:"attachmentLevel" yaml:"attachmentLevel"`
+	Endpoints       []string              `json:"endpoints" yaml:"endpoints"`
+	SecurityGroups  []SecurityGroupOutput `json:"securityGroups" yaml:"securityGroups"`
+	TargetPorts     []TargetPortOutput    `json:"targetPorts" yaml:"targetPorts"`
+}
+
+// TargetPortOutput lists the ingress rules that permit a target port.
+type TargetPortOutput struct {
+	Name     string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Protocol string            `json:"protocol" yaml:"protocol"`
+	Port     int32             `json:"port" yaml:"port"`
+	Allowed  bool              `json:"allowed" yaml:"allowed"`
+	Rules    []MatchRuleOutput `json:"rules" yaml:"rules"`
+}
+
+// LoadBalancerPathOutput represents the traffic from the load balancer of a Service or Ingress to
+// one of its targets and the ingress rules of the target that permit it.
+type LoadBalancerPathOutput struct {
+	Namespace                  string                `json:"namespace" yaml:"namespace"`
+	Kind                       string                `json:"kind" yaml:"kind"`
+	Name                       string                `json:"name" yaml:"name"`
+	LoadBalancer               string                `json:"loadBalancer,omitempty" yaml:"loadBalancer,omitempty"`
+	DNSName                    string                `json:"dnsName,omitempty" yaml:"dnsName,omitempty"`
+	Type                       string                `json:"type,omitempty" yaml:"type,omitempty"`
+	TargetType                 string                `json:"targetType" yaml:"targetType"`
+	LoadBalancerSecurityGroups []SecurityGroupOutput `json:"loadBalancerSecurityGroups" yaml:"loadBalancerSecurityGroups"`
+	LoadBalancerIPs            []string              `json:"loadBalancerIPs" yaml:"loadBalancerIPs"`
+	Target                     string                `json:"target,omitempty" yaml:"target,omitempty"`
+	TargetIP                   string                `json:"targetIP,omitempty" yaml:"targetIP,omitempty"`
+	Protocol                   string                `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	Port                       int32                 `json:"port,omitempty" yaml:"port,omitempty"`
+	TargetSecurityGroups       []SecurityGroupOutput `json:"targetSecurityGroups" yaml:"targetSecurityGroups"`
+	Status                     string                `json:"status" yaml:"status"`
+	Reason                     string                `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Rules                      []MatchRuleOutput     `json:"rules" yaml:"rules"`
 }
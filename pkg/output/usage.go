@@ -0,0 +1,103 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"gopkg.in/yaml.v3"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+// OutputSecurityGroupUsage formats and outputs the pods and ENIs using each security group
+func OutputSecurityGroupUsage(w io.Writer, data []aws.SecurityGroupUsage, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(toUsageOutput(data))
+	case "json-minimal":
+		b, err := json.Marshal(toUsageOutput(data))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "yaml":
+		b, err := yaml.Marshal(toUsageOutput(data))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, string(b))
+		return err
+	default:
+		return outputUsageTable(w, data)
+	}
+}
+
+// toUsageOutput converts security group usage into its output representation
+func toUsageOutput(data []aws.SecurityGroupUsage) []SecurityGroupUsageOutput {
+	output := make([]SecurityGroupUsageOutput, 0, len(data))
+	for _, d := range data {
+		enis := make([]ENIOutput, 0, len(d.UnmatchedENIs))
+		for _, eni := range d.UnmatchedENIs {
+			var ips []string
+			for _, ip := range eni.PrivateIpAddresses {
+				ips = append(ips, awsSDK.ToString(ip.PrivateIpAddress))
+			}
+			enis = append(enis, ENIOutput{
+				ID:            awsSDK.ToString(eni.NetworkInterfaceId),
+				InterfaceType: string(eni.InterfaceType),
+				Description:   awsSDK.ToString(eni.Description),
+				PrivateIPs:    ips,
+			})
+		}
+		output = append(output, SecurityGroupUsageOutput{
+			ID:            awsSDK.ToString(d.SecurityGroup.GroupId),
+			Name:          awsSDK.ToString(d.SecurityGroup.GroupName),
+			Pods:          toMinimalOutput(d.Pods, SecurityGroupOptions{}),
+			UnmatchedENIs: enis,
+		})
+	}
+	return output
+}
+
+// outputUsageTable outputs one row per pod using each security group, followed by ENIs that match no pod
+func outputUsageTable(w io.Writer, data []aws.SecurityGroupUsage) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SECURITY GROUP\tNAMESPACE\tPOD NAME\tIP ADDRESS\tENI ID\tATTACHMENT")
+
+	for _, d := range data {
+		sg := formatSecurityGroups([]types.SecurityGroup{d.SecurityGroup})
+		for _, p := range d.Pods {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				sg,
+				p.Pod.Namespace,
+				p.Pod.Name,
+				p.Pod.Status.PodIP,
+				p.ENI,
+				p.AttachmentLevel,
+			)
+		}
+		for _, eni := range d.UnmatchedENIs {
+			ip := ""
+			if len(eni.PrivateIpAddresses) > 0 {
+				ip = awsSDK.ToString(eni.PrivateIpAddresses[0].PrivateIpAddress)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				sg,
+				"",
+				"<none>",
+				ip,
+				awsSDK.ToString(eni.NetworkInterfaceId),
+				"",
+			)
+		}
+	}
+
+	return tw.Flush()
+}
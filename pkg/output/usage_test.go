@@ -0,0 +1,68 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOutputSecurityGroupUsage(t *testing.T) {
+	data := []aws.SecurityGroupUsage{
+		{
+			SecurityGroup: awsSDK.SecurityGroup{GroupId: strPtr("sg-1"), GroupName: strPtr("web")},
+			Pods: []aws.PodSecurityGroupInfo{
+				{
+					Pod: corev1.Pod{
+						ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1"},
+						Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+					},
+					ENI:             "eni-1",
+					AttachmentLevel: "pod",
+					SecurityGroups:  []awsSDK.SecurityGroup{{GroupId: strPtr("sg-1"), GroupName: strPtr("web")}},
+				},
+			},
+			UnmatchedENIs: []awsSDK.NetworkInterface{
+				{
+					NetworkInterfaceId: strPtr("eni-2"),
+					InterfaceType:      "interface",
+					Description:        strPtr("lambda"),
+					PrivateIpAddresses: []awsSDK.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: strPtr("10.0.0.50")}},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:     "table output",
+			format:   "table",
+			expected: "SECURITY GROUP  NAMESPACE  POD NAME  IP ADDRESS  ENI ID  ATTACHMENT\nsg-1 (web)      ns1        pod1      10.0.0.1    eni-1   pod\nsg-1 (web)                 <none>    10.0.0.50   eni-2   \n",
+		},
+		{
+			name:     "json-minimal output",
+			format:   "json-minimal",
+			expected: `[{"id":"sg-1","name":"web","pods":[{"podName":"pod1","namespace":"ns1","podIP":"10.0.0.1","eni":"eni-1","attachmentLevel":"pod","securityGroups":[{"id":"sg-1","name":"web"}]}],"unmatchedENIs":[{"id":"eni-2","interfaceType":"interface","description":"lambda","privateIPs":["10.0.0.50"]}]}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputSecurityGroupUsage(&buf, data, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("unexpected output: got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
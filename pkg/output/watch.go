@@ -0,0 +1,44 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+// Watch event types
+const (
+	EventAdded    = "ADDED"
+	EventModified = "MODIFIED"
+	EventDeleted  = "DELETED"
+)
+
+// PodWatchEvent is a change of the security group mapping of a pod
+type PodWatchEvent struct {
+	Type string
+	Info aws.PodSecurityGroupInfo
+}
+
+// OutputPodWatchEvents outputs one table row per event, preceded by the header when header is set
+func OutputPodWatchEvents(w io.Writer, events []PodWatchEvent, header bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if header {
+		fmt.Fprintln(tw, "EVENT\tNAMESPACE\tPOD NAME\tIP ADDRESS\tENI ID\tATTACHMENT\tSECURITY GROUPS")
+	}
+
+	for _, e := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Type,
+			e.Info.Pod.Namespace,
+			e.Info.Pod.Name,
+			e.Info.Pod.Status.PodIP,
+			e.Info.ENI,
+			e.Info.AttachmentLevel,
+			formatSecurityGroups(e.Info.SecurityGroups),
+		)
+	}
+
+	return tw.Flush()
+}
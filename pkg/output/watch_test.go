@@ -0,0 +1,48 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+func TestOutputPodWatchEvents(t *testing.T) {
+	events := []PodWatchEvent{
+		{
+			Type: EventAdded,
+			Info: aws.PodSecurityGroupInfo{
+				Pod: corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+					Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+				},
+				ENI:             "eni-1",
+				AttachmentLevel: "pod",
+				SecurityGroups:  []awsSDK.SecurityGroup{{GroupId: strPtr("sg-1"), GroupName: strPtr("web")}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := OutputPodWatchEvents(&buf, events, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "EVENT  NAMESPACE  POD NAME  IP ADDRESS  ENI ID  ATTACHMENT  SECURITY GROUPS\n" +
+		"ADDED  default    web       10.0.0.1    eni-1   pod         sg-1 (web)\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("unexpected output: got %q, want %q", got, expected)
+	}
+
+	buf.Reset()
+	events[0].Type = EventDeleted
+	if err := OutputPodWatchEvents(&buf, events, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := buf.String(); got != "DELETED  default  web  10.0.0.1  eni-1  pod  sg-1 (web)\n" {
+		t.Errorf("unexpected output without header: %q", got)
+	}
+}
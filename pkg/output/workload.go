@@ -0,0 +1,98 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/workload"
+)
+
+// OutputWorkloadSecurityGroups formats and outputs security group information grouped by workload controller
+func OutputWorkloadSecurityGroups(w io.Writer, data []workload.SecurityGroupInfo, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(toWorkloadOutput(data))
+	case "json-minimal":
+		b, err := json.Marshal(toWorkloadOutput(data))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "yaml":
+		b, err := yaml.Marshal(toWorkloadOutput(data))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, string(b))
+		return err
+	default:
+		return outputWorkloadTable(w, data)
+	}
+}
+
+// toWorkloadOutput converts grouped workload information into its output representation
+func toWorkloadOutput(data []workload.SecurityGroupInfo) []WorkloadOutput {
+	output := make([]WorkloadOutput, 0, len(data))
+	for _, d := range data {
+		sets := make([]SecurityGroupSetOutput, 0, len(d.SecurityGroupSets))
+		for _, set := range d.SecurityGroupSets {
+			sets = append(sets, SecurityGroupSetOutput{
+				Replicas:        len(set.Pods),
+				AttachmentLevel: set.AttachmentLevel,
+				Pods:            set.Pods,
+				SecurityGroups:  toSecurityGroupOutputs(set.SecurityGroups, SecurityGroupOptions{}),
+			})
+		}
+		output = append(output, WorkloadOutput{
+			Kind:              d.Workload.Kind,
+			Namespace:         d.Workload.Namespace,
+			Name:              d.Workload.Name,
+			Replicas:          d.Replicas(),
+			Consistent:        d.Consistent(),
+			SecurityGroupSets: sets,
+			Unresolved:        toMinimalOutput(d.Unresolved, SecurityGroupOptions{}),
+		})
+	}
+	return output
+}
+
+// outputWorkloadTable outputs one row per distinct security group set of each workload
+func outputWorkloadTable(w io.Writer, data []workload.SecurityGroupInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tNAME\tREPLICAS\tATTACHMENT\tSECURITY GROUPS\tCONSISTENT")
+
+	for _, d := range data {
+		consistent := "yes"
+		if !d.Consistent() {
+			consistent = "NO"
+		}
+		if len(d.SecurityGroupSets) == 0 && len(d.Unresolved) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t0\t\t\t%s\n", d.Workload.Namespace, d.Workload.Name, consistent)
+			continue
+		}
+		namespace, name, status := d.Workload.Namespace, d.Workload.Name, consistent
+		for _, set := range d.SecurityGroupSets {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\n",
+				namespace,
+				name,
+				len(set.Pods),
+				set.AttachmentLevel,
+				formatSecurityGroups(set.SecurityGroups),
+				status,
+			)
+			namespace, name, status = "", "", ""
+		}
+		if len(d.Unresolved) > 0 {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t\t<unresolved>\t%s\n", namespace, name, len(d.Unresolved), status)
+		}
+	}
+
+	return tw.Flush()
+}
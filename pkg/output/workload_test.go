@@ -0,0 +1,69 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/workload"
+)
+
+func TestOutputWorkloadSecurityGroups(t *testing.T) {
+	data := []workload.SecurityGroupInfo{
+		{
+			Workload: workload.Ref{Kind: workload.KindDeployment, Namespace: "default", Name: "api"},
+			SecurityGroupSets: []workload.SecurityGroupSet{
+				{
+					SecurityGroups:  []awsSDK.SecurityGroup{{GroupId: strPtr("sg-a"), GroupName: strPtr("api")}},
+					AttachmentLevel: "pod",
+					Pods:            []string{"api-1", "api-2"},
+				},
+				{
+					SecurityGroups:  []awsSDK.SecurityGroup{{GroupId: strPtr("sg-node")}},
+					AttachmentLevel: "node",
+					Pods:            []string{"api-3"},
+				},
+			},
+			Unresolved: []aws.PodSecurityGroupInfo{
+				{Pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api-4", Namespace: "default"}}, Status: aws.StatusNotRunning},
+			},
+		},
+		{
+			Workload: workload.Ref{Kind: workload.KindDeployment, Namespace: "default", Name: "idle"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:     "table output",
+			format:   "table",
+			expected: "NAMESPACE  NAME  REPLICAS  ATTACHMENT  SECURITY GROUPS  CONSISTENT\ndefault    api   2         pod         sg-a (api)       NO\n                 1         node        sg-node          \n                 1                     <unresolved>     \ndefault    idle  0                                      yes\n",
+		},
+		{
+			name:     "json-minimal output",
+			format:   "json-minimal",
+			expected: `[{"kind":"Deployment","namespace":"default","name":"api","replicas":3,"consistent":false,"securityGroupSets":[{"replicas":2,"attachmentLevel":"pod","pods":["api-1","api-2"],"securityGroups":[{"id":"sg-a","name":"api"}]},{"replicas":1,"attachmentLevel":"node","pods":["api-3"],"securityGroups":[{"id":"sg-node"}]}],"unresolved":[{"podName":"api-4","namespace":"default","podIP":"","eni":"","attachmentLevel":"","status":"NotRunning","securityGroups":[]}]},{"kind":"Deployment","namespace":"default","name":"idle","replicas":0,"consistent":true,"securityGroupSets":[]}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputWorkloadSecurityGroups(&buf, data, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("unexpected output: got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Package policy evaluates SecurityGroupPolicy objects against pods and compares the security groups
+// they should have with the security groups actually attached to their ENIs.
+package policy
+
+import (
+	"slices"
+	"sort"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+)
+
+// Drift statuses
+const (
+	// StatusOK means the pod's branch ENI carries exactly the security groups of its policies
+	StatusOK = "ok"
+	// StatusDrift means the pod is selected by a policy but its ENI security groups differ
+	StatusDrift = "drift"
+	// StatusUnmanaged means the pod has a branch ENI but no policy selects it
+	StatusUnmanaged = "unmanaged"
+	// StatusNone means no policy selects the pod and it uses node-level security groups
+	StatusNone = "none"
+	// StatusUnresolved means the ENI of the pod was not found, such as for a pending pod, so its
+	// security groups cannot be compared
+	StatusUnresolved = "unresolved"
+)
+
+// Result compares the expected and actual security groups of a pod
+type Result struct {
+	Info     aws.PodSecurityGroupInfo
+	Policies []string
+	Expected []string
+	Actual   []string
+	Status   string
+}
+
+// MultiplePolicies reports whether more than one policy selects the pod
+func (r Result) MultiplePolicies() bool {
+	return len(r.Policies) > 1
+}
+
+// Matches reports whether the policy selects the pod. When both selectors are set the pod must
+// match both; the service account labels are only consulted for the service account selector.
+func Matches(p kubernetes.SecurityGroupPolicy, pod *corev1.Pod, serviceAccount *corev1.ServiceAccount) bool {
+	if p.Namespace != pod.Namespace {
+		return false
+	}
+	if p.Spec.PodSelector == nil && p.Spec.ServiceAccountSelector == nil {
+		return false
+	}
+	if p.Spec.PodSelector != nil && !selectorMatches(p.Spec.PodSelector, pod.Labels) {
+		return false
+	}
+	if p.Spec.ServiceAccountSelector != nil {
+		if serviceAccount == nil || !selectorMatches(p.Spec.ServiceAccountSelector, serviceAccount.Labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectorMatches reports whether the label selector matches the labels; invalid selectors match nothing
+func selectorMatches(selector *metav1.LabelSelector, set map[string]string) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(labels.Set(set))
+}
+
+// Evaluate compares each pod's ENI security groups against the union of the security groups of
+// the policies selecting it
+func Evaluate(infos []aws.PodSecurityGroupInfo, policies []kubernetes.SecurityGroupPolicy, serviceAccounts []corev1.ServiceAccount) []Result {
+	accounts := make(map[string]*corev1.ServiceAccount, len(serviceAccounts))
+	for i := range serviceAccounts {
+		sa := &serviceAccounts[i]
+		accounts[sa.Namespace+"/"+sa.Name] = sa
+	}
+
+	results := make([]Result, 0, len(infos))
+	for _, info := range infos {
+		saName := info.Pod.Spec.ServiceAccountName
+		if saName == "" {
+			saName = "default"
+		}
+		sa := accounts[info.Pod.Namespace+"/"+saName]
+
+		result := Result{Info: info}
+		for _, p := range policies {
+			if !Matches(p, &info.Pod, sa) {
+				continue
+			}
+			result.Policies = append(result.Policies, p.Name)
+			for _, id := range p.Spec.SecurityGroups.Groups {
+				if !slices.Contains(result.Expected, id) {
+					result.Expected = append(result.Expected, id)
+				}
+			}
+		}
+		sort.Strings(result.Policies)
+		sort.Strings(result.Expected)
+
+		for _, sg := range info.SecurityGroups {
+			result.Actual = append(result.Actual, awsSDK.ToString(sg.GroupId))
+		}
+		sort.Strings(result.Actual)
+
+		result.Status = status(result)
+		results = append(results, result)
+	}
+	return results
+}
+
+// status determines the drift status of a result
+func status(r Result) string {
+	switch {
+	case !r.Info.Mapped():
+		return StatusUnresolved
+	case len(r.Policies) == 0 && r.Info.AttachmentLevel == "pod":
+		return StatusUnmanaged
+	case len(r.Policies) == 0:
+		return StatusNone
+	case r.Info.AttachmentLevel != "pod" || !slices.Equal(r.Expected, r.Actual):
+		return StatusDrift
+	default:
+		return StatusOK
+	}
+}
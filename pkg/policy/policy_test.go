@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/kubernetes"
+)
+
+func newPolicy(name string, podLabels, saLabels map[string]string, groups ...string) kubernetes.SecurityGroupPolicy {
+	p := kubernetes.SecurityGroupPolicy{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	if podLabels != nil {
+		p.Spec.PodSelector = &metav1.LabelSelector{MatchLabels: podLabels}
+	}
+	if saLabels != nil {
+		p.Spec.ServiceAccountSelector = &metav1.LabelSelector{MatchLabels: saLabels}
+	}
+	p.Spec.SecurityGroups.Groups = groups
+	return p
+}
+
+func newInfo(name string, podLabels map[string]string, serviceAccount, attachment string, groups ...string) aws.PodSecurityGroupInfo {
+	info := aws.PodSecurityGroupInfo{
+		Pod: corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: podLabels},
+			Spec:       corev1.PodSpec{ServiceAccountName: serviceAccount},
+		},
+		AttachmentLevel: attachment,
+	}
+	if attachment != "" {
+		info.ENI = "eni-" + name
+	}
+	for _, g := range groups {
+		info.SecurityGroups = append(info.SecurityGroups, types.SecurityGroup{GroupId: awsSDK.String(g)})
+	}
+	return info
+}
+
+func TestMatches(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Labels: map[string]string{"app": "web"}}}
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "payments"}}}
+
+	testCases := []struct {
+		name     string
+		policy   kubernetes.SecurityGroupPolicy
+		expected bool
+	}{
+		{"pod selector", newPolicy("p", map[string]string{"app": "web"}, nil), true},
+		{"pod selector mismatch", newPolicy("p", map[string]string{"app": "db"}, nil), false},
+		{"service account selector", newPolicy("p", nil, map[string]string{"team": "payments"}), true},
+		{"both selectors must match", newPolicy("p", map[string]string{"app": "web"}, map[string]string{"team": "search"}), false},
+		{"no selectors", newPolicy("p", nil, nil), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Matches(tc.policy, pod, sa))
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	policies := []kubernetes.SecurityGroupPolicy{
+		newPolicy("web", map[string]string{"app": "web"}, nil, "sg-web"),
+		newPolicy("payments", nil, map[string]string{"team": "payments"}, "sg-payments"),
+	}
+	serviceAccounts := []corev1.ServiceAccount{
+		{ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "default", Labels: map[string]string{"team": "payments"}}},
+	}
+	infos := []aws.PodSecurityGroupInfo{
+		newInfo("ok", map[string]string{"app": "web"}, "", "pod", "sg-web"),
+		newInfo("drift", map[string]string{"app": "web"}, "", "pod", "sg-web", "sg-extra"),
+		newInfo("node", map[string]string{"app": "web"}, "", "node", "sg-node"),
+		newInfo("multiple", map[string]string{"app": "web"}, "payments", "pod", "sg-payments", "sg-web"),
+		newInfo("unmanaged", nil, "", "pod", "sg-x"),
+		newInfo("none", nil, "", "node", "sg-node"),
+		newInfo("pending", map[string]string{"app": "web"}, "", ""),
+	}
+
+	results := Evaluate(infos, policies, serviceAccounts)
+
+	statuses := make(map[string]string)
+	for _, r := range results {
+		statuses[r.Info.Pod.Name] = r.Status
+	}
+	assert.Equal(t, map[string]string{
+		"ok":        StatusOK,
+		"drift":     StatusDrift,
+		"node":      StatusDrift,
+		"multiple":  StatusOK,
+		"unmanaged": StatusUnmanaged,
+		"none":      StatusNone,
+		"pending":   StatusUnresolved,
+	}, statuses)
+
+	assert.True(t, results[3].MultiplePolicies())
+	assert.Equal(t, []string{"payments", "web"}, results[3].Policies)
+	assert.Equal(t, []string{"sg-payments", "sg-web"}, results[3].Expected)
+	assert.False(t, results[0].MultiplePolicies())
+}
@@ -0,0 +1,199 @@
+// Package rules evaluates security group rules against network traffic.
+package rules
+
+import (
+	"net"
+	"slices"
+	"strings"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+// Rule directions
+const (
+	Ingress = "ingress"
+	Egress  = "egress"
+)
+
+// Peer is the remote end of the traffic as seen from the security group being evaluated
+type Peer struct {
+	IP               net.IP
+	SecurityGroupIDs []string
+}
+
+// Match is a single rule peer that permits the traffic
+type Match struct {
+	SecurityGroup types.SecurityGroup
+	Permission    types.IpPermission
+	Peer          string
+	Description   string
+}
+
+// NormalizeProtocol returns the protocol name used in comparisons for an IpProtocol value or user input
+func NormalizeProtocol(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "-1", "", "all":
+		return "all"
+	case "6", "tcp":
+		return "tcp"
+	case "17", "udp":
+		return "udp"
+	case "1", "icmp":
+		return "icmp"
+	case "58", "icmpv6":
+		return "icmpv6"
+	default:
+		return strings.ToLower(protocol)
+	}
+}
+
+// MatchesProtocolPort reports whether the permission covers the protocol and port.
+// For ICMP the port is ignored and any rule for the protocol matches.
+func MatchesProtocolPort(p types.IpPermission, protocol string, port int32) bool {
+	ruleProtocol := NormalizeProtocol(awsSDK.ToString(p.IpProtocol))
+	if ruleProtocol == "all" {
+		return true
+	}
+	if ruleProtocol != NormalizeProtocol(protocol) {
+		return false
+	}
+	if ruleProtocol == "icmp" || ruleProtocol == "icmpv6" {
+		return true
+	}
+	if p.FromPort == nil || p.ToPort == nil || *p.FromPort == -1 {
+		return true
+	}
+	return *p.FromPort <= port && port <= *p.ToPort
+}
+
+// Evaluate returns every rule peer in the security groups that permits traffic in the given direction
+// for the protocol and port to or from the peer. Prefix list peers are matched against the CIDRs of
+// the prefix lists, by ID. An empty result means the traffic is denied.
+func Evaluate(sgs []types.SecurityGroup, direction, protocol string, port int32, peer Peer, prefixLists map[string]aws.PrefixList) []Match {
+	var matches []Match
+	for _, sg := range sgs {
+		permissions := sg.IpPermissions
+		if direction == Egress {
+			permissions = sg.IpPermissionsEgress
+		}
+		for _, p := range permissions {
+			if !MatchesProtocolPort(p, protocol, port) {
+				continue
+			}
+			for _, r := range p.IpRanges {
+				if cidrContains(awsSDK.ToString(r.CidrIp), peer.IP) {
+					matches = append(matches, Match{sg, p, awsSDK.ToString(r.CidrIp), awsSDK.ToString(r.Description)})
+				}
+			}
+			for _, r := range p.Ipv6Ranges {
+				if cidrContains(awsSDK.ToString(r.CidrIpv6), peer.IP) {
+					matches = append(matches, Match{sg, p, awsSDK.ToString(r.CidrIpv6), awsSDK.ToString(r.Description)})
+				}
+			}
+			for _, g := range p.UserIdGroupPairs {
+				if slices.Contains(peer.SecurityGroupIDs, awsSDK.ToString(g.GroupId)) {
+					matches = append(matches, Match{sg, p, awsSDK.ToString(g.GroupId), awsSDK.ToString(g.Description)})
+				}
+			}
+			for _, pl := range p.PrefixListIds {
+				id := awsSDK.ToString(pl.PrefixListId)
+				if slices.ContainsFunc(prefixLists[id].CIDRs, func(cidr string) bool { return cidrContains(cidr, peer.IP) }) {
+					matches = append(matches, Match{sg, p, id, awsSDK.ToString(pl.Description)})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// Permitting returns every rule peer in the security groups that permits traffic in the given direction
+// for the protocol and port, whatever the peer. Managed prefix lists are included as peers.
+func Permitting(sgs []types.SecurityGroup, direction, protocol string, port int32) []Match {
+	var matches []Match
+	for _, sg := range sgs {
+		permissions := sg.IpPermissions
+		if direction == Egress {
+			permissions = sg.IpPermissionsEgress
+		}
+		for _, p := range permissions {
+			if !MatchesProtocolPort(p, protocol, port) {
+				continue
+			}
+			for _, r := range p.IpRanges {
+				matches = append(matches, Match{sg, p, awsSDK.ToString(r.CidrIp), awsSDK.ToString(r.Description)})
+			}
+			for _, r := range p.Ipv6Ranges {
+				matches = append(matches, Match{sg, p, awsSDK.ToString(r.CidrIpv6), awsSDK.ToString(r.Description)})
+			}
+			for _, g := range p.UserIdGroupPairs {
+				matches = append(matches, Match{sg, p, awsSDK.ToString(g.GroupId), awsSDK.ToString(g.Description)})
+			}
+			for _, pl := range p.PrefixListIds {
+				matches = append(matches, Match{sg, p, awsSDK.ToString(pl.PrefixListId), awsSDK.ToString(pl.Description)})
+			}
+		}
+	}
+	return matches
+}
+
+// cidrContains reports whether the CIDR contains the IP
+func cidrContains(cidr string, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	return network.Contains(ip)
+}
+
+// Reachability is the result of evaluating traffic from a source pod to a destination pod
+type Reachability struct {
+	Source      aws.PodSecurityGroupInfo
+	Destination aws.PodSecurityGroupInfo
+	Protocol    string
+	Port        int32
+	Egress      []Match
+	Ingress     []Match
+}
+
+// EgressAllowed reports whether the source's egress rules permit the traffic
+func (r Reachability) EgressAllowed() bool {
+	return len(r.Egress) > 0
+}
+
+// IngressAllowed reports whether the destination's ingress rules permit the traffic
+func (r Reachability) IngressAllowed() bool {
+	return len(r.Ingress) > 0
+}
+
+// Allowed reports whether the traffic is permitted in both directions
+func (r Reachability) Allowed() bool {
+	return r.EgressAllowed() && r.IngressAllowed()
+}
+
+// CheckReachability evaluates the source's egress rules and the destination's ingress rules for the
+// traffic, with the prefix lists referenced by the rules by ID
+func CheckReachability(src, dst aws.PodSecurityGroupInfo, protocol string, port int32, prefixLists map[string]aws.PrefixList) Reachability {
+	return Reachability{
+		Source:      src,
+		Destination: dst,
+		Protocol:    NormalizeProtocol(protocol),
+		Port:        port,
+		Egress:      Evaluate(src.SecurityGroups, Egress, protocol, port, peerOf(dst), prefixLists),
+		Ingress:     Evaluate(dst.SecurityGroups, Ingress, protocol, port, peerOf(src), prefixLists),
+	}
+}
+
+// peerOf returns the pod's IP and security groups as a rule peer
+func peerOf(info aws.PodSecurityGroupInfo) Peer {
+	ids := make([]string, 0, len(info.SecurityGroups))
+	for _, sg := range info.SecurityGroups {
+		ids = append(ids, awsSDK.ToString(sg.GroupId))
+	}
+	return Peer{IP: net.ParseIP(info.Pod.Status.PodIP), SecurityGroupIDs: ids}
+}
@@ -0,0 +1,173 @@
+package rules
+
+import (
+	"net"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+func TestMatchesProtocolPort(t *testing.T) {
+	testCases := []struct {
+		name       string
+		permission types.IpPermission
+		protocol   string
+		port       int32
+		expected   bool
+	}{
+		{"all protocols", types.IpPermission{IpProtocol: awsSDK.String("-1")}, "udp", 53, true},
+		{"port in range", types.IpPermission{IpProtocol: awsSDK.String("tcp"), FromPort: awsSDK.Int32(8000), ToPort: awsSDK.Int32(9000)}, "tcp", 8080, true},
+		{"port out of range", types.IpPermission{IpProtocol: awsSDK.String("tcp"), FromPort: awsSDK.Int32(443), ToPort: awsSDK.Int32(443)}, "tcp", 80, false},
+		{"protocol mismatch", types.IpPermission{IpProtocol: awsSDK.String("udp"), FromPort: awsSDK.Int32(53), ToPort: awsSDK.Int32(53)}, "tcp", 53, false},
+		{"numeric protocol", types.IpPermission{IpProtocol: awsSDK.String("6"), FromPort: awsSDK.Int32(22), ToPort: awsSDK.Int32(22)}, "tcp", 22, true},
+		{"icmp ignores port", types.IpPermission{IpProtocol: awsSDK.String("icmp"), FromPort: awsSDK.Int32(8), ToPort: awsSDK.Int32(-1)}, "icmp", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, MatchesProtocolPort(tc.permission, tc.protocol, tc.port))
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	sgs := []types.SecurityGroup{
+		{
+			GroupId: awsSDK.String("sg-backend"),
+			IpPermissions: []types.IpPermission{
+				{
+					IpProtocol:       awsSDK.String("tcp"),
+					FromPort:         awsSDK.Int32(8080),
+					ToPort:           awsSDK.Int32(8080),
+					UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: awsSDK.String("sg-frontend"), Description: awsSDK.String("from frontend")}},
+				},
+				{
+					IpProtocol: awsSDK.String("tcp"),
+					FromPort:   awsSDK.Int32(8080),
+					ToPort:     awsSDK.Int32(8080),
+					IpRanges:   []types.IpRange{{CidrIp: awsSDK.String("192.168.0.0/16")}},
+				},
+			},
+			IpPermissionsEgress: []types.IpPermission{
+				{IpProtocol: awsSDK.String("-1"), IpRanges: []types.IpRange{{CidrIp: awsSDK.String("0.0.0.0/0")}}},
+			},
+		},
+	}
+
+	t.Run("ingress allowed by security group reference", func(t *testing.T) {
+		matches := Evaluate(sgs, Ingress, "tcp", 8080, Peer{IP: net.ParseIP("10.0.0.1"), SecurityGroupIDs: []string{"sg-frontend"}}, nil)
+		assert.Len(t, matches, 1)
+		assert.Equal(t, "sg-frontend", matches[0].Peer)
+		assert.Equal(t, "from frontend", matches[0].Description)
+	})
+
+	t.Run("ingress allowed by cidr", func(t *testing.T) {
+		matches := Evaluate(sgs, Ingress, "tcp", 8080, Peer{IP: net.ParseIP("192.168.1.1")}, nil)
+		assert.Len(t, matches, 1)
+		assert.Equal(t, "192.168.0.0/16", matches[0].Peer)
+	})
+
+	t.Run("ingress denied", func(t *testing.T) {
+		matches := Evaluate(sgs, Ingress, "tcp", 443, Peer{IP: net.ParseIP("192.168.1.1"), SecurityGroupIDs: []string{"sg-frontend"}}, nil)
+		assert.Empty(t, matches)
+	})
+
+	t.Run("ingress allowed by prefix list", func(t *testing.T) {
+		withPrefixList := []types.SecurityGroup{{
+			GroupId: awsSDK.String("sg-backend"),
+			IpPermissions: []types.IpPermission{{
+				IpProtocol:    awsSDK.String("tcp"),
+				FromPort:      awsSDK.Int32(8080),
+				ToPort:        awsSDK.Int32(8080),
+				PrefixListIds: []types.PrefixListId{{PrefixListId: awsSDK.String("pl-office"), Description: awsSDK.String("from office")}},
+			}},
+		}}
+		prefixLists := map[string]aws.PrefixList{"pl-office": {ID: "pl-office", CIDRs: []string{"192.0.2.0/24", "198.51.100.0/24"}}}
+
+		matches := Evaluate(withPrefixList, Ingress, "tcp", 8080, Peer{IP: net.ParseIP("198.51.100.7")}, prefixLists)
+		assert.Len(t, matches, 1)
+		assert.Equal(t, "pl-office", matches[0].Peer)
+		assert.Equal(t, "from office", matches[0].Description)
+
+		assert.Empty(t, Evaluate(withPrefixList, Ingress, "tcp", 8080, Peer{IP: net.ParseIP("10.0.0.1")}, prefixLists))
+		assert.Empty(t, Evaluate(withPrefixList, Ingress, "tcp", 8080, Peer{IP: net.ParseIP("198.51.100.7")}, nil))
+	})
+
+	t.Run("egress allowed", func(t *testing.T) {
+		matches := Evaluate(sgs, Egress, "udp", 53, Peer{IP: net.ParseIP("10.0.0.2")}, nil)
+		assert.Len(t, matches, 1)
+	})
+}
+
+func TestPermitting(t *testing.T) {
+	sgs := []types.SecurityGroup{
+		{
+			GroupId: awsSDK.String("sg-web"),
+			IpPermissions: []types.IpPermission{
+				{
+					IpProtocol:       awsSDK.String("tcp"),
+					FromPort:         awsSDK.Int32(8080),
+					ToPort:           awsSDK.Int32(8080),
+					UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: awsSDK.String("sg-lb")}},
+					PrefixListIds:    []types.PrefixListId{{PrefixListId: awsSDK.String("pl-1")}},
+				},
+				{
+					IpProtocol: awsSDK.String("udp"),
+					FromPort:   awsSDK.Int32(8080),
+					ToPort:     awsSDK.Int32(8080),
+					IpRanges:   []types.IpRange{{CidrIp: awsSDK.String("10.0.0.0/8")}},
+				},
+			},
+		},
+	}
+
+	matches := Permitting(sgs, Ingress, "TCP", 8080)
+	assert.Len(t, matches, 2)
+	assert.Equal(t, "sg-lb", matches[0].Peer)
+	assert.Equal(t, "pl-1", matches[1].Peer)
+
+	assert.Empty(t, Permitting(sgs, Ingress, "tcp", 9090))
+	assert.Empty(t, Permitting(sgs, Egress, "tcp", 8080))
+}
+
+func TestCheckReachability(t *testing.T) {
+	src := aws.PodSecurityGroupInfo{
+		Pod: corev1.Pod{Status: corev1.PodStatus{PodIP: "10.0.1.5"}},
+		SecurityGroups: []types.SecurityGroup{
+			{
+				GroupId: awsSDK.String("sg-frontend"),
+				IpPermissionsEgress: []types.IpPermission{
+					{IpProtocol: awsSDK.String("tcp"), FromPort: awsSDK.Int32(8080), ToPort: awsSDK.Int32(8080), IpRanges: []types.IpRange{{CidrIp: awsSDK.String("10.0.2.0/24")}}},
+				},
+			},
+		},
+	}
+	dst := aws.PodSecurityGroupInfo{
+		Pod: corev1.Pod{Status: corev1.PodStatus{PodIP: "10.0.2.7"}},
+		SecurityGroups: []types.SecurityGroup{
+			{
+				GroupId: awsSDK.String("sg-backend"),
+				IpPermissions: []types.IpPermission{
+					{IpProtocol: awsSDK.String("tcp"), FromPort: awsSDK.Int32(8080), ToPort: awsSDK.Int32(8080), UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: awsSDK.String("sg-frontend")}}},
+				},
+			},
+		},
+	}
+
+	allowed := CheckReachability(src, dst, "tcp", 8080, nil)
+	assert.True(t, allowed.Allowed())
+
+	denied := CheckReachability(src, dst, "tcp", 9090, nil)
+	assert.False(t, denied.EgressAllowed())
+	assert.False(t, denied.IngressAllowed())
+	assert.False(t, denied.Allowed())
+
+	reverse := CheckReachability(dst, src, "tcp", 8080, nil)
+	assert.False(t, reverse.EgressAllowed())
+	assert.False(t, reverse.Allowed())
+}
@@ -0,0 +1,234 @@
+// Package service resolves the endpoints of Kubernetes Services to pods and checks the ingress rules of
+// their security groups against the target ports of the Services.
+package service
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+	"github.com/naka-gawa/kubectl-sgmap/pkg/rules"
+)
+
+// Port is a target port of a Service, as resolved in its EndpointSlices
+type Port struct {
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+	Protocol string `json:"protocol" yaml:"protocol"`
+	Port     int32  `json:"port" yaml:"port"`
+}
+
+// Endpoint is a ready endpoint of a Service with the pod it resolves to and the target ports it serves
+type Endpoint struct {
+	Pod   corev1.Pod
+	Ports []Port
+}
+
+// PortCheck lists the ingress rules of a security group set that permit a target port
+type PortCheck struct {
+	Port  Port
+	Rules []rules.Match
+}
+
+// Allowed reports whether some ingress rule permits the target port
+func (c PortCheck) Allowed() bool {
+	return len(c.Rules) > 0
+}
+
+// SecurityGroupSet is a distinct combination of security groups shared by one or more endpoints
+type SecurityGroupSet struct {
+	SecurityGroups  []types.SecurityGroup
+	AttachmentLevel string
+	Endpoints       []string
+	Ports           []PortCheck
+}
+
+// GroupIDs returns the sorted security group IDs of the set
+func (s SecurityGroupSet) GroupIDs() []string {
+	ids := make([]string, 0, len(s.SecurityGroups))
+	for _, sg := range s.SecurityGroups {
+		ids = append(ids, awsSDK.ToString(sg.GroupId))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Blocked returns the target ports no ingress rule of the set permits
+func (s SecurityGroupSet) Blocked() []Port {
+	var blocked []Port
+	for _, c := range s.Ports {
+		if !c.Allowed() {
+			blocked = append(blocked, c.Port)
+		}
+	}
+	return blocked
+}
+
+// SecurityGroupInfo represents the security groups behind the ready endpoints of a Service
+type SecurityGroupInfo struct {
+	Service           corev1.Service
+	SecurityGroupSets []SecurityGroupSet
+	// Unresolved are the endpoints whose ENI or security groups could not be found
+	Unresolved []aws.PodSecurityGroupInfo
+}
+
+// Endpoints returns the number of ready endpoints of the Service
+func (i SecurityGroupInfo) Endpoints() int {
+	n := len(i.Unresolved)
+	for _, set := range i.SecurityGroupSets {
+		n += len(set.Endpoints)
+	}
+	return n
+}
+
+// Consistent reports whether all endpoints of the Service carry the same security groups
+func (i SecurityGroupInfo) Consistent() bool {
+	return len(i.SecurityGroupSets) <= 1
+}
+
+// Blocked reports whether a target port is permitted by no ingress rule for some endpoints
+func (i SecurityGroupInfo) Blocked() bool {
+	for _, set := range i.SecurityGroupSets {
+		if len(set.Blocked()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadyEndpoints returns the ready endpoints in the EndpointSlices of the Service. Endpoints
+// referencing a known pod resolve to that pod; others resolve to a running pod named after the
+// target reference or the IP, so that they can still be looked up by IP.
+func ReadyEndpoints(svc corev1.Service, endpointSlices []discoveryv1.EndpointSlice, pods []corev1.Pod) []Endpoint {
+	podsByName := make(map[string]corev1.Pod, len(pods))
+	for _, pod := range pods {
+		podsByName[pod.Namespace+"/"+pod.Name] = pod
+	}
+
+	var endpoints []Endpoint
+	index := make(map[string]int)
+	for _, slice := range endpointSlices {
+		if slice.Namespace != svc.Namespace || slice.Labels[discoveryv1.LabelServiceName] != svc.Name {
+			continue
+		}
+		if slice.AddressType == discoveryv1.AddressTypeFQDN {
+			continue
+		}
+		ports := slicePorts(slice)
+		for _, ep := range slice.Endpoints {
+			if len(ep.Addresses) == 0 || (ep.Conditions.Ready != nil && !*ep.Conditions.Ready) {
+				continue
+			}
+			pod := endpointPod(svc.Namespace, ep, podsByName)
+			key := pod.Namespace + "/" + pod.Name
+			if i, ok := index[key]; ok {
+				endpoints[i].Ports = mergePorts(endpoints[i].Ports, ports)
+				continue
+			}
+			index[key] = len(endpoints)
+			endpoints = append(endpoints, Endpoint{Pod: pod, Ports: slices.Clone(ports)})
+		}
+	}
+	return endpoints
+}
+
+// endpointPod returns the pod an endpoint resolves to
+func endpointPod(namespace string, ep discoveryv1.Endpoint, podsByName map[string]corev1.Pod) corev1.Pod {
+	name := ep.Addresses[0]
+	if ref := ep.TargetRef; ref != nil && ref.Kind == "Pod" {
+		if ref.Namespace != "" {
+			namespace = ref.Namespace
+		}
+		if pod, ok := podsByName[namespace+"/"+ref.Name]; ok {
+			return pod
+		}
+		name = ref.Name
+	}
+	pod := corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ep.Addresses[0]}}
+	pod.Name, pod.Namespace = name, namespace
+	if ep.NodeName != nil {
+		pod.Spec.NodeName = *ep.NodeName
+	}
+	return pod
+}
+
+// slicePorts returns the target ports of an EndpointSlice
+func slicePorts(slice discoveryv1.EndpointSlice) []Port {
+	var ports []Port
+	for _, p := range slice.Ports {
+		if p.Port == nil {
+			continue
+		}
+		port := Port{Protocol: string(corev1.ProtocolTCP), Port: *p.Port}
+		if p.Name != nil {
+			port.Name = *p.Name
+		}
+		if p.Protocol != nil {
+			port.Protocol = string(*p.Protocol)
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// mergePorts appends the ports missing from a to it
+func mergePorts(a, b []Port) []Port {
+	for _, p := range b {
+		if !slices.Contains(a, p) {
+			a = append(a, p)
+		}
+	}
+	return a
+}
+
+// Group groups the security group information of the endpoints of a Service by security group set and
+// checks the ingress rules of each set against the target ports its endpoints serve. infos holds the
+// security group information of the endpoint pods, keyed by namespace/name. Sets are ordered by
+// endpoint count, largest first.
+func Group(svc corev1.Service, endpoints []Endpoint, infos map[string]aws.PodSecurityGroupInfo) SecurityGroupInfo {
+	result := SecurityGroupInfo{Service: svc}
+	var ports [][]Port
+	for _, ep := range endpoints {
+		info, ok := infos[ep.Pod.Namespace+"/"+ep.Pod.Name]
+		if !ok {
+			continue
+		}
+		if !info.Mapped() {
+			result.Unresolved = append(result.Unresolved, info)
+			continue
+		}
+
+		candidate := SecurityGroupSet{SecurityGroups: info.SecurityGroups, AttachmentLevel: info.AttachmentLevel}
+		key := strings.Join(candidate.GroupIDs(), ",")
+		i := slices.IndexFunc(result.SecurityGroupSets, func(set SecurityGroupSet) bool {
+			return strings.Join(set.GroupIDs(), ",") == key
+		})
+		if i < 0 {
+			i = len(result.SecurityGroupSets)
+			result.SecurityGroupSets = append(result.SecurityGroupSets, candidate)
+			ports = append(ports, nil)
+		}
+		result.SecurityGroupSets[i].Endpoints = append(result.SecurityGroupSets[i].Endpoints, ep.Pod.Name)
+		ports[i] = mergePorts(ports[i], ep.Ports)
+	}
+
+	for i := range result.SecurityGroupSets {
+		set := &result.SecurityGroupSets[i]
+		sort.Strings(set.Endpoints)
+		for _, port := range ports[i] {
+			set.Ports = append(set.Ports, PortCheck{
+				Port:  port,
+				Rules: rules.Permitting(set.SecurityGroups, rules.Ingress, port.Protocol, port.Port),
+			})
+		}
+	}
+	sort.SliceStable(result.SecurityGroupSets, func(i, j int) bool {
+		return len(result.SecurityGroupSets[i].Endpoints) > len(result.SecurityGroupSets[j].Endpoints)
+	})
+	return result
+}
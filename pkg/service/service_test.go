@@ -0,0 +1,128 @@
+package service
+
+import (
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+func newService(name string) corev1.Service {
+	return corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+}
+
+func newSlice(service string, ports []Port, endpoints ...discoveryv1.Endpoint) discoveryv1.EndpointSlice {
+	slice := discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      service + "-abcde",
+			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints:   endpoints,
+	}
+	for _, p := range ports {
+		protocol := corev1.Protocol(p.Protocol)
+		slice.Ports = append(slice.Ports, discoveryv1.EndpointPort{Name: awsSDK.String(p.Name), Protocol: &protocol, Port: awsSDK.Int32(p.Port)})
+	}
+	return slice
+}
+
+func podEndpoint(ip, pod string, ready bool) discoveryv1.Endpoint {
+	ep := discoveryv1.Endpoint{Addresses: []string{ip}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}}
+	if pod != "" {
+		ep.TargetRef = &corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: pod}
+	}
+	return ep
+}
+
+func TestReadyEndpoints(t *testing.T) {
+	http := Port{Name: "http", Protocol: "TCP", Port: 8080}
+	metrics := Port{Name: "metrics", Protocol: "TCP", Port: 9090}
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"}, Spec: corev1.PodSpec{HostNetwork: true}},
+	}
+	slices := []discoveryv1.EndpointSlice{
+		newSlice("web", []Port{http},
+			podEndpoint("10.0.0.1", "web-1", true),
+			podEndpoint("10.0.0.2", "web-2", true),
+			podEndpoint("10.0.0.3", "web-3", false),
+		),
+		newSlice("web", []Port{metrics}, podEndpoint("10.0.0.1", "web-1", true)),
+		newSlice("web", nil, podEndpoint("192.168.0.1", "", true)),
+		newSlice("db", []Port{http}, podEndpoint("10.0.0.9", "db-0", true)),
+	}
+
+	endpoints := ReadyEndpoints(newService("web"), slices, pods)
+	require.Len(t, endpoints, 3)
+
+	assert.True(t, endpoints[0].Pod.Spec.HostNetwork, "known pods resolve to the listed pod")
+	assert.Equal(t, []Port{http, metrics}, endpoints[0].Ports)
+
+	assert.Equal(t, "web-2", endpoints[1].Pod.Name)
+	assert.Equal(t, "10.0.0.2", endpoints[1].Pod.Status.PodIP)
+	assert.Equal(t, corev1.PodRunning, endpoints[1].Pod.Status.Phase)
+	assert.Equal(t, []Port{http}, endpoints[1].Ports)
+
+	assert.Equal(t, "192.168.0.1", endpoints[2].Pod.Name)
+	assert.Empty(t, endpoints[2].Ports)
+}
+
+func TestGroup(t *testing.T) {
+	web := types.SecurityGroup{
+		GroupId: awsSDK.String("sg-web"),
+		IpPermissions: []types.IpPermission{
+			{
+				IpProtocol: awsSDK.String("tcp"),
+				FromPort:   awsSDK.Int32(8080),
+				ToPort:     awsSDK.Int32(8080),
+				IpRanges:   []types.IpRange{{CidrIp: awsSDK.String("10.0.0.0/8")}},
+			},
+		},
+	}
+	node := types.SecurityGroup{GroupId: awsSDK.String("sg-node")}
+	http := Port{Protocol: "TCP", Port: 8080}
+
+	pod := func(name string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	}
+	endpoints := []Endpoint{
+		{Pod: pod("web-1"), Ports: []Port{http}},
+		{Pod: pod("web-2"), Ports: []Port{http}},
+		{Pod: pod("web-3"), Ports: []Port{http}},
+		{Pod: pod("web-4"), Ports: []Port{http}},
+	}
+	infos := map[string]aws.PodSecurityGroupInfo{
+		"default/web-1": {Pod: pod("web-1"), ENI: "eni-1", AttachmentLevel: "pod", SecurityGroups: []types.SecurityGroup{web}},
+		"default/web-2": {Pod: pod("web-2"), ENI: "eni-2", AttachmentLevel: "node", SecurityGroups: []types.SecurityGroup{node}},
+		"default/web-3": {Pod: pod("web-3"), ENI: "eni-3", AttachmentLevel: "pod", SecurityGroups: []types.SecurityGroup{web}},
+		"default/web-4": {Pod: pod("web-4"), Status: aws.StatusENINotFound},
+	}
+
+	info := Group(newService("web"), endpoints, infos)
+
+	assert.Equal(t, 4, info.Endpoints())
+	assert.False(t, info.Consistent())
+	assert.True(t, info.Blocked())
+	require.Len(t, info.Unresolved, 1)
+	assert.Equal(t, "web-4", info.Unresolved[0].Pod.Name)
+
+	require.Len(t, info.SecurityGroupSets, 2)
+	first := info.SecurityGroupSets[0]
+	assert.Equal(t, []string{"web-1", "web-3"}, first.Endpoints)
+	require.Len(t, first.Ports, 1)
+	assert.True(t, first.Ports[0].Allowed())
+	assert.Equal(t, "10.0.0.0/8", first.Ports[0].Rules[0].Peer)
+	assert.Empty(t, first.Blocked())
+
+	second := info.SecurityGroupSets[1]
+	assert.Equal(t, []string{"web-2"}, second.Endpoints)
+	assert.Equal(t, []Port{http}, second.Blocked())
+}
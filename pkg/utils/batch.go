@@ -2,49 +2,126 @@ package utils
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"maps"
+	"math/rand/v2"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Default backoff bounds used when BatchOptions retries a batch without explicit delays
+const (
+	DefaultBaseDelay = 200 * time.Millisecond
+	DefaultMaxDelay  = 10 * time.Second
 )
 
 // ProcessBatchFunc defines the function signature for processing a batch
 // T is input item type, R is output result type (map key: string)
 type ProcessBatchFunc[T any, R any] func(context.Context, []T) (map[string]R, error)
 
+// BatchOptions configures how RunBatchParallel splits, schedules and retries batches
+type BatchOptions struct {
+	// BatchSize is the maximum number of items per batch
+	BatchSize int
+	// MaxParallel is the maximum number of batches processed at the same time
+	MaxParallel int
+	// RateLimit is the maximum number of batch attempts started per second across all workers,
+	// 0 for no limit
+	RateLimit float64
+	// MaxRetries is how many times a batch failing with a retryable error is attempted again
+	MaxRetries int
+	// BaseDelay and MaxDelay bound the exponential backoff between attempts, which is jittered
+	// over the whole interval. DefaultBaseDelay and DefaultMaxDelay are used when zero.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Timeout bounds each attempt of a batch, 0 for no timeout. An attempt that times out is retried.
+	Timeout time.Duration
+	// Retryable reports whether a failed attempt may succeed when retried. No error is retried when nil.
+	Retryable func(error) bool
+	// Stats counts batches, retries and failures when set
+	Stats *BatchStats
+	// Partial keeps processing the other batches when a batch fails, and returns the results of the
+	// successful batches together with a *BatchError describing the failed ones
+	Partial bool
+}
+
+// BatchStats counts the work of RunBatchParallel. It may be shared by several runs.
+type BatchStats struct {
+	Batches  atomic.Int64
+	Retries  atomic.Int64
+	Failures atomic.Int64
+}
+
+// BatchFailure is a batch that failed after its retries, with the error of its last attempt
+type BatchFailure[T any] struct {
+	Items []T
+	Err   error
+}
+
+// BatchError is returned in partial mode when some batches failed
+type BatchError[T any] struct {
+	Failures []BatchFailure[T]
+	// Total is the number of batches, including the successful ones
+	Total int
+}
+
+func (e *BatchError[T]) Error() string {
+	return fmt.Sprintf("%d of %d batches failed: %v", len(e.Failures), e.Total, e.Failures[0].Err)
+}
+
+// Unwrap returns the errors of the failed batches
+func (e *BatchError[T]) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		errs = append(errs, f.Err)
+	}
+	return errs
+}
+
 // batchResult represents the result of a single batch
-type batchResult[R any] struct {
-	data map[string]R
-	err  error
+type batchResult[T any, R any] struct {
+	items []T
+	data  map[string]R
+	err   error
 }
 
 // RunBatchParallel splits items into batches and processes them concurrently.
 //
 // Arguments:
 //
-//	ctx         - Context for cancellation
-//	items       - Input items
-//	batchSize   - Size of each batch
-//	maxParallel - Maximum number of parallel workers
-//	process     - Processing function
+//	ctx     - Context for cancellation
+//	items   - Input items
+//	opts    - Batch size, parallelism, rate limit, retries and timeout
+//	process - Processing function
 //
 // Returns:
 //
 //	A map with string keys representing merged batch results.
-//	Returns an error if any batch fails.
+//	Returns an error if any batch fails after its retries. In partial mode the results of the
+//	successful batches are returned along with a *BatchError[T].
 func RunBatchParallel[T any, R any](
 	ctx context.Context,
 	items []T,
-	batchSize int,
-	maxParallel int,
+	opts BatchOptions,
 	process ProcessBatchFunc[T, R],
 ) (map[string]R, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	batches := chunkSlice(items, batchSize)
-	resultCh := make(chan batchResult[R], len(batches))
+	batches := chunkSlice(items, max(opts.BatchSize, 1))
+	resultCh := make(chan batchResult[T, R], len(batches))
+
+	var limiter *rate.Limiter
+	if opts.RateLimit > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.RateLimit), 1)
+	}
 
 	var wg sync.WaitGroup
-	sem := make(chan struct{}, maxParallel)
+	sem := make(chan struct{}, max(opts.MaxParallel, 1))
 
 	for _, batch := range batches {
 		wg.Add(1)
@@ -55,7 +132,7 @@ func RunBatchParallel[T any, R any](
 				wg.Done()
 				<-sem
 			}()
-			runBatch(ctx, b, process, resultCh, cancel)
+			runBatch(ctx, b, opts, limiter, process, resultCh, cancel)
 		}(batch)
 	}
 
@@ -64,49 +141,140 @@ func RunBatchParallel[T any, R any](
 		close(resultCh)
 	}()
 
-	return collectResults(resultCh)
+	return collectResults(resultCh, opts.Partial)
 }
 
-// runBatch processes a single batch and sends the result to the channel
+// runBatch processes a single batch, retrying retryable failures, and sends the result to the channel
 //
 // Arguments:
 //
 //	ctx: Context for cancellation
 //	batch: Batch of items
+//	opts: Retry, timeout and statistics options
+//	limiter: Rate limiter shared by all batches, nil for no limit
 //	process: Processing function
 //	resultCh: Channel for results
-//	cancel: Cancellation function
+//	cancel: Cancellation function, called when the batch fails unless in partial mode
 func runBatch[T any, R any](
 	ctx context.Context,
 	batch []T,
+	opts BatchOptions,
+	limiter *rate.Limiter,
 	process ProcessBatchFunc[T, R],
-	resultCh chan<- batchResult[R],
+	resultCh chan<- batchResult[T, R],
 	cancel context.CancelFunc,
 ) {
-	res, err := process(ctx, batch)
+	if opts.Stats != nil {
+		opts.Stats.Batches.Add(1)
+	}
+
+	var res map[string]R
+	var err error
+	for attempt := 0; ; attempt++ {
+		res, err = runAttempt(ctx, batch, opts.Timeout, limiter, process)
+		if err == nil || attempt >= opts.MaxRetries || !retryable(ctx, err, opts.Retryable) {
+			break
+		}
+		if opts.Stats != nil {
+			opts.Stats.Retries.Add(1)
+		}
+		if sleepErr := sleep(ctx, backoff(attempt, opts.BaseDelay, opts.MaxDelay)); sleepErr != nil {
+			err = sleepErr
+			break
+		}
+	}
+
 	if err != nil {
-		cancel()
+		if opts.Stats != nil {
+			opts.Stats.Failures.Add(1)
+		}
+		if !opts.Partial {
+			cancel()
+		}
+	}
+	resultCh <- batchResult[T, R]{items: batch, data: res, err: err}
+}
+
+// runAttempt waits for the rate limiter and processes the batch once within the timeout
+func runAttempt[T any, R any](ctx context.Context, batch []T, timeout time.Duration, limiter *rate.Limiter, process ProcessBatchFunc[T, R]) (map[string]R, error) {
+	if limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return process(ctx, batch)
+}
+
+// retryable reports whether a failed attempt should be retried. Attempts that ran out of their own
+// timeout are retried, while a cancelled or expired parent context stops the retries.
+func retryable(ctx context.Context, err error, isRetryable func(error) bool) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	return isRetryable != nil && isRetryable(err)
+}
+
+// backoff returns a random delay up to the exponential backoff of the attempt, capped by maxDelay
+func backoff(attempt int, baseDelay, maxDelay time.Duration) time.Duration {
+	if baseDelay <= 0 {
+		baseDelay = DefaultBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxDelay
+	}
+	delay := maxDelay
+	if attempt < 32 && baseDelay<<attempt < maxDelay && baseDelay<<attempt > 0 {
+		delay = baseDelay << attempt
+	}
+	return rand.N(delay) + 1
+}
+
+// sleep waits for the delay or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
-	resultCh <- batchResult[R]{data: res, err: err}
 }
 
-// collectResults collects results from the channel and merges them into a single map
-func collectResults[R any](results <-chan batchResult[R]) (map[string]R, error) {
+// collectResults collects results from the channel and merges them into a single map.
+// In partial mode the results of the successful batches are kept and the failures are aggregated.
+func collectResults[T any, R any](results <-chan batchResult[T, R], partial bool) (map[string]R, error) {
 	final := make(map[string]R)
 	var firstErr error
+	batchErr := &BatchError[T]{}
 
 	for res := range results {
-		if res.err != nil && firstErr == nil {
-			firstErr = res.err
+		batchErr.Total++
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			batchErr.Failures = append(batchErr.Failures, BatchFailure[T]{Items: res.items, Err: res.err})
 			continue
 		}
 		maps.Copy(final, res.data)
 	}
 
-	if firstErr != nil {
-		return nil, firstErr
+	switch {
+	case firstErr == nil:
+		return final, nil
+	case partial:
+		return final, batchErr
 	}
-	return final, nil
+	return nil, firstErr
 }
 
 // chunkSlice splits a slice into chunks of the given size
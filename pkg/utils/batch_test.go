@@ -5,7 +5,9 @@ import (
 	"errors"
 	"reflect"
 	"sort"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestRunBatchParallel(t *testing.T) {
@@ -52,7 +54,7 @@ func TestRunBatchParallel(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx := context.Background()
-			got, err := RunBatchParallel(ctx, tt.items, tt.batchSize, tt.maxParallel, tt.process)
+			got, err := RunBatchParallel(ctx, tt.items, BatchOptions{BatchSize: tt.batchSize, MaxParallel: tt.maxParallel}, tt.process)
 
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
@@ -87,3 +89,167 @@ func makeProcessWithErrorIP(errorIp string) ProcessBatchFunc[string, string] {
 		return res, nil
 	}
 }
+
+var errThrottled = errors.New("throttled")
+
+func isThrottled(err error) bool {
+	return errors.Is(err, errThrottled)
+}
+
+func TestRunBatchParallel_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32
+		err          error
+		maxRetries   int
+		wantErr      bool
+		wantCalls    int32
+		wantRetries  int64
+		wantFailures int64
+	}{
+		{name: "retryable error succeeds after retries", failures: 2, err: errThrottled, maxRetries: 3, wantCalls: 3, wantRetries: 2},
+		{name: "retries exhausted", failures: 5, err: errThrottled, maxRetries: 2, wantErr: true, wantCalls: 3, wantRetries: 2, wantFailures: 1},
+		{name: "other errors are not retried", failures: 1, err: errors.New("access denied"), maxRetries: 3, wantErr: true, wantCalls: 1, wantFailures: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			process := func(ctx context.Context, batch []string) (map[string]string, error) {
+				if calls.Add(1) <= tt.failures {
+					return nil, tt.err
+				}
+				return map[string]string{batch[0]: "ok"}, nil
+			}
+
+			stats := &BatchStats{}
+			opts := BatchOptions{
+				BatchSize:   10,
+				MaxParallel: 1,
+				MaxRetries:  tt.maxRetries,
+				BaseDelay:   time.Millisecond,
+				MaxDelay:    time.Millisecond,
+				Retryable:   isThrottled,
+				Stats:       stats,
+			}
+			got, err := RunBatchParallel(context.Background(), []string{"a"}, opts, process)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && got["a"] != "ok" {
+				t.Errorf("expected result for a, got %v", got)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, calls.Load())
+			}
+			if stats.Batches.Load() != 1 || stats.Retries.Load() != tt.wantRetries || stats.Failures.Load() != tt.wantFailures {
+				t.Errorf("unexpected stats: batches=%d retries=%d failures=%d", stats.Batches.Load(), stats.Retries.Load(), stats.Failures.Load())
+			}
+		})
+	}
+}
+
+func TestRunBatchParallel_Timeout(t *testing.T) {
+	var calls atomic.Int32
+	process := func(ctx context.Context, batch []string) (map[string]string, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return map[string]string{batch[0]: "ok"}, nil
+	}
+
+	opts := BatchOptions{
+		BatchSize:   1,
+		MaxParallel: 1,
+		MaxRetries:  1,
+		BaseDelay:   time.Millisecond,
+		Timeout:     10 * time.Millisecond,
+	}
+	got, err := RunBatchParallel(context.Background(), []string{"a"}, opts, process)
+	if err != nil {
+		t.Fatalf("expected the timed out batch to be retried, got: %v", err)
+	}
+	if got["a"] != "ok" || calls.Load() != 2 {
+		t.Errorf("expected 2 calls and a result, got %d calls and %v", calls.Load(), got)
+	}
+}
+
+func TestRunBatchParallel_CanceledContextStopsRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	process := func(ctx context.Context, batch []string) (map[string]string, error) {
+		calls.Add(1)
+		cancel()
+		return nil, errThrottled
+	}
+
+	opts := BatchOptions{BatchSize: 1, MaxParallel: 1, MaxRetries: 5, Retryable: isThrottled}
+	if _, err := RunBatchParallel(ctx, []string{"a"}, opts, process); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected no retries after cancellation, got %d calls", calls.Load())
+	}
+}
+
+func TestRunBatchParallel_RateLimit(t *testing.T) {
+	items := []string{"a", "b", "c", "d"}
+	opts := BatchOptions{BatchSize: 1, MaxParallel: 4, RateLimit: 50}
+
+	start := time.Now()
+	got, err := RunBatchParallel(context.Background(), items, opts, makeProcessWithErrorIP(""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != len(items) {
+		t.Errorf("expected %d results, got %d", len(items), len(got))
+	}
+	// The first batch starts at once and the others wait 20ms each for a token
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected batches to be rate limited, took %v", elapsed)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := range 40 {
+		d := backoff(attempt, 100*time.Millisecond, time.Second)
+		if d <= 0 || d > time.Second {
+			t.Errorf("attempt %d: backoff %v out of range", attempt, d)
+		}
+		if attempt == 0 && d > 100*time.Millisecond {
+			t.Errorf("attempt 0: backoff %v exceeds the base delay", d)
+		}
+	}
+}
+
+func TestRunBatchParallel_Partial(t *testing.T) {
+	items := []string{"192.168.0.1", "192.168.0.2", "192.168.0.3"}
+	opts := BatchOptions{BatchSize: 1, MaxParallel: 1, Partial: true}
+
+	got, err := RunBatchParallel(context.Background(), items, opts, makeProcessWithErrorIP("192.168.0.2"))
+
+	var batchErr *BatchError[string]
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a *BatchError, got: %v", err)
+	}
+	if batchErr.Total != 3 || len(batchErr.Failures) != 1 {
+		t.Fatalf("expected 1 of 3 batches to fail, got %d of %d", len(batchErr.Failures), batchErr.Total)
+	}
+	if !reflect.DeepEqual(batchErr.Failures[0].Items, []string{"192.168.0.2"}) {
+		t.Errorf("unexpected failed items %v", batchErr.Failures[0].Items)
+	}
+	if batchErr.Error() != "1 of 3 batches failed: error from first batch" {
+		t.Errorf("unexpected error message %q", batchErr.Error())
+	}
+
+	var gotKeys []string
+	for k := range got {
+		gotKeys = append(gotKeys, k)
+	}
+	sort.Strings(gotKeys)
+	if !reflect.DeepEqual(gotKeys, []string{"192.168.0.1", "192.168.0.3"}) {
+		t.Errorf("expected the successful batches to be kept, got %v", gotKeys)
+	}
+}
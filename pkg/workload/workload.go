@@ -0,0 +1,196 @@
+// Package workload resolves pods to their top-level controllers and groups security group information by controller.
+package workload
+
+import (
+	"sort"
+	"strings"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+// Supported controller kinds
+const (
+	KindDeployment  = "Deployment"
+	KindReplicaSet  = "ReplicaSet"
+	KindStatefulSet = "StatefulSet"
+	KindDaemonSet   = "DaemonSet"
+	KindJob         = "Job"
+	KindCronJob     = "CronJob"
+)
+
+// Ref identifies a workload controller
+type Ref struct {
+	Kind      string `json:"kind" yaml:"kind"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Name      string `json:"name" yaml:"name"`
+}
+
+// String returns the ref in kind/namespace/name form
+func (r Ref) String() string {
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+// Resolver walks owner references from pods up to their top-level controller
+type Resolver struct {
+	replicaSets map[string]*metav1.OwnerReference
+	jobs        map[string]*metav1.OwnerReference
+}
+
+// NewResolver creates a Resolver from the intermediate controllers that may sit between a pod and its top-level owner
+func NewResolver(replicaSets []appsv1.ReplicaSet, jobs []batchv1.Job) *Resolver {
+	r := &Resolver{
+		replicaSets: make(map[string]*metav1.OwnerReference, len(replicaSets)),
+		jobs:        make(map[string]*metav1.OwnerReference, len(jobs)),
+	}
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		r.replicaSets[rs.Namespace+"/"+rs.Name] = metav1.GetControllerOf(rs)
+	}
+	for i := range jobs {
+		job := &jobs[i]
+		r.jobs[job.Namespace+"/"+job.Name] = metav1.GetControllerOf(job)
+	}
+	return r
+}
+
+// OwnerOf returns the top-level controller of the pod, or false if the pod is not managed by a controller
+func (r *Resolver) OwnerOf(pod *corev1.Pod) (Ref, bool) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return Ref{}, false
+	}
+
+	ref := Ref{Kind: owner.Kind, Namespace: pod.Namespace, Name: owner.Name}
+	var parent *metav1.OwnerReference
+	switch owner.Kind {
+	case KindReplicaSet:
+		parent = r.replicaSets[pod.Namespace+"/"+owner.Name]
+	case KindJob:
+		parent = r.jobs[pod.Namespace+"/"+owner.Name]
+	}
+	if parent != nil && (parent.Kind == KindDeployment || parent.Kind == KindCronJob) {
+		ref.Kind = parent.Kind
+		ref.Name = parent.Name
+	}
+	return ref, true
+}
+
+// SecurityGroupSet is a distinct combination of security groups shared by one or more replicas
+type SecurityGroupSet struct {
+	SecurityGroups  []types.SecurityGroup
+	AttachmentLevel string
+	Pods            []string
+}
+
+// key identifies the security groups and attachment level of the set
+func (s SecurityGroupSet) key() string {
+	return s.AttachmentLevel + "/" + strings.Join(s.GroupIDs(), ",")
+}
+
+// GroupIDs returns the sorted security group IDs of the set
+func (s SecurityGroupSet) GroupIDs() []string {
+	ids := make([]string, 0, len(s.SecurityGroups))
+	for _, sg := range s.SecurityGroups {
+		ids = append(ids, awsSDK.ToString(sg.GroupId))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SecurityGroupInfo represents the security groups carried by the replicas of a workload controller
+type SecurityGroupInfo struct {
+	Workload          Ref
+	SecurityGroupSets []SecurityGroupSet
+	// Unresolved are the replicas whose ENI or security groups could not be found, such as pending
+	// or completed pods
+	Unresolved []aws.PodSecurityGroupInfo
+}
+
+// Replicas returns the number of resolved replicas across all security group sets
+func (i SecurityGroupInfo) Replicas() int {
+	n := 0
+	for _, set := range i.SecurityGroupSets {
+		n += len(set.Pods)
+	}
+	return n
+}
+
+// Consistent reports whether all resolved replicas of the workload carry the same security groups
+func (i SecurityGroupInfo) Consistent() bool {
+	return len(i.SecurityGroupSets) <= 1
+}
+
+// Group groups pod security group information by workload. Every workload in refs appears in the result,
+// even when none of its pods could be resolved. Pods that are not mapped are listed as unresolved
+// rather than as a set without security groups. Sets are ordered by replica count, largest first.
+func Group(refs []Ref, infos []aws.PodSecurityGroupInfo, resolver *Resolver) []SecurityGroupInfo {
+	index := make(map[Ref]int, len(refs))
+	result := make([]SecurityGroupInfo, 0, len(refs))
+	for _, ref := range refs {
+		if _, ok := index[ref]; ok {
+			continue
+		}
+		index[ref] = len(result)
+		result = append(result, SecurityGroupInfo{Workload: ref})
+	}
+
+	for _, info := range infos {
+		ref, ok := resolver.OwnerOf(&info.Pod)
+		if !ok {
+			continue
+		}
+		i, ok := index[ref]
+		if !ok {
+			continue
+		}
+		if !info.Mapped() {
+			result[i].Unresolved = append(result[i].Unresolved, info)
+			continue
+		}
+		result[i].SecurityGroupSets = addToSet(result[i].SecurityGroupSets, info)
+	}
+
+	for i := range result {
+		sets := result[i].SecurityGroupSets
+		for _, set := range sets {
+			sort.Strings(set.Pods)
+		}
+		sort.SliceStable(result[i].Unresolved, func(a, b int) bool {
+			return result[i].Unresolved[a].Pod.Name < result[i].Unresolved[b].Pod.Name
+		})
+		sort.SliceStable(sets, func(a, b int) bool {
+			return len(sets[a].Pods) > len(sets[b].Pods)
+		})
+	}
+
+	sort.SliceStable(result, func(a, b int) bool {
+		if result[a].Workload.Namespace != result[b].Workload.Namespace {
+			return result[a].Workload.Namespace < result[b].Workload.Namespace
+		}
+		return result[a].Workload.Name < result[b].Workload.Name
+	})
+	return result
+}
+
+// addToSet adds the pod to the set matching its security groups and attachment level, creating a
+// new set if none matches. Replicas with the same security groups attached to their own ENI and to
+// the ENI of their node are in different sets, so the workload is reported inconsistent.
+func addToSet(sets []SecurityGroupSet, info aws.PodSecurityGroupInfo) []SecurityGroupSet {
+	candidate := SecurityGroupSet{SecurityGroups: info.SecurityGroups, AttachmentLevel: info.AttachmentLevel}
+	key := candidate.key()
+	for i := range sets {
+		if sets[i].key() == key {
+			sets[i].Pods = append(sets[i].Pods, info.Pod.Name)
+			return sets
+		}
+	}
+	candidate.Pods = []string{info.Pod.Name}
+	return append(sets, candidate)
+}
@@ -0,0 +1,166 @@
+package workload
+
+import (
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/naka-gawa/kubectl-sgmap/pkg/aws"
+)
+
+func controllerRef(kind, name string) []metav1.OwnerReference {
+	isController := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &isController}}
+}
+
+func newPod(name, ownerKind, ownerName string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			OwnerReferences: controllerRef(ownerKind, ownerName),
+		},
+	}
+}
+
+func TestResolver_OwnerOf(t *testing.T) {
+	resolver := NewResolver(
+		[]appsv1.ReplicaSet{
+			{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default", OwnerReferences: controllerRef(KindDeployment, "web")}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "bare-rs", Namespace: "default"}},
+		},
+		[]batchv1.Job{
+			{ObjectMeta: metav1.ObjectMeta{Name: "backup-123", Namespace: "default", OwnerReferences: controllerRef(KindCronJob, "backup")}},
+		},
+	)
+
+	testCases := []struct {
+		name     string
+		pod      corev1.Pod
+		expected Ref
+		found    bool
+	}{
+		{"deployment", newPod("web-abc-1", KindReplicaSet, "web-abc"), Ref{KindDeployment, "default", "web"}, true},
+		{"bare replicaset", newPod("bare-rs-1", KindReplicaSet, "bare-rs"), Ref{KindReplicaSet, "default", "bare-rs"}, true},
+		{"statefulset", newPod("db-0", KindStatefulSet, "db"), Ref{KindStatefulSet, "default", "db"}, true},
+		{"cronjob", newPod("backup-123-x", KindJob, "backup-123"), Ref{KindCronJob, "default", "backup"}, true},
+		{"standalone job", newPod("migrate-x", KindJob, "migrate"), Ref{KindJob, "default", "migrate"}, true},
+		{"no owner", corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bare"}}, Ref{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ref, ok := resolver.OwnerOf(&tc.pod)
+			assert.Equal(t, tc.found, ok)
+			assert.Equal(t, tc.expected, ref)
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	resolver := NewResolver([]appsv1.ReplicaSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default", OwnerReferences: controllerRef(KindDeployment, "web")}},
+	}, nil)
+
+	sgA := types.SecurityGroup{GroupId: awsSDK.String("sg-a")}
+	sgB := types.SecurityGroup{GroupId: awsSDK.String("sg-b")}
+	infos := []aws.PodSecurityGroupInfo{
+		{Pod: newPod("web-abc-1", KindReplicaSet, "web-abc"), SecurityGroups: []types.SecurityGroup{sgA, sgB}, AttachmentLevel: "pod", ENI: "eni-1"},
+		{Pod: newPod("web-abc-2", KindReplicaSet, "web-abc"), SecurityGroups: []types.SecurityGroup{sgB, sgA}, AttachmentLevel: "pod", ENI: "eni-2"},
+		{Pod: newPod("web-abc-3", KindReplicaSet, "web-abc"), SecurityGroups: []types.SecurityGroup{sgA}, AttachmentLevel: "node", ENI: "eni-3"},
+		{Pod: newPod("other-1", KindStatefulSet, "other"), ENI: "eni-4"},
+	}
+	refs := []Ref{
+		{KindDeployment, "default", "web"},
+		{KindDeployment, "default", "idle"},
+	}
+
+	result := Group(refs, infos, resolver)
+
+	assert.Len(t, result, 2)
+	assert.Equal(t, "idle", result[0].Workload.Name)
+	assert.Empty(t, result[0].SecurityGroupSets)
+	assert.True(t, result[0].Consistent())
+
+	web := result[1]
+	assert.Equal(t, 3, web.Replicas())
+	assert.False(t, web.Consistent())
+	assert.Len(t, web.SecurityGroupSets, 2)
+	assert.Equal(t, []string{"sg-a", "sg-b"}, web.SecurityGroupSets[0].GroupIDs())
+	assert.Equal(t, []string{"web-abc-1", "web-abc-2"}, web.SecurityGroupSets[0].Pods)
+	assert.Equal(t, []string{"web-abc-3"}, web.SecurityGroupSets[1].Pods)
+}
+
+func TestGroup_AttachmentLevels(t *testing.T) {
+	resolver := NewResolver([]appsv1.ReplicaSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default", OwnerReferences: controllerRef(KindDeployment, "web")}},
+	}, nil)
+
+	// The same security groups on the pod ENI of one replica and the node ENI of another
+	sgA := types.SecurityGroup{GroupId: awsSDK.String("sg-a")}
+	infos := []aws.PodSecurityGroupInfo{
+		{Pod: newPod("web-abc-1", KindReplicaSet, "web-abc"), SecurityGroups: []types.SecurityGroup{sgA}, AttachmentLevel: "pod", ENI: "eni-1"},
+		{Pod: newPod("web-abc-2", KindReplicaSet, "web-abc"), SecurityGroups: []types.SecurityGroup{sgA}, AttachmentLevel: "node", ENI: "eni-2"},
+		{Pod: newPod("web-abc-3", KindReplicaSet, "web-abc"), SecurityGroups: []types.SecurityGroup{sgA}, AttachmentLevel: "pod", ENI: "eni-3"},
+	}
+
+	result := Group([]Ref{{KindDeployment, "default", "web"}}, infos, resolver)
+
+	assert.Len(t, result, 1)
+	web := result[0]
+	assert.False(t, web.Consistent())
+	assert.Len(t, web.SecurityGroupSets, 2)
+	assert.Equal(t, "pod", web.SecurityGroupSets[0].AttachmentLevel)
+	assert.Equal(t, []string{"web-abc-1", "web-abc-3"}, web.SecurityGroupSets[0].Pods)
+	assert.Equal(t, "node", web.SecurityGroupSets[1].AttachmentLevel)
+	assert.Equal(t, []string{"web-abc-2"}, web.SecurityGroupSets[1].Pods)
+}
+
+func TestGroup_Unresolved(t *testing.T) {
+	resolver := NewResolver([]appsv1.ReplicaSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default", OwnerReferences: controllerRef(KindDeployment, "web")}},
+	}, []batchv1.Job{
+		{ObjectMeta: metav1.ObjectMeta{Name: "report-1", Namespace: "default", OwnerReferences: controllerRef(KindCronJob, "report")}},
+	})
+
+	sgA := types.SecurityGroup{GroupId: awsSDK.String("sg-a")}
+	infos := []aws.PodSecurityGroupInfo{
+		{Pod: newPod("web-abc-1", KindReplicaSet, "web-abc"), SecurityGroups: []types.SecurityGroup{sgA}, AttachmentLevel: "pod", ENI: "eni-1", Status: aws.StatusMapped},
+		{Pod: newPod("web-abc-2", KindReplicaSet, "web-abc"), SecurityGroups: []types.SecurityGroup{sgA}, AttachmentLevel: "pod", ENI: "eni-2", Status: aws.StatusMapped},
+		// A replica still pending during a rollout
+		{Pod: newPod("web-abc-3", KindReplicaSet, "web-abc"), Status: aws.StatusNotRunning, Reason: "pod phase is Pending"},
+		// A replica whose security group lookup failed, leaving it without its security groups
+		{Pod: newPod("web-abc-4", KindReplicaSet, "web-abc"), AttachmentLevel: "pod", ENI: "eni-4", Status: aws.StatusLookupFailed, Reason: "failed to look up security groups sg-a: throttled"},
+		// A completed run of the CronJob
+		{Pod: newPod("report-1-x", KindJob, "report-1"), Status: aws.StatusNotRunning, Reason: "pod phase is Succeeded"},
+	}
+	refs := []Ref{
+		{KindDeployment, "default", "web"},
+		{KindCronJob, "default", "report"},
+	}
+
+	result := Group(refs, infos, resolver)
+
+	assert.Len(t, result, 2)
+	report := result[0]
+	assert.Equal(t, "report", report.Workload.Name)
+	assert.Empty(t, report.SecurityGroupSets)
+	assert.True(t, report.Consistent())
+	assert.Len(t, report.Unresolved, 1)
+	assert.Equal(t, "report-1-x", report.Unresolved[0].Pod.Name)
+
+	web := result[1]
+	assert.True(t, web.Consistent())
+	assert.Equal(t, 2, web.Replicas())
+	assert.Len(t, web.SecurityGroupSets, 1)
+	assert.Equal(t, []string{"web-abc-1", "web-abc-2"}, web.SecurityGroupSets[0].Pods)
+	assert.Len(t, web.Unresolved, 2)
+	assert.Equal(t, "web-abc-3", web.Unresolved[0].Pod.Name)
+	assert.Equal(t, "web-abc-4", web.Unresolved[1].Pod.Name)
+}